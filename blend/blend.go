@@ -0,0 +1,181 @@
+// Package blend mixes vibes, so a world can move from one vibe to another
+// gradually instead of switching at once.
+//
+// A blend of weighted vibes takes the weighted mean of their energy and
+// sensor targets, mixes their colors in the OKLab color space (where equal
+// steps look like equal changes), and picks the mood with the most weight.
+package blend
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// ErrNothingToBlend is returned when no vibe has a positive weight
+var ErrNothingToBlend = errors.New("blend: no vibe with a positive weight")
+
+// Part is a vibe and its share of a blend. Weights need not add up to one.
+type Part struct {
+	Vibe   models.Vibe
+	Weight float64
+}
+
+// Vibes blends the parts with a positive weight. The result has the IDs and
+// names of the parts joined with "+", the sharing settings of the heaviest
+// part, and no version.
+func Vibes(parts ...Part) (models.Vibe, error) {
+	var used []Part
+	total := 0.0
+	for _, part := range parts {
+		if math.IsNaN(part.Weight) || math.IsInf(part.Weight, 0) || part.Weight < 0 {
+			return models.Vibe{}, fmt.Errorf("blend: invalid weight %v for vibe %q", part.Weight, part.Vibe.ID)
+		}
+		if part.Weight > 0 {
+			used = append(used, part)
+			total += part.Weight
+		}
+	}
+	if len(used) == 0 {
+		return models.Vibe{}, ErrNothingToBlend
+	}
+	if len(used) == 1 {
+		return used[0].Vibe, nil
+	}
+
+	weights := make([]float64, len(used))
+	heaviest := 0
+	ids := make([]string, len(used))
+	names := make([]string, len(used))
+	shares := make([]string, len(used))
+	for i, part := range used {
+		weights[i] = part.Weight / total
+		if weights[i] > weights[heaviest] {
+			heaviest = i
+		}
+		ids[i] = part.Vibe.ID
+		names[i] = part.Vibe.Name
+		shares[i] = fmt.Sprintf("%s %.0f%%", part.Vibe.ID, weights[i]*100)
+	}
+
+	blended := models.Vibe{
+		ID:          strings.Join(ids, "+"),
+		Name:        strings.Join(names, " + "),
+		Description: "Blend of " + strings.Join(shares, ", "),
+		Sharing:     used[heaviest].Vibe.Sharing,
+	}
+	for i, part := range used {
+		blended.Energy += weights[i] * part.Vibe.Energy
+	}
+	blended.Mood = mood(used, weights)
+	blended.Colors = colors(used, weights)
+	blended.SensorData = sensorData(used, weights)
+	return blended, nil
+}
+
+// Between returns the vibe a fraction t of the way from one vibe to
+// another. t is clamped to [0, 1]; at the ends the vibes come back unchanged.
+func Between(from, to models.Vibe, t float64) models.Vibe {
+	if math.IsNaN(t) || t <= 0 {
+		return from
+	}
+	if t >= 1 {
+		return to
+	}
+	blended, _ := Vibes(Part{Vibe: from, Weight: 1 - t}, Part{Vibe: to, Weight: t})
+	return blended
+}
+
+// Smoothstep eases a linear progress in [0, 1] so a transition starts and
+// ends gently
+func Smoothstep(t float64) float64 {
+	t = math.Max(0, math.Min(1, t))
+	return t * t * (3 - 2*t)
+}
+
+// mood returns the mood with the most weight; ties go to the earliest part
+func mood(parts []Part, weights []float64) string {
+	totals := make(map[string]float64)
+	best := ""
+	for i, part := range parts {
+		if part.Vibe.Mood == "" {
+			continue
+		}
+		totals[part.Vibe.Mood] += weights[i]
+		if best == "" || totals[part.Vibe.Mood] > totals[best] {
+			best = part.Vibe.Mood
+		}
+	}
+	return best
+}
+
+// colors mixes the palettes position by position; a shorter palette repeats
+// to the length of the longest one. Colors that do not parse are skipped.
+func colors(parts []Part, weights []float64) []string {
+	longest := 0
+	for _, part := range parts {
+		if len(part.Vibe.Colors) > longest {
+			longest = len(part.Vibe.Colors)
+		}
+	}
+	if longest == 0 {
+		return nil
+	}
+
+	mixed := make([]string, 0, longest)
+	for position := 0; position < longest; position++ {
+		var sum oklab
+		total := 0.0
+		withAlpha := false
+		for i, part := range parts {
+			palette := part.Vibe.Colors
+			if len(palette) == 0 {
+				continue
+			}
+			c, hasAlpha, ok := parseHex(palette[position%len(palette)])
+			if !ok {
+				continue
+			}
+			withAlpha = withAlpha || hasAlpha
+			lab := c.toOKLab()
+			sum.L += weights[i] * lab.L
+			sum.A += weights[i] * lab.A
+			sum.B += weights[i] * lab.B
+			sum.alpha += weights[i] * lab.alpha
+			total += weights[i]
+		}
+		if total == 0 {
+			continue
+		}
+		sum.L /= total
+		sum.A /= total
+		sum.B /= total
+		sum.alpha /= total
+		mixed = append(mixed, sum.toRGB().hex(withAlpha))
+	}
+	return mixed
+}
+
+// sensorData takes the weighted mean of each reading, fixed field or
+// channel, over the parts that have it
+func sensorData(parts []Part, weights []float64) models.SensorData {
+	sums := make(map[string]float64)
+	totals := make(map[string]float64)
+	for i, part := range parts {
+		for name, v := range part.Vibe.SensorData.Readings() {
+			sums[name] += weights[i] * v
+			totals[name] += weights[i]
+		}
+	}
+
+	var blended models.SensorData
+	for name, total := range totals {
+		if total > 0 {
+			blended.Set(name, sums[name]/total)
+		}
+	}
+	return blended
+}
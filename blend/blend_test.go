@@ -0,0 +1,101 @@
+package blend
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+var (
+	calm = models.Vibe{
+		ID: "calm", Name: "Calm", Energy: 0.2, Mood: models.MoodCalm,
+		Colors:     []string{"#0000FF", "#FFFFFF"},
+		SensorData: models.SensorData{Temperature: floatPtr(20), Light: floatPtr(100)},
+		Sharing:    models.SharingSettings{IsPublic: true},
+	}
+	energetic = models.Vibe{
+		ID: "energetic", Name: "Energetic", Energy: 0.9, Mood: models.MoodEnergetic,
+		Colors:     []string{"#FFFF00"},
+		SensorData: models.SensorData{Temperature: floatPtr(24), Sound: floatPtr(70)},
+	}
+)
+
+func TestVibes(t *testing.T) {
+	blended, err := Vibes(Part{Vibe: calm, Weight: 3}, Part{Vibe: energetic, Weight: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "calm+energetic", blended.ID)
+	assert.Equal(t, "Calm + Energetic", blended.Name)
+	assert.InDelta(t, 0.375, blended.Energy, 1e-9)
+	assert.Equal(t, models.MoodCalm, blended.Mood)
+	assert.True(t, blended.Sharing.IsPublic, "Expected the heaviest part's sharing")
+
+	// Readings average over the parts that have them
+	assert.InDelta(t, 21, *blended.SensorData.Temperature, 1e-9)
+	assert.InDelta(t, 100, *blended.SensorData.Light, 1e-9)
+	assert.InDelta(t, 70, *blended.SensorData.Sound, 1e-9)
+	assert.Nil(t, blended.SensorData.Humidity)
+
+	// The shorter palette repeats, and the mixes are valid colors
+	assert.Len(t, blended.Colors, 2)
+	for _, color := range blended.Colors {
+		assert.True(t, models.IsHexColor(color), color)
+	}
+	assert.NoError(t, blended.Validate())
+}
+
+func TestVibesRejectsBadWeights(t *testing.T) {
+	_, err := Vibes(Part{Vibe: calm, Weight: 0})
+	assert.Equal(t, ErrNothingToBlend, err)
+	_, err = Vibes(Part{Vibe: calm, Weight: -1}, Part{Vibe: energetic, Weight: 1})
+	assert.Error(t, err)
+	_, err = Vibes(Part{Vibe: calm, Weight: math.NaN()})
+	assert.Error(t, err)
+
+	single, err := Vibes(Part{Vibe: calm, Weight: 2}, Part{Vibe: energetic, Weight: 0})
+	assert.NoError(t, err)
+	assert.Equal(t, calm.ID, single.ID)
+}
+
+func TestBetween(t *testing.T) {
+	assert.Equal(t, calm, Between(calm, energetic, 0))
+	assert.Equal(t, energetic, Between(calm, energetic, 1.5))
+
+	halfway := Between(calm, energetic, 0.5)
+	assert.InDelta(t, 0.55, halfway.Energy, 1e-9)
+	// Equal weights tie on mood, which goes to the first vibe
+	assert.Equal(t, models.MoodCalm, halfway.Mood)
+	assert.Equal(t, models.MoodEnergetic, Between(calm, energetic, 0.6).Mood)
+}
+
+func TestOKLabRoundTrip(t *testing.T) {
+	for _, hex := range []string{"#000000", "#FFFFFF", "#6A98DC", "#FF7043", "#12345678"} {
+		c, hasAlpha, ok := parseHex(hex)
+		assert.True(t, ok, hex)
+		assert.Equal(t, hex, c.toOKLab().toRGB().hex(hasAlpha))
+	}
+	c, _, ok := parseHex("#fa0")
+	assert.True(t, ok)
+	assert.Equal(t, "#FFAA00", c.hex(false))
+	for _, bad := range []string{"FFAA00", "#FFAA0", "#GGGGGG"} {
+		_, _, ok := parseHex(bad)
+		assert.False(t, ok, bad)
+	}
+
+	// Mixing blue and yellow in OKLab goes through a light, not a muddy grey
+	mixed := colors([]Part{{Vibe: models.Vibe{Colors: []string{"#0000FF"}}}, {Vibe: models.Vibe{Colors: []string{"#FFFF00"}}}}, []float64{0.5, 0.5})
+	c, _, _ = parseHex(mixed[0])
+	assert.Greater(t, c.toOKLab().L, 0.6)
+}
+
+func TestSmoothstep(t *testing.T) {
+	assert.Equal(t, 0.0, Smoothstep(-1))
+	assert.Equal(t, 0.5, Smoothstep(0.5))
+	assert.Equal(t, 1.0, Smoothstep(2))
+	assert.Less(t, Smoothstep(0.1), 0.1)
+}
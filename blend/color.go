@@ -0,0 +1,103 @@
+package blend
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// rgb is a color in sRGB with components in [0, 1]
+type rgb struct {
+	R, G, B, alpha float64
+}
+
+// oklab is a color in the OKLab perceptual color space
+type oklab struct {
+	L, A, B, alpha float64
+}
+
+// parseHex reads a #RGB, #RRGGBB or #RRGGBBAA color and reports whether it
+// had an alpha channel
+func parseHex(s string) (rgb, bool, bool) {
+	digits := strings.TrimPrefix(s, "#")
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	if len(digits) != 6 && len(digits) != 8 || len(digits) == len(s) {
+		return rgb{}, false, false
+	}
+	n, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return rgb{}, false, false
+	}
+
+	hasAlpha := len(digits) == 8
+	alpha := 1.0
+	if hasAlpha {
+		alpha = float64(n&0xFF) / 255
+		n >>= 8
+	}
+	return rgb{
+		R:     float64(n>>16&0xFF) / 255,
+		G:     float64(n>>8&0xFF) / 255,
+		B:     float64(n&0xFF) / 255,
+		alpha: alpha,
+	}, hasAlpha, true
+}
+
+// hex formats c as #RRGGBB, or #RRGGBBAA with alpha
+func (c rgb) hex(withAlpha bool) string {
+	channel := func(v float64) int {
+		return int(math.Round(math.Max(0, math.Min(1, v)) * 255))
+	}
+	if withAlpha {
+		return fmt.Sprintf("#%02X%02X%02X%02X", channel(c.R), channel(c.G), channel(c.B), channel(c.alpha))
+	}
+	return fmt.Sprintf("#%02X%02X%02X", channel(c.R), channel(c.G), channel(c.B))
+}
+
+func toLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func fromLinear(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// toOKLab converts c, using the matrices published with OKLab
+func (c rgb) toOKLab() oklab {
+	r, g, b := toLinear(c.R), toLinear(c.G), toLinear(c.B)
+
+	l := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*b)
+	m := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*b)
+	s := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*b)
+
+	return oklab{
+		L:     0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		A:     1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		B:     0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+		alpha: c.alpha,
+	}
+}
+
+// toRGB converts c back to sRGB; out-of-gamut components are clamped by hex
+func (c oklab) toRGB() rgb {
+	l := c.L + 0.3963377774*c.A + 0.2158037573*c.B
+	m := c.L - 0.1055613458*c.A - 0.0638541728*c.B
+	s := c.L - 0.0894841775*c.A - 1.2914855480*c.B
+	l, m, s = l*l*l, m*m*m, s*s*s
+
+	return rgb{
+		R:     fromLinear(+4.0767416621*l - 3.3077115913*m + 0.2309699292*s),
+		G:     fromLinear(-1.2684380046*l + 2.6097574011*m - 0.3413193965*s),
+		B:     fromLinear(-0.0041960863*l - 0.7034186147*m + 1.7076147010*s),
+		alpha: c.alpha,
+	}
+}
@@ -3,15 +3,18 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/bmorphism/vibespace-mcp-go/models"
 	"github.com/bmorphism/vibespace-mcp-go/repository"
 	"github.com/bmorphism/vibespace-mcp-go/rpcmethods"
+	"github.com/bmorphism/vibespace-mcp-go/sensors"
 	"github.com/bmorphism/vibespace-mcp-go/streaming"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -20,17 +23,39 @@ import (
 const (
 	serverPort         = 8080
 	startupMessageVibe = "Experience running at http://localhost:8080 - Ready to vibe!"
+	sensorReadingsPath = "/sensors/readings"
 )
 
+var summarizeChildrenFlag = flag.Bool("summarize-children", os.Getenv("VIBESPACE_SUMMARIZE_CHILDREN") == "1",
+	"add a summary of their child worlds to the moments of parent worlds (env VIBESPACE_SUMMARIZE_CHILDREN=1)")
+
+var deltaStreamingFlag = flag.Bool("delta-streaming", os.Getenv("VIBESPACE_DELTA_STREAMING") == "1",
+	"publish periodic moments only when they change, as deltas between keyframes (env VIBESPACE_DELTA_STREAMING=1)")
+
+var jetStreamFlag = flag.Bool("jetstream", os.Getenv("VIBESPACE_JETSTREAM") == "1",
+	"keep a day of published moments and vibe updates in a JetStream stream for replay (env VIBESPACE_JETSTREAM=1)")
+
+var natsCommandsFlag = flag.Bool("nats-commands", os.Getenv("VIBESPACE_NATS_COMMANDS") == "1",
+	"run world commands sent to {streamID}.cmd.world.* once connected to NATS (env VIBESPACE_NATS_COMMANDS=1)")
+
+var natsQueriesFlag = flag.Bool("nats-queries", os.Getenv("VIBESPACE_NATS_QUERIES") == "1",
+	"answer world and moment queries sent to {streamID}.query.> once connected to NATS (env VIBESPACE_NATS_QUERIES=1)")
+
 func main() {
-	// Create a repository
-	repo := repository.NewRepository()
+	flag.Parse()
 
-	// Add some initial vibes
-	addInitialVibes(repo)
+	// Create a repository using the configured backend
+	repo, closeRepo, err := openRepository(*storeFlag, *dataDirFlag, *dbFlag, *seedFlag)
+	if err != nil {
+		log.Fatalf("Failed to open %s repository: %v", *storeFlag, err)
+	}
+	defer closeRepo()
 
-	// Add some initial worlds
-	addInitialWorlds(repo)
+	// Keep the recent sensor readings of each world for the moments, and
+	// their history for queries over time
+	sensorStore := sensors.NewStore(sensors.DefaultWindow, sensors.DefaultMaxReadings)
+	sensorStore.SetHistory(sensors.NewTimeSeries(sensors.DefaultRetention))
+	sensorIngester := sensors.NewIngester(sensorStore, repo)
 
 	// Set up NATS streaming configuration
 	streamingConfig := &streaming.StreamingConfig{
@@ -40,6 +65,17 @@ func main() {
 		StreamID:       "preworm",
 		StreamInterval: 5 * time.Second,
 		AutoStart:      false,
+		SummarizeChildren: *summarizeChildrenFlag,
+		Sensors:        sensorStore,
+		Commands:       *natsCommandsFlag,
+		Queries:        *natsQueriesFlag,
+	}
+	if *deltaStreamingFlag {
+		deltaConfig := streaming.DefaultDeltaConfig()
+		streamingConfig.Delta = &deltaConfig
+	}
+	if *jetStreamFlag {
+		streamingConfig.JetStream = &streaming.JetStreamConfig{MaxAge: 24 * time.Hour}
 	}
 
 	// Start the streaming service
@@ -52,8 +88,11 @@ func main() {
 	mcpServer := server.NewMCPServer("vibespace-mcp", "1.0.0")
 	
 	// Create categorical tools for WrapPreview integration
-	categoricalTools := rpcmethods.NewCategoricalTools()
+	categoricalTools := rpcmethods.NewCategoricalToolsWithRepository(repo)
 	rpcmethods.RegisterCategoricalTools(mcpServer, categoricalTools)
+	rpcmethods.RegisterSchemaResources(mcpServer)
+	rpcmethods.RegisterSensorTools(mcpServer, sensorIngester)
+	rpcmethods.RegisterSensorHistory(mcpServer, repo, sensorStore.History())
 
 	// Get the streaming tool methods and register them
 	fmt.Println("Registering streaming tools:")
@@ -61,7 +100,7 @@ func main() {
 	// Register streaming tools with the MCPServer
 	startStreamingTool := mcp.NewTool("streaming_startStreaming", func(t *mcp.Tool) {
 		t.Description = "Start streaming world moments"
-	})
+	}, rpcmethods.WithInputSchema("streaming_startStreaming"))
 	mcpServer.AddTool(startStreamingTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Convert JSON-RPC request to our internal format
 		interval := 0
@@ -95,7 +134,7 @@ func main() {
 	// Register stop streaming tool
 	stopStreamingTool := mcp.NewTool("streaming_stopStreaming", func(t *mcp.Tool) {
 		t.Description = "Stop streaming world moments"
-	})
+	}, rpcmethods.WithInputSchema("streaming_stopStreaming"))
 	mcpServer.AddTool(stopStreamingTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		response, err := streamingTools.StopStreaming()
 		
@@ -115,7 +154,7 @@ func main() {
 	// Register status tool
 	statusTool := mcp.NewTool("streaming_status", func(t *mcp.Tool) {
 		t.Description = "Get current streaming status"
-	})
+	}, rpcmethods.WithInputSchema("streaming_status"))
 	mcpServer.AddTool(statusTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		status, err := streamingTools.Status()
 		
@@ -136,7 +175,7 @@ func main() {
 	// Register streamWorld tool
 	streamWorldTool := mcp.NewTool("streaming_streamWorld", func(t *mcp.Tool) {
 		t.Description = "Stream a single world moment"
-	})
+	}, rpcmethods.WithInputSchema("streaming_streamWorld"))
 	mcpServer.AddTool(streamWorldTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
 		var worldID, userID string
@@ -197,7 +236,7 @@ func main() {
 	// Register updateConfig tool
 	updateConfigTool := mcp.NewTool("streaming_updateConfig", func(t *mcp.Tool) {
 		t.Description = "Update streaming configuration"
-	})
+	}, rpcmethods.WithInputSchema("streaming_updateConfig"))
 	mcpServer.AddTool(updateConfigTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
 		config := &streaming.UpdateConfigRequest{}
@@ -239,43 +278,155 @@ func main() {
 		return mcp.NewToolResultText(resultText), nil
 	})
 	fmt.Println("  - streaming_updateConfig: Update streaming configuration")
+	
+	// Register the streaming interval tools
+	setIntervalTool := mcp.NewTool("streaming_setInterval", func(t *mcp.Tool) {
+		t.Description = "Set how often a world, or every world of a type, is streamed"
+	}, rpcmethods.WithInputSchema("streaming_setInterval"))
+	mcpServer.AddTool(setIntervalTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		request := &streaming.SetIntervalRequest{}
+		if args, ok := req.Params.Arguments.(map[string]interface{}); ok {
+			if worldID, ok := args["worldId"].(string); ok {
+				request.WorldID = worldID
+			}
+			if worldType, ok := args["worldType"].(string); ok {
+				request.WorldType = worldType
+			}
+			if interval, ok := args["interval"].(float64); ok {
+				request.Interval = int(interval)
+			}
+		}
+		
+		response, err := streamingTools.SetInterval(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if !response.Success {
+			return mcp.NewToolResultError(response.Message), nil
+		}
+		return mcp.NewToolResultText(response.Message), nil
+	})
+	fmt.Println("  - streaming_setInterval: Set the streaming interval of a world or world type")
+	
+	listIntervalsTool := mcp.NewTool("streaming_listIntervals", func(t *mcp.Tool) {
+		t.Description = "List the streaming intervals of worlds and world types"
+	}, rpcmethods.WithInputSchema("streaming_listIntervals"))
+	mcpServer.AddTool(listIntervalsTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		intervals, err := streamingTools.ListIntervals()
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		
+		intervalsJSON, err := json.Marshal(intervals)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error marshaling intervals: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(intervalsJSON)), nil
+	})
+	fmt.Println("  - streaming_listIntervals: List the streaming intervals of worlds and world types")
+	
+	clearIntervalTool := mcp.NewTool("streaming_clearInterval", func(t *mcp.Tool) {
+		t.Description = "Clear the streaming interval of a world or world type"
+	}, rpcmethods.WithInputSchema("streaming_clearInterval"))
+	mcpServer.AddTool(clearIntervalTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		request := &streaming.ClearIntervalRequest{}
+		if args, ok := req.Params.Arguments.(map[string]interface{}); ok {
+			if worldID, ok := args["worldId"].(string); ok {
+				request.WorldID = worldID
+			}
+			if worldType, ok := args["worldType"].(string); ok {
+				request.WorldType = worldType
+			}
+		}
+		
+		response, err := streamingTools.ClearInterval(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if !response.Success {
+			return mcp.NewToolResultError(response.Message), nil
+		}
+		return mcp.NewToolResultText(response.Message), nil
+	})
+	fmt.Println("  - streaming_clearInterval: Clear the streaming interval of a world or world type")
+	
+	// Register replay tool
+	replayTool := mcp.NewTool("streaming_replay", func(t *mcp.Tool) {
+		t.Description = "Replay published moments, deltas and vibe updates kept in JetStream"
+	}, rpcmethods.WithInputSchema("streaming_replay"))
+	mcpServer.AddTool(replayTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		request := &streaming.ReplayRequest{}
+		if args, ok := req.Params.Arguments.(map[string]interface{}); ok {
+			if worldID, ok := args["worldId"].(string); ok {
+				request.WorldID = worldID
+			}
+			if since, ok := args["since"].(float64); ok {
+				request.Since = int64(since)
+			}
+			if sequence, ok := args["sequence"].(float64); ok {
+				request.Sequence = uint64(sequence)
+			}
+			if limit, ok := args["limit"].(float64); ok {
+				request.Limit = int(limit)
+			}
+		}
+		
+		response, err := streamingTools.Replay(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if !response.Success {
+			return mcp.NewToolResultError(response.Message), nil
+		}
+		
+		replayJSON, err := json.Marshal(response.Messages)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error marshaling replayed messages: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(replayJSON)), nil
+	})
+	fmt.Println("  - streaming_replay: Replay published messages kept in JetStream")
 
 	// Register resource handlers from server wrapper
 	handler := rpcmethods.WrapMCPServer(mcpServer)
 
-	// Configure HTTP server
-	httpServer := &http.Server{
-		Addr: fmt.Sprintf(":%d", serverPort),
-		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Handle MCP RPC requests
-			if r.Method == http.MethodPost {
-				// Read the request body
-				body, err := io.ReadAll(r.Body)
-				if err != nil {
-					http.Error(w, fmt.Sprintf("Error reading request body: %v", err), http.StatusBadRequest)
-					return
-				}
-				defer r.Body.Close()
-				
-				// Process the request
-				response := handler.HandleMessage(r.Context(), body)
-				
-				// Marshal the response
-				responseJSON, err := json.Marshal(response)
-				if err != nil {
-					http.Error(w, fmt.Sprintf("Error marshaling response: %v", err), http.StatusInternalServerError)
-					return
-				}
-				
-				// Set content type and write response
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusOK)
-				w.Write(responseJSON)
-			} else {
-				w.WriteHeader(http.StatusMethodNotAllowed)
-				w.Write([]byte("Method not allowed"))
+	// Configure HTTP server: sensor readings have their own endpoint, and
+	// everything else is MCP
+	mux := http.NewServeMux()
+	mux.Handle(sensorReadingsPath, sensorIngester)
+	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Handle MCP RPC requests
+		if r.Method == http.MethodPost {
+			// Read the request body
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Error reading request body: %v", err), http.StatusBadRequest)
+				return
 			}
-		}),
+			defer r.Body.Close()
+			
+			// Process the request
+			response := handler.HandleMessage(r.Context(), body)
+			
+			// Marshal the response
+			responseJSON, err := json.Marshal(response)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Error marshaling response: %v", err), http.StatusInternalServerError)
+				return
+			}
+			
+			// Set content type and write response
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(responseJSON)
+		} else {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte("Method not allowed"))
+		}
+	}))
+	httpServer := &http.Server{
+		Addr:    fmt.Sprintf(":%d", serverPort),
+		Handler: mux,
 	}
 
 	// Start the server
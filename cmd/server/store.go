@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+	_ "modernc.org/sqlite" // registers the pure Go "sqlite" driver, so builds need no cgo
+)
+
+// Repository backends selectable with -store / VIBESPACE_STORE
+const (
+	storeMemory = "memory"
+	storeFile   = "file"
+	storeSQLite = "sqlite"
+)
+
+var (
+	storeFlag   = flag.String("store", envOrDefault("VIBESPACE_STORE", storeMemory), "repository backend: memory, file or sqlite (env VIBESPACE_STORE)")
+	dataDirFlag = flag.String("data-dir", envOrDefault("VIBESPACE_DATA_DIR", "data"), "data directory for the file store (env VIBESPACE_DATA_DIR)")
+	dbFlag      = flag.String("db", envOrDefault("VIBESPACE_DB", "vibespace.db"), "database file for the sqlite store (env VIBESPACE_DB)")
+	seedFlag    = flag.String("seed", os.Getenv("VIBESPACE_SEED"), "bundle file to seed an empty store with instead of the built-in vibes and worlds (env VIBESPACE_SEED)")
+)
+
+// envOrDefault returns the value of the environment variable or the fallback if unset
+func envOrDefault(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// openRepository creates the configured repository backend and seeds it when
+// it is empty, from the bundle at seedPath if set or with the initial vibes
+// and worlds otherwise. The returned function releases any resources held by
+// the backend.
+func openRepository(store, dataDir, dbPath, seedPath string) (repository.VibeWorldRepository, func(), error) {
+	switch store {
+	case storeMemory:
+		// A seed bundle replaces the sample vibes and worlds instead of joining them
+		repo := repository.NewRepositoryWithSampleData(seedPath == "")
+		if err := seedRepository(repo, seedPath); err != nil {
+			return nil, nil, err
+		}
+		return repo, func() {}, nil
+
+	case storeFile:
+		repo, err := repository.NewFileRepository(dataDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Only seed a brand new store so restarts keep user changes
+		if len(repo.GetAllVibes()) == 0 && len(repo.GetAllWorlds()) == 0 {
+			if err := seedRepository(repo, seedPath); err != nil {
+				repo.Close()
+				return nil, nil, err
+			}
+		}
+		fmt.Printf("Using file store in %s\n", repo.Dir())
+		return repo, func() { repo.Close() }, nil
+
+	case storeSQLite:
+		db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", dbPath))
+		if err != nil {
+			return nil, nil, err
+		}
+		// SQLite allows a single writer; one connection avoids lock contention
+		db.SetMaxOpenConns(1)
+
+		repo, err := repository.NewSQLRepository(db)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		if len(repo.GetAllVibes()) == 0 && len(repo.GetAllWorlds()) == 0 {
+			if err := seedRepository(repo, seedPath); err != nil {
+				db.Close()
+				return nil, nil, err
+			}
+		}
+		fmt.Printf("Using sqlite store %s\n", dbPath)
+		return repo, func() { db.Close() }, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown store %q (expected %s, %s or %s)", store, storeMemory, storeFile, storeSQLite)
+	}
+}
+
+// seedRepository fills an empty repository from the bundle at seedPath, or
+// with the initial vibes and worlds when seedPath is empty
+func seedRepository(repo repository.VibeWorldRepository, seedPath string) error {
+	if seedPath == "" {
+		addInitialVibes(repo)
+		addInitialWorlds(repo)
+		return nil
+	}
+
+	file, err := os.Open(seedPath)
+	if err != nil {
+		return fmt.Errorf("failed to open seed bundle: %w", err)
+	}
+	defer file.Close()
+
+	bundle, err := repository.ReadBundle(file)
+	if err != nil {
+		return fmt.Errorf("failed to read seed bundle %s: %w", seedPath, err)
+	}
+	result, err := repo.ImportBundle(bundle, repository.ImportFail)
+	if err != nil {
+		return fmt.Errorf("failed to import seed bundle %s: %w", seedPath, err)
+	}
+	fmt.Printf("Seeded %d vibes and %d worlds from %s\n", result.Vibes.Created, result.Worlds.Created, seedPath)
+	return nil
+}
@@ -0,0 +1,84 @@
+package models
+
+import "strings"
+
+// Edge kinds describe how two adjacent worlds are connected
+const (
+	EdgeKindWall   = "wall"   // Physical spaces sharing a wall
+	EdgeKindDoor   = "door"   // Physical spaces people walk between
+	EdgeKindPortal = "portal" // Virtual link between any two worlds
+)
+
+// DefaultEdgeWeight is the weight of an edge stored without one
+const DefaultEdgeWeight = 1.0
+
+// EdgeKinds returns the known edge kinds
+func EdgeKinds() []string {
+	return []string{EdgeKindWall, EdgeKindDoor, EdgeKindPortal}
+}
+
+// WorldEdge connects two adjacent worlds. Edges are undirected: swapping From
+// and To gives the same edge. The weight says how strongly the worlds affect
+// each other, from barely (near 0) to fully (1).
+type WorldEdge struct {
+	From   string  `json:"from" jsonschema:"required,maxLength=128,pattern=^[^\\s/?#]+$"`
+	To     string  `json:"to" jsonschema:"required,maxLength=128,pattern=^[^\\s/?#]+$"`
+	Weight float64 `json:"weight,omitempty" jsonschema:"minimum=0,maximum=1" jsonschema_description:"Strength of the connection; 1 when omitted"`
+	Kind   string  `json:"kind,omitempty" jsonschema:"enum=wall,enum=door,enum=portal"`
+}
+
+// Other returns the end of the edge that is not id
+func (e WorldEdge) Other(id string) string {
+	if e.From == id {
+		return e.To
+	}
+	return e.From
+}
+
+// Oriented returns the edge oriented away from id, so that From is id
+func (e WorldEdge) Oriented(id string) WorldEdge {
+	if e.To == id {
+		e.From, e.To = e.To, e.From
+	}
+	return e
+}
+
+// Normalized returns the edge with its ends in ID order and its default
+// weight filled in, the form it is stored in
+func (e WorldEdge) Normalized() WorldEdge {
+	if e.To < e.From {
+		e.From, e.To = e.To, e.From
+	}
+	if e.Weight == 0 {
+		e.Weight = DefaultEdgeWeight
+	}
+	return e
+}
+
+// Validate checks an edge: two distinct usable world IDs, a weight above 0 up
+// to 1 (0 standing for the default) and a known kind, if any. It returns a
+// *ValidationError listing every problem.
+func (e WorldEdge) Validate() error {
+	f := &fieldErrors{}
+	f.id("from", e.From)
+	f.id("to", e.To)
+	if e.From != "" && e.From == e.To {
+		f.add("to", e.To, "must not be the same world as from")
+	}
+	if f.finite("weight", e.Weight) && (e.Weight < 0 || e.Weight > 1) {
+		f.add("weight", e.Weight, "must be between 0 and 1")
+	}
+	if e.Kind != "" && !isEdgeKind(e.Kind) {
+		f.add("kind", e.Kind, "must be one of %s", strings.Join(EdgeKinds(), ", "))
+	}
+	return f.result("edge")
+}
+
+func isEdgeKind(kind string) bool {
+	for _, known := range EdgeKinds() {
+		if kind == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorldEdge(t *testing.T) {
+	edge := WorldEdge{From: "studio", To: "lobby", Kind: EdgeKindDoor}
+
+	normalized := edge.Normalized()
+	assert.Equal(t, "lobby", normalized.From)
+	assert.Equal(t, "studio", normalized.To)
+	assert.Equal(t, DefaultEdgeWeight, normalized.Weight)
+
+	assert.Equal(t, "studio", normalized.Other("lobby"))
+	assert.Equal(t, "lobby", normalized.Other("studio"))
+	assert.Equal(t, WorldEdge{From: "studio", To: "lobby", Weight: 1, Kind: EdgeKindDoor}, normalized.Oriented("studio"))
+}
+
+func TestWorldEdgeValidation(t *testing.T) {
+	assert.NoError(t, WorldEdge{From: "a", To: "b"}.Validate())
+	assert.NoError(t, WorldEdge{From: "a", To: "b", Weight: 0.25, Kind: EdgeKindPortal}.Validate())
+
+	err := WorldEdge{From: "a", To: "a", Weight: 1.5, Kind: "tunnel"}.Validate()
+	assert.True(t, errors.Is(err, ErrValidation))
+	var invalid *ValidationError
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, "edge", invalid.Kind)
+		fields := make([]string, len(invalid.Fields))
+		for i, field := range invalid.Fields {
+			fields[i] = field.Field
+		}
+		assert.Equal(t, []string{"to", "weight", "kind"}, fields)
+	}
+
+	assert.Error(t, WorldEdge{From: "a/b", To: ""}.Validate())
+}
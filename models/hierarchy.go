@@ -0,0 +1,56 @@
+package models
+
+// WorldRollup summarizes a world together with the worlds nested in it, such
+// as a floor and its rooms
+type WorldRollup struct {
+	WorldID     string   `json:"worldId" jsonschema:"required"`
+	Children    []string `json:"children"`                                  // IDs of the direct children, sorted
+	Descendants int      `json:"descendants" jsonschema:"minimum=0"`        // Worlds nested at any depth
+	Occupancy   int      `json:"occupancy" jsonschema:"minimum=0"`          // Occupancy of the world and all its descendants
+	Activity    float64  `json:"activity" jsonschema:"minimum=0,maximum=1"` // Mean activity of the leaf worlds
+	Leaves      int      `json:"leaves" jsonschema:"minimum=1"`             // Worlds without children, which the activity averages over
+}
+
+// IsNested reports whether w is contained in another world
+func (w World) IsNested() bool {
+	return w.ParentID != ""
+}
+
+// OccupancyActivity turns an occupancy into an activity level from 0 to 1,
+// reaching 1 at 100 people
+func OccupancyActivity(occupancy int) float64 {
+	activity := float64(occupancy) / 100.0
+	if activity > 1.0 {
+		activity = 1.0
+	}
+	if activity < 0 {
+		activity = 0
+	}
+	return activity
+}
+
+// RollUp summarizes world given the rollups of its direct children. A world
+// without children is its own only leaf.
+func RollUp(world World, children []WorldRollup) WorldRollup {
+	rollup := WorldRollup{
+		WorldID:   world.ID,
+		Children:  make([]string, 0, len(children)),
+		Occupancy: world.Occupancy,
+	}
+	if len(children) == 0 {
+		rollup.Activity = OccupancyActivity(world.Occupancy)
+		rollup.Leaves = 1
+		return rollup
+	}
+
+	activity := 0.0
+	for _, child := range children {
+		rollup.Children = append(rollup.Children, child.WorldID)
+		rollup.Descendants += 1 + child.Descendants
+		rollup.Occupancy += child.Occupancy
+		rollup.Leaves += child.Leaves
+		activity += child.Activity * float64(child.Leaves)
+	}
+	rollup.Activity = activity / float64(rollup.Leaves)
+	return rollup
+}
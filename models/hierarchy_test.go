@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollUp(t *testing.T) {
+	room := RollUp(World{ID: "room", Occupancy: 150}, nil)
+	assert.Equal(t, WorldRollup{WorldID: "room", Children: []string{}, Occupancy: 150, Activity: 1, Leaves: 1}, room)
+
+	quiet := RollUp(World{ID: "quiet"}, nil)
+	floor := RollUp(World{ID: "floor", Occupancy: 5}, []WorldRollup{room, quiet})
+	assert.Equal(t, []string{"room", "quiet"}, floor.Children)
+	assert.Equal(t, 2, floor.Descendants)
+	assert.Equal(t, 155, floor.Occupancy)
+	assert.Equal(t, 0.5, floor.Activity, "The floor's own occupancy is not a leaf")
+
+	// Each leaf counts once, however deep it is
+	building := RollUp(World{ID: "building"}, []WorldRollup{floor, RollUp(World{ID: "lobby", Occupancy: 20}, nil)})
+	assert.Equal(t, 4, building.Descendants)
+	assert.Equal(t, 3, building.Leaves)
+	assert.InDelta(t, 0.4, building.Activity, 1e-9)
+}
+
+func TestWorldParentValidation(t *testing.T) {
+	world := World{ID: "room", Type: WorldTypeVirtual, ParentID: "room"}
+	assert.Error(t, world.Validate())
+	world.ParentID = "floor"
+	assert.NoError(t, world.Validate())
+	world.ParentID = "bad id"
+	assert.Error(t, world.Validate())
+}
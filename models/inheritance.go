@@ -0,0 +1,150 @@
+package models
+
+import (
+	"reflect"
+)
+
+// Vibe fields a derived vibe inherits from its parent unless it overrides
+// them, by JSON name
+const (
+	VibeFieldName        = "name"
+	VibeFieldDescription = "description"
+	VibeFieldEnergy      = "energy"
+	VibeFieldMood        = "mood"
+	VibeFieldColors      = "colors"
+	VibeFieldSensorData  = "sensorData"
+	VibeFieldSharing     = "sharing"
+)
+
+// InheritableVibeFields returns the fields a derived vibe can inherit, in
+// the order they are declared
+func InheritableVibeFields() []string {
+	return []string{
+		VibeFieldName, VibeFieldDescription, VibeFieldEnergy, VibeFieldMood,
+		VibeFieldColors, VibeFieldSensorData, VibeFieldSharing,
+	}
+}
+
+// IsInheritableVibeField reports whether field is one of InheritableVibeFields
+func IsInheritableVibeField(field string) bool {
+	for _, known := range InheritableVibeFields() {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDerived reports whether v inherits from a parent vibe
+func (v Vibe) IsDerived() bool {
+	return v.ParentID != ""
+}
+
+// OverridesField reports whether v sets field itself rather than inheriting it
+func (v Vibe) OverridesField(field string) bool {
+	for _, override := range v.Overrides {
+		if override == field {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFields returns the inheritable fields of v that hold non-zero values,
+// which are the ones a new derived vibe overrides when it lists none
+func (v Vibe) SetFields() []string {
+	fields := []string{}
+	values := map[string]interface{}{
+		VibeFieldName:        v.Name,
+		VibeFieldDescription: v.Description,
+		VibeFieldEnergy:      v.Energy,
+		VibeFieldMood:        v.Mood,
+		VibeFieldColors:      v.Colors,
+		VibeFieldSensorData:  v.SensorData,
+		VibeFieldSharing:     v.Sharing,
+	}
+	for _, field := range InheritableVibeFields() {
+		if !reflect.ValueOf(values[field]).IsZero() {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// NormalizedOverrides returns v's overrides without duplicates, in the order
+// of InheritableVibeFields, or nil if there are none
+func (v Vibe) NormalizedOverrides() []string {
+	var fields []string
+	for _, field := range InheritableVibeFields() {
+		if v.OverridesField(field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Inherit returns the effective vibe of v: every inheritable field it does
+// not override is taken from parent, which must itself be effective.
+func (v Vibe) Inherit(parent Vibe) Vibe {
+	if !v.OverridesField(VibeFieldName) {
+		v.Name = parent.Name
+	}
+	if !v.OverridesField(VibeFieldDescription) {
+		v.Description = parent.Description
+	}
+	if !v.OverridesField(VibeFieldEnergy) {
+		v.Energy = parent.Energy
+	}
+	if !v.OverridesField(VibeFieldMood) {
+		v.Mood = parent.Mood
+	}
+	if !v.OverridesField(VibeFieldColors) {
+		v.Colors = copyStrings(parent.Colors)
+	}
+	if !v.OverridesField(VibeFieldSensorData) {
+		v.SensorData = parent.SensorData.copy()
+	}
+	if !v.OverridesField(VibeFieldSharing) {
+		v.Sharing = parent.Sharing
+		v.Sharing.AllowedUsers = copyStrings(parent.Sharing.AllowedUsers)
+	}
+	return v
+}
+
+// copy returns s with its own copies of the readings
+func (s SensorData) copy() SensorData {
+	return SensorData{
+		Temperature: copyFloat(s.Temperature),
+		Humidity:    copyFloat(s.Humidity),
+		Light:       copyFloat(s.Light),
+		Sound:       copyFloat(s.Sound),
+		Movement:    copyFloat(s.Movement),
+		Channels:    copyChannels(s.Channels),
+	}
+}
+
+func copyFloat(f *float64) *float64 {
+	if f == nil {
+		return nil
+	}
+	v := *f
+	return &v
+}
+
+func copyChannels(channels map[string]float64) map[string]float64 {
+	if channels == nil {
+		return nil
+	}
+	copied := make(map[string]float64, len(channels))
+	for name, v := range channels {
+		copied[name] = v
+	}
+	return copied
+}
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s...)
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVibeInherit(t *testing.T) {
+	temperature := 21.0
+	parent := Vibe{
+		ID: "base", Name: "Base", Energy: 0.6, Mood: MoodFocused, Colors: []string{"#0000FF"},
+		SensorData: SensorData{Temperature: &temperature, Channels: map[string]float64{"co2": 450}},
+	}
+	child := Vibe{ID: "warm", ParentID: "base", Colors: []string{"#FFAA00"}, Energy: 0.1, Overrides: []string{VibeFieldColors}}
+
+	effective := child.Inherit(parent)
+	assert.Equal(t, "Base", effective.Name)
+	assert.Equal(t, 0.6, effective.Energy)
+	assert.Equal(t, []string{"#FFAA00"}, effective.Colors)
+	assert.Equal(t, "warm", effective.ID)
+	assert.Equal(t, map[string]float64{"co2": 450}, effective.SensorData.Channels)
+
+	// Inherited values are copies
+	*effective.SensorData.Temperature = 30
+	assert.Equal(t, 21.0, temperature)
+	effective.SensorData.Channels["co2"] = 900
+	assert.Equal(t, 450.0, parent.SensorData.Channels["co2"])
+}
+
+func TestVibeSetFieldsAndNormalizedOverrides(t *testing.T) {
+	vibe := Vibe{ID: "warm", ParentID: "base", Mood: MoodCalm, Colors: []string{"#FFAA00"}}
+	assert.Equal(t, []string{VibeFieldMood, VibeFieldColors}, vibe.SetFields())
+
+	vibe.Overrides = []string{VibeFieldSharing, VibeFieldName, VibeFieldSharing}
+	assert.Equal(t, []string{VibeFieldName, VibeFieldSharing}, vibe.NormalizedOverrides())
+
+	vibe.Overrides = []string{"creatorId"}
+	assert.Equal(t, []string{"overrides[0]"}, fieldNames(t, vibe.Validate()))
+	vibe.ParentID = vibe.ID
+	vibe.Overrides = nil
+	assert.Equal(t, []string{"parentId"}, fieldNames(t, vibe.Validate()))
+}
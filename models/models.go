@@ -25,13 +25,16 @@ const (
 	MoodNeutral      string = "neutral"
 )
 
-// SensorData represents environmental sensor data that contributes to a vibe
+// SensorData represents environmental sensor data that contributes to a vibe.
+// The five most common readings have their own fields; readings of any other
+// channel, registered in a SensorRegistry or not, go in Channels.
 type SensorData struct {
-	Temperature *float64 `json:"temperature,omitempty"` // in Celsius
-	Humidity    *float64 `json:"humidity,omitempty"`    // percentage
-	Light       *float64 `json:"light,omitempty"`       // in lux
-	Sound       *float64 `json:"sound,omitempty"`       // in dB
-	Movement    *float64 `json:"movement,omitempty"`    // relative activity level 0-1
+	Temperature *float64 `json:"temperature,omitempty" jsonschema:"minimum=-273.15"` // in Celsius
+	Humidity    *float64 `json:"humidity,omitempty" jsonschema:"minimum=0,maximum=100"`    // percentage
+	Light       *float64 `json:"light,omitempty" jsonschema:"minimum=0"`       // in lux
+	Sound       *float64 `json:"sound,omitempty" jsonschema:"minimum=0"`       // in dB
+	Movement    *float64 `json:"movement,omitempty" jsonschema:"minimum=0,maximum=1"`    // relative activity level 0-1
+	Channels    map[string]float64 `json:"channels,omitempty" jsonschema_description:"Readings of other sensor channels by name, such as co2 or occupancyCount"`
 }
 
 // ContextLevel defines how much context is shared with viewers
@@ -48,20 +51,23 @@ const (
 type SharingSettings struct {
 	IsPublic     bool         `json:"isPublic"`                // Whether this is visible to all users
 	AllowedUsers []string     `json:"allowedUsers,omitempty"`  // Specific users who can access this
-	ContextLevel ContextLevel `json:"contextLevel"`            // Amount of context to share
+	ContextLevel ContextLevel `json:"contextLevel" jsonschema:"enum=none,enum=partial,enum=full"` // Amount of context to share
 }
 
 // Vibe represents the emotional atmosphere of a space
 type Vibe struct {
-	ID          string         `json:"id"`
+	ID          string         `json:"id" jsonschema:"required,maxLength=128,pattern=^[^\\s/?#]+$"`
 	Name        string         `json:"name"`
 	Description string         `json:"description"`
-	Energy      float64        `json:"energy"`  // from 0.0 (low) to 1.0 (high)
-	Mood        string         `json:"mood"`    // e.g., "relaxed", "energetic", "contemplative"
-	Colors      []string       `json:"colors"`  // hex colors
+	Energy      float64        `json:"energy" jsonschema:"minimum=0,maximum=1"`  // from 0.0 (low) to 1.0 (high)
+	Mood        string         `json:"mood" jsonschema:"enum=calm,enum=focused,enum=relaxed,enum=energetic,enum=creative,enum=contemplative,enum=productive,enum=neutral"`    // e.g., "relaxed", "energetic", "contemplative"
+	Colors      []string       `json:"colors" jsonschema:"pattern=^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6}|[0-9A-Fa-f]{8})$"`  // hex colors
 	SensorData  SensorData     `json:"sensorData,omitempty"`
 	CreatorID   string         `json:"creatorId,omitempty"`    // User who created this vibe
 	Sharing     SharingSettings `json:"sharing,omitempty"`     // How this vibe is shared
+	ParentID    string         `json:"parentId,omitempty" jsonschema:"maxLength=128,pattern=^[^\\s/?#]+$" jsonschema_description:"Vibe this one derives from"`
+	Overrides   []string       `json:"overrides,omitempty" jsonschema:"enum=name,enum=description,enum=energy,enum=mood,enum=colors,enum=sensorData,enum=sharing" jsonschema_description:"Fields set on this vibe instead of inherited from the parent"`
+	Version     int64          `json:"version,omitempty"`      // Incremented by the repository on every write
 }
 
 // WorldType represents the type of a world
@@ -75,26 +81,40 @@ const (
 
 // Resource URI schemes
 const (
-	VibeScheme      string = "vibe://"
-	WorldScheme     string = "world://"
-	VibeListURI     string = "vibe://list"
-	WorldListURI    string = "world://list"
-	WorldVibeSubURI string = "/vibe"
+	VibeScheme       string = "vibe://"
+	WorldScheme      string = "world://"
+	VibeListURI      string = "vibe://list"
+	WorldListURI     string = "world://list"
+	VibeTrashURI     string = "vibe://trash"
+	WorldTrashURI    string = "world://trash"
+	WorldGraphURI    string = "world://graph"
+	WorldVibeSubURI  string = "/vibe"
+	HistorySubURI    string = "/history"
+	VibeWorldsSubURI string = "/worlds"
+	ChildrenSubURI   string = "/children"
+	AncestorsSubURI  string = "/ancestors"
+	RollupSubURI     string = "/rollup"
+	NeighborsSubURI  string = "/neighbors"
+	SensorsSubURI    string = "/sensors"
+	SchemaScheme     string = "schema://"
 )
 
 // World represents a physical or virtual world space
 type World struct {
-	ID          string         `json:"id"`
+	ID          string         `json:"id" jsonschema:"required,maxLength=128,pattern=^[^\\s/?#]+$"`
 	Name        string         `json:"name"`
 	Description string         `json:"description"`
-	Type        WorldType      `json:"type"`
+	Type        WorldType      `json:"type" jsonschema:"required,enum=PHYSICAL,enum=VIRTUAL,enum=HYBRID"`
 	Location    string         `json:"location,omitempty"`     // physical location or virtual address
-	CurrentVibe string         `json:"currentVibe,omitempty"`  // ID of current vibe
+	CurrentVibe string         `json:"currentVibe,omitempty" jsonschema:"maxLength=128,pattern=^[^\\s/?#]+$"`  // ID of current vibe
 	Size        string         `json:"size,omitempty"`         // description of size/scale
 	Features    []string       `json:"features,omitempty"`     // special characteristics
 	CreatorID   string         `json:"creatorId,omitempty"`    // User who created this world
 	Sharing     SharingSettings `json:"sharing,omitempty"`     // How this world is shared
-	Occupancy   int            `json:"occupancy,omitempty"`    // Current number of people
+	Occupancy   int            `json:"occupancy,omitempty" jsonschema:"minimum=0"`    // Current number of people
+	Transition  *VibeTransition `json:"transition,omitempty"`  // Gradual change to CurrentVibe, if one was scheduled
+	ParentID    string         `json:"parentId,omitempty" jsonschema:"maxLength=128,pattern=^[^\\s/?#]+$" jsonschema_description:"World this one is nested in"`
+	Version     int64          `json:"version,omitempty"`      // Incremented by the repository on every write
 }
 
 // DataEncoding represents the encoding format of binary or ternary data
@@ -220,21 +240,22 @@ func FromDecimal(n int64) BalancedTernaryData {
 
 // WorldMoment represents a moment in time for a world with its current state
 type WorldMoment struct {
-	WorldID     string          `json:"worldId"`              // ID of the world
-	Timestamp   int64           `json:"timestamp"`            // Unix timestamp in milliseconds
+	WorldID     string          `json:"worldId" jsonschema:"required"`              // ID of the world
+	Timestamp   int64           `json:"timestamp" jsonschema:"required,minimum=1"`            // Unix timestamp in milliseconds
 	VibeID      string          `json:"vibeId,omitempty"`     // Current vibe ID
 	Vibe        *Vibe           `json:"vibe,omitempty"`       // Full vibe object (optional)
 	SensorData  SensorData      `json:"sensorData,omitempty"` // Current sensor data
-	Occupancy   int             `json:"occupancy,omitempty"`  // Number of people/entities in the world
-	Activity    float64         `json:"activity,omitempty"`   // Activity level 0.0-1.0
+	Occupancy   int             `json:"occupancy,omitempty" jsonschema:"minimum=0"`  // Number of people/entities in the world
+	Activity    float64         `json:"activity,omitempty" jsonschema:"minimum=0,maximum=1"`   // Activity level 0.0-1.0
 	CustomData  string          `json:"customData,omitempty"` // Custom JSON data for extension
 	
 	// Binary and balanced ternary data
 	BinaryData          *BinaryData          `json:"binaryData,omitempty"`          // Binary payload
-	BalancedTernaryData *BalancedTernaryData `json:"balancedTernaryData,omitempty"` // Balanced ternary data
+	BalancedTernaryData *BalancedTernaryData `json:"balancedTernaryData,omitempty" jsonschema:"enum=-1,enum=0,enum=1"` // Balanced ternary data
 	
 	// Multiplayer additions
 	CreatorID   string          `json:"creatorId,omitempty"`  // User who created this moment
 	Viewers     []string        `json:"viewers,omitempty"`    // Users currently viewing this world
 	Sharing     SharingSettings `json:"sharing,omitempty"`    // How this moment should be shared
+	Rollup      *WorldRollup    `json:"rollup,omitempty"`     // Summary of the world's children, if enabled
 }
\ No newline at end of file
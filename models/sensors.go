@@ -0,0 +1,349 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Aggregation says how the readings of a sensor channel combine, over time
+// or across worlds
+type Aggregation string
+
+const (
+	AggregateMean Aggregation = "mean" // Average level, such as a temperature
+	AggregateSum  Aggregation = "sum"  // Total count, such as people detected
+	AggregateMin  Aggregation = "min"
+	AggregateMax  Aggregation = "max"  // Worst case, such as an air quality index
+	AggregateLast Aggregation = "last" // Latest reading only
+)
+
+// IsValid reports whether a is one of the Aggregation constants
+func (a Aggregation) IsValid() bool {
+	switch a {
+	case AggregateMean, AggregateSum, AggregateMin, AggregateMax, AggregateLast:
+		return true
+	}
+	return false
+}
+
+// Names of the sensor channels every registry knows. The first five are the
+// fixed fields of SensorData.
+const (
+	SensorTemperature    = "temperature"
+	SensorHumidity       = "humidity"
+	SensorLight          = "light"
+	SensorSound          = "sound"
+	SensorMovement       = "movement"
+	SensorCO2            = "co2"
+	SensorAirQuality     = "airQuality"
+	SensorNoiseLow       = "noiseLow"
+	SensorNoiseMid       = "noiseMid"
+	SensorNoiseHigh      = "noiseHigh"
+	SensorOccupancyCount = "occupancyCount"
+)
+
+// SensorChannelsURI is the resource listing the registered sensor channels
+const SensorChannelsURI = "sensor://channels"
+
+// ErrSensorChannelExists is returned when registering a channel name twice
+var ErrSensorChannelExists = errors.New("sensor channel already registered")
+
+// sensorNamePattern is what sensor channel names look like
+var sensorNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]{0,63}$`)
+
+// SensorChannel describes one kind of sensor reading. Readings outside Min
+// and Max, when set, are invalid. Private channels are stripped from what
+// users see below the full context level.
+type SensorChannel struct {
+	Name        string      `json:"name" jsonschema:"required,maxLength=64"`
+	Unit        string      `json:"unit,omitempty"`
+	Min         *float64    `json:"min,omitempty"`
+	Max         *float64    `json:"max,omitempty"`
+	Aggregation Aggregation `json:"aggregation" jsonschema:"required,enum=mean,enum=sum,enum=min,enum=max,enum=last"`
+	Private     bool        `json:"private,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// Validate checks a channel definition: a usable name, a known aggregation
+// and a range whose minimum does not exceed its maximum. It returns a
+// *ValidationError listing every problem.
+func (c SensorChannel) Validate() error {
+	f := &fieldErrors{}
+	if !sensorNamePattern.MatchString(c.Name) {
+		f.add("name", c.Name, "must start with a letter and contain only letters, digits, '_', '.' or '-', up to 64 characters")
+	}
+	if !c.Aggregation.IsValid() {
+		f.add("aggregation", string(c.Aggregation), "must be one of %s, %s, %s, %s, %s",
+			AggregateMean, AggregateSum, AggregateMin, AggregateMax, AggregateLast)
+	}
+	if c.Min != nil {
+		f.finite("min", *c.Min)
+	}
+	if c.Max != nil {
+		f.finite("max", *c.Max)
+	}
+	if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+		f.add("max", *c.Max, "must not be below min %g", *c.Min)
+	}
+	return f.result("sensorChannel")
+}
+
+// validateReading checks one reading of the channel
+func (c SensorChannel) validateReading(f *fieldErrors, field string, v float64) {
+	switch {
+	case !f.finite(field, v):
+	case c.Min != nil && c.Max != nil:
+		f.between(field, v, *c.Min, *c.Max)
+	case c.Min != nil:
+		f.atLeast(field, v, *c.Min)
+	case c.Max != nil && v > *c.Max:
+		f.add(field, v, "must be at most %g", *c.Max)
+	}
+}
+
+// Aggregate combines readings of the channel, in time order, with its
+// aggregation rule. It returns false when there are no readings.
+func (c SensorChannel) Aggregate(values []float64) (float64, bool) {
+	if len(values) == 0 {
+		return 0, false
+	}
+	switch c.Aggregation {
+	case AggregateSum:
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		return sum, true
+	case AggregateMin:
+		min := math.Inf(1)
+		for _, v := range values {
+			min = math.Min(min, v)
+		}
+		return min, true
+	case AggregateMax:
+		max := math.Inf(-1)
+		for _, v := range values {
+			max = math.Max(max, v)
+		}
+		return max, true
+	case AggregateLast:
+		return values[len(values)-1], true
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values)), true
+}
+
+// SensorRegistry holds the known sensor channels. It is safe for concurrent use.
+type SensorRegistry struct {
+	mu       sync.RWMutex
+	channels map[string]SensorChannel
+}
+
+// NewSensorRegistry creates a registry of the built-in channels
+func NewSensorRegistry() *SensorRegistry {
+	r := &SensorRegistry{channels: make(map[string]SensorChannel)}
+	for _, channel := range builtinSensorChannels() {
+		r.channels[channel.Name] = channel
+	}
+	return r
+}
+
+// DefaultSensorRegistry is the registry readings are validated against
+var DefaultSensorRegistry = NewSensorRegistry()
+
+// Register adds a channel. Names are unique, including the built-in ones.
+func (r *SensorRegistry) Register(channel SensorChannel) error {
+	if err := channel.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.channels[channel.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrSensorChannelExists, channel.Name)
+	}
+	r.channels[channel.Name] = channel
+	return nil
+}
+
+// Channel returns the channel with the given name
+func (r *SensorRegistry) Channel(name string) (SensorChannel, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	channel, ok := r.channels[name]
+	return channel, ok
+}
+
+// Channels returns every channel, ordered by name
+func (r *SensorRegistry) Channels() []SensorChannel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	channels := make([]SensorChannel, 0, len(r.channels))
+	for _, channel := range r.channels {
+		channels = append(channels, channel)
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i].Name < channels[j].Name })
+	return channels
+}
+
+// RegisterSensorChannel adds a channel to the DefaultSensorRegistry
+func RegisterSensorChannel(channel SensorChannel) error {
+	return DefaultSensorRegistry.Register(channel)
+}
+
+// LookupSensorChannel returns a channel of the DefaultSensorRegistry. A name
+// that is not registered gets an unbounded channel averaged over time.
+func LookupSensorChannel(name string) SensorChannel {
+	if channel, ok := DefaultSensorRegistry.Channel(name); ok {
+		return channel
+	}
+	return SensorChannel{Name: name, Aggregation: AggregateMean}
+}
+
+func builtinSensorChannels() []SensorChannel {
+	bound := func(v float64) *float64 { return &v }
+	return []SensorChannel{
+		{Name: SensorTemperature, Unit: "°C", Min: bound(TemperatureMin), Aggregation: AggregateMean},
+		{Name: SensorHumidity, Unit: "%", Min: bound(HumidityMin), Max: bound(HumidityMax), Aggregation: AggregateMean},
+		{Name: SensorLight, Unit: "lx", Min: bound(0), Aggregation: AggregateMean},
+		{Name: SensorSound, Unit: "dB", Min: bound(0), Aggregation: AggregateMean},
+		{Name: SensorMovement, Min: bound(MovementMin), Max: bound(MovementMax), Aggregation: AggregateMean,
+			Description: "Relative activity level"},
+		{Name: SensorCO2, Unit: "ppm", Min: bound(0), Aggregation: AggregateMean},
+		{Name: SensorAirQuality, Unit: "AQI", Min: bound(0), Max: bound(500), Aggregation: AggregateMax},
+		{Name: SensorNoiseLow, Unit: "dB", Min: bound(0), Aggregation: AggregateMean, Description: "Noise below 250 Hz"},
+		{Name: SensorNoiseMid, Unit: "dB", Min: bound(0), Aggregation: AggregateMean, Description: "Noise from 250 Hz to 4 kHz"},
+		{Name: SensorNoiseHigh, Unit: "dB", Min: bound(0), Aggregation: AggregateMean, Description: "Noise above 4 kHz"},
+		{Name: SensorOccupancyCount, Unit: "people", Min: bound(0), Aggregation: AggregateMax, Private: true,
+			Description: "People counted by occupancy sensors"},
+	}
+}
+
+// fixedReading returns the SensorData field holding a built-in reading
+func (s *SensorData) fixedReading(name string) (**float64, bool) {
+	switch name {
+	case SensorTemperature:
+		return &s.Temperature, true
+	case SensorHumidity:
+		return &s.Humidity, true
+	case SensorLight:
+		return &s.Light, true
+	case SensorSound:
+		return &s.Sound, true
+	case SensorMovement:
+		return &s.Movement, true
+	}
+	return nil, false
+}
+
+// Get returns the reading of a channel, whether it is a fixed field or not
+func (s SensorData) Get(name string) (float64, bool) {
+	if field, ok := s.fixedReading(name); ok {
+		if *field == nil {
+			return 0, false
+		}
+		return **field, true
+	}
+	v, ok := s.Channels[name]
+	return v, ok
+}
+
+// Set stores the reading of a channel, in its fixed field if it has one
+func (s *SensorData) Set(name string, v float64) {
+	if field, ok := s.fixedReading(name); ok {
+		*field = &v
+		return
+	}
+	channels := make(map[string]float64, len(s.Channels)+1)
+	for k, existing := range s.Channels {
+		channels[k] = existing
+	}
+	channels[name] = v
+	s.Channels = channels
+}
+
+// Readings returns every reading by channel name
+func (s SensorData) Readings() map[string]float64 {
+	readings := make(map[string]float64, len(s.Channels)+5)
+	for name, v := range s.Channels {
+		readings[name] = v
+	}
+	for _, name := range []string{SensorTemperature, SensorHumidity, SensorLight, SensorSound, SensorMovement} {
+		if v, ok := s.Get(name); ok {
+			readings[name] = v
+		}
+	}
+	return readings
+}
+
+// Names returns the names of the channels with a reading, sorted
+func (s SensorData) Names() []string {
+	readings := s.Readings()
+	names := make([]string, 0, len(readings))
+	for name := range readings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsEmpty reports whether there is no reading at all
+func (s SensorData) IsEmpty() bool {
+	return len(s.Names()) == 0
+}
+
+// WithoutPrivate returns the readings of channels that are not private in
+// the DefaultSensorRegistry
+func (s SensorData) WithoutPrivate() SensorData {
+	var public SensorData
+	for name, v := range s.Readings() {
+		if !LookupSensorChannel(name).Private {
+			public.Set(name, v)
+		}
+	}
+	return public
+}
+
+// SensorReading is what the sensors of a world measured at one time. Readings
+// need not carry every channel; each one updates the channels it has.
+type SensorReading struct {
+	WorldID    string     `json:"worldId" jsonschema:"required"`
+	Timestamp  int64      `json:"timestamp,omitempty" jsonschema_description:"Time of the measurement in Unix milliseconds; defaults to when it is received"`
+	Source     string     `json:"source,omitempty" jsonschema_description:"Sensor or gateway that took the measurement"`
+	SensorData SensorData `json:"sensorData" jsonschema:"required"`
+}
+
+// MaxSensorClockSkew is how far past the current time a reading's timestamp
+// may be, for sensors whose clocks run slightly ahead
+const MaxSensorClockSkew = time.Minute
+
+// Validate checks a reading against the current time; see ValidateAt
+func (r SensorReading) Validate() error {
+	return r.ValidateAt(time.Now())
+}
+
+// ValidateAt checks a reading received at now: a usable world ID, a
+// timestamp that is not negative nor more than MaxSensorClockSkew after now,
+// and at least one valid sensor reading. It returns a *ValidationError
+// listing every problem.
+func (r SensorReading) ValidateAt(now time.Time) error {
+	f := &fieldErrors{}
+	f.id("worldId", r.WorldID)
+	if r.Timestamp < 0 {
+		f.add("timestamp", r.Timestamp, "must be a Unix time in milliseconds")
+	} else if r.Timestamp > now.Add(MaxSensorClockSkew).UnixMilli() {
+		f.add("timestamp", r.Timestamp, "must not be in the future")
+	}
+	if r.SensorData.IsEmpty() {
+		f.add("sensorData", nil, "must have at least one reading")
+	}
+	f.nested("sensorData", r.SensorData.validate)
+	return f.result("sensorReading")
+}
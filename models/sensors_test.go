@@ -0,0 +1,111 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSensorDataChannels(t *testing.T) {
+	var data SensorData
+	data.Set(SensorTemperature, 21.5)
+	data.Set(SensorCO2, 640)
+	data.Set(SensorOccupancyCount, 7)
+
+	// The fixed fields keep their JSON, other channels go under channels
+	encoded, err := json.Marshal(data)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"temperature":21.5,"channels":{"co2":640,"occupancyCount":7}}`, string(encoded))
+
+	var decoded SensorData
+	require.NoError(t, json.Unmarshal([]byte(`{"humidity":40,"channels":{"airQuality":42}}`), &decoded))
+	humidity, ok := decoded.Get(SensorHumidity)
+	assert.True(t, ok)
+	assert.Equal(t, 40.0, humidity)
+	assert.Equal(t, []string{SensorAirQuality, SensorHumidity}, decoded.Names())
+
+	// Setting a channel does not touch the map of a copy
+	copied := data
+	copied.Set(SensorCO2, 900)
+	co2, _ := data.Get(SensorCO2)
+	assert.Equal(t, 640.0, co2)
+
+	public := data.WithoutPrivate()
+	assert.Equal(t, []string{SensorCO2, SensorTemperature}, public.Names())
+	assert.True(t, SensorData{}.IsEmpty())
+}
+
+func TestSensorDataChannelValidation(t *testing.T) {
+	data := SensorData{Channels: map[string]float64{
+		SensorCO2:        500,
+		SensorAirQuality: 700,
+		"9lives":         1,
+		"temperature":    20,
+		"custom.lux":     -3, // Not registered, so any finite value goes
+	}}
+	err := data.Validate()
+	require.True(t, errors.Is(err, ErrValidation))
+
+	var invalid *ValidationError
+	require.True(t, errors.As(err, &invalid))
+	fields := make([]string, len(invalid.Fields))
+	for i, field := range invalid.Fields {
+		fields[i] = field.Field
+	}
+	assert.Equal(t, []string{"channels.9lives", "channels.airQuality", "channels.temperature"}, fields)
+}
+
+func TestSensorRegistry(t *testing.T) {
+	registry := NewSensorRegistry()
+	max := 5000.0
+	require.NoError(t, registry.Register(SensorChannel{Name: "voc", Unit: "ppb", Max: &max, Aggregation: AggregateMax}))
+
+	err := registry.Register(SensorChannel{Name: SensorCO2, Aggregation: AggregateMean})
+	assert.True(t, errors.Is(err, ErrSensorChannelExists))
+	assert.True(t, errors.Is(registry.Register(SensorChannel{Name: "bad name", Aggregation: "median"}), ErrValidation))
+
+	voc, ok := registry.Channel("voc")
+	require.True(t, ok)
+	assert.Equal(t, "ppb", voc.Unit)
+	_, ok = NewSensorRegistry().Channel("voc")
+	assert.False(t, ok, "registries are independent")
+
+	// Each aggregation rule combines readings in its own way
+	values := []float64{3, 1, 2}
+	for aggregation, want := range map[Aggregation]float64{
+		AggregateMean: 2, AggregateSum: 6, AggregateMin: 1, AggregateMax: 3, AggregateLast: 2,
+	} {
+		got, ok := SensorChannel{Aggregation: aggregation}.Aggregate(values)
+		assert.True(t, ok)
+		assert.Equal(t, want, got, "aggregation %s", aggregation)
+	}
+	_, ok = voc.Aggregate(nil)
+	assert.False(t, ok)
+
+	// Unregistered channels are averaged
+	assert.Equal(t, AggregateMean, LookupSensorChannel("unregistered").Aggregation)
+	assert.True(t, LookupSensorChannel(SensorOccupancyCount).Private)
+}
+
+func TestSensorReadingValidation(t *testing.T) {
+	reading := SensorReading{WorldID: "office", Timestamp: 1700000000000}
+	reading.SensorData.Set(SensorCO2, 450)
+	assert.NoError(t, reading.Validate())
+	received := time.UnixMilli(reading.Timestamp)
+	assert.NoError(t, reading.ValidateAt(received.Add(-MaxSensorClockSkew)), "a clock slightly ahead is tolerated")
+	assert.True(t, errors.Is(reading.ValidateAt(received.Add(-time.Hour)), ErrValidation), "a reading from the future is rejected")
+
+	err := SensorReading{Timestamp: -1}.Validate()
+	var invalid *ValidationError
+	require.True(t, errors.As(err, &invalid))
+	assert.Equal(t, "sensorReading", invalid.Kind)
+	fields := make([]string, len(invalid.Fields))
+	for i, field := range invalid.Fields {
+		fields[i] = field.Field
+	}
+	assert.Equal(t, []string{"worldId", "timestamp", "sensorData"}, fields)
+}
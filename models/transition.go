@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// VibeTransition is a gradual change of a world's vibe, from FromVibe to the
+// world's CurrentVibe, starting at StartedAt and lasting Duration
+type VibeTransition struct {
+	FromVibe  string `json:"fromVibe" jsonschema:"required,maxLength=128,pattern=^[^\\s/?#]+$"`
+	StartedAt int64  `json:"startedAt" jsonschema:"required,minimum=1"` // Unix time in milliseconds
+	Duration  int64  `json:"duration" jsonschema:"required,minimum=1"`  // in milliseconds
+}
+
+// NewVibeTransition starts a transition from a vibe at now
+func NewVibeTransition(fromVibe string, now time.Time, duration time.Duration) *VibeTransition {
+	return &VibeTransition{
+		FromVibe:  fromVibe,
+		StartedAt: now.UnixMilli(),
+		Duration:  duration.Milliseconds(),
+	}
+}
+
+// Progress returns how far the transition is at now, from 0 to 1
+func (t VibeTransition) Progress(now time.Time) float64 {
+	if t.Duration <= 0 {
+		return 1
+	}
+	progress := float64(now.UnixMilli()-t.StartedAt) / float64(t.Duration)
+	if progress < 0 {
+		return 0
+	}
+	if progress > 1 {
+		return 1
+	}
+	return progress
+}
+
+// Active reports whether the transition is still under way at now
+func (t VibeTransition) Active(now time.Time) bool {
+	return t.Progress(now) < 1
+}
+
+// EndsAt returns when the transition is complete
+func (t VibeTransition) EndsAt() time.Time {
+	return time.UnixMilli(t.StartedAt + t.Duration).UTC()
+}
+
+func (t VibeTransition) validate(f *fieldErrors) {
+	f.id("fromVibe", t.FromVibe)
+	if t.StartedAt <= 0 {
+		f.add("startedAt", t.StartedAt, "must be a positive Unix time in milliseconds")
+	}
+	if t.Duration <= 0 {
+		f.add("duration", t.Duration, "must be positive")
+	}
+}
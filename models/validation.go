@@ -0,0 +1,334 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ErrValidation is matched by every *ValidationError
+var ErrValidation = errors.New("validation failed")
+
+// Limits enforced by Validate
+const (
+	MaxIDLength    = 128
+	TemperatureMin = -273.15 // Absolute zero, in Celsius
+	HumidityMin    = 0.0
+	HumidityMax    = 100.0
+	ActivityMin    = 0.0
+	ActivityMax    = 1.0
+)
+
+// idForbiddenChars would break the resource URIs built from IDs
+const idForbiddenChars = "/?#"
+
+// ReservedWorldID cannot be the ID of a world, as it names the lists of
+// worlds, such as the {streamID}.query.world.list subject
+const ReservedWorldID = "list"
+
+// FieldError describes one invalid field. Field is the JSON path of the
+// field, such as "colors[1]" or "sensorData.humidity".
+type FieldError struct {
+	Field   string      `json:"field"`
+	Value   interface{} `json:"value,omitempty"`
+	Message string      `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationError lists every invalid field of a value. It matches
+// ErrValidation with errors.Is.
+type ValidationError struct {
+	Kind   string       `json:"kind"` // "vibe", "world", "edge", "sensorData", "sensorChannel", "sensorReading" or "moment"
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Error()
+	}
+	return fmt.Sprintf("invalid %s: %s", e.Kind, strings.Join(messages, "; "))
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Moods returns the known moods, in the order they are declared
+func Moods() []string {
+	return []string{
+		MoodCalm, MoodFocused, MoodRelaxed, MoodEnergetic,
+		MoodCreative, MoodContemplative, MoodProductive, MoodNeutral,
+	}
+}
+
+// IsKnownMood reports whether mood is one of the Mood constants
+func IsKnownMood(mood string) bool {
+	for _, known := range Moods() {
+		if mood == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValid reports whether t is one of the WorldType constants
+func (t WorldType) IsValid() bool {
+	switch t {
+	case WorldTypePhysical, WorldTypeVirtual, WorldTypeHybrid:
+		return true
+	}
+	return false
+}
+
+// IsValid reports whether l is one of the ContextLevel constants
+func (l ContextLevel) IsValid() bool {
+	switch l {
+	case ContextLevelNone, ContextLevelPartial, ContextLevelFull:
+		return true
+	}
+	return false
+}
+
+// IsHexColor reports whether s is a #RGB, #RRGGBB or #RRGGBBAA color
+func IsHexColor(s string) bool {
+	if !strings.HasPrefix(s, "#") {
+		return false
+	}
+	digits := s[1:]
+	if len(digits) != 3 && len(digits) != 6 && len(digits) != 8 {
+		return false
+	}
+	for _, c := range digits {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldErrors accumulates the field errors of a value being validated
+type fieldErrors struct {
+	prefix string
+	errors []FieldError
+}
+
+func (f *fieldErrors) add(field string, value interface{}, format string, args ...interface{}) {
+	f.errors = append(f.errors, FieldError{Field: f.prefix + field, Value: value, Message: fmt.Sprintf(format, args...)})
+}
+
+// nested validates a sub-value with its fields prefixed by field
+func (f *fieldErrors) nested(field string, validate func(*fieldErrors)) {
+	sub := &fieldErrors{prefix: f.prefix + field + "."}
+	validate(sub)
+	f.errors = append(f.errors, sub.errors...)
+}
+
+func (f *fieldErrors) id(field, id string) {
+	switch {
+	case id == "":
+		f.add(field, nil, "is required")
+	case len(id) > MaxIDLength:
+		f.add(field, id, "must be at most %d characters", MaxIDLength)
+	case strings.ContainsAny(id, idForbiddenChars) || strings.IndexFunc(id, isSpace) >= 0:
+		f.add(field, id, "must not contain whitespace or any of %q", idForbiddenChars)
+	}
+}
+
+// finite reports a NaN or infinite number and whether it was finite
+func (f *fieldErrors) finite(field string, v float64) bool {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		f.add(field, nil, "must be a finite number")
+		return false
+	}
+	return true
+}
+
+func (f *fieldErrors) between(field string, v, min, max float64) {
+	if f.finite(field, v) && (v < min || v > max) {
+		f.add(field, v, "must be between %g and %g", min, max)
+	}
+}
+
+func (f *fieldErrors) atLeast(field string, v, min float64) {
+	if f.finite(field, v) && v < min {
+		f.add(field, v, "must be at least %g", min)
+	}
+}
+
+func (f *fieldErrors) result(kind string) error {
+	if len(f.errors) == 0 {
+		return nil
+	}
+	return &ValidationError{Kind: kind, Fields: f.errors}
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// Validate checks a vibe: a usable ID, energy between EnergyMin and
+// EnergyMax, a known mood if any, hex colors, valid sensor data and sharing
+// settings, and inheritable overrides with a parent other than itself. It
+// returns a *ValidationError listing every problem.
+func (v Vibe) Validate() error {
+	f := &fieldErrors{}
+	v.validate(f)
+	return f.result("vibe")
+}
+
+func (v Vibe) validate(f *fieldErrors) {
+	f.id("id", v.ID)
+	f.between("energy", v.Energy, EnergyMin, EnergyMax)
+	if v.Mood != "" && !IsKnownMood(v.Mood) {
+		f.add("mood", v.Mood, "must be one of %s", strings.Join(Moods(), ", "))
+	}
+	for i, color := range v.Colors {
+		if !IsHexColor(color) {
+			f.add(fmt.Sprintf("colors[%d]", i), color, "must be a hex color like #A1B2C3")
+		}
+	}
+	f.nested("sensorData", v.SensorData.validate)
+	f.nested("sharing", v.Sharing.validate)
+	if v.ParentID != "" {
+		f.id("parentId", v.ParentID)
+		if v.ParentID == v.ID {
+			f.add("parentId", v.ParentID, "must not be the vibe itself")
+		}
+	}
+	for i, field := range v.Overrides {
+		if !IsInheritableVibeField(field) {
+			f.add(fmt.Sprintf("overrides[%d]", i), field, "must be one of %s", strings.Join(InheritableVibeFields(), ", "))
+		}
+	}
+}
+
+// Validate checks a world: a usable ID other than ReservedWorldID, a parent
+// other than itself, a known type, a non-negative occupancy, valid sharing
+// settings and a complete transition, if any. It returns a *ValidationError
+// listing every problem.
+func (w World) Validate() error {
+	f := &fieldErrors{}
+	f.id("id", w.ID)
+	if w.ID == ReservedWorldID {
+		f.add("id", w.ID, "is reserved")
+	}
+	if !w.Type.IsValid() {
+		f.add("type", string(w.Type), "must be one of %s, %s, %s", WorldTypePhysical, WorldTypeVirtual, WorldTypeHybrid)
+	}
+	if w.CurrentVibe != "" {
+		f.id("currentVibe", w.CurrentVibe)
+	}
+	if w.ParentID != "" {
+		f.id("parentId", w.ParentID)
+		if w.ParentID == w.ID {
+			f.add("parentId", w.ParentID, "must not be the world itself")
+		}
+	}
+	if w.Occupancy < 0 {
+		f.add("occupancy", w.Occupancy, "must not be negative")
+	}
+	for i, feature := range w.Features {
+		if strings.TrimSpace(feature) == "" {
+			f.add(fmt.Sprintf("features[%d]", i), feature, "must not be blank")
+		}
+	}
+	f.nested("sharing", w.Sharing.validate)
+	if w.Transition != nil {
+		f.nested("transition", w.Transition.validate)
+	}
+	return f.result("world")
+}
+
+// Validate checks that every reading present is physically plausible, and
+// that channel readings are named properly and within the range of their
+// registered channel. It returns a *ValidationError listing every problem.
+func (s SensorData) Validate() error {
+	f := &fieldErrors{}
+	s.validate(f)
+	return f.result("sensorData")
+}
+
+func (s SensorData) validate(f *fieldErrors) {
+	if s.Temperature != nil {
+		f.atLeast("temperature", *s.Temperature, TemperatureMin)
+	}
+	if s.Humidity != nil {
+		f.between("humidity", *s.Humidity, HumidityMin, HumidityMax)
+	}
+	if s.Light != nil {
+		f.atLeast("light", *s.Light, 0)
+	}
+	if s.Sound != nil {
+		f.atLeast("sound", *s.Sound, 0)
+	}
+	if s.Movement != nil {
+		f.between("movement", *s.Movement, MovementMin, MovementMax)
+	}
+	names := make([]string, 0, len(s.Channels))
+	for name := range s.Channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := "channels." + name
+		if _, fixed := s.fixedReading(name); fixed {
+			f.add(field, s.Channels[name], "must be given as the %s field", name)
+			continue
+		}
+		if !sensorNamePattern.MatchString(name) {
+			f.add(field, nil, "must be named with a letter followed by letters, digits, '_', '.' or '-', up to 64 characters")
+			continue
+		}
+		LookupSensorChannel(name).validateReading(f, field, s.Channels[name])
+	}
+}
+
+func (s SharingSettings) validate(f *fieldErrors) {
+	if s.ContextLevel != "" && !s.ContextLevel.IsValid() {
+		f.add("contextLevel", string(s.ContextLevel), "must be one of %s, %s, %s", ContextLevelNone, ContextLevelPartial, ContextLevelFull)
+	}
+	for i, user := range s.AllowedUsers {
+		if user == "" {
+			f.add(fmt.Sprintf("allowedUsers[%d]", i), nil, "must not be empty")
+		}
+	}
+}
+
+// Validate checks a moment: its world ID and timestamp, activity between
+// ActivityMin and ActivityMax, custom data that is JSON, balanced ternary
+// digits, and the embedded vibe, sensor data and sharing settings. It
+// returns a *ValidationError listing every problem.
+func (m WorldMoment) Validate() error {
+	f := &fieldErrors{}
+	f.id("worldId", m.WorldID)
+	if m.Timestamp <= 0 {
+		f.add("timestamp", m.Timestamp, "must be a positive Unix time in milliseconds")
+	}
+	if m.Vibe != nil {
+		f.nested("vibe", m.Vibe.validate)
+	}
+	f.nested("sensorData", m.SensorData.validate)
+	if m.Occupancy < 0 {
+		f.add("occupancy", m.Occupancy, "must not be negative")
+	}
+	f.between("activity", m.Activity, ActivityMin, ActivityMax)
+	if m.CustomData != "" && !json.Valid([]byte(m.CustomData)) {
+		f.add("customData", nil, "must be valid JSON")
+	}
+	if m.BalancedTernaryData != nil {
+		for i, digit := range *m.BalancedTernaryData {
+			if digit < -1 || digit > 1 {
+				f.add(fmt.Sprintf("balancedTernaryData[%d]", i), digit, "must be -1, 0 or 1")
+			}
+		}
+	}
+	f.nested("sharing", m.Sharing.validate)
+	return f.result("moment")
+}
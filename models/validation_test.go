@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fieldNames returns the fields named by a *ValidationError
+func fieldNames(t *testing.T, err error) []string {
+	t.Helper()
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Expected a *ValidationError, got %v", err)
+	}
+	names := make([]string, len(invalid.Fields))
+	for i, field := range invalid.Fields {
+		names[i] = field.Field
+	}
+	return names
+}
+
+func TestVibeValidate(t *testing.T) {
+	humidity := 140.0
+	valid := Vibe{ID: "calm", Energy: 0.3, Mood: MoodCalm, Colors: []string{"#6A98DC", "#fff"}}
+	assert.NoError(t, valid.Validate())
+
+	invalid := Vibe{
+		Energy:     7.3,
+		Mood:       "grumpy",
+		Colors:     []string{"#6A98DC", "blue", "#12345"},
+		SensorData: SensorData{Humidity: &humidity},
+		Sharing:    SharingSettings{ContextLevel: "everything"},
+	}
+	err := invalid.Validate()
+	assert.True(t, errors.Is(err, ErrValidation))
+	assert.Equal(t, []string{"id", "energy", "mood", "colors[1]", "colors[2]", "sensorData.humidity", "sharing.contextLevel"}, fieldNames(t, err))
+
+	for _, id := range []string{"has space", "a/b", "what?"} {
+		assert.Equal(t, []string{"id"}, fieldNames(t, Vibe{ID: id}.Validate()), id)
+	}
+	assert.Equal(t, []string{"energy"}, fieldNames(t, Vibe{ID: "nan", Energy: math.NaN()}.Validate()))
+}
+
+func TestWorldValidate(t *testing.T) {
+	valid := World{ID: "office", Type: WorldTypePhysical, CurrentVibe: "calm", Features: []string{"whiteboard"}}
+	assert.NoError(t, valid.Validate())
+
+	invalid := World{ID: "office", Type: "banana", Occupancy: -1, Features: []string{" "}}
+	assert.Equal(t, []string{"type", "occupancy", "features[0]"}, fieldNames(t, invalid.Validate()))
+
+	reserved := World{ID: ReservedWorldID, Type: WorldTypeVirtual}
+	assert.Equal(t, []string{"id"}, fieldNames(t, reserved.Validate()))
+}
+
+func TestSensorDataValidate(t *testing.T) {
+	cold, bright, movement := -300.0, 800.0, 1.5
+	assert.NoError(t, SensorData{Light: &bright}.Validate())
+	assert.Equal(t, []string{"temperature", "movement"}, fieldNames(t, SensorData{Temperature: &cold, Movement: &movement}.Validate()))
+}
+
+func TestWorldMomentValidate(t *testing.T) {
+	valid := WorldMoment{WorldID: "office", Timestamp: 1700000000000, Activity: 0.5, CustomData: `{"note":"hi"}`}
+	assert.NoError(t, valid.Validate())
+
+	ternary := BalancedTernaryData{1, 2}
+	invalid := WorldMoment{
+		Vibe:                &Vibe{ID: "calm", Energy: 2},
+		Activity:            1.2,
+		CustomData:          "{not json",
+		BalancedTernaryData: &ternary,
+	}
+	assert.Equal(t, []string{"worldId", "timestamp", "vibe.energy", "activity", "customData", "balancedTernaryData[1]"}, fieldNames(t, invalid.Validate()))
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// ErrEdgeNotFound is returned when two worlds are not connected
+var ErrEdgeNotFound = errors.New("worlds are not connected")
+
+// entityKindEdge identifies edges where entities of several kinds are handled
+const entityKindEdge = "edge"
+
+// AdjacencyRepository stores which worlds are next to each other, such as
+// rooms sharing a wall or spaces linked by a virtual portal. Edges are
+// undirected and connect two live worlds; moving a world to the trash
+// removes its edges, and restoring it does not bring them back. Edges have
+// no version or history of their own.
+type AdjacencyRepository interface {
+	// ConnectWorlds stores an edge between two worlds, replacing the weight
+	// and kind of an existing one
+	ConnectWorlds(edge models.WorldEdge) error
+	// DisconnectWorlds removes the edge between two worlds
+	DisconnectWorlds(a, b string) error
+	// WorldEdges returns the edges of a world oriented away from it, ordered
+	// by the ID of the world at the other end
+	WorldEdges(id string) ([]models.WorldEdge, error)
+	// AllWorldEdges returns every edge with its ends in ID order, sorted
+	AllWorldEdges() ([]models.WorldEdge, error)
+}
+
+// edgeKey identifies the edge between a and b, whichever way round they are.
+// IDs cannot contain spaces, so the key is unambiguous.
+func edgeKey(a, b string) string {
+	if b < a {
+		a, b = b, a
+	}
+	return a + " " + b
+}
+
+// splitEdgeKey returns the worlds an edgeKey connects, in ID order
+func splitEdgeKey(key string) (string, string) {
+	a, b, _ := strings.Cut(key, " ")
+	return a, b
+}
+
+// sortEdges orders edges by their From and then their To world
+func sortEdges(edges []models.WorldEdge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+}
+
+// putEdge builds a mutation that stores the given edge
+func putEdge(edge models.WorldEdge) Mutation {
+	return Mutation{Op: OpPutEdge, ID: edgeKey(edge.From, edge.To), Edge: &edge}
+}
+
+// removeEdges drops the edges of a world that left. Callers must hold the
+// write lock.
+func (r *Repository) removeEdges(worldID string) {
+	for key, edge := range r.edges {
+		if edge.From == worldID || edge.To == worldID {
+			delete(r.edges, key)
+		}
+	}
+}
+
+// ConnectWorlds stores an edge between two worlds
+func (r *Repository) ConnectWorlds(edge models.WorldEdge) error {
+	return r.connectWorlds("", edge)
+}
+
+func (r *Repository) connectWorlds(actor string, edge models.WorldEdge) error {
+	return r.write(actor, func(t *writeTxn) error { return t.connectWorlds(edge) })
+}
+
+// DisconnectWorlds removes the edge between two worlds
+func (r *Repository) DisconnectWorlds(a, b string) error {
+	return r.disconnectWorlds("", a, b)
+}
+
+func (r *Repository) disconnectWorlds(actor, a, b string) error {
+	return r.write(actor, func(t *writeTxn) error { return t.disconnectWorlds(a, b) })
+}
+
+// WorldEdges returns the edges of a world oriented away from it
+func (r *Repository) WorldEdges(id string) ([]models.WorldEdge, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.worlds[id]; !ok {
+		return nil, ErrWorldNotFound
+	}
+	edges := []models.WorldEdge{}
+	for _, edge := range r.edges {
+		if edge.From == id || edge.To == id {
+			edges = append(edges, edge.Oriented(id))
+		}
+	}
+	sortEdges(edges)
+	return edges, nil
+}
+
+// AllWorldEdges returns every edge, sorted
+func (r *Repository) AllWorldEdges() ([]models.WorldEdge, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	edges := make([]models.WorldEdge, 0, len(r.edges))
+	for _, edge := range r.edges {
+		edges = append(edges, edge)
+	}
+	sortEdges(edges)
+	return edges, nil
+}
+
+// getEdge returns the edge between a and b as staged so far. An edge is gone
+// once either of its worlds is.
+func (t *writeTxn) getEdge(a, b string) (models.WorldEdge, bool) {
+	if _, ok := t.getWorld(a); !ok {
+		return models.WorldEdge{}, false
+	}
+	if _, ok := t.getWorld(b); !ok {
+		return models.WorldEdge{}, false
+	}
+	key := edgeKey(a, b)
+	if edge, ok := t.edges[key]; ok {
+		if edge == nil {
+			return models.WorldEdge{}, false
+		}
+		return *edge, true
+	}
+	edge, ok := t.r.edges[key]
+	return edge, ok
+}
+
+func (t *writeTxn) connectWorlds(edge models.WorldEdge) error {
+	if err := edge.Validate(); err != nil {
+		return err
+	}
+	for _, id := range []string{edge.From, edge.To} {
+		if _, ok := t.getWorld(id); !ok {
+			return ErrWorldNotFound
+		}
+	}
+	t.stage(putEdge(edge.Normalized()))
+	return nil
+}
+
+func (t *writeTxn) disconnectWorlds(a, b string) error {
+	if _, ok := t.getEdge(a, b); !ok {
+		return ErrEdgeNotFound
+	}
+	t.stage(Mutation{Op: OpDeleteEdge, ID: edgeKey(a, b)})
+	return nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// MaxBatchSize bounds the number of operations in a single batch
+const MaxBatchSize = 1000
+
+// ErrInvalidBatch is matched by every error about a malformed batch
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// BatchOpType names an operation in a batch, after the MCP tool doing the same
+type BatchOpType string
+
+const (
+	BatchCreateVibe   BatchOpType = "create_vibe"
+	BatchUpdateVibe   BatchOpType = "update_vibe"
+	BatchDeleteVibe   BatchOpType = "delete_vibe"
+	BatchCreateWorld  BatchOpType = "create_world"
+	BatchUpdateWorld  BatchOpType = "update_world"
+	BatchDeleteWorld  BatchOpType = "delete_world"
+	BatchSetWorldVibe BatchOpType = "set_world_vibe"
+	BatchConnect      BatchOpType = "connect_worlds"
+	BatchDisconnect   BatchOpType = "disconnect_worlds"
+)
+
+// BatchOp is one operation of a batch. Which fields are used depends on Op:
+// Vibe for create_vibe and update_vibe, World for create_world and
+// update_world, ID (with Mode and ReplacementID for vibes) for deletes, and
+// WorldID, VibeID, Version and Duration for set_world_vibe, and Edge for
+// connect_worlds and disconnect_worlds. Creates may leave the entity's ID
+// empty to have one generated from its name.
+type BatchOp struct {
+	Op            BatchOpType       `json:"op" jsonschema:"required,enum=create_vibe,enum=update_vibe,enum=delete_vibe,enum=create_world,enum=update_world,enum=delete_world,enum=set_world_vibe,enum=connect_worlds,enum=disconnect_worlds"`
+	Vibe          *models.Vibe      `json:"vibe,omitempty"`
+	World         *models.World     `json:"world,omitempty"`
+	ID            string            `json:"id,omitempty"`
+	Mode          DeleteMode        `json:"mode,omitempty" jsonschema:"enum=reject,enum=reassign,enum=clear"`
+	ReplacementID string            `json:"replacementId,omitempty"`
+	WorldID       string            `json:"worldId,omitempty"`
+	VibeID        string            `json:"vibeId,omitempty"`
+	Version       int64             `json:"version,omitempty" jsonschema:"minimum=0"`  // Expected world version for set_world_vibe; 0 skips the check
+	Duration      int64             `json:"duration,omitempty" jsonschema:"minimum=0"` // Transition length in milliseconds for set_world_vibe
+	Edge          *models.WorldEdge `json:"edge,omitempty"`
+}
+
+// BatchError reports which operation made a batch fail. It unwraps to the
+// operation's error, so errors.Is works with the usual sentinels.
+type BatchError struct {
+	Index int
+	Op    BatchOpType
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch operation %d (%s) failed: %v", e.Index, e.Op, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BatchRepository applies several writes as one
+type BatchRepository interface {
+	// ApplyBatch applies ops in order, all or nothing. The batch is checked
+	// for malformed operations before anything runs, and each operation sees
+	// the effects of the ones before it. On failure nothing is written and
+	// the error is a *BatchError naming the failed operation. Creates
+	// without an ID get a generated one; the returned IDs hold, for each
+	// operation, the ID of the vibe or world it created, or "" if it
+	// created nothing.
+	ApplyBatch(ops []BatchOp) ([]string, error)
+}
+
+// validateBatch checks that every operation is well formed
+func validateBatch(ops []BatchOp) error {
+	if len(ops) == 0 {
+		return fmt.Errorf("%w: no operations", ErrInvalidBatch)
+	}
+	if len(ops) > MaxBatchSize {
+		return fmt.Errorf("%w: %d operations exceed the limit of %d", ErrInvalidBatch, len(ops), MaxBatchSize)
+	}
+	for i, op := range ops {
+		if err := op.validate(); err != nil {
+			return &BatchError{Index: i, Op: op.Op, Err: fmt.Errorf("%w: %v", ErrInvalidBatch, err)}
+		}
+	}
+	return nil
+}
+
+// validate checks that an operation has the fields it needs
+func (op BatchOp) validate() error {
+	switch op.Op {
+	case BatchCreateVibe:
+		if op.Vibe == nil {
+			return errors.New("vibe is required")
+		}
+	case BatchUpdateVibe:
+		if op.Vibe == nil || op.Vibe.ID == "" {
+			return errors.New("vibe with an id is required")
+		}
+	case BatchCreateWorld:
+		if op.World == nil {
+			return errors.New("world is required")
+		}
+	case BatchUpdateWorld:
+		if op.World == nil || op.World.ID == "" {
+			return errors.New("world with an id is required")
+		}
+	case BatchDeleteVibe:
+		if op.ID == "" {
+			return errors.New("id is required")
+		}
+		if _, err := ParseDeleteMode(string(op.Mode)); err != nil {
+			return err
+		}
+	case BatchDeleteWorld:
+		if op.ID == "" {
+			return errors.New("id is required")
+		}
+	case BatchSetWorldVibe:
+		if op.WorldID == "" || op.VibeID == "" {
+			return errors.New("worldId and vibeId are required")
+		}
+		if op.Duration < 0 {
+			return errors.New("duration must not be negative")
+		}
+	case BatchConnect, BatchDisconnect:
+		if op.Edge == nil || op.Edge.From == "" || op.Edge.To == "" {
+			return errors.New("edge with from and to is required")
+		}
+	default:
+		return fmt.Errorf("unknown operation %q", op.Op)
+	}
+	return nil
+}
+
+// ApplyBatch applies ops in order, all or nothing, as a single change
+func (r *Repository) ApplyBatch(ops []BatchOp) ([]string, error) {
+	return r.applyBatch("", ops)
+}
+
+func (r *Repository) applyBatch(actor string, ops []BatchOp) ([]string, error) {
+	if err := validateBatch(ops); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(ops))
+	err := r.write(actor, func(t *writeTxn) error {
+		for i, op := range ops {
+			id, err := t.applyOp(op)
+			if err != nil {
+				return &BatchError{Index: i, Op: op.Op, Err: err}
+			}
+			ids[i] = id
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// applyOp stages a single validated batch operation, returning the ID of the
+// entity it created, if any
+func (t *writeTxn) applyOp(op BatchOp) (string, error) {
+	switch op.Op {
+	case BatchCreateVibe:
+		vibe, err := t.addVibe(*op.Vibe)
+		return vibe.ID, err
+	case BatchUpdateVibe:
+		_, err := t.updateVibe(*op.Vibe)
+		return "", err
+	case BatchDeleteVibe:
+		_, err := t.deleteVibe(op.ID, VibeDeleteOptions{Mode: op.Mode, ReplacementID: op.ReplacementID})
+		return "", err
+	case BatchCreateWorld:
+		world, err := t.addWorld(*op.World)
+		return world.ID, err
+	case BatchUpdateWorld:
+		_, err := t.updateWorld(*op.World)
+		return "", err
+	case BatchDeleteWorld:
+		return "", t.deleteWorld(op.ID)
+	case BatchSetWorldVibe:
+		_, err := t.setWorldVibe(op.WorldID, op.VibeID, op.Version, time.Duration(op.Duration)*time.Millisecond)
+		return "", err
+	case BatchConnect:
+		return "", t.connectWorlds(*op.Edge)
+	case BatchDisconnect:
+		return "", t.disconnectWorlds(op.Edge.From, op.Edge.To)
+	}
+	return "", fmt.Errorf("unknown operation %q", op.Op)
+}
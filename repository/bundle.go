@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// BundleFormat identifies a vibespace bundle, and BundleVersion is the
+// newest bundle layout this package reads and the one it writes
+const (
+	BundleFormat  = "vibespace-bundle"
+	BundleVersion = 1
+)
+
+var (
+	// ErrInvalidBundle is matched by every error about a malformed bundle
+	ErrInvalidBundle = errors.New("invalid bundle")
+	// ErrImportConflict is returned by ImportFail when an entity in the
+	// bundle already exists
+	ErrImportConflict = errors.New("entity already exists")
+)
+
+// Bundle is a portable snapshot of every vibe and world. Relations between
+// them (a world's current vibe) and sharing settings travel inside the
+// entities, and the adjacency of worlds in Edges. History and the trash are
+// not included.
+type Bundle struct {
+	Format     string             `json:"format" jsonschema:"required,enum=vibespace-bundle"`
+	Version    int                `json:"version" jsonschema:"required,minimum=1,maximum=1"`
+	ExportedAt time.Time          `json:"exportedAt"`
+	Vibes      []models.Vibe      `json:"vibes" jsonschema:"required"`
+	Worlds     []models.World     `json:"worlds" jsonschema:"required"`
+	Edges      []models.WorldEdge `json:"edges,omitempty"`
+}
+
+// ImportStrategy chooses what happens to entities of a bundle whose ID is
+// already in use
+type ImportStrategy string
+
+const (
+	ImportOverwrite ImportStrategy = "overwrite" // Replace the existing entity
+	ImportSkip      ImportStrategy = "skip"      // Keep the existing entity (the default)
+	ImportFail      ImportStrategy = "fail"      // Import nothing, with ErrImportConflict
+)
+
+// ParseImportStrategy converts a strategy name to an ImportStrategy; empty
+// means ImportSkip
+func ParseImportStrategy(strategy string) (ImportStrategy, error) {
+	switch ImportStrategy(strategy) {
+	case "", ImportSkip:
+		return ImportSkip, nil
+	case ImportOverwrite, ImportFail:
+		return ImportStrategy(strategy), nil
+	}
+	return "", fmt.Errorf("invalid import strategy %q: expected overwrite, skip or fail", strategy)
+}
+
+// ImportCounts tallies what an import did with one kind of entity
+type ImportCounts struct {
+	Created     int `json:"created"`
+	Overwritten int `json:"overwritten"`
+	Skipped     int `json:"skipped"`
+
+	// GeneratedIDs holds the IDs given to entities the bundle listed without
+	// one, in bundle order
+	GeneratedIDs []string `json:"generatedIds,omitempty"`
+}
+
+// ImportResult reports what an import did
+type ImportResult struct {
+	Vibes  ImportCounts `json:"vibes"`
+	Worlds ImportCounts `json:"worlds"`
+	Edges  ImportCounts `json:"edges"`
+}
+
+// BundleRepository exports and imports the whole repository
+type BundleRepository interface {
+	// ExportBundle returns a consistent snapshot of every vibe, world and
+	// edge, ordered by ID
+	ExportBundle() (Bundle, error)
+	// ImportBundle writes the vibes, worlds and edges of a bundle, all or nothing,
+	// resolving existing IDs with strategy. Imported entities get the next
+	// version of any entity they replace; the versions in the bundle are
+	// ignored.
+	ImportBundle(bundle Bundle, strategy ImportStrategy) (ImportResult, error)
+}
+
+// NewBundle builds a bundle of the given entities
+func NewBundle(vibes []models.Vibe, worlds []models.World) Bundle {
+	return Bundle{
+		Format:     BundleFormat,
+		Version:    BundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Vibes:      vibes,
+		Worlds:     worlds,
+	}
+}
+
+// WriteBundle encodes a bundle as indented JSON
+func WriteBundle(w io.Writer, bundle Bundle) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bundle)
+}
+
+// ReadBundle decodes and checks a bundle written by WriteBundle
+func ReadBundle(r io.Reader) (Bundle, error) {
+	var bundle Bundle
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return Bundle{}, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if err := bundle.validate(); err != nil {
+		return Bundle{}, err
+	}
+	return bundle, nil
+}
+
+// validate checks the bundle header and that no two entities share an ID.
+// Entities without an ID are created with a generated one.
+func (b Bundle) validate() error {
+	if b.Format != BundleFormat {
+		return fmt.Errorf("%w: format %q is not %q", ErrInvalidBundle, b.Format, BundleFormat)
+	}
+	if b.Version < 1 || b.Version > BundleVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidBundle, b.Version)
+	}
+
+	vibeIDs := make(map[string]bool, len(b.Vibes))
+	for _, vibe := range b.Vibes {
+		if vibe.ID == "" {
+			continue
+		}
+		if vibeIDs[vibe.ID] {
+			return fmt.Errorf("%w: duplicate vibe %q", ErrInvalidBundle, vibe.ID)
+		}
+		vibeIDs[vibe.ID] = true
+	}
+	worldIDs := make(map[string]bool, len(b.Worlds))
+	for _, world := range b.Worlds {
+		if world.ID == "" {
+			continue
+		}
+		if worldIDs[world.ID] {
+			return fmt.Errorf("%w: duplicate world %q", ErrInvalidBundle, world.ID)
+		}
+		worldIDs[world.ID] = true
+	}
+	edgeKeys := make(map[string]bool, len(b.Edges))
+	for i, edge := range b.Edges {
+		if edge.From == "" || edge.To == "" {
+			return fmt.Errorf("%w: edge %d needs from and to", ErrInvalidBundle, i)
+		}
+		key := edgeKey(edge.From, edge.To)
+		if edgeKeys[key] {
+			return fmt.Errorf("%w: duplicate edge between %q and %q", ErrInvalidBundle, edge.From, edge.To)
+		}
+		edgeKeys[key] = true
+	}
+	return nil
+}
+
+// planImport turns a bundle into the batch operations that import it,
+// vibes first so worlds can refer to them, parents before the vibes and
+// worlds nested in them, and edges last. exists reports whether an entity of
+// a kind is stored; edges are identified by their edgeKey.
+func planImport(bundle Bundle, strategy ImportStrategy, exists func(kind, id string) (bool, error)) ([]BatchOp, ImportResult, error) {
+	var result ImportResult
+	if err := bundle.validate(); err != nil {
+		return nil, result, err
+	}
+	if _, err := ParseImportStrategy(string(strategy)); err != nil {
+		return nil, result, err
+	}
+
+	var ops []BatchOp
+	// plan decides the operation for one entity and counts it
+	plan := func(kind, id string, counts *ImportCounts, create, overwrite BatchOp) error {
+		found := false
+		if id != "" {
+			var err error
+			if found, err = exists(kind, id); err != nil {
+				return err
+			}
+		}
+		switch {
+		case !found:
+			ops = append(ops, create)
+			counts.Created++
+		case strategy == ImportOverwrite:
+			ops = append(ops, overwrite)
+			counts.Overwritten++
+		case strategy == ImportFail:
+			return fmt.Errorf("%w: %s %q", ErrImportConflict, kind, id)
+		default:
+			counts.Skipped++
+		}
+		return nil
+	}
+
+	for _, vibe := range parentsFirst(bundle.Vibes, func(v models.Vibe) (string, string) { return v.ID, v.ParentID }) {
+		vibe := vibe
+		vibe.Version = 0 // Overwrite whatever version is stored
+		if vibe.IsDerived() && vibe.Overrides == nil {
+			vibe.Overrides = []string{} // A bundle lists every override
+		}
+		err := plan(entityKindVibe, vibe.ID, &result.Vibes,
+			BatchOp{Op: BatchCreateVibe, Vibe: &vibe},
+			BatchOp{Op: BatchUpdateVibe, Vibe: &vibe})
+		if err != nil {
+			return nil, ImportResult{}, err
+		}
+	}
+	for _, world := range parentsFirst(bundle.Worlds, func(w models.World) (string, string) { return w.ID, w.ParentID }) {
+		world := world
+		world.Version = 0
+		err := plan(entityKindWorld, world.ID, &result.Worlds,
+			BatchOp{Op: BatchCreateWorld, World: &world},
+			BatchOp{Op: BatchUpdateWorld, World: &world})
+		if err != nil {
+			return nil, ImportResult{}, err
+		}
+	}
+	for _, edge := range bundle.Edges {
+		edge := edge
+		op := BatchOp{Op: BatchConnect, Edge: &edge}
+		if err := plan(entityKindEdge, edgeKey(edge.From, edge.To), &result.Edges, op, op); err != nil {
+			return nil, ImportResult{}, err
+		}
+	}
+	return ops, result, nil
+}
+
+// parentsFirst orders entities so that each parent in the list comes before
+// the entities nested in it, keeping the order of the list otherwise. ids
+// returns the ID and parent ID of an entity.
+func parentsFirst[T any](entities []T, ids func(T) (string, string)) []T {
+	byID := make(map[string]int, len(entities))
+	for i, entity := range entities {
+		if id, _ := ids(entity); id != "" {
+			byID[id] = i
+		}
+	}
+
+	ordered := make([]T, 0, len(entities))
+	visited := make([]bool, len(entities))
+	var visit func(i int)
+	visit = func(i int) {
+		if visited[i] {
+			return // Done, or a cycle that the import will reject
+		}
+		visited[i] = true
+		_, parentID := ids(entities[i])
+		if parent, ok := byID[parentID]; ok && parentID != "" {
+			visit(parent)
+		}
+		ordered = append(ordered, entities[i])
+	}
+	for i := range entities {
+		visit(i)
+	}
+	return ordered
+}
+
+// noteGenerated records the ID an import operation gave an entity the bundle
+// listed without one
+func (r *ImportResult) noteGenerated(op BatchOp, id string) {
+	switch {
+	case op.Op == BatchCreateVibe && op.Vibe.ID == "":
+		r.Vibes.GeneratedIDs = append(r.Vibes.GeneratedIDs, id)
+	case op.Op == BatchCreateWorld && op.World.ID == "":
+		r.Worlds.GeneratedIDs = append(r.Worlds.GeneratedIDs, id)
+	}
+}
+
+// importError names the entity whose import failed
+func importError(op BatchOp, err error) error {
+	if op.Vibe != nil {
+		return fmt.Errorf("importing vibe %q: %w", op.Vibe.ID, err)
+	}
+	if op.Edge != nil {
+		return fmt.Errorf("importing edge between %q and %q: %w", op.Edge.From, op.Edge.To, err)
+	}
+	return fmt.Errorf("importing world %q: %w", op.World.ID, err)
+}
+
+// ExportBundle returns a snapshot of every vibe, world and edge, ordered by ID
+func (r *Repository) ExportBundle() (Bundle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	vibes := make([]models.Vibe, 0, len(r.vibes))
+	for _, vibe := range r.vibes {
+		vibes = append(vibes, vibe)
+	}
+	worlds := make([]models.World, 0, len(r.worlds))
+	for _, world := range r.worlds {
+		worlds = append(worlds, world)
+	}
+	sort.Slice(vibes, func(i, j int) bool { return vibes[i].ID < vibes[j].ID })
+	sort.Slice(worlds, func(i, j int) bool { return worlds[i].ID < worlds[j].ID })
+	edges := make([]models.WorldEdge, 0, len(r.edges))
+	for _, edge := range r.edges {
+		edges = append(edges, edge)
+	}
+	sortEdges(edges)
+
+	bundle := NewBundle(vibes, worlds)
+	bundle.Edges = edges
+	return bundle, nil
+}
+
+// ImportBundle writes the vibes, worlds and edges of a bundle, all or nothing
+func (r *Repository) ImportBundle(bundle Bundle, strategy ImportStrategy) (ImportResult, error) {
+	return r.importBundle("", bundle, strategy)
+}
+
+func (r *Repository) importBundle(actor string, bundle Bundle, strategy ImportStrategy) (ImportResult, error) {
+	var result ImportResult
+	err := r.write(actor, func(t *writeTxn) error {
+		ops, planned, err := planImport(bundle, strategy, func(kind, id string) (bool, error) {
+			switch kind {
+			case entityKindVibe:
+				_, ok := t.getVibe(id)
+				return ok, nil
+			case entityKindEdge:
+				_, ok := t.getEdge(splitEdgeKey(id))
+				return ok, nil
+			}
+			_, ok := t.getWorld(id)
+			return ok, nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, op := range ops {
+			id, err := t.applyOp(op)
+			if err != nil {
+				return importError(op, err)
+			}
+			planned.noteGenerated(op, id)
+		}
+		result = planned
+		return nil
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// ErrInvalidReplacement is returned when a reassigning delete names a
+// replacement vibe that does not exist or is the vibe being deleted
+var ErrInvalidReplacement = errors.New("replacement vibe must be a different, existing vibe")
+
+// DeleteMode chooses what happens to the worlds using a vibe being deleted
+type DeleteMode string
+
+const (
+	DeleteReject   DeleteMode = "reject"   // Fail with ErrVibeInUse (the default)
+	DeleteReassign DeleteMode = "reassign" // Point the worlds at a replacement vibe
+	DeleteClear    DeleteMode = "clear"    // Leave the worlds without a vibe
+)
+
+// ParseDeleteMode converts a mode name to a DeleteMode; empty means DeleteReject
+func ParseDeleteMode(mode string) (DeleteMode, error) {
+	switch DeleteMode(mode) {
+	case "", DeleteReject:
+		return DeleteReject, nil
+	case DeleteReassign, DeleteClear:
+		return DeleteMode(mode), nil
+	}
+	return "", fmt.Errorf("invalid delete mode %q: expected reject, reassign or clear", mode)
+}
+
+// VibeDeleteOptions configures DeleteVibeWithOptions
+type VibeDeleteOptions struct {
+	Mode          DeleteMode
+	ReplacementID string // Vibe to assign with DeleteReassign
+}
+
+// DependentsRepository tracks which worlds use each vibe
+type DependentsRepository interface {
+	// GetVibeWorlds returns the worlds whose current vibe is vibeID, ordered by ID
+	GetVibeWorlds(vibeID string) ([]models.World, error)
+	// DeleteVibeWithOptions deletes a vibe, handling the worlds that use it
+	// according to opts. Every affected world and the vibe change together
+	// or not at all. It returns the IDs of the worlds that were changed.
+	DeleteVibeWithOptions(id string, opts VibeDeleteOptions) ([]string, error)
+}
+
+// replacementVibe returns the vibe worlds get when their vibe is deleted with opts
+func replacementVibe(id string, opts VibeDeleteOptions, exists func(string) bool) (string, error) {
+	switch opts.Mode {
+	case "", DeleteReject:
+		return "", ErrVibeInUse
+	case DeleteClear:
+		return "", nil
+	case DeleteReassign:
+		if opts.ReplacementID == "" || opts.ReplacementID == id || !exists(opts.ReplacementID) {
+			return "", ErrInvalidReplacement
+		}
+		return opts.ReplacementID, nil
+	}
+	return "", fmt.Errorf("invalid delete mode %q", opts.Mode)
+}
+
+// indexWorld updates the vibe-to-worlds index for a world going from before
+// to after; either may be nil. Callers must hold the write lock.
+func (r *Repository) indexWorld(before, after *models.World) {
+	if before != nil && before.CurrentVibe != "" {
+		delete(r.vibeWorlds[before.CurrentVibe], before.ID)
+		if len(r.vibeWorlds[before.CurrentVibe]) == 0 {
+			delete(r.vibeWorlds, before.CurrentVibe)
+		}
+	}
+	if after != nil && after.CurrentVibe != "" {
+		if r.vibeWorlds[after.CurrentVibe] == nil {
+			r.vibeWorlds[after.CurrentVibe] = make(map[string]struct{})
+		}
+		r.vibeWorlds[after.CurrentVibe][after.ID] = struct{}{}
+	}
+}
+
+// dependentIDs returns the IDs of the worlds using a vibe, sorted. Callers
+// must hold the lock.
+func (r *Repository) dependentIDs(vibeID string) []string {
+	ids := make([]string, 0, len(r.vibeWorlds[vibeID]))
+	for id := range r.vibeWorlds[vibeID] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// GetVibeWorlds returns the worlds whose current vibe is vibeID, ordered by ID
+func (r *Repository) GetVibeWorlds(vibeID string) ([]models.World, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.vibes[vibeID]; !ok {
+		return nil, ErrVibeNotFound
+	}
+	worlds := []models.World{}
+	for _, id := range r.dependentIDs(vibeID) {
+		worlds = append(worlds, r.worlds[id])
+	}
+	return worlds, nil
+}
+
+// DeleteVibeWithOptions deletes a vibe, handling the worlds that use it
+// according to opts
+func (r *Repository) DeleteVibeWithOptions(id string, opts VibeDeleteOptions) ([]string, error) {
+	return r.deleteVibeWithOptions("", id, opts)
+}
+
+func (r *Repository) deleteVibeWithOptions(actor, id string, opts VibeDeleteOptions) ([]string, error) {
+	var dependents []string
+	err := r.write(actor, func(t *writeTxn) error {
+		var err error
+		dependents, err = t.deleteVibe(id, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dependents, nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// DefaultEventBuffer is the channel capacity used when SubscribeChanges is
+// given a non-positive buffer size
+const DefaultEventBuffer = 256
+
+// ChangeEventType identifies what a ChangeEvent describes
+type ChangeEventType string
+
+const (
+	EventWorldCreated ChangeEventType = "world.created"
+	EventWorldUpdated ChangeEventType = "world.updated"
+	EventWorldDeleted ChangeEventType = "world.deleted"
+	EventWorldVibeSet ChangeEventType = "world.vibeSet" // The world's current vibe changed
+	EventVibeChanged  ChangeEventType = "vibe.changed"  // A vibe was created, updated or deleted
+)
+
+// ChangeEvent describes one committed write
+type ChangeEvent struct {
+	Type         ChangeEventType `json:"type"`
+	ID           string          `json:"id"` // ID of the world or vibe
+	Time         time.Time       `json:"time"`
+	Actor        string          `json:"actor,omitempty"`
+	World        *models.World   `json:"world,omitempty"`        // World after the write; nil for vibe events and deletes
+	Vibe         *models.Vibe    `json:"vibe,omitempty"`         // Vibe after the write; nil for world events and deletes
+	PreviousVibe string          `json:"previousVibe,omitempty"` // Former current vibe, for EventWorldVibeSet
+}
+
+// ChangeNotifier lets callers follow writes as they are committed
+type ChangeNotifier interface {
+	// SubscribeChanges returns a channel receiving every committed change, in
+	// commit order, and a function that ends the subscription and closes the
+	// channel. A subscriber that lets its buffer fill up misses events rather
+	// than blocking writers.
+	SubscribeChanges(buffer int) (<-chan ChangeEvent, func())
+}
+
+// vibeChangeEvent describes a write to a vibe; after is nil for deletes
+func vibeChangeEvent(when time.Time, actor, id string, after *models.Vibe) ChangeEvent {
+	return ChangeEvent{Type: EventVibeChanged, ID: id, Time: when, Actor: actor, Vibe: after}
+}
+
+// worldChangeEvent describes a world going from before to after; either may be nil
+func worldChangeEvent(when time.Time, actor, id string, before, after *models.World) ChangeEvent {
+	event := ChangeEvent{Type: EventWorldUpdated, ID: id, Time: when, Actor: actor, World: after}
+	switch {
+	case before == nil:
+		event.Type = EventWorldCreated
+	case after == nil:
+		event.Type = EventWorldDeleted
+	case before.CurrentVibe != after.CurrentVibe:
+		event.Type = EventWorldVibeSet
+		event.PreviousVibe = before.CurrentVibe
+	}
+	return event
+}
+
+// eventBus fans change events out to subscribers. The zero value is ready to use.
+type eventBus struct {
+	mu     sync.Mutex
+	subs   map[int]chan ChangeEvent
+	nextID int
+}
+
+// subscribe registers a new subscriber channel
+func (b *eventBus) subscribe(buffer int) (<-chan ChangeEvent, func()) {
+	if buffer <= 0 {
+		buffer = DefaultEventBuffer
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs == nil {
+		b.subs = make(map[int]chan ChangeEvent)
+	}
+	id := b.nextID
+	b.nextID++
+	ch := make(chan ChangeEvent, buffer)
+	b.subs[id] = ch
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs, id)
+			close(ch)
+		})
+	}
+}
+
+// publish delivers events to every subscriber without blocking
+func (b *eventBus) publish(events ...ChangeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, event := range events {
+		for _, ch := range b.subs {
+			select {
+			case ch <- event:
+			default:
+				fmt.Printf("Dropping %s event for %s: subscriber is not keeping up\n", event.Type, event.ID)
+			}
+		}
+	}
+}
+
+// SubscribeChanges returns a channel receiving every committed change
+func (r *Repository) SubscribeChanges(buffer int) (<-chan ChangeEvent, func()) {
+	return r.events.subscribe(buffer)
+}
+
+// SubscribeChanges returns a channel receiving every committed change
+func (r *SQLRepository) SubscribeChanges(buffer int) (<-chan ChangeEvent, func()) {
+	return r.events.subscribe(buffer)
+}
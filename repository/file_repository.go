@@ -0,0 +1,388 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+const (
+	// SnapshotFileName is the name of the snapshot file inside the data directory
+	SnapshotFileName = "snapshot.json"
+
+	// JournalFileName is the name of the append-only journal inside the data directory
+	JournalFileName = "journal.log"
+
+	// DefaultCompactEvery is the number of journal records after which the
+	// journal is folded into a fresh snapshot
+	DefaultCompactEvery = 1000
+)
+
+var (
+	// ErrCorruptJournal is returned when a complete journal record cannot be decoded
+	ErrCorruptJournal = errors.New("journal is corrupt")
+
+	// ErrRepositoryClosed is returned when writing to a closed FileRepository
+	ErrRepositoryClosed = errors.New("repository is closed")
+)
+
+// journalRecord is one line of the journal file
+type journalRecord struct {
+	Seq uint64 `json:"seq"`
+	Change
+}
+
+// snapshot is the on-disk representation of the full repository state
+type snapshot struct {
+	Seq     uint64         `json:"seq"`
+	Vibes   []models.Vibe  `json:"vibes"`
+	Worlds  []models.World `json:"worlds"`
+	History []HistoryEntry `json:"history,omitempty"`
+
+	// HistoryCuts holds the time of the newest dropped history entry, keyed by historyKey
+	HistoryCuts map[string]time.Time `json:"historyCuts,omitempty"`
+
+	Edges []models.WorldEdge `json:"edges,omitempty"`
+
+	TrashedVibes  []DeletedVibe  `json:"trashedVibes,omitempty"`
+	TrashedWorlds []DeletedWorld `json:"trashedWorlds,omitempty"`
+}
+
+// FileRepository is a VibeWorldRepository that survives restarts.
+//
+// State is served from an embedded in-memory Repository. Every write is first
+// appended to a journal file and fsynced, and only then applied in memory, so
+// a write that returned successfully is on disk. The journal is periodically
+// folded into a snapshot file. On startup the snapshot is loaded and the
+// journal replayed on top of it; a torn record at the end of the journal (left
+// by a crash mid-write) is discarded.
+type FileRepository struct {
+	*Repository
+
+	dir          string
+	file         *os.File
+	seq          uint64 // sequence number of the last journal record written
+	records      int    // journal records written since the last snapshot
+	compactEvery int
+	compacting   bool
+	mu           sync.Mutex
+}
+
+// Ensure FileRepository implements VibeWorldRepository interface
+var _ VibeWorldRepository = (*FileRepository)(nil)
+
+// NewFileRepository opens (or creates) a file-backed repository in dir
+func NewFileRepository(dir string) (*FileRepository, error) {
+	return NewFileRepositoryWithSampleData(dir, false)
+}
+
+// NewFileRepositoryWithSampleData opens (or creates) a file-backed repository
+// in dir, optionally seeding a new one with the same sample data as
+// NewRepository. The sample data is written to a snapshot at once, so it is
+// only ever added to an empty directory.
+func NewFileRepositoryWithSampleData(dir string, includeSampleData bool) (*FileRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	fr := &FileRepository{
+		Repository:   NewRepositoryWithSampleData(false),
+		dir:          dir,
+		compactEvery: DefaultCompactEvery,
+	}
+
+	if err := fr.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := fr.replayJournal(); err != nil {
+		return nil, err
+	}
+
+	fr.Repository.journal = fr
+
+	if includeSampleData && fr.seq == 0 && len(fr.Repository.vibes) == 0 && len(fr.Repository.worlds) == 0 {
+		fr.Repository.addSampleData()
+		if err := fr.Compact(); err != nil {
+			fr.Close()
+			return nil, fmt.Errorf("failed to seed sample data: %w", err)
+		}
+	}
+	return fr, nil
+}
+
+// SetCompactEvery changes how many journal records trigger a compaction.
+// A value of zero or less disables automatic compaction.
+func (fr *FileRepository) SetCompactEvery(n int) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.compactEvery = n
+}
+
+// Dir returns the data directory of the repository
+func (fr *FileRepository) Dir() string {
+	return fr.dir
+}
+
+// Close flushes and closes the journal file
+func (fr *FileRepository) Close() error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	if fr.file == nil {
+		return nil
+	}
+	err := fr.file.Close()
+	fr.file = nil
+	return err
+}
+
+// Append writes a change to the journal and fsyncs it.
+// It is called by the embedded Repository while it holds its write lock.
+func (fr *FileRepository) Append(change Change) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	if fr.file == nil {
+		return ErrRepositoryClosed
+	}
+
+	record := journalRecord{Seq: fr.seq + 1, Change: change}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal record: %w", err)
+	}
+	line = append(line, '\n')
+
+	offset, err := fr.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to locate journal end: %w", err)
+	}
+	if _, err := fr.file.Write(line); err != nil {
+		fr.discardFrom(offset)
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := fr.file.Sync(); err != nil {
+		fr.discardFrom(offset)
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+
+	fr.seq = record.Seq
+	fr.records++
+
+	// Compaction needs the repository lock our caller is holding, so it runs
+	// once the current write has been applied.
+	if fr.compactEvery > 0 && fr.records >= fr.compactEvery && !fr.compacting {
+		fr.compacting = true
+		go func() {
+			if err := fr.Compact(); err != nil && !errors.Is(err, ErrRepositoryClosed) {
+				fmt.Printf("Error compacting repository journal: %v\n", err)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// discardFrom drops a partially written record so the next one starts on a
+// clean line. Errors are ignored: replay tolerates a torn tail anyway.
+func (fr *FileRepository) discardFrom(offset int64) {
+	fr.file.Truncate(offset)
+	fr.file.Seek(offset, io.SeekStart)
+}
+
+// Compact writes the current state to a new snapshot and truncates the journal
+func (fr *FileRepository) Compact() error {
+	// Block writers so the snapshot and journal agree
+	fr.Repository.mu.Lock()
+	defer fr.Repository.mu.Unlock()
+
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	defer func() { fr.compacting = false }()
+
+	if fr.file == nil {
+		return ErrRepositoryClosed
+	}
+
+	snap := snapshot{
+		Seq:     fr.seq,
+		Vibes:   make([]models.Vibe, 0, len(fr.Repository.vibes)),
+		Worlds:  make([]models.World, 0, len(fr.Repository.worlds)),
+		History: fr.Repository.allHistory(),
+
+		HistoryCuts: fr.Repository.historyCuts,
+	}
+	for _, vibe := range fr.Repository.vibes {
+		snap.Vibes = append(snap.Vibes, vibe)
+	}
+	for _, world := range fr.Repository.worlds {
+		snap.Worlds = append(snap.Worlds, world)
+	}
+	for _, edge := range fr.Repository.edges {
+		snap.Edges = append(snap.Edges, edge)
+	}
+	for _, vibe := range fr.Repository.trashedVibes {
+		snap.TrashedVibes = append(snap.TrashedVibes, vibe)
+	}
+	for _, world := range fr.Repository.trashedWorlds {
+		snap.TrashedWorlds = append(snap.TrashedWorlds, world)
+	}
+
+	if err := writeFileAtomic(filepath.Join(fr.dir, SnapshotFileName), snap); err != nil {
+		return err
+	}
+
+	// Records up to snap.Seq are now covered by the snapshot. If we crash
+	// before the truncation completes they are skipped on replay.
+	if err := fr.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate journal: %w", err)
+	}
+	if _, err := fr.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind journal: %w", err)
+	}
+	if err := fr.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+
+	fr.records = 0
+	return nil
+}
+
+// loadSnapshot reads the snapshot file, if any, into the in-memory repository
+func (fr *FileRepository) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(fr.dir, SnapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	for _, vibe := range snap.Vibes {
+		fr.Repository.vibes[vibe.ID] = vibe
+	}
+	for _, world := range snap.Worlds {
+		fr.Repository.worlds[world.ID] = world
+		fr.Repository.indexWorld(nil, &world)
+	}
+	for _, edge := range snap.Edges {
+		fr.Repository.edges[edgeKey(edge.From, edge.To)] = edge
+	}
+	for _, entry := range snap.History {
+		fr.Repository.record(entry)
+	}
+	for key, at := range snap.HistoryCuts {
+		fr.Repository.cutHistory(key, at)
+	}
+	for _, vibe := range snap.TrashedVibes {
+		fr.Repository.trashedVibes[vibe.ID] = vibe
+	}
+	for _, world := range snap.TrashedWorlds {
+		fr.Repository.trashedWorlds[world.ID] = world
+	}
+	fr.seq = snap.Seq
+	return nil
+}
+
+// replayJournal applies journal records newer than the snapshot and leaves the
+// journal file open for appending
+func (fr *FileRepository) replayJournal() error {
+	path := filepath.Join(fr.dir, JournalFileName)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	snapshotSeq := fr.seq
+	offset := 0
+	line := 0
+	for offset < len(data) {
+		end := bytes.IndexByte(data[offset:], '\n')
+		if end < 0 {
+			// Torn write at the tail: the record was never acknowledged
+			break
+		}
+		line++
+
+		var record journalRecord
+		if err := json.Unmarshal(data[offset:offset+end], &record); err != nil {
+			file.Close()
+			return fmt.Errorf("%w: line %d: %v", ErrCorruptJournal, line, err)
+		}
+		offset += end + 1
+
+		if record.Seq <= snapshotSeq {
+			continue
+		}
+		fr.Repository.apply(record.Change)
+		fr.seq = record.Seq
+		fr.records++
+	}
+
+	// Drop any torn tail so new records start on a clean line
+	if err := file.Truncate(int64(offset)); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to truncate journal: %w", err)
+	}
+	if _, err := file.Seek(int64(offset), io.SeekStart); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to seek journal: %w", err)
+	}
+
+	fr.file = file
+	return nil
+}
+
+// writeFileAtomic encodes v as JSON into path via a synced temporary file and a rename
+func writeFileAtomic(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Base(tmp), err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(tmp), err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync %s: %w", filepath.Base(tmp), err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", filepath.Base(tmp), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filepath.Base(path), err)
+	}
+
+	// Make the rename itself durable
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
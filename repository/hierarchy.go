@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+var (
+	// ErrWorldHasChildren is returned when deleting a world other worlds are nested in
+	ErrWorldHasChildren = errors.New("world contains one or more child worlds")
+	// ErrWorldCycle is returned when a world would be nested in itself
+	ErrWorldCycle = errors.New("world would be nested in itself")
+)
+
+// WorldTreeRepository nests worlds in other worlds, such as rooms in a floor,
+// through their ParentID. A world's parent must exist, and a world with
+// children cannot be deleted.
+type WorldTreeRepository interface {
+	// ChildWorlds returns the worlds directly inside a world, ordered by ID
+	ChildWorlds(id string) ([]models.World, error)
+	// WorldAncestors returns the world's parent, then its parent's parent,
+	// up to a top-level world. It is empty for a top-level world.
+	WorldAncestors(id string) ([]models.World, error)
+	// WorldRollup sums the occupancy of a world and every world nested in it,
+	// and averages the activity of the innermost ones
+	WorldRollup(id string) (models.WorldRollup, error)
+}
+
+// worldLookup returns a live world and whether it exists
+type worldLookup func(id string) (models.World, bool, error)
+
+// ancestorsOf follows parent links from world up to a top-level world. It
+// stops at a missing parent, or at one it has already passed.
+func ancestorsOf(world models.World, lookup worldLookup) ([]models.World, error) {
+	ancestors := []models.World{}
+	seen := map[string]bool{world.ID: true}
+	for world.IsNested() && !seen[world.ParentID] {
+		parent, ok, err := lookup(world.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		seen[parent.ID] = true
+		ancestors = append(ancestors, parent)
+		world = parent
+	}
+	return ancestors, nil
+}
+
+// checkWorldParent verifies that the parent of a world about to be stored
+// exists and is not nested in the world
+func checkWorldParent(world models.World, lookup worldLookup) error {
+	if !world.IsNested() {
+		return nil
+	}
+	_, ok, err := lookup(world.ParentID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrWorldNotFound
+	}
+	ancestors, err := ancestorsOf(world, lookup)
+	if err != nil {
+		return err
+	}
+	for _, ancestor := range ancestors {
+		if ancestor.ParentID == world.ID {
+			return ErrWorldCycle
+		}
+	}
+	return nil
+}
+
+// rollUpWorld summarizes world and its descendants; children returns the
+// worlds directly inside a world
+func rollUpWorld(world models.World, children func(id string) ([]models.World, error)) (models.WorldRollup, error) {
+	seen := make(map[string]bool)
+	var rollUp func(world models.World) (models.WorldRollup, error)
+	rollUp = func(world models.World) (models.WorldRollup, error) {
+		seen[world.ID] = true
+		nested, err := children(world.ID)
+		if err != nil {
+			return models.WorldRollup{}, err
+		}
+		rollups := make([]models.WorldRollup, 0, len(nested))
+		for _, child := range nested {
+			if seen[child.ID] {
+				continue
+			}
+			rollup, err := rollUp(child)
+			if err != nil {
+				return models.WorldRollup{}, err
+			}
+			rollups = append(rollups, rollup)
+		}
+		return models.RollUp(world, rollups), nil
+	}
+	return rollUp(world)
+}
+
+// childWorlds returns the worlds whose parent is id, ordered by ID. Callers
+// must hold the lock.
+func (r *Repository) childWorlds(id string) []models.World {
+	children := []models.World{}
+	for _, world := range r.worlds {
+		if world.ParentID == id {
+			children = append(children, world)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].ID < children[j].ID })
+	return children
+}
+
+// lookupWorld is the worldLookup of the committed worlds. Callers must hold the lock.
+func (r *Repository) lookupWorld(id string) (models.World, bool, error) {
+	world, ok := r.worlds[id]
+	return world, ok, nil
+}
+
+// ChildWorlds returns the worlds directly inside a world, ordered by ID
+func (r *Repository) ChildWorlds(id string) ([]models.World, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.worlds[id]; !ok {
+		return nil, ErrWorldNotFound
+	}
+	return r.childWorlds(id), nil
+}
+
+// WorldAncestors returns the worlds containing a world, innermost first
+func (r *Repository) WorldAncestors(id string) ([]models.World, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	world, ok := r.worlds[id]
+	if !ok {
+		return nil, ErrWorldNotFound
+	}
+	return ancestorsOf(world, r.lookupWorld)
+}
+
+// WorldRollup summarizes a world and every world nested in it
+func (r *Repository) WorldRollup(id string) (models.WorldRollup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	world, ok := r.worlds[id]
+	if !ok {
+		return models.WorldRollup{}, ErrWorldNotFound
+	}
+	return rollUpWorld(world, func(id string) ([]models.World, error) { return r.childWorlds(id), nil })
+}
+
+// lookupWorld is the worldLookup of the worlds as staged so far
+func (t *writeTxn) lookupWorld(id string) (models.World, bool, error) {
+	world, ok := t.getWorld(id)
+	return world, ok, nil
+}
+
+// hasChildWorlds reports whether any world as staged so far is nested in id
+func (t *writeTxn) hasChildWorlds(id string) bool {
+	for worldID, world := range t.r.worlds {
+		if _, staged := t.worlds[worldID]; !staged && world.ParentID == id {
+			return true
+		}
+	}
+	for _, world := range t.worlds {
+		if world != nil && world.ParentID == id {
+			return true
+		}
+	}
+	return false
+}
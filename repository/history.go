@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// DefaultHistoryLimit is the number of history entries the in-memory
+// repository keeps per entity before dropping the oldest
+const DefaultHistoryLimit = 1000
+
+// ErrHistoryTruncated is returned when asking for the state of an entity
+// before the oldest history entry still kept for it
+var ErrHistoryTruncated = errors.New("history before this time is no longer kept")
+
+// HistoryOp is the kind of change recorded in a HistoryEntry
+type HistoryOp string
+
+const (
+	HistoryCreate  HistoryOp = "create"
+	HistoryUpdate  HistoryOp = "update"
+	HistoryDelete  HistoryOp = "delete"
+	HistoryRestore HistoryOp = "restore" // Brought back from the trash
+)
+
+// FieldChange is one top-level JSON field that differs between the states
+// before and after a change. Before or After is empty if the field was unset.
+type FieldChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// HistoryEntry records a single write to a vibe or world
+type HistoryEntry struct {
+	Seq     uint64          `json:"seq"`             // Increases with every entry
+	Time    time.Time       `json:"time"`            // When the write was committed
+	Actor   string          `json:"actor,omitempty"` // Who made the write, if known
+	Op      HistoryOp       `json:"op"`
+	Kind    string          `json:"kind"` // "vibe" or "world"
+	ID      string          `json:"id"`
+	Before  json.RawMessage `json:"before,omitempty"` // Entity before the write; empty on create
+	After   json.RawMessage `json:"after,omitempty"`  // Entity after the write; empty on delete
+	Changes []FieldChange   `json:"changes,omitempty"`
+}
+
+// HistoryRepository exposes the change history of vibes and worlds
+type HistoryRepository interface {
+	// WithActor returns a view of the repository whose writes are attributed to actor
+	WithActor(actor string) VibeWorldRepository
+	// VibeHistory returns every recorded change to a vibe, oldest first
+	VibeHistory(id string) ([]HistoryEntry, error)
+	// WorldHistory returns every recorded change to a world, oldest first
+	WorldHistory(id string) ([]HistoryEntry, error)
+	// VibeAt returns a vibe as it was at the given time
+	VibeAt(id string, at time.Time) (models.Vibe, error)
+	// WorldAt returns a world as it was at the given time
+	WorldAt(id string, at time.Time) (models.World, error)
+}
+
+// newHistoryEntry describes the change of an entity from before to after;
+// either may be nil for creates and deletes
+func newHistoryEntry(when time.Time, actor, kind, id string, before, after interface{}) HistoryEntry {
+	entry := HistoryEntry{Time: when, Actor: actor, Kind: kind, ID: id, Op: HistoryUpdate}
+	entry.Before = marshalState(before)
+	entry.After = marshalState(after)
+
+	switch {
+	case entry.Before == nil:
+		entry.Op = HistoryCreate
+	case entry.After == nil:
+		entry.Op = HistoryDelete
+	}
+	entry.Changes = diffFields(entry.Before, entry.After)
+	return entry
+}
+
+// marshalState encodes an entity pointer, returning nil for a nil pointer
+func marshalState(state interface{}) json.RawMessage {
+	switch s := state.(type) {
+	case *models.Vibe:
+		if s == nil {
+			return nil
+		}
+	case *models.World:
+		if s == nil {
+			return nil
+		}
+	case nil:
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// diffFields lists the top-level fields that differ between two JSON objects
+func diffFields(before, after json.RawMessage) []FieldChange {
+	var beforeFields, afterFields map[string]json.RawMessage
+	if before != nil {
+		json.Unmarshal(before, &beforeFields)
+	}
+	if after != nil {
+		json.Unmarshal(after, &afterFields)
+	}
+
+	names := make([]string, 0, len(beforeFields)+len(afterFields))
+	for name := range beforeFields {
+		names = append(names, name)
+	}
+	for name := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []FieldChange
+	for _, name := range names {
+		b, a := beforeFields[name], afterFields[name]
+		if !bytes.Equal(b, a) {
+			changes = append(changes, FieldChange{Field: name, Before: b, After: a})
+		}
+	}
+	return changes
+}
+
+// stateAt finds the state of an entity at a point in time from its history.
+// known is false if the entity has no history, in which case it has not
+// changed since history began and its current state applies. A nil state
+// means the entity did not exist at that time.
+func stateAt(entries []HistoryEntry, at time.Time) (state json.RawMessage, known bool) {
+	if len(entries) == 0 {
+		return nil, false
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].Time.After(at) {
+			return entries[i].After, true
+		}
+	}
+	// Every recorded change happened later; the entity was as before the first one
+	return entries[0].Before, true
+}
+
+// historyKey identifies an entity in the in-memory history
+func historyKey(kind, id string) string {
+	return kind + "/" + id
+}
+
+// record appends an entry to the in-memory history, assigning its sequence
+// number and enforcing the per-entity limit. Callers must hold the write lock.
+func (r *Repository) record(entry HistoryEntry) {
+	if r.history == nil {
+		r.history = make(map[string][]HistoryEntry)
+	}
+	if entry.Seq == 0 {
+		r.historySeq++
+		entry.Seq = r.historySeq
+	} else if entry.Seq > r.historySeq {
+		r.historySeq = entry.Seq
+	}
+
+	key := historyKey(entry.Kind, entry.ID)
+	entries := append(r.history[key], entry)
+	if limit := r.historyLimit; limit > 0 && len(entries) > limit {
+		dropped := len(entries) - limit
+		r.cutHistory(key, entries[dropped-1].Time)
+		entries = append([]HistoryEntry(nil), entries[dropped:]...)
+	}
+	r.history[key] = entries
+}
+
+// cutHistory notes that the history of an entity was dropped up to and
+// including an entry made at the given time. Callers must hold the write lock.
+func (r *Repository) cutHistory(key string, at time.Time) {
+	if r.historyCuts == nil {
+		r.historyCuts = make(map[string]time.Time)
+	}
+	if at.After(r.historyCuts[key]) {
+		r.historyCuts[key] = at
+	}
+}
+
+// stateAtLocked is stateAt on the in-memory history, failing with
+// ErrHistoryTruncated for times whose entries were dropped. Callers must
+// hold the lock.
+func (r *Repository) stateAtLocked(kind, id string, at time.Time) (json.RawMessage, bool, error) {
+	key := historyKey(kind, id)
+	if cut, ok := r.historyCuts[key]; ok && at.Before(cut) {
+		return nil, false, ErrHistoryTruncated
+	}
+	state, known := stateAt(r.history[key], at)
+	return state, known, nil
+}
+
+// SetHistoryLimit sets how many history entries are kept per entity; zero
+// keeps everything. Older entries are dropped on the next write.
+func (r *Repository) SetHistoryLimit(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.historyLimit = n
+}
+
+// allHistory returns every history entry ordered by sequence number.
+// Callers must hold the lock.
+func (r *Repository) allHistory() []HistoryEntry {
+	var all []HistoryEntry
+	for _, entries := range r.history {
+		all = append(all, entries...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Seq < all[j].Seq })
+	return all
+}
+
+// VibeHistory returns every recorded change to a vibe, oldest first
+func (r *Repository) VibeHistory(id string) ([]HistoryEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := r.history[historyKey(entityKindVibe, id)]
+	if len(entries) == 0 {
+		if _, ok := r.vibes[id]; !ok {
+			return nil, ErrVibeNotFound
+		}
+	}
+	return append([]HistoryEntry{}, entries...), nil
+}
+
+// WorldHistory returns every recorded change to a world, oldest first
+func (r *Repository) WorldHistory(id string) ([]HistoryEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := r.history[historyKey(entityKindWorld, id)]
+	if len(entries) == 0 {
+		if _, ok := r.worlds[id]; !ok {
+			return nil, ErrWorldNotFound
+		}
+	}
+	return append([]HistoryEntry{}, entries...), nil
+}
+
+// VibeAt returns a vibe as it was at the given time
+func (r *Repository) VibeAt(id string, at time.Time) (models.Vibe, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	state, known, err := r.stateAtLocked(entityKindVibe, id, at)
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	if !known {
+		vibe, ok := r.vibes[id]
+		if !ok {
+			return models.Vibe{}, ErrVibeNotFound
+		}
+		return vibe, nil
+	}
+	return decodeVibeState(state)
+}
+
+// WorldAt returns a world as it was at the given time
+func (r *Repository) WorldAt(id string, at time.Time) (models.World, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	state, known, err := r.stateAtLocked(entityKindWorld, id, at)
+	if err != nil {
+		return models.World{}, err
+	}
+	if !known {
+		world, ok := r.worlds[id]
+		if !ok {
+			return models.World{}, ErrWorldNotFound
+		}
+		return world, nil
+	}
+	return decodeWorldState(state)
+}
+
+// decodeVibeState decodes a recorded vibe; an empty state means it did not exist
+func decodeVibeState(state json.RawMessage) (models.Vibe, error) {
+	var vibe models.Vibe
+	if state == nil {
+		return vibe, ErrVibeNotFound
+	}
+	err := json.Unmarshal(state, &vibe)
+	return vibe, err
+}
+
+// decodeWorldState decodes a recorded world; an empty state means it did not exist
+func decodeWorldState(state json.RawMessage) (models.World, error) {
+	var world models.World
+	if state == nil {
+		return world, ErrWorldNotFound
+	}
+	err := json.Unmarshal(state, &world)
+	return world, err
+}
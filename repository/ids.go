@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxSlugLength bounds the name-derived part of a generated ID
+const maxSlugLength = 64
+
+// maxSlugSuffix is the highest numeric suffix tried before a generated ID
+// falls back to a random one
+const maxSlugSuffix = 100
+
+// reservedIDs are never generated, as they name resource URIs such as
+// world://list rather than an entity
+var reservedIDs = map[string]bool{"list": true, "trash": true, "graph": true}
+
+// ErrDuplicateID is matched by every *DuplicateIDError via errors.Is
+var ErrDuplicateID = errors.New("id already in use")
+
+// DuplicateIDError reports a create whose ID belongs to an existing entity
+type DuplicateIDError struct {
+	Kind string // "vibe" or "world"
+	ID   string
+}
+
+func (e *DuplicateIDError) Error() string {
+	return fmt.Sprintf("%s %q already exists", e.Kind, e.ID)
+}
+
+// Is makes errors.Is(err, ErrDuplicateID) true for duplicate ID errors
+func (e *DuplicateIDError) Is(target error) bool {
+	return target == ErrDuplicateID
+}
+
+// Slug turns a name into an ID fragment: lowercase ASCII letters and digits,
+// with every other run of characters replaced by a single hyphen. It returns
+// "" for a name without letters or digits.
+func Slug(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, c := range strings.ToLower(name) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(c)
+			if b.Len() >= maxSlugLength {
+				break
+			}
+			continue
+		}
+		hyphen = true
+	}
+	return b.String()
+}
+
+// generateID picks an unused ID for an entity named name: its slug, then the
+// slug with a numeric suffix, then the slug with a random suffix. A name
+// without a slug gets a UUID. taken reports whether an ID is in use; reserved
+// IDs count as taken.
+func generateID(name string, taken func(id string) (bool, error)) (string, error) {
+	slug := Slug(name)
+	if slug == "" {
+		return uuid.NewString(), nil
+	}
+
+	candidate := slug
+	for n := 2; n <= maxSlugSuffix+1; n++ {
+		if !reservedIDs[candidate] {
+			inUse, err := taken(candidate)
+			if err != nil {
+				return "", err
+			}
+			if !inUse {
+				return candidate, nil
+			}
+		}
+		candidate = fmt.Sprintf("%s-%d", slug, n)
+	}
+	return slug + "-" + uuid.NewString()[:8], nil
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"sort"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+var (
+	// ErrVibeHasDerived is returned when deleting a vibe other vibes derive from
+	ErrVibeHasDerived = errors.New("vibe is the parent of one or more derived vibes")
+	// ErrInheritanceCycle is returned when a vibe would become its own ancestor
+	ErrInheritanceCycle = errors.New("vibe would inherit from itself")
+)
+
+// vibeTree is the view of the vibes of one write used to resolve inheritance
+type vibeTree interface {
+	// vibe returns a live vibe as the write sees it
+	vibe(id string) (models.Vibe, bool, error)
+	// derived returns the IDs of the live vibes whose parent is id, sorted
+	derived(id string) ([]string, error)
+	// put stores a vibe whose inherited values changed
+	put(vibe models.Vibe) error
+}
+
+// resolveVibe prepares a vibe for storage. A derived vibe keeps the
+// overrides it is given; without any, it keeps those of stored (the vibe it
+// replaces) if the parent is unchanged, or else overrides the fields it sets.
+// Its parent must exist and must not derive from it, and the fields it does
+// not override are filled in from the parent.
+func resolveVibe(tree vibeTree, vibe models.Vibe, stored *models.Vibe) (models.Vibe, error) {
+	if !vibe.IsDerived() {
+		vibe.Overrides = nil
+		return vibe, nil
+	}
+	switch {
+	case vibe.Overrides != nil:
+	case stored != nil && stored.ParentID == vibe.ParentID:
+		vibe.Overrides = stored.Overrides
+	default:
+		vibe.Overrides = vibe.SetFields()
+	}
+	vibe.Overrides = vibe.NormalizedOverrides()
+
+	parent, ok, err := tree.vibe(vibe.ParentID)
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	if !ok {
+		return models.Vibe{}, ErrVibeNotFound
+	}
+	for ancestor := parent; ancestor.IsDerived(); {
+		if ancestor.ParentID == vibe.ID {
+			return models.Vibe{}, ErrInheritanceCycle
+		}
+		next, ok, err := tree.vibe(ancestor.ParentID)
+		if err != nil {
+			return models.Vibe{}, err
+		}
+		if !ok {
+			break
+		}
+		ancestor = next
+	}
+	return vibe.Inherit(parent), nil
+}
+
+// propagateVibe refreshes the inherited values of the vibes derived from
+// parent, and of the vibes derived from those, storing each one that changed
+// with its version incremented
+func propagateVibe(tree vibeTree, parent models.Vibe) error {
+	ids, err := tree.derived(parent.ID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		child, ok, err := tree.vibe(id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		updated := child.Inherit(parent)
+		if reflect.DeepEqual(updated, child) {
+			continue
+		}
+		updated.Version++
+		if err := tree.put(updated); err != nil {
+			return err
+		}
+		if err := propagateVibe(tree, updated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// txnVibeTree resolves inheritance within a write of the in-memory repository
+type txnVibeTree struct {
+	t *writeTxn
+}
+
+func (tree txnVibeTree) vibe(id string) (models.Vibe, bool, error) {
+	vibe, ok := tree.t.getVibe(id)
+	return vibe, ok, nil
+}
+
+func (tree txnVibeTree) derived(id string) ([]string, error) {
+	ids := []string{}
+	for vibeID, vibe := range tree.t.r.vibes {
+		if _, staged := tree.t.vibes[vibeID]; !staged && vibe.ParentID == id {
+			ids = append(ids, vibeID)
+		}
+	}
+	for vibeID, vibe := range tree.t.vibes {
+		if vibe != nil && vibe.ParentID == id {
+			ids = append(ids, vibeID)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (tree txnVibeTree) put(vibe models.Vibe) error {
+	tree.t.stage(putVibe(vibe))
+	return nil
+}
+
+// sqlVibeTree resolves inheritance within a transaction of the SQL repository
+type sqlVibeTree struct {
+	r     *SQLRepository
+	tx    *sql.Tx
+	actor string
+}
+
+func (r *SQLRepository) vibeTree(tx *sql.Tx, actor string) sqlVibeTree {
+	return sqlVibeTree{r: r, tx: tx, actor: actor}
+}
+
+func (tree sqlVibeTree) vibe(id string) (models.Vibe, bool, error) {
+	vibe, err := loadVibeTx(tree.tx, id)
+	if err != nil || vibe == nil {
+		return models.Vibe{}, false, err
+	}
+	return *vibe, true, nil
+}
+
+// derived uses idx_vibes_parent
+func (tree sqlVibeTree) derived(id string) ([]string, error) {
+	rows, err := tree.tx.Query(`SELECT id FROM vibes WHERE parent_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var vibeID string
+		if err := rows.Scan(&vibeID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, vibeID)
+	}
+	return ids, rows.Err()
+}
+
+func (tree sqlVibeTree) put(vibe models.Vibe) error {
+	before, err := loadVibeTx(tree.tx, vibe.ID)
+	if err != nil {
+		return err
+	}
+	if err := putVibeTx(tree.tx, vibe); err != nil {
+		return err
+	}
+	return tree.r.recordHistoryTx(tree.tx, tree.actor, entityKindVibe, vibe.ID, before, &vibe)
+}
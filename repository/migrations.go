@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Migration is a versioned schema change for the SQL repository
+type Migration struct {
+	Version    int      // Strictly increasing version number
+	Name       string   // Short human-readable description
+	Statements []string // SQL statements applied in a single transaction
+}
+
+// sqlMigrations is the ordered schema history of the SQL repository.
+// Append new migrations; never edit one that has been released.
+var sqlMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Statements: []string{
+			`CREATE TABLE vibes (
+				id          TEXT PRIMARY KEY,
+				name        TEXT NOT NULL DEFAULT '',
+				description TEXT NOT NULL DEFAULT '',
+				energy      REAL NOT NULL DEFAULT 0,
+				mood        TEXT NOT NULL DEFAULT '',
+				creator_id  TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX idx_vibes_mood ON vibes (mood)`,
+			`CREATE TABLE vibe_colors (
+				vibe_id  TEXT NOT NULL REFERENCES vibes (id) ON DELETE CASCADE,
+				position INTEGER NOT NULL,
+				color    TEXT NOT NULL,
+				PRIMARY KEY (vibe_id, position)
+			)`,
+			`CREATE TABLE sensor_data (
+				vibe_id     TEXT PRIMARY KEY REFERENCES vibes (id) ON DELETE CASCADE,
+				temperature REAL,
+				humidity    REAL,
+				light       REAL,
+				sound       REAL,
+				movement    REAL
+			)`,
+			`CREATE TABLE worlds (
+				id           TEXT PRIMARY KEY,
+				name         TEXT NOT NULL DEFAULT '',
+				description  TEXT NOT NULL DEFAULT '',
+				type         TEXT NOT NULL DEFAULT '',
+				location     TEXT NOT NULL DEFAULT '',
+				current_vibe TEXT REFERENCES vibes (id),
+				size         TEXT NOT NULL DEFAULT '',
+				creator_id   TEXT NOT NULL DEFAULT '',
+				occupancy    INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX idx_worlds_type ON worlds (type)`,
+			`CREATE INDEX idx_worlds_creator ON worlds (creator_id)`,
+			`CREATE INDEX idx_worlds_current_vibe ON worlds (current_vibe)`,
+			`CREATE TABLE world_features (
+				world_id TEXT NOT NULL REFERENCES worlds (id) ON DELETE CASCADE,
+				position INTEGER NOT NULL,
+				feature  TEXT NOT NULL,
+				PRIMARY KEY (world_id, position)
+			)`,
+			`CREATE TABLE sharing_settings (
+				entity_kind   TEXT NOT NULL,
+				entity_id     TEXT NOT NULL,
+				is_public     INTEGER NOT NULL DEFAULT 0,
+				context_level TEXT NOT NULL DEFAULT '',
+				PRIMARY KEY (entity_kind, entity_id)
+			)`,
+			`CREATE TABLE sharing_allowed_users (
+				entity_kind TEXT NOT NULL,
+				entity_id   TEXT NOT NULL,
+				position    INTEGER NOT NULL,
+				user_id     TEXT NOT NULL,
+				PRIMARY KEY (entity_kind, entity_id, position)
+			)`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "entity versions",
+		Statements: []string{
+			`ALTER TABLE vibes ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+			`ALTER TABLE worlds ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "change history",
+		Statements: []string{
+			`CREATE TABLE history (
+				seq         INTEGER PRIMARY KEY,
+				time_ns     INTEGER NOT NULL,
+				actor       TEXT NOT NULL DEFAULT '',
+				op          TEXT NOT NULL,
+				entity_kind TEXT NOT NULL,
+				entity_id   TEXT NOT NULL,
+				before_json TEXT,
+				after_json  TEXT,
+				changes     TEXT
+			)`,
+			`CREATE INDEX idx_history_entity ON history (entity_kind, entity_id, seq)`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "trash",
+		Statements: []string{
+			`CREATE TABLE trash (
+				entity_kind   TEXT NOT NULL,
+				entity_id     TEXT NOT NULL,
+				deleted_at_ns INTEGER NOT NULL,
+				deleted_by    TEXT NOT NULL DEFAULT '',
+				data          TEXT NOT NULL,
+				PRIMARY KEY (entity_kind, entity_id)
+			)`,
+			`CREATE INDEX idx_trash_deleted_at ON trash (deleted_at_ns)`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "vibe inheritance",
+		Statements: []string{
+			`ALTER TABLE vibes ADD COLUMN parent_id TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE vibes ADD COLUMN overrides TEXT NOT NULL DEFAULT ''`, // Comma-separated field names
+			`CREATE INDEX idx_vibes_parent ON vibes (parent_id)`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "world vibe transitions",
+		Statements: []string{
+			`ALTER TABLE worlds ADD COLUMN transition_from TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE worlds ADD COLUMN transition_started_ms INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE worlds ADD COLUMN transition_duration_ms INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "world hierarchy",
+		Statements: []string{
+			`ALTER TABLE worlds ADD COLUMN parent_id TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX idx_worlds_parent ON worlds (parent_id)`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "world adjacency",
+		Statements: []string{
+			`CREATE TABLE world_edges (
+				world_a TEXT NOT NULL REFERENCES worlds (id) ON DELETE CASCADE,
+				world_b TEXT NOT NULL REFERENCES worlds (id) ON DELETE CASCADE,
+				weight  REAL NOT NULL,
+				kind    TEXT NOT NULL DEFAULT '',
+				PRIMARY KEY (world_a, world_b)
+			)`,
+			`CREATE INDEX idx_world_edges_b ON world_edges (world_b)`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "sensor channels",
+		Statements: []string{
+			`CREATE TABLE sensor_channels (
+				vibe_id TEXT NOT NULL REFERENCES vibes (id) ON DELETE CASCADE,
+				name    TEXT NOT NULL,
+				value   REAL NOT NULL,
+				PRIMARY KEY (vibe_id, name)
+			)`,
+		},
+	},
+}
+
+// Migrate brings the database schema up to date by applying every migration
+// newer than the recorded schema version. Each migration runs in its own
+// transaction together with its bookkeeping row, so a failed migration leaves
+// the schema at the previous version.
+func Migrate(db *sql.DB, migrations []Migration) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	pending := make([]Migration, 0, len(migrations))
+	for _, m := range migrations {
+		if m.Version > current {
+			pending = append(pending, m)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Version < pending[j].Version })
+
+	for i, m := range pending {
+		if i > 0 && pending[i-1].Version == m.Version {
+			return fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+		if err := applyMigration(db, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SchemaVersion returns the highest migration version applied to the database,
+// or zero if none has been applied
+func SchemaVersion(db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// applyMigration runs a single migration and records it
+func applyMigration(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range m.Statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Name, time.Now().UTC().Format(time.RFC3339),
+	); err != nil {
+		return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// Page size limits for QueryVibes and QueryWorlds
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid pagination cursor")
+	ErrInvalidSort   = errors.New("invalid sort field")
+)
+
+// SortField names the field a listing is ordered by. Ties are always broken by ID.
+type SortField string
+
+const (
+	SortByID        SortField = "id"
+	SortByName      SortField = "name"
+	SortByEnergy    SortField = "energy"    // For worlds, the energy of the current vibe
+	SortByOccupancy SortField = "occupancy" // Worlds only
+)
+
+// VibeFilter narrows down a vibe listing. Empty fields match every vibe.
+type VibeFilter struct {
+	Mood      string   // Exact mood, e.g. models.MoodCalm
+	MinEnergy *float64 // Inclusive lower energy bound
+	MaxEnergy *float64 // Inclusive upper energy bound
+	CreatorID string   // User who created the vibe
+	IsPublic  *bool    // Public or private vibes only
+}
+
+// WorldFilter narrows down a world listing. Empty fields match every world.
+type WorldFilter struct {
+	Type      models.WorldType // World type, e.g. models.WorldTypeVirtual
+	Mood      string           // Mood of the world's current vibe
+	MinEnergy *float64         // Inclusive lower bound on the current vibe's energy
+	MaxEnergy *float64         // Inclusive upper bound on the current vibe's energy
+	CreatorID string           // User who created the world
+	Feature   string           // Feature tag the world must have
+	IsPublic  *bool            // Public or private worlds only
+}
+
+// ListOptions controls the order and page of a listing
+type ListOptions struct {
+	SortBy     SortField // Defaults to SortByID
+	Descending bool      // Reverse the sort order
+	Limit      int       // Page size; defaults to DefaultPageLimit, capped at MaxPageLimit
+	Cursor     string    // NextCursor of the previous page; empty for the first page
+}
+
+// VibeQuery selects a page of vibes
+type VibeQuery struct {
+	Filter VibeFilter
+	ListOptions
+}
+
+// WorldQuery selects a page of worlds
+type WorldQuery struct {
+	Filter WorldFilter
+	ListOptions
+}
+
+// VibePage is one page of a vibe listing
+type VibePage struct {
+	Items      []models.Vibe `json:"items"`
+	NextCursor string        `json:"nextCursor,omitempty"` // Empty on the last page
+}
+
+// WorldPage is one page of a world listing
+type WorldPage struct {
+	Items      []models.World `json:"items"`
+	NextCursor string         `json:"nextCursor,omitempty"` // Empty on the last page
+}
+
+// Matches reports whether vibe satisfies the filter
+func (f VibeFilter) Matches(vibe models.Vibe) bool {
+	if f.Mood != "" && vibe.Mood != f.Mood {
+		return false
+	}
+	if f.MinEnergy != nil && vibe.Energy < *f.MinEnergy {
+		return false
+	}
+	if f.MaxEnergy != nil && vibe.Energy > *f.MaxEnergy {
+		return false
+	}
+	if f.CreatorID != "" && vibe.CreatorID != f.CreatorID {
+		return false
+	}
+	if f.IsPublic != nil && vibe.Sharing.IsPublic != *f.IsPublic {
+		return false
+	}
+	return true
+}
+
+// Matches reports whether world satisfies the filter. vibe is the world's
+// current vibe, or nil if it has none.
+func (f WorldFilter) Matches(world models.World, vibe *models.Vibe) bool {
+	if f.Type != "" && world.Type != f.Type {
+		return false
+	}
+	if f.CreatorID != "" && world.CreatorID != f.CreatorID {
+		return false
+	}
+	if f.IsPublic != nil && world.Sharing.IsPublic != *f.IsPublic {
+		return false
+	}
+	if f.Feature != "" && !containsString(world.Features, f.Feature) {
+		return false
+	}
+	if f.Mood != "" || f.MinEnergy != nil || f.MaxEnergy != nil {
+		if vibe == nil {
+			return false
+		}
+		if f.Mood != "" && vibe.Mood != f.Mood {
+			return false
+		}
+		if f.MinEnergy != nil && vibe.Energy < *f.MinEnergy {
+			return false
+		}
+		if f.MaxEnergy != nil && vibe.Energy > *f.MaxEnergy {
+			return false
+		}
+	}
+	return true
+}
+
+// pageLimit returns the effective page size
+func (o ListOptions) pageLimit() int {
+	if o.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	if o.Limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return o.Limit
+}
+
+// sortField returns the effective sort field
+func (o ListOptions) sortField() SortField {
+	if o.SortBy == "" {
+		return SortByID
+	}
+	return o.SortBy
+}
+
+// sortKey is the position of an item in a listing
+type sortKey struct {
+	Str string  `json:"s,omitempty"` // Value of string sort fields
+	Num float64 `json:"n,omitempty"` // Value of numeric sort fields
+	ID  string  `json:"id"`          // Tie breaker
+}
+
+// pageCursor is the decoded form of ListOptions.Cursor
+type pageCursor struct {
+	SortBy     SortField `json:"by"`
+	Descending bool      `json:"desc,omitempty"`
+	After      sortKey   `json:"after"`
+}
+
+// compareKeys orders two keys for the given sort field, ascending
+func compareKeys(field SortField, a, b sortKey) int {
+	switch field {
+	case SortByName:
+		if c := strings.Compare(a.Str, b.Str); c != 0 {
+			return c
+		}
+	case SortByEnergy, SortByOccupancy:
+		if a.Num < b.Num {
+			return -1
+		}
+		if a.Num > b.Num {
+			return 1
+		}
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// encodeCursor builds the opaque cursor pointing after key
+func encodeCursor(opts ListOptions, key sortKey) string {
+	data, _ := json.Marshal(pageCursor{SortBy: opts.sortField(), Descending: opts.Descending, After: key})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor and checks it belongs to the same ordering
+func decodeCursor(opts ListOptions) (*pageCursor, error) {
+	if opts.Cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(opts.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.SortBy != opts.sortField() || cursor.Descending != opts.Descending {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidCursor)
+	}
+	return &cursor, nil
+}
+
+// paginate sorts items, skips everything up to the cursor and returns one page
+// plus the cursor of the next page
+func paginate[T any](items []T, opts ListOptions, key func(T) sortKey) ([]T, string, error) {
+	field := opts.sortField()
+	cursor, err := decodeCursor(opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	less := func(a, b sortKey) bool {
+		c := compareKeys(field, a, b)
+		if opts.Descending {
+			return c > 0
+		}
+		return c < 0
+	}
+
+	sort.Slice(items, func(i, j int) bool { return less(key(items[i]), key(items[j])) })
+
+	start := 0
+	if cursor != nil {
+		start = sort.Search(len(items), func(i int) bool { return less(cursor.After, key(items[i])) })
+	}
+
+	limit := opts.pageLimit()
+	end := start + limit
+	if end >= len(items) {
+		return items[start:], "", nil
+	}
+	return items[start:end], encodeCursor(opts, key(items[end-1])), nil
+}
+
+// vibeSortKey returns the listing position of a vibe
+func vibeSortKey(field SortField) (func(models.Vibe) sortKey, error) {
+	switch field {
+	case SortByID:
+		return func(v models.Vibe) sortKey { return sortKey{ID: v.ID} }, nil
+	case SortByName:
+		return func(v models.Vibe) sortKey { return sortKey{Str: v.Name, ID: v.ID} }, nil
+	case SortByEnergy:
+		return func(v models.Vibe) sortKey { return sortKey{Num: v.Energy, ID: v.ID} }, nil
+	}
+	return nil, fmt.Errorf("%w: vibes cannot be sorted by %q", ErrInvalidSort, field)
+}
+
+// worldSortKey returns the listing position of a world; energies maps world
+// IDs to the energy of their current vibe
+func worldSortKey(field SortField, energies map[string]float64) (func(models.World) sortKey, error) {
+	switch field {
+	case SortByID:
+		return func(w models.World) sortKey { return sortKey{ID: w.ID} }, nil
+	case SortByName:
+		return func(w models.World) sortKey { return sortKey{Str: w.Name, ID: w.ID} }, nil
+	case SortByEnergy:
+		return func(w models.World) sortKey { return sortKey{Num: energies[w.ID], ID: w.ID} }, nil
+	case SortByOccupancy:
+		return func(w models.World) sortKey { return sortKey{Num: float64(w.Occupancy), ID: w.ID} }, nil
+	}
+	return nil, fmt.Errorf("%w: worlds cannot be sorted by %q", ErrInvalidSort, field)
+}
+
+// QueryVibes returns a filtered, sorted page of vibes
+func (r *Repository) QueryVibes(query VibeQuery) (VibePage, error) {
+	key, err := vibeSortKey(query.sortField())
+	if err != nil {
+		return VibePage{}, err
+	}
+
+	r.mu.RLock()
+	vibes := make([]models.Vibe, 0, len(r.vibes))
+	for _, vibe := range r.vibes {
+		if query.Filter.Matches(vibe) {
+			vibes = append(vibes, vibe)
+		}
+	}
+	r.mu.RUnlock()
+
+	items, next, err := paginate(vibes, query.ListOptions, key)
+	if err != nil {
+		return VibePage{}, err
+	}
+	return VibePage{Items: items, NextCursor: next}, nil
+}
+
+// QueryWorlds returns a filtered, sorted page of worlds
+func (r *Repository) QueryWorlds(query WorldQuery) (WorldPage, error) {
+	energies := make(map[string]float64)
+	key, err := worldSortKey(query.sortField(), energies)
+	if err != nil {
+		return WorldPage{}, err
+	}
+
+	r.mu.RLock()
+	worlds := make([]models.World, 0, len(r.worlds))
+	for _, world := range r.worlds {
+		var vibe *models.Vibe
+		if v, ok := r.vibes[world.CurrentVibe]; ok {
+			vibe = &v
+			energies[world.ID] = v.Energy
+		}
+		if query.Filter.Matches(world, vibe) {
+			worlds = append(worlds, world)
+		}
+	}
+	r.mu.RUnlock()
+
+	items, next, err := paginate(worlds, query.ListOptions, key)
+	if err != nil {
+		return WorldPage{}, err
+	}
+	return WorldPage{Items: items, NextCursor: next}, nil
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/bmorphism/vibespace-mcp-go/models"
 )
@@ -19,8 +21,11 @@ var (
 type VibeRepository interface {
 	GetVibe(id string) (models.Vibe, error)
 	GetAllVibes() []models.Vibe
+	QueryVibes(query VibeQuery) (VibePage, error)
 	AddVibe(vibe models.Vibe) error
+	CreateVibe(vibe models.Vibe) (models.Vibe, error)
 	UpdateVibe(vibe models.Vibe) error
+	SaveVibe(vibe models.Vibe) (models.Vibe, error)
 	DeleteVibe(id string) error
 }
 
@@ -28,24 +33,103 @@ type VibeRepository interface {
 type WorldRepository interface {
 	GetWorld(id string) (models.World, error)
 	GetAllWorlds() []models.World
+	QueryWorlds(query WorldQuery) (WorldPage, error)
 	AddWorld(world models.World) error
+	CreateWorld(world models.World) (models.World, error)
 	UpdateWorld(world models.World) error
+	SaveWorld(world models.World) (models.World, error)
 	DeleteWorld(id string) error
 }
 
-// VibeWorldRepository combines both interfaces and adds relation operations
+// VibeWorldRepository combines both interfaces and adds relation operations.
+//
+// AddVibe and AddWorld create entities: they fail with a *DuplicateIDError
+// when the ID belongs to an existing entity, and generate an ID from the
+// name (or a UUID) when none is given. CreateVibe and CreateWorld do the same
+// and return the stored entity, with its assigned ID and version.
+//
+// Every write stores the entity with its version incremented. UpdateVibe and
+// UpdateWorld are compare-and-swap: when the given entity carries a non-zero
+// Version that differs from the stored one, they fail with a *ConflictError.
+// SaveVibe and SaveWorld do the same and return the stored entity.
 type VibeWorldRepository interface {
 	VibeRepository
 	WorldRepository
+	HistoryRepository
+	ChangeNotifier
+	TrashRepository
+	DependentsRepository
+	BatchRepository
+	BundleRepository
+	TransitionRepository
+	WorldTreeRepository
+	AdjacencyRepository
 	SetWorldVibe(worldID, vibeID string) error
+	SetWorldVibeIfVersion(worldID, vibeID string, expectedVersion int64) error
 	GetWorldVibe(worldID string) (models.Vibe, error)
 }
 
+// MutationOp identifies the kind of state change carried by a Mutation
+type MutationOp string
+
+const (
+	OpPutVibe      MutationOp = "putVibe"
+	OpDeleteVibe   MutationOp = "deleteVibe" // Removes the vibe for good, including its tombstone
+	OpTrashVibe    MutationOp = "trashVibe"  // Moves the vibe to the trash
+	OpRestoreVibe  MutationOp = "restoreVibe"
+	OpPutWorld     MutationOp = "putWorld"
+	OpDeleteWorld  MutationOp = "deleteWorld" // Removes the world for good, including its tombstone
+	OpTrashWorld   MutationOp = "trashWorld"  // Moves the world to the trash
+	OpRestoreWorld MutationOp = "restoreWorld"
+	OpPutEdge      MutationOp = "putEdge"
+	OpDeleteEdge   MutationOp = "deleteEdge"
+)
+
+// Mutation is a single state change applied to the repository. It carries the
+// resulting entity rather than the request that produced it, so replaying a
+// sequence of mutations always rebuilds the same state.
+type Mutation struct {
+	Op    MutationOp        `json:"op"`
+	ID    string            `json:"id"`
+	Vibe  *models.Vibe      `json:"vibe,omitempty"`
+	World *models.World     `json:"world,omitempty"`
+	Edge  *models.WorldEdge `json:"edge,omitempty"`
+}
+
+// Change is the batch of mutations made by a single write, with when and by
+// whom it was made
+type Change struct {
+	Time      time.Time  `json:"time"`
+	Actor     string     `json:"actor,omitempty"`
+	Mutations []Mutation `json:"mutations"`
+}
+
+// journal receives every change before it is applied in memory.
+// Returning an error aborts the write and leaves the repository unchanged.
+type journal interface {
+	Append(change Change) error
+}
+
 // Repository handles the storage and retrieval of vibes and worlds
 type Repository struct {
-	vibes  map[string]models.Vibe
-	worlds map[string]models.World
-	mu     sync.RWMutex
+	vibes   map[string]models.Vibe
+	worlds  map[string]models.World
+	journal journal
+	mu      sync.RWMutex
+
+	vibeWorlds map[string]map[string]struct{} // vibe ID -> IDs of the worlds using it
+	edges      map[string]models.WorldEdge    // keyed by edgeKey
+
+	history      map[string][]HistoryEntry // keyed by historyKey
+	historyCuts  map[string]time.Time      // time of the newest dropped entry, keyed by historyKey
+	historySeq   uint64
+	historyLimit int
+
+	trashedVibes   map[string]DeletedVibe
+	trashedWorlds  map[string]DeletedWorld
+	trashRetention time.Duration
+
+	events eventBus
 }
 
 // Ensure Repository implements VibeWorldRepository interface
@@ -59,15 +143,41 @@ func NewRepository() *Repository {
 // NewRepositoryWithSampleData creates a new repository with optional sample data
 func NewRepositoryWithSampleData(includeSampleData bool) *Repository {
 	r := &Repository{
-		vibes:  make(map[string]models.Vibe),
-		worlds: make(map[string]models.World),
+		vibes:        make(map[string]models.Vibe),
+		worlds:       make(map[string]models.World),
+		vibeWorlds:   make(map[string]map[string]struct{}),
+		edges:        make(map[string]models.WorldEdge),
+		history:      make(map[string][]HistoryEntry),
+		historyCuts:  make(map[string]time.Time),
+		historyLimit: DefaultHistoryLimit,
+
+		trashedVibes:   make(map[string]DeletedVibe),
+		trashedWorlds:  make(map[string]DeletedWorld),
+		trashRetention: DefaultTrashRetention,
+	}
+
+	if includeSampleData {
+		r.addSampleData()
 	}
+	return r
+}
 
-	if !includeSampleData {
-		return r
+// addSampleData stores the sample vibes and worlds at version 1, without
+// history or change events
+func (r *Repository) addSampleData() {
+	for _, vibe := range sampleVibes() {
+		vibe.Version = 1
+		r.vibes[vibe.ID] = vibe
 	}
+	for _, world := range sampleWorlds() {
+		world.Version = 1
+		r.worlds[world.ID] = world
+		r.indexWorld(nil, &world)
+	}
+}
 
-	// Add sample vibes
+// sampleVibes returns the vibes every repository backend is seeded with
+func sampleVibes() []models.Vibe {
 	temperature := 21.5
 	humidity := 45.0
 	light := 500.0
@@ -134,18 +244,18 @@ func NewRepositoryWithSampleData(includeSampleData bool) *Repository {
 		},
 	}
 
-	r.vibes[focusedVibe.ID] = focusedVibe
-	r.vibes[calmVibe.ID] = calmVibe
-	r.vibes[energeticVibe.ID] = energeticVibe
+	return []models.Vibe{focusedVibe, calmVibe, energeticVibe}
+}
 
-	// Add sample worlds
+// sampleWorlds returns the worlds every repository backend is seeded with
+func sampleWorlds() []models.World {
 	officeWorld := models.World{
 		ID:          "office-space",
 		Name:        "Modern Office",
 		Description: "An open-concept workspace designed for collaboration",
 		Type:        models.WorldTypePhysical,
 		Location:    "Floor 3, Building A",
-		CurrentVibe: focusedVibe.ID,
+		CurrentVibe: "focused-flow",
 		Size:        "Medium (500 sqm)",
 		Features:    []string{"standing desks", "natural light", "acoustic panels"},
 	}
@@ -156,7 +266,7 @@ func NewRepositoryWithSampleData(includeSampleData bool) *Repository {
 		Description: "A virtual peaceful garden for mental relaxation",
 		Type:        models.WorldTypeVirtual,
 		Location:    "https://garden.vibespace.io",
-		CurrentVibe: calmVibe.ID,
+		CurrentVibe: "calm-clarity",
 		Features:    []string{"water sounds", "interactive plants", "meditation spots"},
 	}
 
@@ -166,16 +276,12 @@ func NewRepositoryWithSampleData(includeSampleData bool) *Repository {
 		Description: "A hybrid space for both physical and virtual creative collaboration",
 		Type:        models.WorldTypeHybrid,
 		Location:    "Floor 5, Innovation Center + VR instance",
-		CurrentVibe: energeticVibe.ID,
+		CurrentVibe: "energetic-spark",
 		Size:        "Large (1000 sqm physical + unlimited virtual)",
 		Features:    []string{"AR overlays", "digital whiteboard", "spatial audio"},
 	}
 
-	r.worlds[officeWorld.ID] = officeWorld
-	r.worlds[virtualWorld.ID] = virtualWorld
-	r.worlds[hybridWorld.ID] = hybridWorld
-
-	return r
+	return []models.World{officeWorld, virtualWorld, hybridWorld}
 }
 
 // GetVibe retrieves a vibe by ID
@@ -204,46 +310,61 @@ func (r *Repository) GetAllVibes() []models.Vibe {
 
 // AddVibe adds a new vibe
 func (r *Repository) AddVibe(vibe models.Vibe) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	_, err := r.createVibe("", vibe)
+	return err
+}
 
-	r.vibes[vibe.ID] = vibe
-	return nil
+// CreateVibe adds a new vibe and returns it as stored
+func (r *Repository) CreateVibe(vibe models.Vibe) (models.Vibe, error) {
+	return r.createVibe("", vibe)
+}
+
+func (r *Repository) createVibe(actor string, vibe models.Vibe) (models.Vibe, error) {
+	var created models.Vibe
+	err := r.write(actor, func(t *writeTxn) error {
+		var err error
+		created, err = t.addVibe(vibe)
+		return err
+	})
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	return created, nil
 }
 
 // UpdateVibe updates an existing vibe
 func (r *Repository) UpdateVibe(vibe models.Vibe) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	_, ok := r.vibes[vibe.ID]
-	if !ok {
-		return ErrVibeNotFound
-	}
-	r.vibes[vibe.ID] = vibe
-	return nil
+	_, err := r.updateVibe("", vibe)
+	return err
 }
 
-// DeleteVibe removes a vibe
-func (r *Repository) DeleteVibe(id string) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// SaveVibe updates an existing vibe and returns it as stored
+func (r *Repository) SaveVibe(vibe models.Vibe) (models.Vibe, error) {
+	return r.updateVibe("", vibe)
+}
 
-	// Check if vibe exists
-	_, ok := r.vibes[id]
-	if !ok {
-		return ErrVibeNotFound
+func (r *Repository) updateVibe(actor string, vibe models.Vibe) (models.Vibe, error) {
+	var updated models.Vibe
+	err := r.write(actor, func(t *writeTxn) error {
+		var err error
+		updated, err = t.updateVibe(vibe)
+		return err
+	})
+	if err != nil {
+		return models.Vibe{}, err
 	}
+	return updated, nil
+}
 
-	// Check if vibe is in use by any world
-	for _, world := range r.worlds {
-		if world.CurrentVibe == id {
-			return ErrVibeInUse
-		}
-	}
+// DeleteVibe moves a vibe to the trash
+func (r *Repository) DeleteVibe(id string) error {
+	return r.deleteVibe("", id)
+}
 
-	delete(r.vibes, id)
-	return nil
+func (r *Repository) deleteVibe(actor string, id string) error {
+	// The default mode fails with ErrVibeInUse if any world uses the vibe
+	_, err := r.deleteVibeWithOptions(actor, id, VibeDeleteOptions{})
+	return err
 }
 
 // GetWorld retrieves a world by ID
@@ -272,75 +393,109 @@ func (r *Repository) GetAllWorlds() []models.World {
 
 // AddWorld adds a new world
 func (r *Repository) AddWorld(world models.World) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	_, err := r.createWorld("", world)
+	return err
+}
 
-	// If world has a vibe assigned, check if it exists
-	if world.CurrentVibe != "" {
-		_, ok := r.vibes[world.CurrentVibe]
-		if !ok {
-			return ErrVibeNotFound
-		}
-	}
+// CreateWorld adds a new world and returns it as stored
+func (r *Repository) CreateWorld(world models.World) (models.World, error) {
+	return r.createWorld("", world)
+}
 
-	r.worlds[world.ID] = world
-	return nil
+func (r *Repository) createWorld(actor string, world models.World) (models.World, error) {
+	var created models.World
+	err := r.write(actor, func(t *writeTxn) error {
+		var err error
+		created, err = t.addWorld(world)
+		return err
+	})
+	if err != nil {
+		return models.World{}, err
+	}
+	return created, nil
 }
 
 // UpdateWorld updates an existing world
 func (r *Repository) UpdateWorld(world models.World) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	_, err := r.updateWorld("", world)
+	return err
+}
 
-	_, ok := r.worlds[world.ID]
-	if !ok {
-		return ErrWorldNotFound
-	}
+// SaveWorld updates an existing world and returns it as stored
+func (r *Repository) SaveWorld(world models.World) (models.World, error) {
+	return r.updateWorld("", world)
+}
 
-	// If world has a vibe assigned, check if it exists
-	if world.CurrentVibe != "" {
-		_, ok := r.vibes[world.CurrentVibe]
-		if !ok {
-			return ErrVibeNotFound
-		}
+func (r *Repository) updateWorld(actor string, world models.World) (models.World, error) {
+	var updated models.World
+	err := r.write(actor, func(t *writeTxn) error {
+		var err error
+		updated, err = t.updateWorld(world)
+		return err
+	})
+	if err != nil {
+		return models.World{}, err
 	}
-
-	r.worlds[world.ID] = world
-	return nil
+	return updated, nil
 }
 
-// DeleteWorld removes a world
+// DeleteWorld moves a world to the trash
 func (r *Repository) DeleteWorld(id string) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	_, ok := r.worlds[id]
-	if !ok {
-		return ErrWorldNotFound
-	}
+	return r.deleteWorld("", id)
+}
 
-	delete(r.worlds, id)
-	return nil
+func (r *Repository) deleteWorld(actor string, id string) error {
+	return r.write(actor, func(t *writeTxn) error { return t.deleteWorld(id) })
 }
 
 // SetWorldVibe sets a world's vibe
 func (r *Repository) SetWorldVibe(worldID, vibeID string) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	_, err := r.setWorldVibe("", worldID, vibeID, 0, 0)
+	return err
+}
 
-	world, ok := r.worlds[worldID]
-	if !ok {
-		return ErrWorldNotFound
+// SetWorldVibeIfVersion sets a world's vibe if the world is still at
+// expectedVersion; zero skips the check
+func (r *Repository) SetWorldVibeIfVersion(worldID, vibeID string, expectedVersion int64) error {
+	_, err := r.setWorldVibe("", worldID, vibeID, expectedVersion, 0)
+	return err
+}
+
+// TransitionWorldVibe sets a world's vibe, blending from its previous vibe
+// over duration, and returns the world as stored
+func (r *Repository) TransitionWorldVibe(worldID, vibeID string, duration time.Duration, expectedVersion int64) (models.World, error) {
+	return r.setWorldVibe("", worldID, vibeID, expectedVersion, duration)
+}
+
+func (r *Repository) setWorldVibe(actor, worldID, vibeID string, expectedVersion int64, duration time.Duration) (models.World, error) {
+	var updated models.World
+	err := r.write(actor, func(t *writeTxn) error {
+		var err error
+		updated, err = t.setWorldVibe(worldID, vibeID, expectedVersion, duration)
+		return err
+	})
+	if err != nil {
+		return models.World{}, err
 	}
+	return updated, nil
+}
+
+// BlendedWorldVibe returns the vibe a world shows at now
+func (r *Repository) BlendedWorldVibe(worldID string, now time.Time) (models.Vibe, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	_, ok = r.vibes[vibeID]
+	world, ok := r.worlds[worldID]
 	if !ok {
-		return ErrVibeNotFound
+		return models.Vibe{}, ErrWorldNotFound
 	}
-
-	world.CurrentVibe = vibeID
-	r.worlds[worldID] = world
-	return nil
+	return blendedWorldVibe(world, now, func(id string) (models.Vibe, error) {
+		vibe, ok := r.vibes[id]
+		if !ok {
+			return models.Vibe{}, ErrVibeNotFound
+		}
+		return vibe, nil
+	})
 }
 
 // GetWorldVibe gets a world's vibe
@@ -363,4 +518,169 @@ func (r *Repository) GetWorldVibe(worldID string) (models.Vibe, error) {
 	}
 
 	return vibe, nil
-}
\ No newline at end of file
+}
+
+// putVibe builds a mutation that stores the given vibe
+func putVibe(vibe models.Vibe) Mutation {
+	return Mutation{Op: OpPutVibe, ID: vibe.ID, Vibe: &vibe}
+}
+
+// putWorld builds a mutation that stores the given world
+func putWorld(world models.World) Mutation {
+	return Mutation{Op: OpPutWorld, ID: world.ID, World: &world}
+}
+
+// commit hands the mutations to the journal, if one is attached, and then
+// applies them to the in-memory maps. Callers must hold the write lock.
+func (r *Repository) commit(actor string, mutations ...Mutation) error {
+	change := Change{Time: time.Now().UTC(), Actor: actor, Mutations: mutations}
+	if r.journal != nil {
+		if err := r.journal.Append(change); err != nil {
+			return fmt.Errorf("failed to persist change: %w", err)
+		}
+	}
+	r.apply(change)
+	return nil
+}
+
+// apply writes mutations straight into the maps without any validation,
+// records them in the history and notifies subscribers. Callers must hold
+// the write lock.
+func (r *Repository) apply(change Change) {
+	for _, m := range change.Mutations {
+		switch m.Op {
+		case OpPutVibe, OpRestoreVibe, OpTrashVibe, OpDeleteVibe:
+			var before, after *models.Vibe
+			if vibe, ok := r.vibes[m.ID]; ok {
+				before = &vibe
+			}
+			switch {
+			case (m.Op == OpPutVibe || m.Op == OpRestoreVibe) && m.Vibe != nil:
+				r.vibes[m.ID] = *m.Vibe
+				delete(r.trashedVibes, m.ID)
+				after = m.Vibe
+			case m.Op == OpTrashVibe && before != nil:
+				r.trashedVibes[m.ID] = DeletedVibe{Vibe: *before, DeletedAt: change.Time, DeletedBy: change.Actor}
+				delete(r.vibes, m.ID)
+			default:
+				delete(r.vibes, m.ID)
+				delete(r.trashedVibes, m.ID)
+			}
+			if before == nil && after == nil {
+				continue // Purged from the trash
+			}
+			entry := newHistoryEntry(change.Time, change.Actor, entityKindVibe, m.ID, before, after)
+			if m.Op == OpRestoreVibe {
+				entry.Op = HistoryRestore
+			}
+			r.record(entry)
+			r.events.publish(vibeChangeEvent(change.Time, change.Actor, m.ID, after))
+		case OpPutWorld, OpRestoreWorld, OpTrashWorld, OpDeleteWorld:
+			var before, after *models.World
+			if world, ok := r.worlds[m.ID]; ok {
+				before = &world
+			}
+			switch {
+			case (m.Op == OpPutWorld || m.Op == OpRestoreWorld) && m.World != nil:
+				r.worlds[m.ID] = *m.World
+				delete(r.trashedWorlds, m.ID)
+				after = m.World
+			case m.Op == OpTrashWorld && before != nil:
+				r.trashedWorlds[m.ID] = DeletedWorld{World: *before, DeletedAt: change.Time, DeletedBy: change.Actor}
+				delete(r.worlds, m.ID)
+			default:
+				delete(r.worlds, m.ID)
+				delete(r.trashedWorlds, m.ID)
+			}
+			r.indexWorld(before, after)
+			if after == nil {
+				r.removeEdges(m.ID)
+			}
+			if before == nil && after == nil {
+				continue // Purged from the trash
+			}
+			entry := newHistoryEntry(change.Time, change.Actor, entityKindWorld, m.ID, before, after)
+			if m.Op == OpRestoreWorld {
+				entry.Op = HistoryRestore
+			}
+			r.record(entry)
+			r.events.publish(worldChangeEvent(change.Time, change.Actor, m.ID, before, after))
+		case OpPutEdge:
+			if m.Edge != nil {
+				r.edges[m.ID] = *m.Edge
+			}
+		case OpDeleteEdge:
+			delete(r.edges, m.ID)
+		}
+	}
+}
+
+// repositoryActor is a view of a Repository that attributes writes to an actor
+type repositoryActor struct {
+	*Repository
+	actor string
+}
+
+// WithActor returns a view of the repository whose writes are attributed to actor
+func (r *Repository) WithActor(actor string) VibeWorldRepository {
+	return &repositoryActor{Repository: r, actor: actor}
+}
+
+func (a *repositoryActor) AddVibe(vibe models.Vibe) error {
+	_, err := a.createVibe(a.actor, vibe)
+	return err
+}
+func (a *repositoryActor) CreateVibe(vibe models.Vibe) (models.Vibe, error) {
+	return a.createVibe(a.actor, vibe)
+}
+func (a *repositoryActor) UpdateVibe(vibe models.Vibe) error {
+	_, err := a.updateVibe(a.actor, vibe)
+	return err
+}
+func (a *repositoryActor) SaveVibe(vibe models.Vibe) (models.Vibe, error) {
+	return a.updateVibe(a.actor, vibe)
+}
+func (a *repositoryActor) DeleteVibe(id string) error { return a.deleteVibe(a.actor, id) }
+func (a *repositoryActor) AddWorld(world models.World) error {
+	_, err := a.createWorld(a.actor, world)
+	return err
+}
+func (a *repositoryActor) CreateWorld(world models.World) (models.World, error) {
+	return a.createWorld(a.actor, world)
+}
+func (a *repositoryActor) UpdateWorld(world models.World) error {
+	_, err := a.updateWorld(a.actor, world)
+	return err
+}
+func (a *repositoryActor) SaveWorld(world models.World) (models.World, error) {
+	return a.updateWorld(a.actor, world)
+}
+func (a *repositoryActor) DeleteWorld(id string) error { return a.deleteWorld(a.actor, id) }
+func (a *repositoryActor) SetWorldVibe(worldID, vibeID string) error {
+	_, err := a.setWorldVibe(a.actor, worldID, vibeID, 0, 0)
+	return err
+}
+func (a *repositoryActor) SetWorldVibeIfVersion(worldID, vibeID string, expectedVersion int64) error {
+	_, err := a.setWorldVibe(a.actor, worldID, vibeID, expectedVersion, 0)
+	return err
+}
+func (a *repositoryActor) TransitionWorldVibe(worldID, vibeID string, duration time.Duration, expectedVersion int64) (models.World, error) {
+	return a.setWorldVibe(a.actor, worldID, vibeID, expectedVersion, duration)
+}
+func (a *repositoryActor) RestoreVibe(id string) error  { return a.restoreVibe(a.actor, id) }
+func (a *repositoryActor) RestoreWorld(id string) error { return a.restoreWorld(a.actor, id) }
+func (a *repositoryActor) DeleteVibeWithOptions(id string, opts VibeDeleteOptions) ([]string, error) {
+	return a.deleteVibeWithOptions(a.actor, id, opts)
+}
+func (a *repositoryActor) ApplyBatch(ops []BatchOp) ([]string, error) {
+	return a.applyBatch(a.actor, ops)
+}
+func (a *repositoryActor) ConnectWorlds(edge models.WorldEdge) error {
+	return a.connectWorlds(a.actor, edge)
+}
+func (a *repositoryActor) DisconnectWorlds(worldA, worldB string) error {
+	return a.disconnectWorlds(a.actor, worldA, worldB)
+}
+func (a *repositoryActor) ImportBundle(bundle Bundle, strategy ImportStrategy) (ImportResult, error) {
+	return a.importBundle(a.actor, bundle, strategy)
+}
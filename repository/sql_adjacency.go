@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// ConnectWorlds stores an edge between two worlds
+func (r *SQLRepository) ConnectWorlds(edge models.WorldEdge) error {
+	return r.inTx(func(tx *sql.Tx) error { return connectWorldsTx(tx, edge) })
+}
+
+func connectWorldsTx(tx *sql.Tx, edge models.WorldEdge) error {
+	if err := edge.Validate(); err != nil {
+		return err
+	}
+	edge = edge.Normalized()
+	for _, id := range []string{edge.From, edge.To} {
+		exists, err := rowExists(tx, `SELECT 1 FROM worlds WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrWorldNotFound
+		}
+	}
+	_, err := tx.Exec(`
+		INSERT INTO world_edges (world_a, world_b, weight, kind) VALUES (?, ?, ?, ?)
+		ON CONFLICT (world_a, world_b) DO UPDATE SET
+			weight = excluded.weight,
+			kind = excluded.kind`,
+		edge.From, edge.To, edge.Weight, edge.Kind)
+	return err
+}
+
+// DisconnectWorlds removes the edge between two worlds
+func (r *SQLRepository) DisconnectWorlds(a, b string) error {
+	return r.inTx(func(tx *sql.Tx) error { return disconnectWorldsTx(tx, a, b) })
+}
+
+func disconnectWorldsTx(tx *sql.Tx, a, b string) error {
+	if b < a {
+		a, b = b, a
+	}
+	result, err := tx.Exec(`DELETE FROM world_edges WHERE world_a = ? AND world_b = ?`, a, b)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrEdgeNotFound
+	}
+	return nil
+}
+
+// WorldEdges returns the edges of a world oriented away from it
+func (r *SQLRepository) WorldEdges(id string) ([]models.WorldEdge, error) {
+	if _, err := getWorld(r.db, id); err != nil {
+		return nil, err
+	}
+	edges, err := queryEdges(r.db, `WHERE world_a = ?1 OR world_b = ?1`, id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range edges {
+		edges[i] = edges[i].Oriented(id)
+	}
+	sortEdges(edges)
+	return edges, nil
+}
+
+// AllWorldEdges returns every edge, sorted
+func (r *SQLRepository) AllWorldEdges() ([]models.WorldEdge, error) {
+	return queryEdges(r.db, `ORDER BY world_a, world_b`)
+}
+
+// queryEdges loads the edges matching a WHERE and/or ORDER BY clause
+func queryEdges(q querier, clause string, args ...interface{}) ([]models.WorldEdge, error) {
+	rows, err := q.Query(`SELECT world_a, world_b, weight, kind FROM world_edges `+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edges := []models.WorldEdge{}
+	for rows.Next() {
+		var edge models.WorldEdge
+		if err := rows.Scan(&edge.From, &edge.To, &edge.Weight, &edge.Kind); err != nil {
+			return nil, err
+		}
+		edges = append(edges, edge)
+	}
+	return edges, rows.Err()
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ApplyBatch applies ops in order, all or nothing, in a single transaction
+func (r *SQLRepository) ApplyBatch(ops []BatchOp) ([]string, error) {
+	return r.applyBatch("", ops)
+}
+
+func (r *SQLRepository) applyBatch(actor string, ops []BatchOp) ([]string, error) {
+	if err := validateBatch(ops); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(ops))
+	err := r.inTx(func(tx *sql.Tx) error {
+		for i, op := range ops {
+			id, err := r.applyOpTx(tx, actor, op)
+			if err != nil {
+				return &BatchError{Index: i, Op: op.Op, Err: err}
+			}
+			ids[i] = id
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// applyOpTx runs a single validated batch operation in tx, returning the ID of
+// the entity it created, if any
+func (r *SQLRepository) applyOpTx(tx *sql.Tx, actor string, op BatchOp) (string, error) {
+	switch op.Op {
+	case BatchCreateVibe:
+		vibe, err := r.addVibeTx(tx, actor, *op.Vibe)
+		return vibe.ID, err
+	case BatchUpdateVibe:
+		_, err := r.updateVibeTx(tx, actor, *op.Vibe)
+		return "", err
+	case BatchDeleteVibe:
+		_, err := r.deleteVibeWithOptionsTx(tx, actor, op.ID, VibeDeleteOptions{Mode: op.Mode, ReplacementID: op.ReplacementID})
+		return "", err
+	case BatchCreateWorld:
+		world, err := r.addWorldTx(tx, actor, *op.World)
+		return world.ID, err
+	case BatchUpdateWorld:
+		_, err := r.updateWorldTx(tx, actor, *op.World)
+		return "", err
+	case BatchDeleteWorld:
+		return "", r.trashWorldTx(tx, actor, op.ID)
+	case BatchSetWorldVibe:
+		_, err := r.setWorldVibeTx(tx, actor, op.WorldID, op.VibeID, op.Version, time.Duration(op.Duration)*time.Millisecond)
+		return "", err
+	case BatchConnect:
+		return "", connectWorldsTx(tx, *op.Edge)
+	case BatchDisconnect:
+		return "", disconnectWorldsTx(tx, op.Edge.From, op.Edge.To)
+	}
+	return "", fmt.Errorf("unknown operation %q", op.Op)
+}
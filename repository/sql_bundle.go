@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ExportBundle returns a snapshot of every vibe, world and edge, ordered by ID,
+// read in a single transaction
+func (r *SQLRepository) ExportBundle() (Bundle, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return Bundle{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	vibes, err := queryVibes(tx, vibeSelect+` ORDER BY v.id`)
+	if err != nil {
+		return Bundle{}, err
+	}
+	worlds, err := queryWorlds(tx, worldSelect+` ORDER BY w.id`)
+	if err != nil {
+		return Bundle{}, err
+	}
+	edges, err := queryEdges(tx, `ORDER BY world_a, world_b`)
+	if err != nil {
+		return Bundle{}, err
+	}
+
+	bundle := NewBundle(vibes, worlds)
+	bundle.Edges = edges
+	return bundle, nil
+}
+
+// ImportBundle writes the vibes, worlds and edges of a bundle in a single transaction
+func (r *SQLRepository) ImportBundle(bundle Bundle, strategy ImportStrategy) (ImportResult, error) {
+	return r.importBundle("", bundle, strategy)
+}
+
+func (r *SQLRepository) importBundle(actor string, bundle Bundle, strategy ImportStrategy) (ImportResult, error) {
+	var result ImportResult
+	err := r.inTx(func(tx *sql.Tx) error {
+		ops, planned, err := planImport(bundle, strategy, func(kind, id string) (bool, error) {
+			switch kind {
+			case entityKindVibe:
+				return rowExists(tx, `SELECT 1 FROM vibes WHERE id = ?`, id)
+			case entityKindEdge:
+				a, b := splitEdgeKey(id)
+				return rowExists(tx, `SELECT 1 FROM world_edges WHERE world_a = ? AND world_b = ?`, a, b)
+			}
+			return rowExists(tx, `SELECT 1 FROM worlds WHERE id = ?`, id)
+		})
+		if err != nil {
+			return err
+		}
+		for _, op := range ops {
+			id, err := r.applyOpTx(tx, actor, op)
+			if err != nil {
+				return importError(op, err)
+			}
+			planned.noteGenerated(op, id)
+		}
+		result = planned
+		return nil
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// GetVibeWorlds returns the worlds whose current vibe is vibeID, ordered by ID
+func (r *SQLRepository) GetVibeWorlds(vibeID string) ([]models.World, error) {
+	if _, err := getVibe(r.db, vibeID); err != nil {
+		return nil, err
+	}
+	return queryWorlds(r.db, worldSelect+` WHERE w.current_vibe = ? ORDER BY w.id`, vibeID)
+}
+
+// DeleteVibeWithOptions deletes a vibe, handling the worlds that use it
+// according to opts
+func (r *SQLRepository) DeleteVibeWithOptions(id string, opts VibeDeleteOptions) ([]string, error) {
+	return r.deleteVibeWithOptions("", id, opts)
+}
+
+func (r *SQLRepository) deleteVibeWithOptions(actor, id string, opts VibeDeleteOptions) ([]string, error) {
+	var changed []string
+	err := r.inTx(func(tx *sql.Tx) error {
+		var err error
+		changed, err = r.deleteVibeWithOptionsTx(tx, actor, id, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+func (r *SQLRepository) deleteVibeWithOptionsTx(tx *sql.Tx, actor, id string, opts VibeDeleteOptions) ([]string, error) {
+	before, err := loadVibeTx(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil {
+		return nil, ErrVibeNotFound
+	}
+	derived, err := rowExists(tx, `SELECT 1 FROM vibes WHERE parent_id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if derived {
+		return nil, ErrVibeHasDerived
+	}
+
+	// idx_worlds_current_vibe serves as the reverse index
+	dependents, err := queryWorlds(tx, worldSelect+` WHERE w.current_vibe = ? ORDER BY w.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	var existsErr error
+	replacement, err := replacementVibe(id, opts, func(vibeID string) bool {
+		exists, err := rowExists(tx, `SELECT 1 FROM vibes WHERE id = ?`, vibeID)
+		if err != nil {
+			existsErr = err
+		}
+		return exists
+	})
+	if existsErr != nil {
+		return nil, existsErr
+	}
+	if err == ErrVibeInUse && len(dependents) == 0 {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	for _, world := range dependents {
+		after := world
+		after.CurrentVibe = replacement
+		after.Version++
+		var currentVibe interface{}
+		if replacement != "" {
+			currentVibe = replacement
+		}
+		if _, err := tx.Exec(`UPDATE worlds SET current_vibe = ?, version = ? WHERE id = ?`, currentVibe, after.Version, world.ID); err != nil {
+			return nil, err
+		}
+		if err := r.recordHistoryTx(tx, actor, entityKindWorld, world.ID, &world, &after); err != nil {
+			return nil, err
+		}
+		changed = append(changed, world.ID)
+	}
+
+	if err := r.trashTx(tx, entityKindVibe, id, actor, before); err != nil {
+		return nil, err
+	}
+	if err := deleteVibeTx(tx, id); err != nil {
+		return nil, err
+	}
+	if err := r.recordHistoryTx(tx, actor, entityKindVibe, id, before, nil); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// worldLookupTx is the worldLookup of the worlds in tx
+func worldLookupTx(tx *sql.Tx) worldLookup {
+	return func(id string) (models.World, bool, error) {
+		world, err := loadWorldTx(tx, id)
+		if err != nil || world == nil {
+			return models.World{}, false, err
+		}
+		return *world, true, nil
+	}
+}
+
+// lookupWorld is the worldLookup of the committed worlds
+func (r *SQLRepository) lookupWorld(id string) (models.World, bool, error) {
+	world, err := getWorld(r.db, id)
+	if err == ErrWorldNotFound {
+		return models.World{}, false, nil
+	}
+	return world, err == nil, err
+}
+
+// ChildWorlds returns the worlds directly inside a world, ordered by ID
+func (r *SQLRepository) ChildWorlds(id string) ([]models.World, error) {
+	if _, err := getWorld(r.db, id); err != nil {
+		return nil, err
+	}
+	return queryWorlds(r.db, worldSelect+` WHERE w.parent_id = ? ORDER BY w.id`, id)
+}
+
+// WorldAncestors returns the worlds containing a world, innermost first
+func (r *SQLRepository) WorldAncestors(id string) ([]models.World, error) {
+	world, err := getWorld(r.db, id)
+	if err != nil {
+		return nil, err
+	}
+	return ancestorsOf(world, r.lookupWorld)
+}
+
+// WorldRollup summarizes a world and every world nested in it. Only the IDs
+// and occupancy of the nested worlds are loaded, using idx_worlds_parent.
+func (r *SQLRepository) WorldRollup(id string) (models.WorldRollup, error) {
+	world, err := getWorld(r.db, id)
+	if err != nil {
+		return models.WorldRollup{}, err
+	}
+	return rollUpWorld(world, func(id string) ([]models.World, error) {
+		rows, err := r.db.Query(`SELECT id, occupancy FROM worlds WHERE parent_id = ? ORDER BY id`, id)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		children := []models.World{}
+		for rows.Next() {
+			child := models.World{ParentID: id}
+			if err := rows.Scan(&child.ID, &child.Occupancy); err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+		}
+		return children, rows.Err()
+	})
+}
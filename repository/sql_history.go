@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// sqlRepositoryActor is a view of a SQLRepository that attributes writes to an actor
+type sqlRepositoryActor struct {
+	*SQLRepository
+	actor string
+}
+
+// WithActor returns a view of the repository whose writes are attributed to actor
+func (r *SQLRepository) WithActor(actor string) VibeWorldRepository {
+	return &sqlRepositoryActor{SQLRepository: r, actor: actor}
+}
+
+func (a *sqlRepositoryActor) AddVibe(vibe models.Vibe) error {
+	_, err := a.createVibe(a.actor, vibe)
+	return err
+}
+func (a *sqlRepositoryActor) CreateVibe(vibe models.Vibe) (models.Vibe, error) {
+	return a.createVibe(a.actor, vibe)
+}
+func (a *sqlRepositoryActor) UpdateVibe(vibe models.Vibe) error {
+	_, err := a.updateVibe(a.actor, vibe)
+	return err
+}
+func (a *sqlRepositoryActor) SaveVibe(vibe models.Vibe) (models.Vibe, error) {
+	return a.updateVibe(a.actor, vibe)
+}
+func (a *sqlRepositoryActor) DeleteVibe(id string) error { return a.deleteVibe(a.actor, id) }
+func (a *sqlRepositoryActor) AddWorld(world models.World) error {
+	_, err := a.createWorld(a.actor, world)
+	return err
+}
+func (a *sqlRepositoryActor) CreateWorld(world models.World) (models.World, error) {
+	return a.createWorld(a.actor, world)
+}
+func (a *sqlRepositoryActor) UpdateWorld(world models.World) error {
+	_, err := a.updateWorld(a.actor, world)
+	return err
+}
+func (a *sqlRepositoryActor) SaveWorld(world models.World) (models.World, error) {
+	return a.updateWorld(a.actor, world)
+}
+func (a *sqlRepositoryActor) DeleteWorld(id string) error { return a.deleteWorld(a.actor, id) }
+func (a *sqlRepositoryActor) SetWorldVibe(worldID, vibeID string) error {
+	_, err := a.setWorldVibe(a.actor, worldID, vibeID, 0, 0)
+	return err
+}
+func (a *sqlRepositoryActor) SetWorldVibeIfVersion(worldID, vibeID string, expectedVersion int64) error {
+	_, err := a.setWorldVibe(a.actor, worldID, vibeID, expectedVersion, 0)
+	return err
+}
+func (a *sqlRepositoryActor) TransitionWorldVibe(worldID, vibeID string, duration time.Duration, expectedVersion int64) (models.World, error) {
+	return a.setWorldVibe(a.actor, worldID, vibeID, expectedVersion, duration)
+}
+func (a *sqlRepositoryActor) RestoreVibe(id string) error  { return a.restoreVibe(a.actor, id) }
+func (a *sqlRepositoryActor) RestoreWorld(id string) error { return a.restoreWorld(a.actor, id) }
+func (a *sqlRepositoryActor) DeleteVibeWithOptions(id string, opts VibeDeleteOptions) ([]string, error) {
+	return a.deleteVibeWithOptions(a.actor, id, opts)
+}
+func (a *sqlRepositoryActor) ApplyBatch(ops []BatchOp) ([]string, error) {
+	return a.applyBatch(a.actor, ops)
+}
+func (a *sqlRepositoryActor) ImportBundle(bundle Bundle, strategy ImportStrategy) (ImportResult, error) {
+	return a.importBundle(a.actor, bundle, strategy)
+}
+
+// VibeHistory returns every recorded change to a vibe, oldest first
+func (r *SQLRepository) VibeHistory(id string) ([]HistoryEntry, error) {
+	entries, err := queryHistory(r.db, entityKindVibe, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		if _, err := getVibe(r.db, id); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// WorldHistory returns every recorded change to a world, oldest first
+func (r *SQLRepository) WorldHistory(id string) ([]HistoryEntry, error) {
+	entries, err := queryHistory(r.db, entityKindWorld, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		if _, err := getWorld(r.db, id); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// VibeAt returns a vibe as it was at the given time
+func (r *SQLRepository) VibeAt(id string, at time.Time) (models.Vibe, error) {
+	state, known, err := historyStateAt(r.db, entityKindVibe, id, at)
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	if !known {
+		return getVibe(r.db, id)
+	}
+	return decodeVibeState(state)
+}
+
+// WorldAt returns a world as it was at the given time
+func (r *SQLRepository) WorldAt(id string, at time.Time) (models.World, error) {
+	state, known, err := historyStateAt(r.db, entityKindWorld, id, at)
+	if err != nil {
+		return models.World{}, err
+	}
+	if !known {
+		return getWorld(r.db, id)
+	}
+	return decodeWorldState(state)
+}
+
+// historyStateAt looks up the state of an entity at a point in time; see stateAt
+func historyStateAt(q querier, kind, id string, at time.Time) (json.RawMessage, bool, error) {
+	var after sql.NullString
+	err := q.QueryRow(`SELECT after_json FROM history WHERE entity_kind = ? AND entity_id = ? AND time_ns <= ?
+		ORDER BY seq DESC LIMIT 1`, kind, id, at.UnixNano()).Scan(&after)
+	if err == nil {
+		return nullJSON(after), true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	// Every recorded change happened later; the entity was as before the first one
+	var before sql.NullString
+	err = q.QueryRow(`SELECT before_json FROM history WHERE entity_kind = ? AND entity_id = ?
+		ORDER BY seq LIMIT 1`, kind, id).Scan(&before)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return nullJSON(before), true, nil
+}
+
+// queryHistory loads the history of an entity, oldest first
+func queryHistory(q querier, kind, id string) ([]HistoryEntry, error) {
+	rows, err := q.Query(`SELECT seq, time_ns, actor, op, before_json, after_json, changes FROM history
+		WHERE entity_kind = ? AND entity_id = ? ORDER BY seq`, kind, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []HistoryEntry{}
+	for rows.Next() {
+		entry := HistoryEntry{Kind: kind, ID: id}
+		var timeNs int64
+		var op string
+		var before, after, changes sql.NullString
+		if err := rows.Scan(&entry.Seq, &timeNs, &entry.Actor, &op, &before, &after, &changes); err != nil {
+			return nil, err
+		}
+		entry.Time = time.Unix(0, timeNs).UTC()
+		entry.Op = HistoryOp(op)
+		entry.Before = nullJSON(before)
+		entry.After = nullJSON(after)
+		if changes.Valid {
+			if err := json.Unmarshal([]byte(changes.String), &entry.Changes); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// recordHistoryTx stores the history entry of a write made in tx and queues
+// its change event until the transaction commits
+func (r *SQLRepository) recordHistoryTx(tx *sql.Tx, actor, kind, id string, before, after interface{}) error {
+	return r.recordEntryTx(tx, newHistoryEntry(time.Now().UTC(), actor, kind, id, before, after), before, after)
+}
+
+// recordEntryTx stores a prepared history entry and queues its change event
+func (r *SQLRepository) recordEntryTx(tx *sql.Tx, entry HistoryEntry, before, after interface{}) error {
+	actor, kind, id := entry.Actor, entry.Kind, entry.ID
+
+	var changes interface{}
+	if len(entry.Changes) > 0 {
+		data, err := json.Marshal(entry.Changes)
+		if err != nil {
+			return err
+		}
+		changes = string(data)
+	}
+
+	_, err := tx.Exec(`INSERT INTO history (time_ns, actor, op, entity_kind, entity_id, before_json, after_json, changes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Time.UnixNano(), entry.Actor, string(entry.Op), kind, id,
+		jsonArg(entry.Before), jsonArg(entry.After), changes)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case entityKindVibe:
+		vibe, _ := after.(*models.Vibe)
+		r.pending = append(r.pending, vibeChangeEvent(entry.Time, actor, id, vibe))
+	case entityKindWorld:
+		previous, _ := before.(*models.World)
+		world, _ := after.(*models.World)
+		r.pending = append(r.pending, worldChangeEvent(entry.Time, actor, id, previous, world))
+	}
+	return nil
+}
+
+// loadVibeTx loads a vibe, returning nil if it does not exist
+func loadVibeTx(q querier, id string) (*models.Vibe, error) {
+	vibe, err := getVibe(q, id)
+	if err == ErrVibeNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &vibe, nil
+}
+
+// loadWorldTx loads a world, returning nil if it does not exist
+func loadWorldTx(q querier, id string) (*models.World, error) {
+	world, err := getWorld(q, id)
+	if err == ErrWorldNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &world, nil
+}
+
+// nullJSON converts a nullable JSON column to raw JSON
+func nullJSON(v sql.NullString) json.RawMessage {
+	if !v.Valid {
+		return nil
+	}
+	return json.RawMessage(v.String)
+}
+
+// jsonArg converts raw JSON to a nullable query argument
+func jsonArg(v json.RawMessage) interface{} {
+	if v == nil {
+		return nil
+	}
+	return string(v)
+}
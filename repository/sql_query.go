@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// vibeSelect selects vibe columns in the order expected by queryVibes
+const vibeSelect = `SELECT v.id, v.name, v.description, v.energy, v.mood, v.creator_id, v.version, v.parent_id, v.overrides FROM vibes v`
+
+// isPublicCondition compares the is_public flag of the entity identified by
+// idColumn, defaulting to private, with a bound argument
+func isPublicCondition(idColumn string) string {
+	return "COALESCE((SELECT s.is_public FROM sharing_settings s WHERE s.entity_kind = ? AND s.entity_id = " + idColumn + "), 0) = ?"
+}
+
+// sqlConditions accumulates WHERE conditions and their arguments
+type sqlConditions struct {
+	conditions []string
+	args       []interface{}
+}
+
+// add appends a condition with its arguments
+func (c *sqlConditions) add(condition string, args ...interface{}) {
+	c.conditions = append(c.conditions, condition)
+	c.args = append(c.args, args...)
+}
+
+// where renders the WHERE clause, or nothing if there are no conditions
+func (c *sqlConditions) where() string {
+	if len(c.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.conditions, " AND ")
+}
+
+// vibeConditions translates a VibeFilter into conditions on the vibes table (alias v)
+func vibeConditions(filter VibeFilter) *sqlConditions {
+	c := &sqlConditions{}
+	if filter.Mood != "" {
+		c.add("v.mood = ?", filter.Mood)
+	}
+	if filter.MinEnergy != nil {
+		c.add("v.energy >= ?", *filter.MinEnergy)
+	}
+	if filter.MaxEnergy != nil {
+		c.add("v.energy <= ?", *filter.MaxEnergy)
+	}
+	if filter.CreatorID != "" {
+		c.add("v.creator_id = ?", filter.CreatorID)
+	}
+	if filter.IsPublic != nil {
+		c.add(isPublicCondition("v.id"), entityKindVibe, *filter.IsPublic)
+	}
+	return c
+}
+
+// worldConditions translates a WorldFilter into conditions on worldSelect
+func worldConditions(filter WorldFilter) *sqlConditions {
+	c := &sqlConditions{}
+	if filter.Type != "" {
+		c.add("w.type = ?", string(filter.Type))
+	}
+	if filter.CreatorID != "" {
+		c.add("w.creator_id = ?", filter.CreatorID)
+	}
+	if filter.Mood != "" {
+		c.add("v.mood = ?", filter.Mood)
+	}
+	if filter.MinEnergy != nil {
+		c.add("v.energy >= ?", *filter.MinEnergy)
+	}
+	if filter.MaxEnergy != nil {
+		c.add("v.energy <= ?", *filter.MaxEnergy)
+	}
+	if filter.Feature != "" {
+		c.add("EXISTS (SELECT 1 FROM world_features f WHERE f.world_id = w.id AND f.feature = ?)", filter.Feature)
+	}
+	if filter.IsPublic != nil {
+		c.add(isPublicCondition("w.id"), entityKindWorld, *filter.IsPublic)
+	}
+	return c
+}
+
+// addKeyset restricts the query to rows after the cursor and returns the
+// ORDER BY clause of the listing. column is the sort expression, or empty
+// when sorting by ID only.
+func addKeyset(c *sqlConditions, opts ListOptions, cursor *pageCursor, column, idColumn string, after interface{}) string {
+	op, dir := ">", "ASC"
+	if opts.Descending {
+		op, dir = "<", "DESC"
+	}
+
+	if column == "" {
+		if cursor != nil {
+			c.add(idColumn+" "+op+" ?", cursor.After.ID)
+		}
+		return " ORDER BY " + idColumn + " " + dir
+	}
+
+	if cursor != nil {
+		c.add("("+column+" "+op+" ? OR ("+column+" = ? AND "+idColumn+" "+op+" ?))", after, after, cursor.After.ID)
+	}
+	return " ORDER BY " + column + " " + dir + ", " + idColumn + " " + dir
+}
+
+// cursorValue returns the sort value stored in a cursor for field
+func cursorValue(field SortField, cursor *pageCursor) interface{} {
+	if cursor == nil {
+		return nil
+	}
+	if field == SortByName {
+		return cursor.After.Str
+	}
+	return cursor.After.Num
+}
+
+// QueryVibes returns a filtered, sorted page of vibes
+func (r *SQLRepository) QueryVibes(query VibeQuery) (VibePage, error) {
+	field := query.sortField()
+	key, err := vibeSortKey(field)
+	if err != nil {
+		return VibePage{}, err
+	}
+	cursor, err := decodeCursor(query.ListOptions)
+	if err != nil {
+		return VibePage{}, err
+	}
+
+	column := map[SortField]string{SortByName: "v.name", SortByEnergy: "v.energy"}[field]
+	c := vibeConditions(query.Filter)
+	order := addKeyset(c, query.ListOptions, cursor, column, "v.id", cursorValue(field, cursor))
+
+	limit := query.pageLimit()
+	vibes, err := queryVibes(r.db, vibeSelect+c.where()+order+" LIMIT ?", append(c.args, limit+1)...)
+	if err != nil {
+		return VibePage{}, err
+	}
+
+	page := VibePage{Items: vibes}
+	if len(vibes) > limit {
+		page.Items = vibes[:limit]
+		page.NextCursor = encodeCursor(query.ListOptions, key(vibes[limit-1]))
+	}
+	return page, nil
+}
+
+// QueryWorlds returns a filtered, sorted page of worlds
+func (r *SQLRepository) QueryWorlds(query WorldQuery) (WorldPage, error) {
+	field := query.sortField()
+	if _, err := worldSortKey(field, nil); err != nil {
+		return WorldPage{}, err
+	}
+	cursor, err := decodeCursor(query.ListOptions)
+	if err != nil {
+		return WorldPage{}, err
+	}
+
+	column := map[SortField]string{
+		SortByName:      "w.name",
+		SortByEnergy:    "COALESCE(v.energy, 0)",
+		SortByOccupancy: "w.occupancy",
+	}[field]
+	c := worldConditions(query.Filter)
+	order := addKeyset(c, query.ListOptions, cursor, column, "w.id", cursorValue(field, cursor))
+
+	limit := query.pageLimit()
+	worlds, err := queryWorlds(r.db, worldSelect+c.where()+order+" LIMIT ?", append(c.args, limit+1)...)
+	if err != nil {
+		return WorldPage{}, err
+	}
+
+	page := WorldPage{Items: worlds}
+	if len(worlds) > limit {
+		page.Items = worlds[:limit]
+		last := worlds[limit-1]
+
+		energies := make(map[string]float64)
+		if field == SortByEnergy {
+			energy, err := vibeEnergy(r.db, last.CurrentVibe)
+			if err != nil {
+				return WorldPage{}, err
+			}
+			energies[last.ID] = energy
+		}
+		key, _ := worldSortKey(field, energies)
+		page.NextCursor = encodeCursor(query.ListOptions, key(last))
+	}
+	return page, nil
+}
+
+// vibeEnergy returns the energy of a vibe, or zero if it does not exist
+func vibeEnergy(q querier, vibeID string) (float64, error) {
+	var energy float64
+	err := q.QueryRow(`SELECT energy FROM vibes WHERE id = ?`, vibeID).Scan(&energy)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return energy, err
+}
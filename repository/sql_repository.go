@@ -0,0 +1,1007 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// Entity kinds used by the shared sharing tables
+const (
+	entityKindVibe  = "vibe"
+	entityKindWorld = "world"
+)
+
+// sqlInChunk bounds the number of placeholders in a single IN (...) list
+const sqlInChunk = 500
+
+// querier is the subset of *sql.DB and *sql.Tx used by the SQL repository
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// SQLRepository is a VibeWorldRepository stored in relational tables through
+// database/sql. Vibes, worlds, colors, features, sensor data and sharing
+// settings each have their own table, so worlds can be filtered in the
+// database instead of loading everything. Statements use ? placeholders and
+// upserts, and are tested against SQLite.
+//
+// The caller owns the *sql.DB: open it with the driver of their choice and
+// close it when done. For SQLite, a single open connection is recommended.
+type SQLRepository struct {
+	db      *sql.DB
+	writeMu sync.Mutex // serializes read-check-write transactions
+
+	events  eventBus
+	pending []ChangeEvent // events of the open transaction; guarded by writeMu
+
+	trashRetention atomic.Int64 // time.Duration
+}
+
+// Ensure SQLRepository implements VibeWorldRepository interface
+var _ VibeWorldRepository = (*SQLRepository)(nil)
+
+// NewSQLRepository creates a repository on db, applying any pending migrations
+func NewSQLRepository(db *sql.DB) (*SQLRepository, error) {
+	return NewSQLRepositoryWithSampleData(db, false)
+}
+
+// NewSQLRepositoryWithSampleData creates a repository on db, applying any
+// pending migrations and optionally seeding the same sample data as NewRepository
+func NewSQLRepositoryWithSampleData(db *sql.DB, includeSampleData bool) (*SQLRepository, error) {
+	if err := Migrate(db, sqlMigrations); err != nil {
+		return nil, err
+	}
+
+	r := &SQLRepository{db: db}
+	r.trashRetention.Store(int64(DefaultTrashRetention))
+	if !includeSampleData {
+		return r, nil
+	}
+
+	err := r.inTx(func(tx *sql.Tx) error {
+		for _, vibe := range sampleVibes() {
+			vibe.Version = 1
+			if err := putVibeTx(tx, vibe); err != nil {
+				return err
+			}
+		}
+		for _, world := range sampleWorlds() {
+			world.Version = 1
+			if err := putWorldTx(tx, world); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to seed sample data: %w", err)
+	}
+	return r, nil
+}
+
+// GetVibe retrieves a vibe by ID
+func (r *SQLRepository) GetVibe(id string) (models.Vibe, error) {
+	return getVibe(r.db, id)
+}
+
+// GetAllVibes returns all vibes
+func (r *SQLRepository) GetAllVibes() []models.Vibe {
+	vibes, err := queryVibes(r.db, vibeSelect+` ORDER BY v.id`)
+	if err != nil {
+		fmt.Printf("Error listing vibes: %v\n", err)
+		return []models.Vibe{}
+	}
+	return vibes
+}
+
+// AddVibe adds a new vibe
+func (r *SQLRepository) AddVibe(vibe models.Vibe) error {
+	_, err := r.createVibe("", vibe)
+	return err
+}
+
+// CreateVibe adds a new vibe and returns it as stored
+func (r *SQLRepository) CreateVibe(vibe models.Vibe) (models.Vibe, error) {
+	return r.createVibe("", vibe)
+}
+
+func (r *SQLRepository) createVibe(actor string, vibe models.Vibe) (models.Vibe, error) {
+	var created models.Vibe
+	err := r.inTx(func(tx *sql.Tx) error {
+		var err error
+		created, err = r.addVibeTx(tx, actor, vibe)
+		return err
+	})
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	return created, nil
+}
+
+// addVibeTx creates a vibe, generating its ID if it has none. Generated IDs
+// avoid the trash too, so a tombstone is only ever discarded on purpose.
+func (r *SQLRepository) addVibeTx(tx *sql.Tx, actor string, vibe models.Vibe) (models.Vibe, error) {
+	if vibe.ID == "" {
+		id, err := generateID(vibe.Name, func(id string) (bool, error) { return idTakenTx(tx, entityKindVibe, id) })
+		if err != nil {
+			return models.Vibe{}, err
+		}
+		vibe.ID = id
+	}
+	if err := vibe.Validate(); err != nil {
+		return models.Vibe{}, err
+	}
+	exists, err := rowExists(tx, `SELECT 1 FROM vibes WHERE id = ?`, vibe.ID)
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	if exists {
+		return models.Vibe{}, &DuplicateIDError{Kind: entityKindVibe, ID: vibe.ID}
+	}
+	vibe, err = resolveVibe(r.vibeTree(tx, actor), vibe, nil)
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	vibe.Version = 1
+	if err := untrashTx(tx, entityKindVibe, vibe.ID); err != nil {
+		return models.Vibe{}, err
+	}
+	if err := putVibeTx(tx, vibe); err != nil {
+		return models.Vibe{}, err
+	}
+	if err := r.recordHistoryTx(tx, actor, entityKindVibe, vibe.ID, (*models.Vibe)(nil), &vibe); err != nil {
+		return models.Vibe{}, err
+	}
+	return vibe, nil
+}
+
+// UpdateVibe updates an existing vibe
+func (r *SQLRepository) UpdateVibe(vibe models.Vibe) error {
+	_, err := r.updateVibe("", vibe)
+	return err
+}
+
+// SaveVibe updates an existing vibe and returns it as stored
+func (r *SQLRepository) SaveVibe(vibe models.Vibe) (models.Vibe, error) {
+	return r.updateVibe("", vibe)
+}
+
+func (r *SQLRepository) updateVibe(actor string, vibe models.Vibe) (models.Vibe, error) {
+	var updated models.Vibe
+	err := r.inTx(func(tx *sql.Tx) error {
+		var err error
+		updated, err = r.updateVibeTx(tx, actor, vibe)
+		return err
+	})
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	return updated, nil
+}
+
+func (r *SQLRepository) updateVibeTx(tx *sql.Tx, actor string, vibe models.Vibe) (models.Vibe, error) {
+	if err := vibe.Validate(); err != nil {
+		return models.Vibe{}, err
+	}
+	before, err := loadVibeTx(tx, vibe.ID)
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	if before == nil {
+		return models.Vibe{}, ErrVibeNotFound
+	}
+	if err := checkVersion(entityKindVibe, vibe.ID, vibe.Version, before.Version); err != nil {
+		return models.Vibe{}, err
+	}
+	tree := r.vibeTree(tx, actor)
+	vibe, err = resolveVibe(tree, vibe, before)
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	vibe.Version = before.Version + 1
+	if err := putVibeTx(tx, vibe); err != nil {
+		return models.Vibe{}, err
+	}
+	if err := r.recordHistoryTx(tx, actor, entityKindVibe, vibe.ID, before, &vibe); err != nil {
+		return models.Vibe{}, err
+	}
+	if err := propagateVibe(tree, vibe); err != nil {
+		return models.Vibe{}, err
+	}
+	return vibe, nil
+}
+
+// DeleteVibe moves a vibe to the trash
+func (r *SQLRepository) DeleteVibe(id string) error {
+	return r.deleteVibe("", id)
+}
+
+func (r *SQLRepository) deleteVibe(actor string, id string) error {
+	// The default mode fails with ErrVibeInUse if any world uses the vibe
+	_, err := r.deleteVibeWithOptions(actor, id, VibeDeleteOptions{})
+	return err
+}
+
+// GetWorld retrieves a world by ID
+func (r *SQLRepository) GetWorld(id string) (models.World, error) {
+	return getWorld(r.db, id)
+}
+
+// GetAllWorlds returns all worlds
+func (r *SQLRepository) GetAllWorlds() []models.World {
+	worlds, err := queryWorlds(r.db, worldSelect+` ORDER BY w.id`)
+	if err != nil {
+		fmt.Printf("Error listing worlds: %v\n", err)
+		return []models.World{}
+	}
+	return worlds
+}
+
+// FindWorlds returns the worlds matching filter, ordered by ID
+func (r *SQLRepository) FindWorlds(filter WorldFilter) ([]models.World, error) {
+	c := worldConditions(filter)
+	return queryWorlds(r.db, worldSelect+c.where()+" ORDER BY w.id", c.args...)
+}
+
+// AddWorld adds a new world
+func (r *SQLRepository) AddWorld(world models.World) error {
+	_, err := r.createWorld("", world)
+	return err
+}
+
+// CreateWorld adds a new world and returns it as stored
+func (r *SQLRepository) CreateWorld(world models.World) (models.World, error) {
+	return r.createWorld("", world)
+}
+
+func (r *SQLRepository) createWorld(actor string, world models.World) (models.World, error) {
+	var created models.World
+	err := r.inTx(func(tx *sql.Tx) error {
+		var err error
+		created, err = r.addWorldTx(tx, actor, world)
+		return err
+	})
+	if err != nil {
+		return models.World{}, err
+	}
+	return created, nil
+}
+
+// addWorldTx creates a world, generating its ID if it has none
+func (r *SQLRepository) addWorldTx(tx *sql.Tx, actor string, world models.World) (models.World, error) {
+	if world.ID == "" {
+		id, err := generateID(world.Name, func(id string) (bool, error) { return idTakenTx(tx, entityKindWorld, id) })
+		if err != nil {
+			return models.World{}, err
+		}
+		world.ID = id
+	}
+	if err := world.Validate(); err != nil {
+		return models.World{}, err
+	}
+	exists, err := rowExists(tx, `SELECT 1 FROM worlds WHERE id = ?`, world.ID)
+	if err != nil {
+		return models.World{}, err
+	}
+	if exists {
+		return models.World{}, &DuplicateIDError{Kind: entityKindWorld, ID: world.ID}
+	}
+	if err := checkVibeExists(tx, world.CurrentVibe); err != nil {
+		return models.World{}, err
+	}
+	if err := checkWorldParent(world, worldLookupTx(tx)); err != nil {
+		return models.World{}, err
+	}
+	world.Version = 1
+	if err := untrashTx(tx, entityKindWorld, world.ID); err != nil {
+		return models.World{}, err
+	}
+	if err := putWorldTx(tx, world); err != nil {
+		return models.World{}, err
+	}
+	if err := r.recordHistoryTx(tx, actor, entityKindWorld, world.ID, (*models.World)(nil), &world); err != nil {
+		return models.World{}, err
+	}
+	return world, nil
+}
+
+// UpdateWorld updates an existing world
+func (r *SQLRepository) UpdateWorld(world models.World) error {
+	_, err := r.updateWorld("", world)
+	return err
+}
+
+// SaveWorld updates an existing world and returns it as stored
+func (r *SQLRepository) SaveWorld(world models.World) (models.World, error) {
+	return r.updateWorld("", world)
+}
+
+func (r *SQLRepository) updateWorld(actor string, world models.World) (models.World, error) {
+	var updated models.World
+	err := r.inTx(func(tx *sql.Tx) error {
+		var err error
+		updated, err = r.updateWorldTx(tx, actor, world)
+		return err
+	})
+	if err != nil {
+		return models.World{}, err
+	}
+	return updated, nil
+}
+
+func (r *SQLRepository) updateWorldTx(tx *sql.Tx, actor string, world models.World) (models.World, error) {
+	if err := world.Validate(); err != nil {
+		return models.World{}, err
+	}
+	before, err := loadWorldTx(tx, world.ID)
+	if err != nil {
+		return models.World{}, err
+	}
+	if before == nil {
+		return models.World{}, ErrWorldNotFound
+	}
+	if err := checkVersion(entityKindWorld, world.ID, world.Version, before.Version); err != nil {
+		return models.World{}, err
+	}
+	if err := checkVibeExists(tx, world.CurrentVibe); err != nil {
+		return models.World{}, err
+	}
+	if err := checkWorldParent(world, worldLookupTx(tx)); err != nil {
+		return models.World{}, err
+	}
+	world.Transition = keptTransition(*before, world.CurrentVibe)
+	world.Version = before.Version + 1
+	if err := putWorldTx(tx, world); err != nil {
+		return models.World{}, err
+	}
+	if err := r.recordHistoryTx(tx, actor, entityKindWorld, world.ID, before, &world); err != nil {
+		return models.World{}, err
+	}
+	return world, nil
+}
+
+// DeleteWorld moves a world to the trash
+func (r *SQLRepository) DeleteWorld(id string) error {
+	return r.deleteWorld("", id)
+}
+
+func (r *SQLRepository) deleteWorld(actor string, id string) error {
+	return r.inTx(func(tx *sql.Tx) error { return r.trashWorldTx(tx, actor, id) })
+}
+
+func (r *SQLRepository) trashWorldTx(tx *sql.Tx, actor string, id string) error {
+	before, err := loadWorldTx(tx, id)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return ErrWorldNotFound
+	}
+	hasChildren, err := rowExists(tx, `SELECT 1 FROM worlds WHERE parent_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if hasChildren {
+		return ErrWorldHasChildren
+	}
+	if err := r.trashTx(tx, entityKindWorld, id, actor, before); err != nil {
+		return err
+	}
+	if err := deleteWorldTx(tx, id); err != nil {
+		return err
+	}
+	return r.recordHistoryTx(tx, actor, entityKindWorld, id, before, nil)
+}
+
+// SetWorldVibe sets a world's vibe
+func (r *SQLRepository) SetWorldVibe(worldID, vibeID string) error {
+	_, err := r.setWorldVibe("", worldID, vibeID, 0, 0)
+	return err
+}
+
+// SetWorldVibeIfVersion sets a world's vibe if the world is still at
+// expectedVersion; zero skips the check
+func (r *SQLRepository) SetWorldVibeIfVersion(worldID, vibeID string, expectedVersion int64) error {
+	_, err := r.setWorldVibe("", worldID, vibeID, expectedVersion, 0)
+	return err
+}
+
+// TransitionWorldVibe sets a world's vibe, blending from its previous vibe
+// over duration, and returns the world as stored
+func (r *SQLRepository) TransitionWorldVibe(worldID, vibeID string, duration time.Duration, expectedVersion int64) (models.World, error) {
+	return r.setWorldVibe("", worldID, vibeID, expectedVersion, duration)
+}
+
+func (r *SQLRepository) setWorldVibe(actor, worldID, vibeID string, expectedVersion int64, duration time.Duration) (models.World, error) {
+	var updated models.World
+	err := r.inTx(func(tx *sql.Tx) error {
+		var err error
+		updated, err = r.setWorldVibeTx(tx, actor, worldID, vibeID, expectedVersion, duration)
+		return err
+	})
+	if err != nil {
+		return models.World{}, err
+	}
+	return updated, nil
+}
+
+// BlendedWorldVibe returns the vibe a world shows at now
+func (r *SQLRepository) BlendedWorldVibe(worldID string, now time.Time) (models.Vibe, error) {
+	world, err := getWorld(r.db, worldID)
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	return blendedWorldVibe(world, now, func(id string) (models.Vibe, error) { return getVibe(r.db, id) })
+}
+
+func (r *SQLRepository) setWorldVibeTx(tx *sql.Tx, actor, worldID, vibeID string, expectedVersion int64, duration time.Duration) (models.World, error) {
+	before, err := loadWorldTx(tx, worldID)
+	if err != nil {
+		return models.World{}, err
+	}
+	if before == nil {
+		return models.World{}, ErrWorldNotFound
+	}
+	if err := checkVersion(entityKindWorld, worldID, expectedVersion, before.Version); err != nil {
+		return models.World{}, err
+	}
+	if vibeID == "" {
+		return models.World{}, ErrVibeNotFound
+	}
+	if err := checkVibeExists(tx, vibeID); err != nil {
+		return models.World{}, err
+	}
+
+	after := *before
+	after.Transition = nextTransition(before.CurrentVibe, vibeID, time.Now(), duration)
+	after.CurrentVibe = vibeID
+	after.Version++
+	from, startedAt, length := transitionArgs(after.Transition)
+	if _, err := tx.Exec(`UPDATE worlds SET current_vibe = ?, version = ?,
+		transition_from = ?, transition_started_ms = ?, transition_duration_ms = ? WHERE id = ?`,
+		vibeID, after.Version, from, startedAt, length, worldID); err != nil {
+		return models.World{}, err
+	}
+	if err := r.recordHistoryTx(tx, actor, entityKindWorld, worldID, before, &after); err != nil {
+		return models.World{}, err
+	}
+	return after, nil
+}
+
+// GetWorldVibe gets a world's vibe
+func (r *SQLRepository) GetWorldVibe(worldID string) (models.Vibe, error) {
+	world, err := getWorld(r.db, worldID)
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	if world.CurrentVibe == "" {
+		return models.Vibe{}, ErrVibeNotFound
+	}
+	return getVibe(r.db, world.CurrentVibe)
+}
+
+// inTx runs fn in a transaction, committing if it returns nil, and then
+// notifies subscribers of the writes it recorded. Sentinel errors returned
+// by fn are passed through unwrapped.
+func (r *SQLRepository) inTx(fn func(tx *sql.Tx) error) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	r.pending = nil
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	r.events.publish(r.pending...)
+	r.pending = nil
+	return nil
+}
+
+// rowExists reports whether query returns at least one row
+func rowExists(q querier, query string, args ...interface{}) (bool, error) {
+	var one int
+	err := q.QueryRow(query, args...).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// idTakenTx reports whether an entity of kind, live or in the trash, has id
+func idTakenTx(tx *sql.Tx, kind, id string) (bool, error) {
+	table := "vibes"
+	if kind == entityKindWorld {
+		table = "worlds"
+	}
+	return rowExists(tx, `SELECT 1 FROM `+table+` WHERE id = ?
+		UNION ALL SELECT 1 FROM trash WHERE entity_kind = ? AND entity_id = ?`, id, kind, id)
+}
+
+// checkVibeExists returns ErrVibeNotFound if a non-empty vibe ID is unknown
+func checkVibeExists(q querier, vibeID string) error {
+	if vibeID == "" {
+		return nil
+	}
+	exists, err := rowExists(q, `SELECT 1 FROM vibes WHERE id = ?`, vibeID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrVibeNotFound
+	}
+	return nil
+}
+
+// getVibe loads a single vibe with its details
+func getVibe(q querier, id string) (models.Vibe, error) {
+	vibes, err := queryVibes(q, vibeSelect+` WHERE v.id = ?`, id)
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	if len(vibes) == 0 {
+		return models.Vibe{}, ErrVibeNotFound
+	}
+	return vibes[0], nil
+}
+
+// queryVibes runs a query selecting vibe columns and attaches colors, sensor
+// data and sharing settings to the results
+func queryVibes(q querier, query string, args ...interface{}) ([]models.Vibe, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vibes := []models.Vibe{}
+	index := make(map[string]int)
+	for rows.Next() {
+		var vibe models.Vibe
+		var overrides string
+		if err := rows.Scan(&vibe.ID, &vibe.Name, &vibe.Description, &vibe.Energy, &vibe.Mood, &vibe.CreatorID, &vibe.Version, &vibe.ParentID, &overrides); err != nil {
+			return nil, err
+		}
+		if overrides != "" {
+			vibe.Overrides = strings.Split(overrides, ",")
+		}
+		index[vibe.ID] = len(vibes)
+		vibes = append(vibes, vibe)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(vibes) == 0 {
+		return vibes, nil
+	}
+
+	ids := make([]string, len(vibes))
+	for i, vibe := range vibes {
+		ids[i] = vibe.ID
+	}
+
+	err = forEachChunk(ids, func(in string, chunkArgs []interface{}) error {
+		colorRows, err := q.Query(`SELECT vibe_id, color FROM vibe_colors WHERE vibe_id IN `+in+` ORDER BY vibe_id, position`, chunkArgs...)
+		if err != nil {
+			return err
+		}
+		defer colorRows.Close()
+		for colorRows.Next() {
+			var vibeID, color string
+			if err := colorRows.Scan(&vibeID, &color); err != nil {
+				return err
+			}
+			v := &vibes[index[vibeID]]
+			v.Colors = append(v.Colors, color)
+		}
+		return colorRows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = forEachChunk(ids, func(in string, chunkArgs []interface{}) error {
+		sensorRows, err := q.Query(`SELECT vibe_id, temperature, humidity, light, sound, movement FROM sensor_data WHERE vibe_id IN `+in, chunkArgs...)
+		if err != nil {
+			return err
+		}
+		defer sensorRows.Close()
+		for sensorRows.Next() {
+			var vibeID string
+			var temperature, humidity, light, sound, movement sql.NullFloat64
+			if err := sensorRows.Scan(&vibeID, &temperature, &humidity, &light, &sound, &movement); err != nil {
+				return err
+			}
+			vibes[index[vibeID]].SensorData = models.SensorData{
+				Temperature: nullFloatPtr(temperature),
+				Humidity:    nullFloatPtr(humidity),
+				Light:       nullFloatPtr(light),
+				Sound:       nullFloatPtr(sound),
+				Movement:    nullFloatPtr(movement),
+			}
+		}
+		return sensorRows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = forEachChunk(ids, func(in string, chunkArgs []interface{}) error {
+		channelRows, err := q.Query(`SELECT vibe_id, name, value FROM sensor_channels WHERE vibe_id IN `+in, chunkArgs...)
+		if err != nil {
+			return err
+		}
+		defer channelRows.Close()
+		for channelRows.Next() {
+			var vibeID, name string
+			var value float64
+			if err := channelRows.Scan(&vibeID, &name, &value); err != nil {
+				return err
+			}
+			vibes[index[vibeID]].SensorData.Set(name, value)
+		}
+		return channelRows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sharing, err := loadSharing(q, entityKindVibe, ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, settings := range sharing {
+		vibes[index[id]].Sharing = settings
+	}
+
+	return vibes, nil
+}
+
+// worldSelect selects world columns; the vibes join allows filtering by mood
+const worldSelect = `SELECT w.id, w.name, w.description, w.type, w.location, w.current_vibe, w.size, w.creator_id, w.occupancy, w.version,
+	w.transition_from, w.transition_started_ms, w.transition_duration_ms, w.parent_id
+	FROM worlds w LEFT JOIN vibes v ON v.id = w.current_vibe`
+
+// getWorld loads a single world with its details
+func getWorld(q querier, id string) (models.World, error) {
+	worlds, err := queryWorlds(q, worldSelect+` WHERE w.id = ?`, id)
+	if err != nil {
+		return models.World{}, err
+	}
+	if len(worlds) == 0 {
+		return models.World{}, ErrWorldNotFound
+	}
+	return worlds[0], nil
+}
+
+// queryWorlds runs a query selecting world columns and attaches features and
+// sharing settings to the results
+func queryWorlds(q querier, query string, args ...interface{}) ([]models.World, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	worlds := []models.World{}
+	index := make(map[string]int)
+	for rows.Next() {
+		var world models.World
+		var worldType string
+		var currentVibe sql.NullString
+		var transition models.VibeTransition
+		if err := rows.Scan(&world.ID, &world.Name, &world.Description, &worldType, &world.Location,
+			&currentVibe, &world.Size, &world.CreatorID, &world.Occupancy, &world.Version,
+			&transition.FromVibe, &transition.StartedAt, &transition.Duration, &world.ParentID); err != nil {
+			return nil, err
+		}
+		if transition.FromVibe != "" {
+			world.Transition = &transition
+		}
+		world.Type = models.WorldType(worldType)
+		world.CurrentVibe = currentVibe.String
+		index[world.ID] = len(worlds)
+		worlds = append(worlds, world)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(worlds) == 0 {
+		return worlds, nil
+	}
+
+	ids := make([]string, len(worlds))
+	for i, world := range worlds {
+		ids[i] = world.ID
+	}
+
+	err = forEachChunk(ids, func(in string, chunkArgs []interface{}) error {
+		featureRows, err := q.Query(`SELECT world_id, feature FROM world_features WHERE world_id IN `+in+` ORDER BY world_id, position`, chunkArgs...)
+		if err != nil {
+			return err
+		}
+		defer featureRows.Close()
+		for featureRows.Next() {
+			var worldID, feature string
+			if err := featureRows.Scan(&worldID, &feature); err != nil {
+				return err
+			}
+			w := &worlds[index[worldID]]
+			w.Features = append(w.Features, feature)
+		}
+		return featureRows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sharing, err := loadSharing(q, entityKindWorld, ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, settings := range sharing {
+		worlds[index[id]].Sharing = settings
+	}
+
+	return worlds, nil
+}
+
+// loadSharing loads the sharing settings of the given entities
+func loadSharing(q querier, kind string, ids []string) (map[string]models.SharingSettings, error) {
+	result := make(map[string]models.SharingSettings, len(ids))
+
+	err := forEachChunk(ids, func(in string, chunkArgs []interface{}) error {
+		args := append([]interface{}{kind}, chunkArgs...)
+
+		rows, err := q.Query(`SELECT entity_id, is_public, context_level FROM sharing_settings
+			WHERE entity_kind = ? AND entity_id IN `+in, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id, contextLevel string
+			var isPublic bool
+			if err := rows.Scan(&id, &isPublic, &contextLevel); err != nil {
+				return err
+			}
+			settings := result[id]
+			settings.IsPublic = isPublic
+			settings.ContextLevel = models.ContextLevel(contextLevel)
+			result[id] = settings
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		userRows, err := q.Query(`SELECT entity_id, user_id FROM sharing_allowed_users
+			WHERE entity_kind = ? AND entity_id IN `+in+` ORDER BY entity_id, position`, args...)
+		if err != nil {
+			return err
+		}
+		defer userRows.Close()
+		for userRows.Next() {
+			var id, userID string
+			if err := userRows.Scan(&id, &userID); err != nil {
+				return err
+			}
+			settings := result[id]
+			settings.AllowedUsers = append(settings.AllowedUsers, userID)
+			result[id] = settings
+		}
+		return userRows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// putVibeTx inserts or replaces a vibe and all of its details
+func putVibeTx(tx *sql.Tx, vibe models.Vibe) error {
+	if _, err := tx.Exec(`INSERT INTO vibes (id, name, description, energy, mood, creator_id, version, parent_id, overrides)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			name = excluded.name,
+			description = excluded.description,
+			energy = excluded.energy,
+			mood = excluded.mood,
+			creator_id = excluded.creator_id,
+			version = excluded.version,
+			parent_id = excluded.parent_id,
+			overrides = excluded.overrides`,
+		vibe.ID, vibe.Name, vibe.Description, vibe.Energy, vibe.Mood, vibe.CreatorID, vibe.Version,
+		vibe.ParentID, strings.Join(vibe.Overrides, ",")); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM vibe_colors WHERE vibe_id = ?`, vibe.ID); err != nil {
+		return err
+	}
+	for i, color := range vibe.Colors {
+		if _, err := tx.Exec(`INSERT INTO vibe_colors (vibe_id, position, color) VALUES (?, ?, ?)`, vibe.ID, i, color); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM sensor_data WHERE vibe_id = ?`, vibe.ID); err != nil {
+		return err
+	}
+	sd := vibe.SensorData
+	if sd.Temperature != nil || sd.Humidity != nil || sd.Light != nil || sd.Sound != nil || sd.Movement != nil {
+		if _, err := tx.Exec(`INSERT INTO sensor_data (vibe_id, temperature, humidity, light, sound, movement)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			vibe.ID, floatPtrArg(sd.Temperature), floatPtrArg(sd.Humidity), floatPtrArg(sd.Light),
+			floatPtrArg(sd.Sound), floatPtrArg(sd.Movement)); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM sensor_channels WHERE vibe_id = ?`, vibe.ID); err != nil {
+		return err
+	}
+	for name, value := range sd.Channels {
+		if _, err := tx.Exec(`INSERT INTO sensor_channels (vibe_id, name, value) VALUES (?, ?, ?)`, vibe.ID, name, value); err != nil {
+			return err
+		}
+	}
+
+	return putSharingTx(tx, entityKindVibe, vibe.ID, vibe.Sharing)
+}
+
+// putWorldTx inserts or replaces a world and all of its details
+func putWorldTx(tx *sql.Tx, world models.World) error {
+	var currentVibe interface{}
+	if world.CurrentVibe != "" {
+		currentVibe = world.CurrentVibe
+	}
+
+	from, startedAt, length := transitionArgs(world.Transition)
+	if _, err := tx.Exec(`INSERT INTO worlds (id, name, description, type, location, current_vibe, size, creator_id, occupancy, version,
+			transition_from, transition_started_ms, transition_duration_ms, parent_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			name = excluded.name,
+			description = excluded.description,
+			type = excluded.type,
+			location = excluded.location,
+			current_vibe = excluded.current_vibe,
+			size = excluded.size,
+			creator_id = excluded.creator_id,
+			occupancy = excluded.occupancy,
+			version = excluded.version,
+			transition_from = excluded.transition_from,
+			transition_started_ms = excluded.transition_started_ms,
+			transition_duration_ms = excluded.transition_duration_ms,
+			parent_id = excluded.parent_id`,
+		world.ID, world.Name, world.Description, string(world.Type), world.Location, currentVibe,
+		world.Size, world.CreatorID, world.Occupancy, world.Version, from, startedAt, length, world.ParentID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM world_features WHERE world_id = ?`, world.ID); err != nil {
+		return err
+	}
+	for i, feature := range world.Features {
+		if _, err := tx.Exec(`INSERT INTO world_features (world_id, position, feature) VALUES (?, ?, ?)`, world.ID, i, feature); err != nil {
+			return err
+		}
+	}
+
+	return putSharingTx(tx, entityKindWorld, world.ID, world.Sharing)
+}
+
+// putSharingTx replaces the sharing settings of an entity
+func putSharingTx(tx *sql.Tx, kind, id string, sharing models.SharingSettings) error {
+	if err := deleteSharingTx(tx, kind, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO sharing_settings (entity_kind, entity_id, is_public, context_level) VALUES (?, ?, ?, ?)`,
+		kind, id, sharing.IsPublic, string(sharing.ContextLevel)); err != nil {
+		return err
+	}
+	for i, userID := range sharing.AllowedUsers {
+		if _, err := tx.Exec(`INSERT INTO sharing_allowed_users (entity_kind, entity_id, position, user_id) VALUES (?, ?, ?, ?)`,
+			kind, id, i, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteSharingTx removes the sharing settings of an entity
+func deleteSharingTx(tx *sql.Tx, kind, id string) error {
+	if _, err := tx.Exec(`DELETE FROM sharing_allowed_users WHERE entity_kind = ? AND entity_id = ?`, kind, id); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM sharing_settings WHERE entity_kind = ? AND entity_id = ?`, kind, id)
+	return err
+}
+
+// deleteVibeTx removes a vibe and all of its details
+func deleteVibeTx(tx *sql.Tx, id string) error {
+	for _, stmt := range []string{
+		`DELETE FROM vibe_colors WHERE vibe_id = ?`,
+		`DELETE FROM sensor_data WHERE vibe_id = ?`,
+		`DELETE FROM sensor_channels WHERE vibe_id = ?`,
+		`DELETE FROM vibes WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+	}
+	return deleteSharingTx(tx, entityKindVibe, id)
+}
+
+// deleteWorldTx removes a world and all of its details
+func deleteWorldTx(tx *sql.Tx, id string) error {
+	for _, stmt := range []string{
+		`DELETE FROM world_features WHERE world_id = ?`,
+		`DELETE FROM world_edges WHERE world_a = ?1 OR world_b = ?1`,
+		`DELETE FROM worlds WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+	}
+	return deleteSharingTx(tx, entityKindWorld, id)
+}
+
+// forEachChunk calls fn with an IN (...) placeholder list and matching
+// arguments for successive chunks of ids
+func forEachChunk(ids []string, fn func(in string, args []interface{}) error) error {
+	for start := 0; start < len(ids); start += sqlInChunk {
+		end := start + sqlInChunk
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		chunk := ids[start:end]
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+		in := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", ") + ")"
+
+		if err := fn(in, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nullFloatPtr converts a nullable column to an optional sensor value
+func nullFloatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	f := v.Float64
+	return &f
+}
+
+// floatPtrArg converts an optional sensor value to a query argument
+func floatPtrArg(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
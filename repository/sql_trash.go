@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// SetTrashRetention sets how long tombstones are kept; zero keeps them forever
+func (r *SQLRepository) SetTrashRetention(retention time.Duration) {
+	r.trashRetention.Store(int64(retention))
+}
+
+// trashCutoff returns the deletion time, in Unix nanoseconds, before which
+// tombstones have expired
+func (r *SQLRepository) trashCutoff(now time.Time) int64 {
+	retention := time.Duration(r.trashRetention.Load())
+	if retention <= 0 {
+		return math.MinInt64
+	}
+	return now.Add(-retention).UnixNano()
+}
+
+// DeletedVibes lists restorable vibes, most recently deleted first
+func (r *SQLRepository) DeletedVibes() []DeletedVibe {
+	vibes := []DeletedVibe{}
+	err := r.queryTrash(entityKindVibe, func(data []byte, deletedAt time.Time, deletedBy string) error {
+		deleted := DeletedVibe{DeletedAt: deletedAt, DeletedBy: deletedBy}
+		if err := json.Unmarshal(data, &deleted.Vibe); err != nil {
+			return err
+		}
+		vibes = append(vibes, deleted)
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error listing deleted vibes: %v\n", err)
+		return []DeletedVibe{}
+	}
+	return vibes
+}
+
+// DeletedWorlds lists restorable worlds, most recently deleted first
+func (r *SQLRepository) DeletedWorlds() []DeletedWorld {
+	worlds := []DeletedWorld{}
+	err := r.queryTrash(entityKindWorld, func(data []byte, deletedAt time.Time, deletedBy string) error {
+		deleted := DeletedWorld{DeletedAt: deletedAt, DeletedBy: deletedBy}
+		if err := json.Unmarshal(data, &deleted.World); err != nil {
+			return err
+		}
+		worlds = append(worlds, deleted)
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error listing deleted worlds: %v\n", err)
+		return []DeletedWorld{}
+	}
+	return worlds
+}
+
+// queryTrash calls fn for every unexpired tombstone of kind, most recent first
+func (r *SQLRepository) queryTrash(kind string, fn func(data []byte, deletedAt time.Time, deletedBy string) error) error {
+	rows, err := r.db.Query(`SELECT data, deleted_at_ns, deleted_by FROM trash
+		WHERE entity_kind = ? AND deleted_at_ns >= ? ORDER BY deleted_at_ns DESC, entity_id`,
+		kind, r.trashCutoff(time.Now()))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data, deletedBy string
+		var deletedAt int64
+		if err := rows.Scan(&data, &deletedAt, &deletedBy); err != nil {
+			return err
+		}
+		if err := fn([]byte(data), time.Unix(0, deletedAt).UTC(), deletedBy); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// RestoreVibe brings back a deleted vibe
+func (r *SQLRepository) RestoreVibe(id string) error {
+	return r.restoreVibe("", id)
+}
+
+func (r *SQLRepository) restoreVibe(actor, id string) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		var vibe models.Vibe
+		found, err := r.loadTrashTx(tx, entityKindVibe, id, &vibe)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrVibeNotFound
+		}
+		// A derived vibe takes the current values of its parent, which must exist
+		if vibe.IsDerived() {
+			parent, err := loadVibeTx(tx, vibe.ParentID)
+			if err != nil {
+				return err
+			}
+			if parent == nil {
+				return ErrVibeNotFound
+			}
+			vibe = vibe.Inherit(*parent)
+		}
+
+		vibe.Version++
+		if err := untrashTx(tx, entityKindVibe, id); err != nil {
+			return err
+		}
+		if err := putVibeTx(tx, vibe); err != nil {
+			return err
+		}
+		entry := newHistoryEntry(time.Now().UTC(), actor, entityKindVibe, id, nil, &vibe)
+		entry.Op = HistoryRestore
+		return r.recordEntryTx(tx, entry, nil, &vibe)
+	})
+}
+
+// RestoreWorld brings back a deleted world
+func (r *SQLRepository) RestoreWorld(id string) error {
+	return r.restoreWorld("", id)
+}
+
+func (r *SQLRepository) restoreWorld(actor, id string) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		var world models.World
+		found, err := r.loadTrashTx(tx, entityKindWorld, id, &world)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrWorldNotFound
+		}
+
+		// The world's vibe or parent may have been deleted after the world was
+		if err := checkVibeExists(tx, world.CurrentVibe); err != nil {
+			return err
+		}
+		if err := checkWorldParent(world, worldLookupTx(tx)); err != nil {
+			return err
+		}
+
+		world.Version++
+		if err := untrashTx(tx, entityKindWorld, id); err != nil {
+			return err
+		}
+		if err := putWorldTx(tx, world); err != nil {
+			return err
+		}
+		entry := newHistoryEntry(time.Now().UTC(), actor, entityKindWorld, id, nil, &world)
+		entry.Op = HistoryRestore
+		return r.recordEntryTx(tx, entry, nil, &world)
+	})
+}
+
+// PurgeTrash permanently removes tombstones older than the retention period
+func (r *SQLRepository) PurgeTrash() (int, error) {
+	var purged int64
+	err := r.inTx(func(tx *sql.Tx) error {
+		var err error
+		purged, err = r.purgeTrashTx(tx)
+		return err
+	})
+	return int(purged), err
+}
+
+// purgeTrashTx removes expired tombstones in tx
+func (r *SQLRepository) purgeTrashTx(tx *sql.Tx) (int64, error) {
+	result, err := tx.Exec(`DELETE FROM trash WHERE deleted_at_ns < ?`, r.trashCutoff(time.Now()))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// trashTx stores the tombstone of an entity about to be deleted in tx, and
+// purges expired ones
+func (r *SQLRepository) trashTx(tx *sql.Tx, kind, id, actor string, entity interface{}) error {
+	if _, err := r.purgeTrashTx(tx); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO trash (entity_kind, entity_id, deleted_at_ns, deleted_by, data) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (entity_kind, entity_id) DO UPDATE SET
+			deleted_at_ns = excluded.deleted_at_ns, deleted_by = excluded.deleted_by, data = excluded.data`,
+		kind, id, time.Now().UnixNano(), actor, string(data))
+	return err
+}
+
+// loadTrashTx decodes an unexpired tombstone into entity, reporting whether it exists
+func (r *SQLRepository) loadTrashTx(tx *sql.Tx, kind, id string, entity interface{}) (bool, error) {
+	var data string
+	err := tx.QueryRow(`SELECT data FROM trash WHERE entity_kind = ? AND entity_id = ? AND deleted_at_ns >= ?`,
+		kind, id, r.trashCutoff(time.Now())).Scan(&data)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(data), entity)
+}
+
+// untrashTx discards the tombstone of an entity, if any
+func untrashTx(tx *sql.Tx, kind, id string) error {
+	_, err := tx.Exec(`DELETE FROM trash WHERE entity_kind = ? AND entity_id = ?`, kind, id)
+	return err
+}
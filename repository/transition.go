@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/blend"
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// TransitionRepository changes world vibes gradually
+type TransitionRepository interface {
+	// TransitionWorldVibe sets a world's vibe like SetWorldVibeIfVersion, and
+	// records a transition blending from the previous vibe over duration. A
+	// duration under a millisecond, or a world without a previous vibe,
+	// switches at once. It returns the world as stored.
+	TransitionWorldVibe(worldID, vibeID string, duration time.Duration, expectedVersion int64) (models.World, error)
+	// BlendedWorldVibe returns the vibe a world shows at now: while a
+	// transition runs, a blend of its vibes eased with blend.Smoothstep;
+	// otherwise the world's vibe, as GetWorldVibe returns it
+	BlendedWorldVibe(worldID string, now time.Time) (models.Vibe, error)
+}
+
+// nextTransition returns the transition of a world whose vibe changes from
+// one vibe to another at now, or nil for an immediate change
+func nextTransition(from, to string, now time.Time, duration time.Duration) *models.VibeTransition {
+	if duration < time.Millisecond || from == "" || from == to {
+		return nil
+	}
+	return models.NewVibeTransition(from, now, duration)
+}
+
+// keptTransition returns the transition an update of current keeps: its own
+// while the world's vibe stays vibeID, none once the vibe changes. Clients
+// cannot set transitions through updates.
+func keptTransition(current models.World, vibeID string) *models.VibeTransition {
+	if current.CurrentVibe != vibeID {
+		return nil
+	}
+	return current.Transition
+}
+
+// blendedWorldVibe resolves the vibe a world shows at now. A transition whose
+// starting vibe is gone ends early.
+func blendedWorldVibe(world models.World, now time.Time, getVibe func(id string) (models.Vibe, error)) (models.Vibe, error) {
+	if world.CurrentVibe == "" {
+		return models.Vibe{}, ErrVibeNotFound
+	}
+	to, err := getVibe(world.CurrentVibe)
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	if world.Transition == nil || !world.Transition.Active(now) {
+		return to, nil
+	}
+	from, err := getVibe(world.Transition.FromVibe)
+	if err != nil {
+		return to, nil
+	}
+	return blend.Between(from, to, blend.Smoothstep(world.Transition.Progress(now))), nil
+}
+
+// transitionArgs returns the SQL column values of a transition
+func transitionArgs(t *models.VibeTransition) (string, int64, int64) {
+	if t == nil {
+		return "", 0, 0
+	}
+	return t.FromVibe, t.StartedAt, t.Duration
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// DefaultTrashRetention is how long deleted vibes and worlds can be restored
+// before they are purged
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// DeletedVibe is the tombstone of a deleted vibe
+type DeletedVibe struct {
+	models.Vibe
+	DeletedAt time.Time `json:"deletedAt"`
+	DeletedBy string    `json:"deletedBy,omitempty"`
+}
+
+// DeletedWorld is the tombstone of a deleted world
+type DeletedWorld struct {
+	models.World
+	DeletedAt time.Time `json:"deletedAt"`
+	DeletedBy string    `json:"deletedBy,omitempty"`
+}
+
+// TrashRepository keeps deleted vibes and worlds restorable for a retention
+// period. DeleteVibe and DeleteWorld move entities to the trash; adding a new
+// entity with the ID of a deleted one discards its tombstone.
+type TrashRepository interface {
+	// DeletedVibes lists restorable vibes, most recently deleted first
+	DeletedVibes() []DeletedVibe
+	// DeletedWorlds lists restorable worlds, most recently deleted first
+	DeletedWorlds() []DeletedWorld
+	// RestoreVibe brings back a deleted vibe. A derived vibe needs its
+	// parent, and takes the parent's current values.
+	RestoreVibe(id string) error
+	// RestoreWorld brings back a deleted world. Its vibe and parent must
+	// exist, so a world is never restored pointing at one that is gone.
+	RestoreWorld(id string) error
+	// SetTrashRetention sets how long tombstones are kept; zero keeps them forever
+	SetTrashRetention(retention time.Duration)
+	// PurgeTrash permanently removes tombstones older than the retention
+	// period and returns how many were removed. Deletes also purge.
+	PurgeTrash() (int, error)
+}
+
+// expired reports whether a tombstone from deletedAt is past retention
+func expired(deletedAt time.Time, retention time.Duration, now time.Time) bool {
+	return retention > 0 && deletedAt.Before(now.Add(-retention))
+}
+
+// SetTrashRetention sets how long tombstones are kept; zero keeps them forever
+func (r *Repository) SetTrashRetention(retention time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trashRetention = retention
+}
+
+// DeletedVibes lists restorable vibes, most recently deleted first
+func (r *Repository) DeletedVibes() []DeletedVibe {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	vibes := make([]DeletedVibe, 0, len(r.trashedVibes))
+	for _, vibe := range r.trashedVibes {
+		if !expired(vibe.DeletedAt, r.trashRetention, now) {
+			vibes = append(vibes, vibe)
+		}
+	}
+	sort.Slice(vibes, func(i, j int) bool {
+		if !vibes[i].DeletedAt.Equal(vibes[j].DeletedAt) {
+			return vibes[i].DeletedAt.After(vibes[j].DeletedAt)
+		}
+		return vibes[i].ID < vibes[j].ID
+	})
+	return vibes
+}
+
+// DeletedWorlds lists restorable worlds, most recently deleted first
+func (r *Repository) DeletedWorlds() []DeletedWorld {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	worlds := make([]DeletedWorld, 0, len(r.trashedWorlds))
+	for _, world := range r.trashedWorlds {
+		if !expired(world.DeletedAt, r.trashRetention, now) {
+			worlds = append(worlds, world)
+		}
+	}
+	sort.Slice(worlds, func(i, j int) bool {
+		if !worlds[i].DeletedAt.Equal(worlds[j].DeletedAt) {
+			return worlds[i].DeletedAt.After(worlds[j].DeletedAt)
+		}
+		return worlds[i].ID < worlds[j].ID
+	})
+	return worlds
+}
+
+// RestoreVibe brings back a deleted vibe
+func (r *Repository) RestoreVibe(id string) error {
+	return r.restoreVibe("", id)
+}
+
+func (r *Repository) restoreVibe(actor, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted, ok := r.trashedVibes[id]
+	if !ok || expired(deleted.DeletedAt, r.trashRetention, time.Now()) {
+		return ErrVibeNotFound
+	}
+
+	// A derived vibe takes the current values of its parent, which must exist
+	vibe := deleted.Vibe
+	if vibe.IsDerived() {
+		parent, ok := r.vibes[vibe.ParentID]
+		if !ok {
+			return ErrVibeNotFound
+		}
+		vibe = vibe.Inherit(parent)
+	}
+	vibe.Version++
+	return r.commit(actor, Mutation{Op: OpRestoreVibe, ID: id, Vibe: &vibe})
+}
+
+// RestoreWorld brings back a deleted world
+func (r *Repository) RestoreWorld(id string) error {
+	return r.restoreWorld("", id)
+}
+
+func (r *Repository) restoreWorld(actor, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted, ok := r.trashedWorlds[id]
+	if !ok || expired(deleted.DeletedAt, r.trashRetention, time.Now()) {
+		return ErrWorldNotFound
+	}
+
+	// The world's vibe or parent may have been deleted after the world was
+	if deleted.CurrentVibe != "" {
+		if _, ok := r.vibes[deleted.CurrentVibe]; !ok {
+			return ErrVibeNotFound
+		}
+	}
+	if deleted.IsNested() {
+		if _, ok := r.worlds[deleted.ParentID]; !ok {
+			return ErrWorldNotFound
+		}
+	}
+
+	world := deleted.World
+	world.Version++
+	return r.commit(actor, Mutation{Op: OpRestoreWorld, ID: id, World: &world})
+}
+
+// PurgeTrash permanently removes tombstones older than the retention period
+func (r *Repository) PurgeTrash() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purges := r.expiredTrash(time.Now())
+	if len(purges) == 0 {
+		return 0, nil
+	}
+	if err := r.commit("", purges...); err != nil {
+		return 0, err
+	}
+	return len(purges), nil
+}
+
+// expiredTrash builds the mutations that purge tombstones past retention.
+// Callers must hold the lock.
+func (r *Repository) expiredTrash(now time.Time) []Mutation {
+	var purges []Mutation
+	for id, vibe := range r.trashedVibes {
+		if expired(vibe.DeletedAt, r.trashRetention, now) {
+			purges = append(purges, Mutation{Op: OpDeleteVibe, ID: id})
+		}
+	}
+	for id, world := range r.trashedWorlds {
+		if expired(world.DeletedAt, r.trashRetention, now) {
+			purges = append(purges, Mutation{Op: OpDeleteWorld, ID: id})
+		}
+	}
+	return purges
+}
@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// writeTxn stages the mutations of one write of the in-memory repository.
+// Each operation validates against the committed state overlaid with the
+// operations staged before it, so a sequence of operations behaves as if
+// applied one by one while nothing is committed until all of them succeed.
+type writeTxn struct {
+	r         *Repository
+	vibes     map[string]*models.Vibe      // staged vibes; nil means deleted
+	worlds    map[string]*models.World     // staged worlds; nil means deleted
+	edges     map[string]*models.WorldEdge // staged edges by edgeKey; nil means deleted
+	mutations []Mutation
+	purge     bool // whether to purge expired tombstones on commit
+}
+
+// write runs fn in a transaction and commits its mutations in one change
+func (r *Repository) write(actor string, fn func(t *writeTxn) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := &writeTxn{
+		r:      r,
+		vibes:  make(map[string]*models.Vibe),
+		worlds: make(map[string]*models.World),
+		edges:  make(map[string]*models.WorldEdge),
+	}
+	if err := fn(t); err != nil {
+		return err
+	}
+	if len(t.mutations) == 0 {
+		return nil
+	}
+
+	mutations := t.mutations
+	if t.purge {
+		mutations = append(r.expiredTrash(time.Now()), mutations...)
+	}
+	return r.commit(actor, mutations...)
+}
+
+// getVibe returns a vibe as staged so far
+func (t *writeTxn) getVibe(id string) (models.Vibe, bool) {
+	if vibe, ok := t.vibes[id]; ok {
+		if vibe == nil {
+			return models.Vibe{}, false
+		}
+		return *vibe, true
+	}
+	vibe, ok := t.r.vibes[id]
+	return vibe, ok
+}
+
+// getWorld returns a world as staged so far
+func (t *writeTxn) getWorld(id string) (models.World, bool) {
+	if world, ok := t.worlds[id]; ok {
+		if world == nil {
+			return models.World{}, false
+		}
+		return *world, true
+	}
+	world, ok := t.r.worlds[id]
+	return world, ok
+}
+
+// stage records a mutation and its effect on the staged state
+func (t *writeTxn) stage(m Mutation) {
+	t.mutations = append(t.mutations, m)
+	switch m.Op {
+	case OpPutVibe, OpRestoreVibe:
+		t.vibes[m.ID] = m.Vibe
+	case OpTrashVibe, OpDeleteVibe:
+		t.vibes[m.ID] = nil
+		t.purge = true
+	case OpPutWorld, OpRestoreWorld:
+		t.worlds[m.ID] = m.World
+	case OpTrashWorld, OpDeleteWorld:
+		t.worlds[m.ID] = nil
+		t.purge = true
+	case OpPutEdge:
+		t.edges[m.ID] = m.Edge
+	case OpDeleteEdge:
+		t.edges[m.ID] = nil
+	}
+}
+
+// dependents returns the IDs of the staged worlds using a vibe, sorted
+func (t *writeTxn) dependents(vibeID string) []string {
+	ids := []string{}
+	for id := range t.r.vibeWorlds[vibeID] {
+		if _, staged := t.worlds[id]; !staged {
+			ids = append(ids, id)
+		}
+	}
+	for id, world := range t.worlds {
+		if world != nil && world.CurrentVibe == vibeID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// vibeIDTaken reports whether a vibe, live or in the trash, has id
+func (t *writeTxn) vibeIDTaken(id string) bool {
+	if _, ok := t.getVibe(id); ok {
+		return true
+	}
+	_, trashed := t.r.trashedVibes[id]
+	return trashed
+}
+
+// worldIDTaken reports whether a world, live or in the trash, has id
+func (t *writeTxn) worldIDTaken(id string) bool {
+	if _, ok := t.getWorld(id); ok {
+		return true
+	}
+	_, trashed := t.r.trashedWorlds[id]
+	return trashed
+}
+
+// addVibe creates a vibe, generating its ID if it has none. Generated IDs
+// avoid the trash too, so a tombstone is only ever discarded on purpose.
+func (t *writeTxn) addVibe(vibe models.Vibe) (models.Vibe, error) {
+	if vibe.ID == "" {
+		vibe.ID, _ = generateID(vibe.Name, func(id string) (bool, error) { return t.vibeIDTaken(id), nil })
+	}
+	if err := vibe.Validate(); err != nil {
+		return models.Vibe{}, err
+	}
+	if _, ok := t.getVibe(vibe.ID); ok {
+		return models.Vibe{}, &DuplicateIDError{Kind: entityKindVibe, ID: vibe.ID}
+	}
+	vibe, err := resolveVibe(txnVibeTree{t}, vibe, nil)
+	if err != nil {
+		return models.Vibe{}, err
+	}
+	vibe.Version = 1
+	t.stage(putVibe(vibe))
+	return vibe, nil
+}
+
+func (t *writeTxn) updateVibe(vibe models.Vibe) (models.Vibe, error) {
+	if err := vibe.Validate(); err != nil {
+		return models.Vibe{}, err
+	}
+	current, ok := t.getVibe(vibe.ID)
+	if !ok {
+		return models.Vibe{}, ErrVibeNotFound
+	}
+	if err := checkVersion(entityKindVibe, vibe.ID, vibe.Version, current.Version); err != nil {
+		return models.Vibe{}, err
+	}
+	vibe, err := resolveVibe(txnVibeTree{t}, vibe, &current)
+	if err != nil {
+		return models.Vibe{}, err
+	}
+
+	vibe.Version = current.Version + 1
+	t.stage(putVibe(vibe))
+	if err := propagateVibe(txnVibeTree{t}, vibe); err != nil {
+		return models.Vibe{}, err
+	}
+	return vibe, nil
+}
+
+func (t *writeTxn) deleteVibe(id string, opts VibeDeleteOptions) ([]string, error) {
+	if _, ok := t.getVibe(id); !ok {
+		return nil, ErrVibeNotFound
+	}
+	if derived, _ := (txnVibeTree{t}).derived(id); len(derived) > 0 {
+		return nil, ErrVibeHasDerived
+	}
+
+	dependents := t.dependents(id)
+	replacement, err := replacementVibe(id, opts, func(vibeID string) bool {
+		_, ok := t.getVibe(vibeID)
+		return ok
+	})
+	if err == ErrVibeInUse && len(dependents) == 0 {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, worldID := range dependents {
+		world, _ := t.getWorld(worldID)
+		world.CurrentVibe = replacement
+		world.Version++
+		t.stage(putWorld(world))
+	}
+	t.stage(Mutation{Op: OpTrashVibe, ID: id})
+	return dependents, nil
+}
+
+// addWorld creates a world, generating its ID if it has none
+func (t *writeTxn) addWorld(world models.World) (models.World, error) {
+	if world.ID == "" {
+		world.ID, _ = generateID(world.Name, func(id string) (bool, error) { return t.worldIDTaken(id), nil })
+	}
+	if err := world.Validate(); err != nil {
+		return models.World{}, err
+	}
+	if _, ok := t.getWorld(world.ID); ok {
+		return models.World{}, &DuplicateIDError{Kind: entityKindWorld, ID: world.ID}
+	}
+	// If world has a vibe assigned, check if it exists
+	if world.CurrentVibe != "" {
+		if _, ok := t.getVibe(world.CurrentVibe); !ok {
+			return models.World{}, ErrVibeNotFound
+		}
+	}
+	if err := checkWorldParent(world, t.lookupWorld); err != nil {
+		return models.World{}, err
+	}
+
+	world.Version = 1
+	t.stage(putWorld(world))
+	return world, nil
+}
+
+func (t *writeTxn) updateWorld(world models.World) (models.World, error) {
+	if err := world.Validate(); err != nil {
+		return models.World{}, err
+	}
+	current, ok := t.getWorld(world.ID)
+	if !ok {
+		return models.World{}, ErrWorldNotFound
+	}
+	if err := checkVersion(entityKindWorld, world.ID, world.Version, current.Version); err != nil {
+		return models.World{}, err
+	}
+
+	// If world has a vibe assigned, check if it exists
+	if world.CurrentVibe != "" {
+		if _, ok := t.getVibe(world.CurrentVibe); !ok {
+			return models.World{}, ErrVibeNotFound
+		}
+	}
+	if err := checkWorldParent(world, t.lookupWorld); err != nil {
+		return models.World{}, err
+	}
+
+	world.Transition = keptTransition(current, world.CurrentVibe)
+	world.Version = current.Version + 1
+	t.stage(putWorld(world))
+	return world, nil
+}
+
+func (t *writeTxn) deleteWorld(id string) error {
+	if _, ok := t.getWorld(id); !ok {
+		return ErrWorldNotFound
+	}
+	if t.hasChildWorlds(id) {
+		return ErrWorldHasChildren
+	}
+	t.stage(Mutation{Op: OpTrashWorld, ID: id})
+	return nil
+}
+
+func (t *writeTxn) setWorldVibe(worldID, vibeID string, expectedVersion int64, duration time.Duration) (models.World, error) {
+	world, ok := t.getWorld(worldID)
+	if !ok {
+		return models.World{}, ErrWorldNotFound
+	}
+	if err := checkVersion(entityKindWorld, worldID, expectedVersion, world.Version); err != nil {
+		return models.World{}, err
+	}
+	if _, ok := t.getVibe(vibeID); !ok {
+		return models.World{}, ErrVibeNotFound
+	}
+
+	world.Transition = nextTransition(world.CurrentVibe, vibeID, time.Now(), duration)
+	world.CurrentVibe = vibeID
+	world.Version++
+	t.stage(putWorld(world))
+	return world, nil
+}
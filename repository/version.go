@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrVersionConflict is matched by every *ConflictError via errors.Is
+var ErrVersionConflict = errors.New("version conflict")
+
+// ConflictError reports a compare-and-swap write whose expected version no
+// longer matches the stored entity. Clients should re-read and retry.
+type ConflictError struct {
+	Kind     string // "vibe" or "world"
+	ID       string
+	Expected int64 // Version the caller based its write on
+	Actual   int64 // Version currently stored
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %q was modified concurrently: expected version %d, current version is %d",
+		e.Kind, e.ID, e.Expected, e.Actual)
+}
+
+// Is makes errors.Is(err, ErrVersionConflict) true for conflict errors
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// checkVersion compares the version a write expects with the stored one.
+// An expected version of zero skips the check, so callers that do not track
+// versions keep last-write-wins semantics.
+func checkVersion(kind, id string, expected, actual int64) error {
+	if expected != 0 && expected != actual {
+		return &ConflictError{Kind: kind, ID: id, Expected: expected, Actual: actual}
+	}
+	return nil
+}
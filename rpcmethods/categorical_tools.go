@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/bmorphism/vibespace-mcp-go/models"
 	"github.com/bmorphism/vibespace-mcp-go/streaming"
@@ -14,6 +15,7 @@ import (
 // CategoricalTools provides MCP tools for categorical universe artifacts
 type CategoricalTools struct {
 	transformer *streaming.VibeContextualTransformer
+	repo        streaming.RepositoryInterface // Source of world contexts; may be nil
 }
 
 // NewCategoricalTools creates a new categorical tools instance
@@ -23,10 +25,32 @@ func NewCategoricalTools() *CategoricalTools {
 	}
 }
 
+// NewCategoricalToolsWithRepository creates categorical tools that build the
+// context of a world from its vibe and the vibes of its adjacent worlds
+func NewCategoricalToolsWithRepository(repo streaming.RepositoryInterface) *CategoricalTools {
+	tools := NewCategoricalTools()
+	tools.repo = repo
+	return tools
+}
+
+// vibeContext returns the context of the world named by the worldId
+// argument, or an empty context when there is none
+func (ct *CategoricalTools) vibeContext(args map[string]interface{}) (*streaming.ComonadicVibeContext, error) {
+	worldID, _ := args["worldId"].(string)
+	if worldID == "" {
+		return &streaming.ComonadicVibeContext{}, nil
+	}
+	if ct.repo == nil {
+		return nil, fmt.Errorf("worldId requires categorical tools with a repository")
+	}
+	return ct.transformer.WorldContext(ct.repo, worldID, time.Now())
+}
+
 // CategoricalExtractRequest represents an extract operation request
 type CategoricalExtractRequest struct {
-	ContextID   string                           `json:"contextId"`
+	ContextID   string                           `json:"contextId" jsonschema:"required"`
 	VibeContext *streaming.ComonadicVibeContext  `json:"vibeContext,omitempty"`
+	WorldID     string                           `json:"worldId,omitempty" jsonschema_description:"World whose vibe and neighbors form the context"`
 }
 
 // CategoricalExtractResponse represents the extracted value
@@ -38,8 +62,9 @@ type CategoricalExtractResponse struct {
 
 // CategoricalDuplicateRequest represents a duplicate operation request  
 type CategoricalDuplicateRequest struct {
-	ContextID string                           `json:"contextId"`
+	ContextID string                           `json:"contextId" jsonschema:"required"`
 	Context   *streaming.ComonadicVibeContext  `json:"context"`
+	WorldID   string                           `json:"worldId,omitempty" jsonschema_description:"World whose vibe and neighbors form the context"`
 }
 
 // CategoricalDuplicateResponse represents the duplicated context
@@ -51,9 +76,10 @@ type CategoricalDuplicateResponse struct {
 
 // CategoricalExtendRequest represents an extend operation request
 type CategoricalExtendRequest struct {
-	ContextID     string                           `json:"contextId"`
+	ContextID     string                           `json:"contextId" jsonschema:"required"`
 	Context       *streaming.ComonadicVibeContext  `json:"context"`
-	Transformation string                          `json:"transformation"`
+	Transformation string                          `json:"transformation" jsonschema:"required,enum=consensus,enum=amplify,enum=inhibit"`
+	WorldID        string                          `json:"worldId,omitempty" jsonschema_description:"World whose vibe and neighbors form the context"`
 }
 
 // CategoricalExtendResponse represents the extended context
@@ -65,9 +91,9 @@ type CategoricalExtendResponse struct {
 
 // TernaryLogicGateRequest represents a ternary logic operation
 type TernaryLogicGateRequest struct {
-	GateType string                    `json:"gateType"`
-	InputA   streaming.TernaryState    `json:"inputA"`
-	InputB   streaming.TernaryState    `json:"inputB"`
+	GateType string                    `json:"gateType" jsonschema:"required,enum=consensus,enum=amplify,enum=inhibit"`
+	InputA   streaming.TernaryState    `json:"inputA" jsonschema:"required,enum=-1,enum=0,enum=1"`
+	InputB   streaming.TernaryState    `json:"inputB" jsonschema:"required,enum=-1,enum=0,enum=1"`
 }
 
 // TernaryLogicGateResponse represents the gate result
@@ -124,7 +150,7 @@ func RegisterCategoricalTools(mcpServer *server.MCPServer, tools *CategoricalToo
 	// Register categorical extract tool
 	extractTool := mcp.NewTool("categorical_extract", func(t *mcp.Tool) {
 		t.Description = "Extract the focused value from a comonadic context"
-	})
+	}, WithInputSchema("categorical_extract"))
 	
 	mcpServer.AddTool(extractTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return tools.CategoricalExtract(ctx, req)
@@ -133,7 +159,7 @@ func RegisterCategoricalTools(mcpServer *server.MCPServer, tools *CategoricalToo
 	// Register categorical duplicate tool
 	duplicateTool := mcp.NewTool("categorical_duplicate", func(t *mcp.Tool) {
 		t.Description = "Create a context-of-contexts via comonadic duplication"
-	})
+	}, WithInputSchema("categorical_duplicate"))
 	
 	mcpServer.AddTool(duplicateTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return tools.CategoricalDuplicate(ctx, req)
@@ -142,7 +168,7 @@ func RegisterCategoricalTools(mcpServer *server.MCPServer, tools *CategoricalToo
 	// Register categorical extend tool
 	extendTool := mcp.NewTool("categorical_extend", func(t *mcp.Tool) {
 		t.Description = "Apply context-aware transformation via comonadic extension"
-	})
+	}, WithInputSchema("categorical_extend"))
 	
 	mcpServer.AddTool(extendTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return tools.CategoricalExtend(ctx, req)
@@ -151,7 +177,7 @@ func RegisterCategoricalTools(mcpServer *server.MCPServer, tools *CategoricalToo
 	// Register ternary logic gate tool  
 	ternaryTool := mcp.NewTool("ternary_logic_gate", func(t *mcp.Tool) {
 		t.Description = "Execute ternary logic gate operations"
-	})
+	}, WithInputSchema("ternary_logic_gate"))
 	
 	mcpServer.AddTool(ternaryTool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return tools.TernaryLogicGate(ctx, req)
@@ -171,9 +197,10 @@ func (ct *CategoricalTools) CategoricalExtract(ctx context.Context, req mcp.Call
 		return mcp.NewToolResultError("contextId is required"), nil
 	}
 
-	// Create a sample comonadic context (in real implementation, retrieve from storage)
-	vibeCtx := &streaming.ComonadicVibeContext{
-		// This would be populated from actual context storage
+	// Build the context of the requested world, if any
+	vibeCtx, err := ct.vibeContext(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 	
 	// Extract the focused vibe
@@ -219,9 +246,10 @@ func (ct *CategoricalTools) CategoricalDuplicate(ctx context.Context, req mcp.Ca
 		return mcp.NewToolResultError("contextId is required"), nil
 	}
 
-	// Create sample context (retrieve from storage in real implementation)
-	vibeCtx := &streaming.ComonadicVibeContext{
-		// Populated from context storage
+	// Build the context of the requested world, if any
+	vibeCtx, err := ct.vibeContext(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 	
 	// Perform duplication
@@ -284,9 +312,10 @@ func (ct *CategoricalTools) CategoricalExtend(ctx context.Context, req mcp.CallT
 		return mcp.NewToolResultError("transformation is required"), nil
 	}
 
-	// Create sample context
-	vibeCtx := &streaming.ComonadicVibeContext{
-		// Populated from context storage
+	// Build the context of the requested world, if any
+	vibeCtx, err := ct.vibeContext(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 	
 	// Define transformation function based on type
@@ -1,14 +1,19 @@
 package rpcmethods
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/bmorphism/vibespace-mcp-go/models"
 	"github.com/bmorphism/vibespace-mcp-go/repository"
+	"github.com/bmorphism/vibespace-mcp-go/sensors"
 	"github.com/bmorphism/vibespace-mcp-go/streaming"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -23,13 +28,46 @@ type vibeUriHandler struct {
 }
 
 func (h *vibeUriHandler) HandleUri(uri string) (interface{}, error) {
-	if uri == models.VibeListURI {
-		return h.repo.GetAllVibes(), nil
+	if uri == models.VibeTrashURI {
+		return h.repo.DeletedVibes(), nil
+	}
+
+	if values, ok, err := splitListURI(uri, models.VibeListURI); ok {
+		if err != nil {
+			return nil, err
+		}
+		if values == nil {
+			return h.repo.GetAllVibes(), nil
+		}
+		query, err := parseVibeQuery(values)
+		if err != nil {
+			return nil, err
+		}
+		return h.repo.QueryVibes(query)
 	}
 
 	if strings.HasPrefix(uri, models.VibeScheme) {
-		vibeID := strings.TrimPrefix(uri, models.VibeScheme)
-		vibe, err := h.repo.GetVibe(vibeID)
+		vibeURI, at, err := splitAtQuery(strings.TrimPrefix(uri, models.VibeScheme))
+		if err != nil {
+			return nil, err
+		}
+		
+		// Check if it's a vibe history request
+		if strings.HasSuffix(vibeURI, models.HistorySubURI) {
+			return h.repo.VibeHistory(strings.TrimSuffix(vibeURI, models.HistorySubURI))
+		}
+		
+		// Check if it's a request for the worlds using the vibe
+		if strings.HasSuffix(vibeURI, models.VibeWorldsSubURI) {
+			return h.repo.GetVibeWorlds(strings.TrimSuffix(vibeURI, models.VibeWorldsSubURI))
+		}
+		
+		// Point-in-time request
+		if at != nil {
+			return h.repo.VibeAt(vibeURI, *at)
+		}
+		
+		vibe, err := h.repo.GetVibe(vibeURI)
 		if err != nil {
 			return nil, err
 		}
@@ -40,21 +78,77 @@ func (h *vibeUriHandler) HandleUri(uri string) (interface{}, error) {
 }
 
 type worldUriHandler struct {
-	repo Repository
+	repo    Repository
+	history *sensors.TimeSeries // Sensor history of the worlds, if kept
 }
 
 func (h *worldUriHandler) HandleUri(uri string) (interface{}, error) {
-	if uri == models.WorldListURI {
-		return h.repo.GetAllWorlds(), nil
+	if uri == models.WorldTrashURI {
+		return h.repo.DeletedWorlds(), nil
+	}
+	if uri == models.WorldGraphURI {
+		return h.repo.AllWorldEdges()
+	}
+
+	if values, ok, err := splitListURI(uri, models.WorldListURI); ok {
+		if err != nil {
+			return nil, err
+		}
+		if values == nil {
+			return h.repo.GetAllWorlds(), nil
+		}
+		query, err := parseWorldQuery(values)
+		if err != nil {
+			return nil, err
+		}
+		return h.repo.QueryWorlds(query)
 	}
 
 	if strings.HasPrefix(uri, models.WorldScheme) {
-		worldURI := strings.TrimPrefix(uri, models.WorldScheme)
+		// The sensor history has query parameters of its own
+		if path, rawQuery, _ := strings.Cut(strings.TrimPrefix(uri, models.WorldScheme), "?"); strings.HasSuffix(path, models.SensorsSubURI) {
+			return h.sensorHistory(strings.TrimSuffix(path, models.SensorsSubURI), rawQuery)
+		}
+		
+		worldURI, at, err := splitAtQuery(strings.TrimPrefix(uri, models.WorldScheme))
+		if err != nil {
+			return nil, err
+		}
+		
+		// Check if it's a world history request
+		if strings.HasSuffix(worldURI, models.HistorySubURI) {
+			return h.repo.WorldHistory(strings.TrimSuffix(worldURI, models.HistorySubURI))
+		}
+		
+		// Check if it's a request for the worlds around this one
+		switch {
+		case strings.HasSuffix(worldURI, models.ChildrenSubURI):
+			return h.repo.ChildWorlds(strings.TrimSuffix(worldURI, models.ChildrenSubURI))
+		case strings.HasSuffix(worldURI, models.AncestorsSubURI):
+			return h.repo.WorldAncestors(strings.TrimSuffix(worldURI, models.AncestorsSubURI))
+		case strings.HasSuffix(worldURI, models.RollupSubURI):
+			return h.repo.WorldRollup(strings.TrimSuffix(worldURI, models.RollupSubURI))
+		case strings.HasSuffix(worldURI, models.NeighborsSubURI):
+			return h.repo.WorldEdges(strings.TrimSuffix(worldURI, models.NeighborsSubURI))
+		}
+		
+		// Point-in-time request, optionally for the world's vibe at that time
+		if at != nil {
+			worldID := strings.TrimSuffix(worldURI, models.WorldVibeSubURI)
+			world, err := h.repo.WorldAt(worldID, *at)
+			if err != nil || worldID == worldURI {
+				return world, err
+			}
+			if world.CurrentVibe == "" {
+				return nil, repository.ErrVibeNotFound
+			}
+			return h.repo.VibeAt(world.CurrentVibe, *at)
+		}
 		
-		// Check if it's a world vibe request
+		// Check if it's a world vibe request, blended while the vibe changes
 		if strings.HasSuffix(worldURI, models.WorldVibeSubURI) {
 			worldID := strings.TrimSuffix(worldURI, models.WorldVibeSubURI)
-			vibe, err := h.repo.GetWorldVibe(worldID)
+			vibe, err := h.repo.BlendedWorldVibe(worldID, time.Now())
 			if err != nil {
 				return nil, err
 			}
@@ -72,6 +166,26 @@ func (h *worldUriHandler) HandleUri(uri string) (interface{}, error) {
 	return nil, fmt.Errorf("invalid world URI: %s", uri)
 }
 
+// sensorHistory answers world://{id}/sensors with the history of a world's
+// sensor readings
+func (h *worldUriHandler) sensorHistory(worldID, rawQuery string) (interface{}, error) {
+	if h.history == nil {
+		return nil, fmt.Errorf("sensor history is not kept")
+	}
+	if _, err := h.repo.GetWorld(worldID); err != nil {
+		return nil, err
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query in %s: %v", models.WorldScheme+worldID+models.SensorsSubURI, err)
+	}
+	query, err := parseSensorQuery(values)
+	if err != nil {
+		return nil, err
+	}
+	return h.history.Query(worldID, query)
+}
+
 // Define tool handlers
 func createVibeTools(repo Repository) map[string]interface{} {
 	return map[string]interface{}{
@@ -81,14 +195,16 @@ func createVibeTools(repo Repository) map[string]interface{} {
 				return nil, fmt.Errorf("invalid vibe data: %v", err)
 			}
 			
-			if err := repo.AddVibe(vibe); err != nil {
+			created, err := toolActor(repo, req).CreateVibe(vibe)
+			if err != nil {
 				return nil, err
 			}
 			
 			return map[string]interface{}{
 				"success": true,
-				"id":      vibe.ID,
-				"message": fmt.Sprintf("Vibe '%s' created successfully", vibe.Name),
+				"id":      created.ID,
+				"version": created.Version,
+				"message": fmt.Sprintf("Vibe '%s' created successfully as %s", created.Name, created.ID),
 			}, nil
 		},
 		"update_vibe": func(req json.RawMessage) (interface{}, error) {
@@ -97,31 +213,59 @@ func createVibeTools(repo Repository) map[string]interface{} {
 				return nil, fmt.Errorf("invalid vibe data: %v", err)
 			}
 			
-			if err := repo.UpdateVibe(vibe); err != nil {
+			updated, err := toolActor(repo, req).SaveVibe(vibe)
+			if err != nil {
 				return nil, err
 			}
 			
 			return map[string]interface{}{
 				"success": true,
-				"id":      vibe.ID,
-				"message": fmt.Sprintf("Vibe '%s' updated successfully", vibe.Name),
+				"id":      updated.ID,
+				"version": updated.Version,
+				"message": fmt.Sprintf("Vibe '%s' updated successfully", updated.Name),
 			}, nil
 		},
 		"delete_vibe": func(req json.RawMessage) (interface{}, error) {
-			var params struct {
-				ID string `json:"id"`
+			var params deleteVibeParams
+			if err := json.Unmarshal(req, &params); err != nil {
+				return nil, fmt.Errorf("invalid request: %v", err)
+			}
+			mode, err := repository.ParseDeleteMode(params.Mode)
+			if err != nil {
+				return nil, err
+			}
+			
+			affected, err := toolActor(repo, req).DeleteVibeWithOptions(params.ID, repository.VibeDeleteOptions{
+				Mode:          mode,
+				ReplacementID: params.ReplacementID,
+			})
+			if err == repository.ErrVibeInUse {
+				return nil, fmt.Errorf("vibe '%s' is used by worlds (see vibe://%s/worlds); delete with mode reassign or clear", params.ID, params.ID)
+			}
+			if err != nil {
+				return nil, err
 			}
+			
+			return map[string]interface{}{
+				"success":        true,
+				"affectedWorlds": affected,
+				"message":        fmt.Sprintf("Vibe with ID '%s' moved to trash; use restore_vibe to undo", params.ID),
+			}, nil
+		},
+		"restore_vibe": func(req json.RawMessage) (interface{}, error) {
+			var params idParams
 			if err := json.Unmarshal(req, &params); err != nil {
 				return nil, fmt.Errorf("invalid request: %v", err)
 			}
 			
-			if err := repo.DeleteVibe(params.ID); err != nil {
+			if err := toolActor(repo, req).RestoreVibe(params.ID); err != nil {
 				return nil, err
 			}
 			
 			return map[string]interface{}{
 				"success": true,
-				"message": fmt.Sprintf("Vibe with ID '%s' deleted successfully", params.ID),
+				"id":      params.ID,
+				"message": fmt.Sprintf("Vibe with ID '%s' restored successfully", params.ID),
 			}, nil
 		},
 	}
@@ -135,14 +279,16 @@ func createWorldTools(repo Repository) map[string]interface{} {
 				return nil, fmt.Errorf("invalid world data: %v", err)
 			}
 			
-			if err := repo.AddWorld(world); err != nil {
+			created, err := toolActor(repo, req).CreateWorld(world)
+			if err != nil {
 				return nil, err
 			}
 			
 			return map[string]interface{}{
 				"success": true,
-				"id":      world.ID,
-				"message": fmt.Sprintf("World '%s' created successfully", world.Name),
+				"id":      created.ID,
+				"version": created.Version,
+				"message": fmt.Sprintf("World '%s' created successfully as %s", created.Name, created.ID),
 			}, nil
 		},
 		"update_world": func(req json.RawMessage) (interface{}, error) {
@@ -151,58 +297,267 @@ func createWorldTools(repo Repository) map[string]interface{} {
 				return nil, fmt.Errorf("invalid world data: %v", err)
 			}
 			
-			if err := repo.UpdateWorld(world); err != nil {
+			updated, err := toolActor(repo, req).SaveWorld(world)
+			if err != nil {
 				return nil, err
 			}
 			
 			return map[string]interface{}{
 				"success": true,
-				"id":      world.ID,
-				"message": fmt.Sprintf("World '%s' updated successfully", world.Name),
+				"id":      updated.ID,
+				"version": updated.Version,
+				"message": fmt.Sprintf("World '%s' updated successfully", updated.Name),
 			}, nil
 		},
 		"delete_world": func(req json.RawMessage) (interface{}, error) {
-			var params struct {
-				ID string `json:"id"`
+			var params idParams
+			if err := json.Unmarshal(req, &params); err != nil {
+				return nil, fmt.Errorf("invalid request: %v", err)
+			}
+			
+			if err := toolActor(repo, req).DeleteWorld(params.ID); err != nil {
+				return nil, err
 			}
+			
+			return map[string]interface{}{
+				"success": true,
+				"message": fmt.Sprintf("World with ID '%s' moved to trash; use restore_world to undo", params.ID),
+			}, nil
+		},
+		"restore_world": func(req json.RawMessage) (interface{}, error) {
+			var params idParams
 			if err := json.Unmarshal(req, &params); err != nil {
 				return nil, fmt.Errorf("invalid request: %v", err)
 			}
 			
-			if err := repo.DeleteWorld(params.ID); err != nil {
+			if err := toolActor(repo, req).RestoreWorld(params.ID); err != nil {
+				if err == repository.ErrVibeNotFound {
+					return nil, fmt.Errorf("cannot restore world '%s': its vibe no longer exists; restore the vibe first", params.ID)
+				}
 				return nil, err
 			}
 			
 			return map[string]interface{}{
 				"success": true,
-				"message": fmt.Sprintf("World with ID '%s' deleted successfully", params.ID),
+				"id":      params.ID,
+				"message": fmt.Sprintf("World with ID '%s' restored successfully", params.ID),
 			}, nil
 		},
 		"set_world_vibe": func(req json.RawMessage) (interface{}, error) {
-			var params struct {
-				WorldID string `json:"worldId"`
-				VibeID  string `json:"vibeId"`
+			var params setWorldVibeParams
+			if err := json.Unmarshal(req, &params); err != nil {
+				return nil, fmt.Errorf("invalid request: %v", err)
 			}
+			
+			duration := time.Duration(params.Duration) * time.Millisecond
+			world, err := toolActor(repo, req).TransitionWorldVibe(params.WorldID, params.VibeID, duration, params.Version)
+			if err != nil {
+				return nil, err
+			}
+			
+			message := fmt.Sprintf("Vibe '%s' set for world '%s'", params.VibeID, params.WorldID)
+			if params.Duration > 0 {
+				message += fmt.Sprintf(" over %v", duration)
+			}
+			return map[string]interface{}{
+				"success": true,
+				"version": world.Version,
+				"message": message,
+			}, nil
+		},
+		"connect_worlds": func(req json.RawMessage) (interface{}, error) {
+			var params connectWorldsParams
+			if err := json.Unmarshal(req, &params); err != nil {
+				return nil, fmt.Errorf("invalid request: %v", err)
+			}
+			
+			if err := toolActor(repo, req).ConnectWorlds(params.WorldEdge); err != nil {
+				return nil, err
+			}
+			
+			return map[string]interface{}{
+				"success": true,
+				"edge":    params.WorldEdge.Normalized(),
+				"message": fmt.Sprintf("Worlds '%s' and '%s' connected", params.From, params.To),
+			}, nil
+		},
+		"disconnect_worlds": func(req json.RawMessage) (interface{}, error) {
+			var params disconnectWorldsParams
 			if err := json.Unmarshal(req, &params); err != nil {
 				return nil, fmt.Errorf("invalid request: %v", err)
 			}
 			
-			if err := repo.SetWorldVibe(params.WorldID, params.VibeID); err != nil {
+			if err := toolActor(repo, req).DisconnectWorlds(params.From, params.To); err != nil {
 				return nil, err
 			}
 			
 			return map[string]interface{}{
 				"success": true,
-				"message": fmt.Sprintf("Vibe '%s' set for world '%s'", params.VibeID, params.WorldID),
+				"message": fmt.Sprintf("Worlds '%s' and '%s' disconnected", params.From, params.To),
+			}, nil
+		},
+	}
+}
+
+func createBatchTools(repo Repository) map[string]interface{} {
+	return map[string]interface{}{
+		"apply_batch": func(req json.RawMessage) (interface{}, error) {
+			var params applyBatchParams
+			if err := json.Unmarshal(req, &params); err != nil {
+				return nil, fmt.Errorf("invalid batch: %v", err)
+			}
+			
+			ids, err := toolActor(repo, req).ApplyBatch(params.Operations)
+			if err != nil {
+				return nil, err
+			}
+			
+			return map[string]interface{}{
+				"success": true,
+				"applied": len(params.Operations),
+				"ids":     ids,
+				"message": fmt.Sprintf("Applied %d operations", len(params.Operations)),
+			}, nil
+		},
+	}
+}
+
+func createBundleTools(repo Repository) map[string]interface{} {
+	return map[string]interface{}{
+		"export_bundle": func(req json.RawMessage) (interface{}, error) {
+			bundle, err := repo.ExportBundle()
+			if err != nil {
+				return nil, err
+			}
+			
+			var buf strings.Builder
+			if err := repository.WriteBundle(&buf, bundle); err != nil {
+				return nil, err
+			}
+			return buf.String(), nil
+		},
+		"import_bundle": func(req json.RawMessage) (interface{}, error) {
+			var params struct {
+				Bundle   json.RawMessage `json:"bundle"`
+				Strategy string          `json:"strategy"`
+			}
+			if err := json.Unmarshal(req, &params); err != nil {
+				return nil, fmt.Errorf("invalid parameters: %v", err)
+			}
+			if len(params.Bundle) == 0 {
+				return nil, fmt.Errorf("bundle is required")
+			}
+			
+			strategy, err := repository.ParseImportStrategy(params.Strategy)
+			if err != nil {
+				return nil, err
+			}
+			bundle, err := repository.ReadBundle(bytes.NewReader(params.Bundle))
+			if err != nil {
+				return nil, err
+			}
+			
+			result, err := toolActor(repo, req).ImportBundle(bundle, strategy)
+			if errors.Is(err, repository.ErrImportConflict) {
+				return nil, fmt.Errorf("%v; retry with strategy overwrite or skip", err)
+			}
+			if err != nil {
+				return nil, err
+			}
+			
+			imported := result.Vibes.Created + result.Vibes.Overwritten + result.Worlds.Created + result.Worlds.Overwritten
+			skipped := result.Vibes.Skipped + result.Worlds.Skipped
+			return map[string]interface{}{
+				"success":  true,
+				"strategy": strategy,
+				"vibes":    result.Vibes,
+				"worlds":   result.Worlds,
+				"message":  fmt.Sprintf("Imported %d entities, skipped %d", imported, skipped),
 			}, nil
 		},
 	}
 }
 
-// CreateMCPRequestHandler creates an HTTP handler for MCP requests
-func CreateMCPRequestHandler(repo Repository, streamingTools *streaming.StreamingTools) http.Handler {
+func createSensorTools(ingester *sensors.Ingester) map[string]interface{} {
+	return map[string]interface{}{
+		"ingest_sensor_reading": func(req json.RawMessage) (interface{}, error) {
+			var reading models.SensorReading
+			if err := json.Unmarshal(req, &reading); err != nil {
+				return nil, fmt.Errorf("invalid sensor reading: %v", err)
+			}
+			
+			stored, err := ingester.Ingest(reading)
+			if err != nil {
+				return nil, err
+			}
+			
+			return map[string]interface{}{
+				"success":   true,
+				"worldId":   stored.WorldID,
+				"timestamp": stored.Timestamp,
+				"message":   fmt.Sprintf("Recorded %d sensor values for world %s", len(stored.SensorData.Names()), stored.WorldID),
+			}, nil
+		},
+	}
+}
+
+// RegisterSensorTools adds the tool recording sensor readings with ingester
+func RegisterSensorTools(mcpServer *server.MCPServer, ingester *sensors.Ingester) {
+	addJSONTools(mcpServer, "Sensor", createSensorTools(ingester))
+}
+
+// RegisterSensorHistory serves world://{id}/sensors from history, for
+// servers that do not have the other world resources
+func RegisterSensorHistory(mcpServer *server.MCPServer, repo Repository, history *sensors.TimeSeries) {
+	worlds := &worldUriHandler{repo: repo, history: history}
+	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate(
+		"world://{id}/sensors{+query}",
+		"world sensors",
+		mcp.WithTemplateDescription("Sensor history of a world, with optional from, to, step and channel parameters"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return worlds.HandleRead(ctx, request)
+	})
+}
+
+// CreateMCPRequestHandler creates an HTTP handler for MCP requests. With an
+// ingester, it also records sensor readings and serves their history.
+func CreateMCPRequestHandler(repo Repository, streamingTools *streaming.StreamingTools, ingester *sensors.Ingester) http.Handler {
+	// Create and return the wrapped handler to improve error messages
+	wrapper := WrapMCPServer(newMCPServer(repo, streamingTools, ingester))
+	
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		
+		// Read request body
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+		
+		// Process the request
+		response := wrapper.HandleMessage(r.Context(), body)
+		
+		// Send response
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	})
+}
+
+// newMCPServer creates the MCP server with vibe and world resources and
+// tools, and the sensor tools and history if there is an ingester
+func newMCPServer(repo Repository, streamingTools *streaming.StreamingTools, ingester *sensors.Ingester) *server.MCPServer {
 	// Create MCP server with name and version
 	mcpServer := server.NewMCPServer("vibespace-mcp-go", "1.0.0")
+	worlds := &worldUriHandler{repo: repo}
+	if ingester != nil {
+		worlds.history = ingester.Store().History()
+		RegisterSensorTools(mcpServer, ingester)
+	}
 	
 	// Add resource handlers (equivalent to URI handlers)
 	mcpServer.AddResource(mcp.Resource{
@@ -223,44 +578,128 @@ func CreateMCPRequestHandler(repo Repository, streamingTools *streaming.Streamin
 		MIMEType:    "application/json",
 	}, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
 		// Convert to worldUriHandler call
-		handler := &worldUriHandler{repo: repo}
+		return worlds.HandleRead(ctx, request)
+	})
+	
+	// Templates route everything below the schemes, including list queries
+	// such as world://list?type=VIRTUAL&limit=20, to the same handlers
+	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate(
+		"vibe://{+path}",
+		"vibe",
+		mcp.WithTemplateDescription("Vibe by ID, vibe://{id}/worlds, vibe://trash, or vibe://list with optional filter, sort and paging parameters"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		handler := &vibeUriHandler{repo: repo}
 		return handler.HandleRead(ctx, request)
 	})
 	
-	// Add vibe tools
-	for name, toolFunc := range createVibeTools(repo) {
-		mcpServer.AddTool(mcp.Tool{
-			Name:        name,
-			Description: fmt.Sprintf("Vibe tool: %s", name),
-		}, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			// Convert arguments to JSON first
-			var args json.RawMessage
-			if request.Params.Arguments != nil {
-				if argBytes, err := json.Marshal(request.Params.Arguments); err == nil {
-					args = argBytes
-				} else {
-					return nil, fmt.Errorf("failed to marshal arguments: %v", err)
-				}
-			}
-			
-			// Convert the old tool function to new format
-			if fn, ok := toolFunc.(func(json.RawMessage) (interface{}, error)); ok {
-				result, err := fn(args)
-				if err != nil {
-					return nil, err
-				}
-				return mcp.NewToolResultText(fmt.Sprintf("%v", result)), nil
-			} else {
-				return nil, fmt.Errorf("invalid tool function type: %T", toolFunc)
-			}
-		})
+	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate(
+		"world://{+path}",
+		"world",
+		mcp.WithTemplateDescription("World by ID, world://{id}/vibe, world://{id}/children, world://{id}/ancestors, world://{id}/rollup, world://{id}/neighbors, world://{id}/sensors, world://graph, world://trash, or world://list with optional filter, sort and paging parameters"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return worlds.HandleRead(ctx, request)
+	})
+	
+	mcpServer.AddResource(mcp.Resource{
+		URI:         models.SensorChannelsURI,
+		Name:        "sensor channels",
+		Description: "Registered sensor channels with their units, ranges and aggregation rules",
+		MIMEType:    "application/json",
+	}, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		data, err := json.Marshal(models.DefaultSensorRegistry.Channels())
+		if err != nil {
+			return nil, err
+		}
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      models.SensorChannelsURI,
+				MIMEType: "application/json",
+				Text:     string(data),
+			},
+		}, nil
+	})
+	
+	// Add vibe, world and batch tools
+	addJSONTools(mcpServer, "Vibe", createVibeTools(repo))
+	addJSONTools(mcpServer, "World", createWorldTools(repo))
+	addJSONTools(mcpServer, "Batch", createBatchTools(repo))
+	addJSONTools(mcpServer, "Bundle", createBundleTools(repo))
+	
+	// Publish the schemas of the models
+	RegisterSchemaResources(mcpServer)
+	
+	// Add streaming tools
+	for name := range streaming.GetStreamingToolMethods() {
+		switch name {
+		case "streaming_startStreaming":
+			mcpServer.AddTool(mcp.Tool{
+				Name:           name,
+				Description:    "Start streaming",
+				RawInputSchema: ToolInputSchema(name),
+			}, createStreamingToolHandler(streamingTools.StartStreaming))
+		case "streaming_stopStreaming":
+			mcpServer.AddTool(mcp.Tool{
+				Name:           name,
+				Description:    "Stop streaming",
+				RawInputSchema: ToolInputSchema(name),
+			}, createStreamingToolHandler(streamingTools.StopStreaming))
+		case "streaming_status":
+			mcpServer.AddTool(mcp.Tool{
+				Name:           name,
+				Description:    "Get streaming status",
+				RawInputSchema: ToolInputSchema(name),
+			}, createStreamingToolHandler(streamingTools.Status))
+		case "streaming_streamWorld":
+			mcpServer.AddTool(mcp.Tool{
+				Name:           name,
+				Description:    "Stream world",
+				RawInputSchema: ToolInputSchema(name),
+			}, createStreamingToolHandler(streamingTools.StreamWorld))
+		case "streaming_updateConfig":
+			mcpServer.AddTool(mcp.Tool{
+				Name:           name,
+				Description:    "Update streaming config",
+				RawInputSchema: ToolInputSchema(name),
+			}, createStreamingToolHandler(streamingTools.UpdateConfig))
+		case "streaming_setInterval":
+			mcpServer.AddTool(mcp.Tool{
+				Name:           name,
+				Description:    "Set the streaming interval of a world or world type",
+				RawInputSchema: ToolInputSchema(name),
+			}, createStreamingToolHandler(streamingTools.SetInterval))
+		case "streaming_listIntervals":
+			mcpServer.AddTool(mcp.Tool{
+				Name:           name,
+				Description:    "List streaming intervals",
+				RawInputSchema: ToolInputSchema(name),
+			}, createStreamingToolHandler(streamingTools.ListIntervals))
+		case "streaming_clearInterval":
+			mcpServer.AddTool(mcp.Tool{
+				Name:           name,
+				Description:    "Clear the streaming interval of a world or world type",
+				RawInputSchema: ToolInputSchema(name),
+			}, createStreamingToolHandler(streamingTools.ClearInterval))
+		case "streaming_replay":
+			mcpServer.AddTool(mcp.Tool{
+				Name:           name,
+				Description:    "Replay published messages from JetStream",
+				RawInputSchema: ToolInputSchema(name),
+			}, createStreamingToolHandler(streamingTools.Replay))
+		}
 	}
 	
-	// Add world tools
-	for name, toolFunc := range createWorldTools(repo) {
+	return mcpServer
+}
+
+// addJSONTools registers tool functions that take their arguments as raw JSON
+func addJSONTools(mcpServer *server.MCPServer, kind string, tools map[string]interface{}) {
+	for name, toolFunc := range tools {
 		mcpServer.AddTool(mcp.Tool{
-			Name:        name,
-			Description: fmt.Sprintf("World tool: %s", name),
+			Name:           name,
+			Description:    fmt.Sprintf("%s tool: %s", kind, name),
+			RawInputSchema: ToolInputSchema(name),
 		}, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 			// Convert arguments to JSON first
 			var args json.RawMessage
@@ -275,6 +714,18 @@ func CreateMCPRequestHandler(repo Repository, streamingTools *streaming.Streamin
 			// Convert the old tool function to new format
 			if fn, ok := toolFunc.(func(json.RawMessage) (interface{}, error)); ok {
 				result, err := fn(args)
+				if conflict := conflictToolResult(err); conflict != nil {
+					return conflict, nil
+				}
+				if duplicate := duplicateToolResult(err); duplicate != nil {
+					return duplicate, nil
+				}
+				if failed := batchToolResult(err); failed != nil {
+					return failed, nil
+				}
+				if invalid := validationToolResult(err); invalid != nil {
+					return invalid, nil
+				}
 				if err != nil {
 					return nil, err
 				}
@@ -284,61 +735,6 @@ func CreateMCPRequestHandler(repo Repository, streamingTools *streaming.Streamin
 			}
 		})
 	}
-	
-	// Add streaming tools
-	for name := range streaming.GetStreamingToolMethods() {
-		switch name {
-		case "streaming_startStreaming":
-			mcpServer.AddTool(mcp.Tool{
-				Name:        name,
-				Description: "Start streaming",
-			}, createStreamingToolHandler(streamingTools.StartStreaming))
-		case "streaming_stopStreaming":
-			mcpServer.AddTool(mcp.Tool{
-				Name:        name,
-				Description: "Stop streaming",
-			}, createStreamingToolHandler(streamingTools.StopStreaming))
-		case "streaming_status":
-			mcpServer.AddTool(mcp.Tool{
-				Name:        name,
-				Description: "Get streaming status",
-			}, createStreamingToolHandler(streamingTools.Status))
-		case "streaming_streamWorld":
-			mcpServer.AddTool(mcp.Tool{
-				Name:        name,
-				Description: "Stream world",
-			}, createStreamingToolHandler(streamingTools.StreamWorld))
-		case "streaming_updateConfig":
-			mcpServer.AddTool(mcp.Tool{
-				Name:        name,
-				Description: "Update streaming config",
-			}, createStreamingToolHandler(streamingTools.UpdateConfig))
-		}
-	}
-	
-	// Create and return the wrapped handler to improve error messages
-	wrapper := WrapMCPServer(mcpServer)
-	
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-		
-		// Read request body
-		var body json.RawMessage
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
-			return
-		}
-		
-		// Process the request
-		response := wrapper.HandleMessage(r.Context(), body)
-		
-		// Send response
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
-	})
 }
 
 // Helper function to convert URI handlers to resource handlers
@@ -382,6 +778,101 @@ func (h *worldUriHandler) HandleRead(ctx context.Context, request mcp.ReadResour
 	}, nil
 }
 
+// toolActor returns the repository view that attributes writes to the
+// optional userId argument of a tool call
+func toolActor(repo Repository, req json.RawMessage) Repository {
+	var params struct {
+		UserID string `json:"userId"`
+	}
+	json.Unmarshal(req, &params)
+	if params.UserID == "" {
+		return repo
+	}
+	return repo.WithActor(params.UserID)
+}
+
+// conflictToolResult turns a version conflict into a tool error result that
+// tells the client which version is current, so it can re-read and retry.
+// It returns nil for any other error.
+func conflictToolResult(err error) *mcp.CallToolResult {
+	var conflict *repository.ConflictError
+	if !errors.As(err, &conflict) {
+		return nil
+	}
+	
+	data, _ := json.Marshal(map[string]interface{}{
+		"success":         false,
+		"error":           "version_conflict",
+		"kind":            conflict.Kind,
+		"id":              conflict.ID,
+		"expectedVersion": conflict.Expected,
+		"currentVersion":  conflict.Actual,
+		"message":         fmt.Sprintf("%v; re-read the %s and retry with its current version", conflict, conflict.Kind),
+	})
+	return mcp.NewToolResultError(string(data))
+}
+
+// duplicateToolResult turns a create with an ID already in use into a tool
+// error result naming the entity. It returns nil for any other error,
+// including a failed batch, which batchToolResult reports.
+func duplicateToolResult(err error) *mcp.CallToolResult {
+	var duplicate *repository.DuplicateIDError
+	var batchErr *repository.BatchError
+	if !errors.As(err, &duplicate) || errors.As(err, &batchErr) {
+		return nil
+	}
+	
+	data, _ := json.Marshal(map[string]interface{}{
+		"success": false,
+		"error":   "duplicate_id",
+		"kind":    duplicate.Kind,
+		"id":      duplicate.ID,
+		"message": fmt.Sprintf("%v; omit the id to have one generated, or update the existing %s", duplicate, duplicate.Kind),
+	})
+	return mcp.NewToolResultError(string(data))
+}
+
+// batchToolResult turns a failed batch into a tool error result naming the
+// operation that failed. It returns nil for any other error.
+func batchToolResult(err error) *mcp.CallToolResult {
+	var batchErr *repository.BatchError
+	if !errors.As(err, &batchErr) {
+		return nil
+	}
+	
+	result := map[string]interface{}{
+		"success":     false,
+		"error":       "batch_failed",
+		"failedIndex": batchErr.Index,
+		"op":          batchErr.Op,
+		"message":     fmt.Sprintf("%v; no operation was applied", batchErr),
+	}
+	var invalid *models.ValidationError
+	if errors.As(err, &invalid) {
+		result["fields"] = invalid.Fields
+	}
+	data, _ := json.Marshal(result)
+	return mcp.NewToolResultError(string(data))
+}
+
+// validationToolResult turns invalid input into a tool error result listing
+// each invalid field. It returns nil for any other error.
+func validationToolResult(err error) *mcp.CallToolResult {
+	var invalid *models.ValidationError
+	if !errors.As(err, &invalid) {
+		return nil
+	}
+	
+	data, _ := json.Marshal(map[string]interface{}{
+		"success": false,
+		"error":   "validation_failed",
+		"kind":    invalid.Kind,
+		"fields":  invalid.Fields,
+		"message": err.Error(),
+	})
+	return mcp.NewToolResultError(string(data))
+}
+
 // Helper function to create streaming tool handlers
 func createStreamingToolHandler(toolFunc interface{}) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
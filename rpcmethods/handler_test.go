@@ -0,0 +1,771 @@
+package rpcmethods
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+	"github.com/bmorphism/vibespace-mcp-go/sensors"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestWorldUriHandlerListQuery(t *testing.T) {
+	handler := &worldUriHandler{repo: repository.NewRepository()}
+
+	// The bare list URI keeps returning a plain array
+	result, err := handler.HandleUri(models.WorldListURI)
+	if err != nil {
+		t.Fatalf("HandleUri(%s) error: %v", models.WorldListURI, err)
+	}
+	if worlds, ok := result.([]models.World); !ok || len(worlds) != 3 {
+		t.Errorf("Expected 3 worlds, got %#v", result)
+	}
+
+	result, err = handler.HandleUri("world://list?type=virtual&limit=1")
+	if err != nil {
+		t.Fatalf("HandleUri error: %v", err)
+	}
+	page, ok := result.(repository.WorldPage)
+	if !ok {
+		t.Fatalf("Expected a WorldPage, got %T", result)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != "virtual-garden" {
+		t.Errorf("Expected only virtual-garden, got %v", page.Items)
+	}
+	if page.NextCursor != "" {
+		t.Errorf("Expected no next page, got cursor %q", page.NextCursor)
+	}
+
+	// Walk all worlds two at a time, ordered by name
+	var names []string
+	uri := "world://list?sort=name&limit=2"
+	for uri != "" {
+		result, err := handler.HandleUri(uri)
+		if err != nil {
+			t.Fatalf("HandleUri(%s) error: %v", uri, err)
+		}
+		page := result.(repository.WorldPage)
+		for _, world := range page.Items {
+			names = append(names, world.Name)
+		}
+		uri = ""
+		if page.NextCursor != "" {
+			uri = "world://list?sort=name&limit=2&cursor=" + page.NextCursor
+		}
+	}
+	if strings.Join(names, ",") != "Creative Studio,Modern Office,Zen Garden" {
+		t.Errorf("Unexpected names when paging by name: %v", names)
+	}
+
+	for _, bad := range []string{
+		"world://list?limit=-1",
+		"world://list?order=sideways",
+		"world://list?minEnergy=high",
+		"world://list?public=maybe",
+		"world://list?colour=red",
+		"world://list?sort=colour",
+		"world://list?cursor=bogus",
+	} {
+		if _, err := handler.HandleUri(bad); err == nil {
+			t.Errorf("Expected an error for %s", bad)
+		}
+	}
+}
+
+func TestVibeUriHandlerListQuery(t *testing.T) {
+	handler := &vibeUriHandler{repo: repository.NewRepository()}
+
+	result, err := handler.HandleUri("vibe://list?minEnergy=0.5&sort=energy&order=desc")
+	if err != nil {
+		t.Fatalf("HandleUri error: %v", err)
+	}
+	page, ok := result.(repository.VibePage)
+	if !ok {
+		t.Fatalf("Expected a VibePage, got %T", result)
+	}
+	if len(page.Items) != 2 || page.Items[0].ID != "energetic-spark" || page.Items[1].ID != "focused-flow" {
+		t.Errorf("Expected energetic-spark then focused-flow, got %v", page.Items)
+	}
+
+	// Worlds-only parameters are rejected for vibes
+	if _, err := handler.HandleUri("vibe://list?type=VIRTUAL"); err == nil {
+		t.Errorf("Expected an error for a type filter on vibes")
+	}
+}
+
+func TestReadListQueryResource(t *testing.T) {
+	mcpServer := newMCPServer(repository.NewRepository(), nil, nil)
+
+	message := `{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"world://list?type=HYBRID"}}`
+	response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), json.RawMessage(message)))
+	if err != nil {
+		t.Fatalf("Error marshaling response: %v", err)
+	}
+
+	var decoded struct {
+		Result struct {
+			Contents []struct {
+				Text string `json:"text"`
+			} `json:"contents"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(response, &decoded); err != nil {
+		t.Fatalf("Invalid response %s: %v", response, err)
+	}
+	if decoded.Error != nil {
+		t.Fatalf("Expected the list query to be routed, got error: %s", decoded.Error.Message)
+	}
+	if len(decoded.Result.Contents) != 1 {
+		t.Fatalf("Expected one content item, got %d", len(decoded.Result.Contents))
+	}
+
+	var page repository.WorldPage
+	if err := json.Unmarshal([]byte(decoded.Result.Contents[0].Text), &page); err != nil {
+		t.Fatalf("Invalid page JSON: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != "hybrid-studio" {
+		t.Errorf("Expected only hybrid-studio, got %v", page.Items)
+	}
+}
+
+func TestUpdateToolReportsConflict(t *testing.T) {
+	repo := repository.NewRepository()
+	mcpServer := newMCPServer(repo, nil, nil)
+
+	callTool := func(name, arguments string) mcp.CallToolResult {
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + name + `","arguments":` + arguments + `}}`
+		response, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("Expected a successful JSON-RPC response for %s", name)
+		}
+		result, ok := response.Result.(mcp.CallToolResult)
+		if !ok {
+			t.Fatalf("Expected a tool result, got %T", response.Result)
+		}
+		return result
+	}
+
+	result := callTool("update_world", `{"id":"office-space","name":"Renamed Office","type":"PHYSICAL","version":1}`)
+	if result.IsError {
+		t.Fatalf("Expected first versioned update to succeed, got %v", result.Content)
+	}
+
+	// A second client still holding version 1 gets a conflict it can act on
+	result = callTool("update_world", `{"id":"office-space","name":"Other Office","type":"PHYSICAL","version":1}`)
+	if !result.IsError {
+		t.Fatalf("Expected a conflict error result")
+	}
+	var conflict struct {
+		Error          string `json:"error"`
+		CurrentVersion int64  `json:"currentVersion"`
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if err := json.Unmarshal([]byte(text), &conflict); err != nil {
+		t.Fatalf("Expected JSON conflict details, got %q", text)
+	}
+	if conflict.Error != "version_conflict" || conflict.CurrentVersion != 2 {
+		t.Errorf("Unexpected conflict details: %+v", conflict)
+	}
+
+	result = callTool("set_world_vibe", `{"worldId":"office-space","vibeId":"calm-clarity","version":1}`)
+	if !result.IsError {
+		t.Errorf("Expected set_world_vibe with a stale version to report a conflict")
+	}
+
+	world, _ := repo.GetWorld("office-space")
+	if world.Name != "Renamed Office" || world.CurrentVibe == "calm-clarity" {
+		t.Errorf("Expected conflicting writes to be rejected, got %q with vibe %q", world.Name, world.CurrentVibe)
+	}
+}
+
+func TestUpdateToolReportsVersion(t *testing.T) {
+	repo := repository.NewRepository()
+	mcpServer := newMCPServer(repo, nil, nil)
+
+	callTool := func(name, arguments string) string {
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + name + `","arguments":` + arguments + `}}`
+		response, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("Expected a successful JSON-RPC response for %s", name)
+		}
+		result, ok := response.Result.(mcp.CallToolResult)
+		if !ok || result.IsError {
+			t.Fatalf("Expected %s to succeed, got %v", name, response.Result)
+		}
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	// Without a version the write is last-write-wins, and still reports the
+	// version it stored
+	if text := callTool("update_world", `{"id":"office-space","name":"Renamed Office","type":"PHYSICAL"}`); !strings.Contains(text, "version:2") {
+		t.Errorf("Expected update_world to report version 2, got %q", text)
+	}
+	if text := callTool("set_world_vibe", `{"worldId":"office-space","vibeId":"calm-clarity"}`); !strings.Contains(text, "version:3") {
+		t.Errorf("Expected set_world_vibe to report version 3, got %q", text)
+	}
+	if text := callTool("set_world_vibe", `{"worldId":"office-space","vibeId":"energetic-spark","version":3}`); !strings.Contains(text, "version:4") {
+		t.Errorf("Expected a versioned set_world_vibe to report version 4, got %q", text)
+	}
+	world, _ := repo.GetWorld("office-space")
+	if world.Version != 4 {
+		t.Errorf("Expected office-space at version 4, got %d", world.Version)
+	}
+
+	vibe, _ := repo.GetVibe("calm-clarity")
+	want := fmt.Sprintf("version:%d", vibe.Version+1)
+	if text := callTool("update_vibe", `{"id":"calm-clarity","name":"Calmer Clarity","energy":0.3,"mood":"calm"}`); !strings.Contains(text, want) {
+		t.Errorf("Expected update_vibe to report %s, got %q", want, text)
+	}
+}
+
+func TestWorldHistoryResources(t *testing.T) {
+	repo := repository.NewRepository()
+	mcpServer := newMCPServer(repo, nil, nil)
+	handler := &worldUriHandler{repo: repo}
+
+	before := time.Now()
+	message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"set_world_vibe","arguments":{"worldId":"office-space","vibeId":"calm-clarity","userId":"alice"}}}`
+	if _, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse); !ok {
+		t.Fatalf("Expected set_world_vibe to succeed")
+	}
+
+	result, err := handler.HandleUri("world://office-space/history")
+	if err != nil {
+		t.Fatalf("HandleUri error: %v", err)
+	}
+	entries, ok := result.([]repository.HistoryEntry)
+	if !ok || len(entries) != 1 {
+		t.Fatalf("Expected one history entry, got %#v", result)
+	}
+	if entries[0].Actor != "alice" {
+		t.Errorf("Expected the change to be attributed to alice, got %q", entries[0].Actor)
+	}
+
+	at := before.UTC().Format(time.RFC3339Nano)
+	result, err = handler.HandleUri("world://office-space?at=" + at)
+	if err != nil {
+		t.Fatalf("HandleUri error: %v", err)
+	}
+	if world := result.(models.World); world.CurrentVibe != "focused-flow" {
+		t.Errorf("Expected focused-flow before the change, got %q", world.CurrentVibe)
+	}
+
+	result, err = handler.HandleUri("world://office-space/vibe?at=" + at)
+	if err != nil {
+		t.Fatalf("HandleUri error: %v", err)
+	}
+	if vibe := result.(models.Vibe); vibe.ID != "focused-flow" {
+		t.Errorf("Expected the focused-flow vibe, got %q", vibe.ID)
+	}
+
+	if _, err := handler.HandleUri("world://office-space?at=yesterday"); err == nil {
+		t.Errorf("Expected an invalid timestamp to be rejected")
+	}
+}
+
+func TestTrashResourcesAndRestoreTools(t *testing.T) {
+	repo := repository.NewRepository()
+	mcpServer := newMCPServer(repo, nil, nil)
+	handler := &worldUriHandler{repo: repo}
+
+	callTool := func(name, arguments string) mcp.CallToolResult {
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + name + `","arguments":` + arguments + `}}`
+		response, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("Expected a successful JSON-RPC response for %s", name)
+		}
+		return response.Result.(mcp.CallToolResult)
+	}
+
+	callTool("delete_world", `{"id":"office-space","userId":"alice"}`)
+
+	result, err := handler.HandleUri(models.WorldTrashURI)
+	if err != nil {
+		t.Fatalf("HandleUri(%s) error: %v", models.WorldTrashURI, err)
+	}
+	deleted, ok := result.([]repository.DeletedWorld)
+	if !ok || len(deleted) != 1 || deleted[0].ID != "office-space" || deleted[0].DeletedBy != "alice" {
+		t.Fatalf("Expected office-space deleted by alice, got %#v", result)
+	}
+
+	vibes, err := (&vibeUriHandler{repo: repo}).HandleUri(models.VibeTrashURI)
+	if err != nil || len(vibes.([]repository.DeletedVibe)) != 0 {
+		t.Errorf("Expected an empty vibe trash, got %#v (%v)", vibes, err)
+	}
+
+	if result := callTool("restore_world", `{"id":"office-space"}`); result.IsError {
+		t.Fatalf("Expected restore_world to succeed, got %v", result.Content)
+	}
+	if _, err := repo.GetWorld("office-space"); err != nil {
+		t.Errorf("Expected office-space to be back, got %v", err)
+	}
+}
+
+func TestVibeWorldsResourceAndDeleteModes(t *testing.T) {
+	repo := repository.NewRepository()
+	mcpServer := newMCPServer(repo, nil, nil)
+	handler := &vibeUriHandler{repo: repo}
+
+	result, err := handler.HandleUri("vibe://calm-clarity/worlds")
+	if err != nil {
+		t.Fatalf("HandleUri error: %v", err)
+	}
+	if worlds, ok := result.([]models.World); !ok || len(worlds) != 1 || worlds[0].ID != "virtual-garden" {
+		t.Fatalf("Expected virtual-garden, got %#v", result)
+	}
+
+	callTool := func(arguments string) (mcp.JSONRPCMessage, bool) {
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"delete_vibe","arguments":` + arguments + `}}`
+		response := mcpServer.HandleMessage(context.Background(), json.RawMessage(message))
+		_, ok := response.(mcp.JSONRPCResponse)
+		return response, ok
+	}
+
+	// The default mode points at the dependents
+	response, ok := callTool(`{"id":"calm-clarity"}`)
+	if ok {
+		t.Fatalf("Expected deleting a vibe in use to fail")
+	}
+	if data, _ := json.Marshal(response); !strings.Contains(string(data), "vibe://calm-clarity/worlds") {
+		t.Errorf("Expected the error to mention the dependents resource, got %s", data)
+	}
+
+	if _, ok := callTool(`{"id":"calm-clarity","mode":"reassign","replacementId":"focused-flow"}`); !ok {
+		t.Fatalf("Expected delete with reassign to succeed")
+	}
+	world, _ := repo.GetWorld("virtual-garden")
+	if world.CurrentVibe != "focused-flow" {
+		t.Errorf("Expected virtual-garden reassigned to focused-flow, got %q", world.CurrentVibe)
+	}
+
+	if _, ok := callTool(`{"id":"focused-flow","mode":"cascade"}`); ok {
+		t.Errorf("Expected an unknown mode to be rejected")
+	}
+}
+
+func TestApplyBatchTool(t *testing.T) {
+	repo := repository.NewRepository()
+	mcpServer := newMCPServer(repo, nil, nil)
+
+	callTool := func(arguments string) mcp.CallToolResult {
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"apply_batch","arguments":` + arguments + `}}`
+		response, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("Expected a JSON-RPC response for apply_batch")
+		}
+		return response.Result.(mcp.CallToolResult)
+	}
+
+	// The second operation fails, so the first must not be applied either
+	result := callTool(`{"operations":[
+		{"op":"create_vibe","vibe":{"id":"batch-vibe","name":"Batch","energy":0.5}},
+		{"op":"set_world_vibe","worldId":"office-space","vibeId":"missing"}]}`)
+	if !result.IsError {
+		t.Fatalf("Expected a failed batch to return an error result")
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"failedIndex":1`) {
+		t.Errorf("Expected the failed operation's index, got %s", text)
+	}
+	if _, err := repo.GetVibe("batch-vibe"); err != repository.ErrVibeNotFound {
+		t.Errorf("Expected batch-vibe not to exist after a failed batch, got %v", err)
+	}
+
+	result = callTool(`{"operations":[
+		{"op":"create_vibe","vibe":{"id":"batch-vibe","name":"Batch","energy":0.5}},
+		{"op":"set_world_vibe","worldId":"office-space","vibeId":"batch-vibe"}]}`)
+	if result.IsError {
+		t.Fatalf("Expected the batch to succeed, got %v", result.Content)
+	}
+	if world, _ := repo.GetWorld("office-space"); world.CurrentVibe != "batch-vibe" {
+		t.Errorf("Expected office-space to use batch-vibe, got %q", world.CurrentVibe)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "ids:[batch-vibe ]") {
+		t.Errorf("Expected the created vibe's ID in the result, got %s", text)
+	}
+}
+
+func TestBundleTools(t *testing.T) {
+	source := repository.NewRepository()
+	target := repository.NewRepositoryWithSampleData(false)
+
+	callTool := func(repo Repository, name, arguments string) mcp.CallToolResult {
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + name + `","arguments":` + arguments + `}}`
+		response, ok := newMCPServer(repo, nil, nil).HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("Expected a successful JSON-RPC response for %s", name)
+		}
+		return response.Result.(mcp.CallToolResult)
+	}
+
+	exported := callTool(source, "export_bundle", `{}`).Content[0].(mcp.TextContent).Text
+	if result := callTool(target, "import_bundle", `{"bundle":`+exported+`,"strategy":"fail","userId":"bob"}`); result.IsError {
+		t.Fatalf("Expected import_bundle to succeed, got %v", result.Content)
+	}
+	if len(target.GetAllVibes()) != len(source.GetAllVibes()) || len(target.GetAllWorlds()) != len(source.GetAllWorlds()) {
+		t.Errorf("Expected the target to hold every exported vibe and world")
+	}
+	entries, _ := target.WorldHistory("office-space")
+	if len(entries) != 1 || entries[0].Actor != "bob" {
+		t.Errorf("Expected the import attributed to bob, got %+v", entries)
+	}
+
+	// Importing again with fail is rejected as a tool error
+	message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"import_bundle","arguments":{"bundle":` + exported + `,"strategy":"fail"}}}`
+	if _, ok := newMCPServer(target, nil, nil).HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse); ok {
+		t.Errorf("Expected importing existing entities with strategy fail to be rejected")
+	}
+}
+
+func TestToolsReportValidationErrors(t *testing.T) {
+	repo := repository.NewRepository()
+	mcpServer := newMCPServer(repo, nil, nil)
+
+	message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"create_vibe","arguments":{"id":"loud","name":"Loud","energy":7.3,"colors":["#FF0000","red"]}}}`
+	response, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("Expected a JSON-RPC response for create_vibe")
+	}
+	result := response.Result.(mcp.CallToolResult)
+	if !result.IsError {
+		t.Fatalf("Expected an invalid vibe to return an error result")
+	}
+
+	var body struct {
+		Error  string              `json:"error"`
+		Fields []models.FieldError `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &body); err != nil {
+		t.Fatalf("Expected a JSON error body: %v", err)
+	}
+	if body.Error != "validation_failed" || len(body.Fields) != 2 || body.Fields[0].Field != "energy" || body.Fields[1].Field != "colors[1]" {
+		t.Errorf("Expected energy and colors[1] to be reported, got %+v", body)
+	}
+	if _, err := repo.GetVibe("loud"); err != repository.ErrVibeNotFound {
+		t.Errorf("Expected the invalid vibe not to be stored, got %v", err)
+	}
+}
+
+func TestToolInputSchemasAndSchemaResources(t *testing.T) {
+	repo := repository.NewRepository()
+	mcpServer := newMCPServer(repo, nil, sensors.NewIngester(sensors.NewStore(0, 0), repo))
+	RegisterCategoricalTools(mcpServer, NewCategoricalTools())
+
+	response, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("Expected a tools/list response")
+	}
+	data, _ := json.Marshal(response.Result)
+	var listed struct {
+		Tools []struct {
+			Name        string `json:"name"`
+			InputSchema struct {
+				Type       string                     `json:"type"`
+				Properties map[string]json.RawMessage `json:"properties"`
+				Required   []string                   `json:"required"`
+			} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(data, &listed); err != nil {
+		t.Fatalf("Error decoding tools/list: %v", err)
+	}
+	if len(listed.Tools) != len(toolInputs) {
+		t.Errorf("Expected %d tools, got %d", len(toolInputs), len(listed.Tools))
+	}
+	for _, tool := range listed.Tools {
+		if _, ok := toolInputs[tool.Name]; !ok || tool.InputSchema.Type != "object" {
+			t.Errorf("Expected a derived input schema for %s, got %+v", tool.Name, tool.InputSchema)
+		}
+		if tool.Name == "update_world" && strings.Join(tool.InputSchema.Required, ",") != "id,type" {
+			t.Errorf("Expected update_world to require id and type, got %v", tool.InputSchema.Required)
+		}
+		if tool.Name == "create_world" {
+			// The id is generated when omitted
+			if strings.Join(tool.InputSchema.Required, ",") != "type" {
+				t.Errorf("Expected create_world to require only type, got %v", tool.InputSchema.Required)
+			}
+			if !strings.Contains(string(tool.InputSchema.Properties["type"]), `"HYBRID"`) {
+				t.Errorf("Expected the world types as an enum, got %s", tool.InputSchema.Properties["type"])
+			}
+			if _, ok := tool.InputSchema.Properties["userId"]; !ok {
+				t.Errorf("Expected create_world to accept userId")
+			}
+		}
+	}
+
+	read := `{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"schema://vibe"}}`
+	response, ok = mcpServer.HandleMessage(context.Background(), json.RawMessage(read)).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("Expected schema://vibe to be readable")
+	}
+	contents := response.Result.(mcp.ReadResourceResult).Contents
+	text := contents[0].(mcp.TextResourceContents).Text
+	var vibeSchema struct {
+		Title      string `json:"title"`
+		Properties struct {
+			Energy struct {
+				Minimum float64 `json:"minimum"`
+				Maximum float64 `json:"maximum"`
+			} `json:"energy"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(text), &vibeSchema); err != nil {
+		t.Fatalf("Error decoding schema://vibe: %v", err)
+	}
+	if vibeSchema.Title != "vibe" || vibeSchema.Properties.Energy.Maximum != models.EnergyMax {
+		t.Errorf("Expected the vibe schema with the energy range, got %s", text)
+	}
+}
+
+func TestCreateToolsAssignIDs(t *testing.T) {
+	repo := repository.NewRepositoryWithSampleData(false)
+	mcpServer := newMCPServer(repo, nil, nil)
+
+	call := func(name, arguments string) mcp.CallToolResult {
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + name + `","arguments":` + arguments + `}}`
+		response, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("Expected a JSON-RPC response for %s", name)
+		}
+		return response.Result.(mcp.CallToolResult)
+	}
+
+	for _, want := range []string{"midnight-drive", "midnight-drive-2"} {
+		result := call("create_vibe", `{"name":"Midnight Drive!","energy":0.4}`)
+		if result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "id:"+want) {
+			t.Fatalf("Expected create_vibe to report id %s, got %+v", want, result.Content)
+		}
+		if _, err := repo.GetVibe(want); err != nil {
+			t.Errorf("Expected vibe %s to be stored, got %v", want, err)
+		}
+	}
+
+	result := call("create_world", `{"id":"studio","name":"Studio","type":"VIRTUAL"}`)
+	if result.IsError {
+		t.Fatalf("Expected create_world to succeed, got %+v", result.Content)
+	}
+	result = call("create_world", `{"id":"studio","name":"Other Studio","type":"VIRTUAL"}`)
+	if !result.IsError {
+		t.Fatalf("Expected a duplicate world id to be rejected")
+	}
+	var body struct {
+		Error string `json:"error"`
+		Kind  string `json:"kind"`
+		ID    string `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &body); err != nil {
+		t.Fatalf("Expected a JSON error body: %v", err)
+	}
+	if body.Error != "duplicate_id" || body.Kind != "world" || body.ID != "studio" {
+		t.Errorf("Expected a duplicate_id error for world studio, got %+v", body)
+	}
+	if world, _ := repo.GetWorld("studio"); world.Name != "Studio" {
+		t.Errorf("Expected the existing world to be kept, got %q", world.Name)
+	}
+}
+
+func TestSetWorldVibeTransition(t *testing.T) {
+	repo := repository.NewRepository()
+	mcpServer := newMCPServer(repo, nil, nil)
+	handler := &worldUriHandler{repo: repo}
+
+	message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"set_world_vibe","arguments":{"worldId":"office-space","vibeId":"calm-clarity","duration":60000}}}`
+	response, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse)
+	if !ok || response.Result.(mcp.CallToolResult).IsError {
+		t.Fatalf("Expected set_world_vibe with a duration to succeed, got %+v", response)
+	}
+
+	world, _ := repo.GetWorld("office-space")
+	if world.Transition == nil || world.Transition.FromVibe != "focused-flow" || world.Transition.Duration != 60000 {
+		t.Fatalf("Expected a one minute transition from focused-flow, got %+v", world.Transition)
+	}
+
+	result, err := handler.HandleUri("world://office-space/vibe")
+	if err != nil {
+		t.Fatalf("HandleUri error: %v", err)
+	}
+	if vibe := result.(models.Vibe); vibe.ID != "focused-flow" && vibe.ID != "focused-flow+calm-clarity" {
+		t.Errorf("Expected the vibe near the start of the transition, got %s", vibe.ID)
+	}
+}
+
+func TestWorldHierarchyResources(t *testing.T) {
+	repo := repository.NewRepositoryWithSampleData(false)
+	handler := &worldUriHandler{repo: repo}
+	for _, world := range []models.World{
+		{ID: "floor", Name: "Floor", Type: models.WorldTypePhysical},
+		{ID: "room", Name: "Room", Type: models.WorldTypePhysical, ParentID: "floor", Occupancy: 12},
+	} {
+		if err := repo.AddWorld(world); err != nil {
+			t.Fatalf("Error adding world %s: %v", world.ID, err)
+		}
+	}
+
+	result, err := handler.HandleUri("world://floor/children")
+	if children, ok := result.([]models.World); err != nil || !ok || len(children) != 1 || children[0].ID != "room" {
+		t.Errorf("Expected the room as the floor's child, got %+v, %v", result, err)
+	}
+	result, err = handler.HandleUri("world://room/ancestors")
+	if ancestors, ok := result.([]models.World); err != nil || !ok || len(ancestors) != 1 || ancestors[0].ID != "floor" {
+		t.Errorf("Expected the floor as the room's ancestor, got %+v, %v", result, err)
+	}
+	result, err = handler.HandleUri("world://floor/rollup")
+	if rollup, ok := result.(models.WorldRollup); err != nil || !ok || rollup.Occupancy != 12 {
+		t.Errorf("Expected the floor's rollup to count the room, got %+v, %v", result, err)
+	}
+	if _, err := handler.HandleUri("world://missing/children"); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected ErrWorldNotFound, got %v", err)
+	}
+}
+
+func TestWorldAdjacencyTools(t *testing.T) {
+	repo := repository.NewRepository()
+	mcpServer := newMCPServer(repo, nil, nil)
+	handler := &worldUriHandler{repo: repo}
+
+	call := func(name, arguments string) mcp.CallToolResult {
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + name + `","arguments":` + arguments + `}}`
+		response, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("Expected a response to %s, got %+v", name, response)
+		}
+		return response.Result.(mcp.CallToolResult)
+	}
+
+	if result := call("connect_worlds", `{"from":"office-space","to":"hybrid-studio","weight":0.7,"kind":"wall"}`); result.IsError {
+		t.Fatalf("Expected connect_worlds to succeed, got %+v", result)
+	}
+	if result := call("connect_worlds", `{"from":"office-space","to":"virtual-garden","kind":"wormhole"}`); !result.IsError {
+		t.Errorf("Expected an unknown edge kind to be rejected")
+	}
+
+	result, err := handler.HandleUri("world://hybrid-studio/neighbors")
+	edges, ok := result.([]models.WorldEdge)
+	if err != nil || !ok || len(edges) != 1 || edges[0].From != "hybrid-studio" || edges[0].To != "office-space" || edges[0].Weight != 0.7 {
+		t.Errorf("Expected the studio's edge to the office, got %+v, %v", result, err)
+	}
+	result, err = handler.HandleUri(models.WorldGraphURI)
+	if edges, ok := result.([]models.WorldEdge); err != nil || !ok || len(edges) != 1 {
+		t.Errorf("Expected one edge in the graph, got %+v, %v", result, err)
+	}
+
+	// The categorical tools take the context of a world from the graph
+	tools := NewCategoricalToolsWithRepository(repo)
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]interface{}{"contextId": "office", "worldId": "office-space", "transformation": "consensus"}
+	extended, err := tools.CategoricalExtend(context.Background(), req)
+	if err != nil || extended.IsError {
+		t.Fatalf("Expected categorical_extend with a world to succeed, got %+v, %v", extended, err)
+	}
+	var response CategoricalExtendResponse
+	if err := json.Unmarshal([]byte(extended.Content[0].(mcp.TextContent).Text), &response); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	if response.Artifact == nil || response.Artifact.Title != "Extended Context via consensus" {
+		t.Errorf("Expected an extend artifact, got %+v", response.Artifact)
+	}
+	req.Params.Arguments = map[string]interface{}{"contextId": "nowhere", "worldId": "missing", "transformation": "consensus"}
+	if result, _ := tools.CategoricalExtend(context.Background(), req); !result.IsError {
+		t.Errorf("Expected a missing world to be reported")
+	}
+
+	if result := call("disconnect_worlds", `{"from":"hybrid-studio","to":"office-space"}`); result.IsError {
+		t.Fatalf("Expected disconnect_worlds to succeed, got %+v", result)
+	}
+	message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"disconnect_worlds","arguments":{"from":"hybrid-studio","to":"office-space"}}}`
+	if response, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCError); !ok ||
+		!strings.Contains(response.Error.Message, repository.ErrEdgeNotFound.Error()) {
+		t.Errorf("Expected disconnecting twice to fail with ErrEdgeNotFound, got %+v", response)
+	}
+}
+
+func TestIngestSensorReadingTool(t *testing.T) {
+	repo := repository.NewRepository()
+	store := sensors.NewStore(0, 0)
+	mcpServer := newMCPServer(repo, nil, sensors.NewIngester(store, repo))
+
+	call := func(arguments string) mcp.JSONRPCMessage {
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"ingest_sensor_reading","arguments":` + arguments + `}}`
+		return mcpServer.HandleMessage(context.Background(), json.RawMessage(message))
+	}
+
+	timestamp := time.Now().UnixMilli()
+	response, ok := call(fmt.Sprintf(`{"worldId":"virtual-garden","timestamp":%d,"sensorData":{"light":320,"channels":{"noiseLow":31}}}`, timestamp)).(mcp.JSONRPCResponse)
+	if !ok || response.Result.(mcp.CallToolResult).IsError {
+		t.Fatalf("Expected ingest_sensor_reading to succeed, got %+v", response)
+	}
+	latest, ok := store.Latest("virtual-garden")
+	if !ok || latest.Timestamp != timestamp || len(latest.SensorData.Names()) != 2 {
+		t.Errorf("Expected the reading stored, got %+v", latest)
+	}
+
+	response, ok = call(`{"worldId":"virtual-garden","sensorData":{"movement":2}}`).(mcp.JSONRPCResponse)
+	if !ok || !response.Result.(mcp.CallToolResult).IsError {
+		t.Errorf("Expected an invalid reading to be rejected, got %+v", response)
+	}
+	if failed, ok := call(`{"worldId":"nowhere","sensorData":{"light":1}}`).(mcp.JSONRPCError); !ok || !strings.Contains(failed.Error.Message, repository.ErrWorldNotFound.Error()) {
+		t.Errorf("Expected an unknown world to be reported, got %+v", failed)
+	}
+	if latest, _ := store.Latest("virtual-garden"); latest.Timestamp != timestamp {
+		t.Errorf("Expected only the valid reading stored, got %+v", latest)
+	}
+}
+
+func TestSensorHistoryResource(t *testing.T) {
+	repo := repository.NewRepository()
+	store := sensors.NewStore(0, 0)
+	store.SetHistory(sensors.NewTimeSeries(sensors.DefaultRetention))
+	ingester := sensors.NewIngester(store, repo)
+
+	now := time.Now().Truncate(time.Hour)
+	for i, temperature := range []float64{20, 22, 27} {
+		reading := models.SensorReading{WorldID: "office-space", Timestamp: now.Add(time.Duration(i) * 20 * time.Second).UnixMilli()}
+		reading.SensorData.Set(models.SensorTemperature, temperature)
+		if _, err := ingester.Ingest(reading); err != nil {
+			t.Fatalf("Error ingesting reading: %v", err)
+		}
+	}
+
+	handler := &worldUriHandler{repo: repo, history: store.History()}
+	uri := fmt.Sprintf("world://office-space/sensors?from=%d&to=%d&step=1m&channel=temperature", now.Unix(), now.Add(time.Hour).Unix())
+	result, err := handler.HandleUri(uri)
+	history, ok := result.(sensors.QueryResult)
+	if err != nil || !ok {
+		t.Fatalf("Expected the sensor history, got %+v, %v", result, err)
+	}
+	buckets := history.Channels[models.SensorTemperature]
+	if len(buckets) != 1 || buckets[0].Count != 3 || buckets[0].Min != 20 || buckets[0].Max != 27 || buckets[0].Avg != 23 {
+		t.Errorf("Expected one minute of three readings, got %+v", buckets)
+	}
+
+	for _, bad := range []string{
+		"world://nowhere/sensors",
+		"world://office-space/sensors?step=soon",
+		"world://office-space/sensors?at=1700000000",
+	} {
+		if _, err := handler.HandleUri(bad); err == nil {
+			t.Errorf("Expected an error for %s", bad)
+		}
+	}
+	if _, err := (&worldUriHandler{repo: repo}).HandleUri("world://office-space/sensors"); err == nil {
+		t.Errorf("Expected an error without a sensor history")
+	}
+
+	// The history is also served on its own, without the other world resources
+	mcpServer := server.NewMCPServer("sensors", "1.0.0")
+	RegisterSensorHistory(mcpServer, repo, store.History())
+	for _, uri := range []string{"world://office-space/sensors", "world://office-space/sensors?step=1h"} {
+		message := `{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"` + uri + `"}}`
+		if _, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse); !ok {
+			t.Errorf("Expected %s to be served", uri)
+		}
+	}
+}
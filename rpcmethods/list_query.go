@@ -0,0 +1,224 @@
+package rpcmethods
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+	"github.com/bmorphism/vibespace-mcp-go/sensors"
+)
+
+// Query parameters accepted by vibe://list and world://list
+var (
+	vibeListParams  = []string{"mood", "minEnergy", "maxEnergy", "creator", "public", "sort", "order", "limit", "cursor"}
+	worldListParams = append([]string{"type", "feature"}, vibeListParams...)
+)
+
+// splitListURI splits a list URI into its query parameters. ok is false if
+// uri is not listURI, with or without a query string.
+func splitListURI(uri, listURI string) (values url.Values, ok bool, err error) {
+	if uri == listURI {
+		return nil, true, nil
+	}
+	if !strings.HasPrefix(uri, listURI+"?") {
+		return nil, false, nil
+	}
+	values, err = url.ParseQuery(strings.TrimPrefix(uri, listURI+"?"))
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid query in %s: %v", uri, err)
+	}
+	return values, true, nil
+}
+
+// parseVibeQuery converts vibe://list query parameters into a repository query
+func parseVibeQuery(values url.Values) (repository.VibeQuery, error) {
+	var query repository.VibeQuery
+	if err := checkListParams(values, vibeListParams); err != nil {
+		return query, err
+	}
+
+	var err error
+	query.Filter.Mood = values.Get("mood")
+	query.Filter.CreatorID = values.Get("creator")
+	if query.Filter.MinEnergy, err = parseFloatParam(values, "minEnergy"); err != nil {
+		return query, err
+	}
+	if query.Filter.MaxEnergy, err = parseFloatParam(values, "maxEnergy"); err != nil {
+		return query, err
+	}
+	if query.Filter.IsPublic, err = parseBoolParam(values, "public"); err != nil {
+		return query, err
+	}
+	query.ListOptions, err = parseListOptions(values)
+	return query, err
+}
+
+// parseWorldQuery converts world://list query parameters into a repository query
+func parseWorldQuery(values url.Values) (repository.WorldQuery, error) {
+	var query repository.WorldQuery
+	if err := checkListParams(values, worldListParams); err != nil {
+		return query, err
+	}
+
+	var err error
+	query.Filter.Type = models.WorldType(strings.ToUpper(values.Get("type")))
+	query.Filter.Feature = values.Get("feature")
+	query.Filter.Mood = values.Get("mood")
+	query.Filter.CreatorID = values.Get("creator")
+	if query.Filter.MinEnergy, err = parseFloatParam(values, "minEnergy"); err != nil {
+		return query, err
+	}
+	if query.Filter.MaxEnergy, err = parseFloatParam(values, "maxEnergy"); err != nil {
+		return query, err
+	}
+	if query.Filter.IsPublic, err = parseBoolParam(values, "public"); err != nil {
+		return query, err
+	}
+	query.ListOptions, err = parseListOptions(values)
+	return query, err
+}
+
+// parseListOptions reads the sort, order, limit and cursor parameters
+func parseListOptions(values url.Values) (repository.ListOptions, error) {
+	opts := repository.ListOptions{
+		SortBy: repository.SortField(values.Get("sort")),
+		Cursor: values.Get("cursor"),
+	}
+
+	switch order := values.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		return opts, fmt.Errorf("invalid order %q: expected asc or desc", order)
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return opts, fmt.Errorf("invalid limit %q: expected a positive integer", limit)
+		}
+		opts.Limit = n
+	}
+	return opts, nil
+}
+
+// checkListParams rejects parameters that the listing does not understand
+func checkListParams(values url.Values, allowed []string) error {
+	for name := range values {
+		known := false
+		for _, a := range allowed {
+			if name == a {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown query parameter %q (supported: %s)", name, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// parseFloatParam reads an optional numeric parameter
+func parseFloatParam(values url.Values, name string) (*float64, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: expected a number", name, raw)
+	}
+	return &f, nil
+}
+
+// parseBoolParam reads an optional boolean parameter
+func parseBoolParam(values url.Values, name string) (*bool, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: expected true or false", name, raw)
+	}
+	return &b, nil
+}
+
+// splitAtQuery splits an entity path from an optional ?at=<timestamp> query
+func splitAtQuery(path string) (string, *time.Time, error) {
+	i := strings.Index(path, "?")
+	if i < 0 {
+		return path, nil, nil
+	}
+
+	values, err := url.ParseQuery(path[i+1:])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid query in %s: %v", path, err)
+	}
+	if err := checkListParams(values, []string{"at"}); err != nil {
+		return "", nil, err
+	}
+	raw := values.Get("at")
+	if raw == "" {
+		return path[:i], nil, nil
+	}
+	at, err := parseTimestamp(raw)
+	if err != nil {
+		return "", nil, err
+	}
+	return path[:i], &at, nil
+}
+
+// parseTimestamp accepts RFC 3339 timestamps and Unix seconds
+func parseTimestamp(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+		return t, nil
+	}
+	if secs, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q: expected RFC 3339 or Unix seconds", raw)
+}
+
+// sensorHistoryParams are the query parameters of world://{id}/sensors
+var sensorHistoryParams = []string{"from", "to", "step", "channel"}
+
+// parseSensorQuery converts world://{id}/sensors query parameters into a
+// sensor history query. channel may be repeated or a comma-separated list.
+func parseSensorQuery(values url.Values) (sensors.Query, error) {
+	var query sensors.Query
+	if err := checkListParams(values, sensorHistoryParams); err != nil {
+		return query, err
+	}
+
+	var err error
+	if raw := values.Get("from"); raw != "" {
+		if query.From, err = parseTimestamp(raw); err != nil {
+			return query, err
+		}
+	}
+	if raw := values.Get("to"); raw != "" {
+		if query.To, err = parseTimestamp(raw); err != nil {
+			return query, err
+		}
+	}
+	if raw := values.Get("step"); raw != "" {
+		if query.Step, err = time.ParseDuration(raw); err != nil || query.Step <= 0 {
+			return query, fmt.Errorf("invalid step %q: expected a positive duration such as 1m or 1h", raw)
+		}
+	}
+	for _, list := range values["channel"] {
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				query.Channels = append(query.Channels, name)
+			}
+		}
+	}
+	return query, nil
+}
@@ -0,0 +1,192 @@
+package rpcmethods
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+	"github.com/bmorphism/vibespace-mcp-go/schema"
+	"github.com/bmorphism/vibespace-mcp-go/streaming"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// actorParams is accepted by every tool that writes
+type actorParams struct {
+	UserID string `json:"userId,omitempty" jsonschema_description:"User the change is attributed to in history"`
+}
+
+// vibeParams are the arguments of update_vibe
+type vibeParams struct {
+	models.Vibe
+	actorParams
+}
+
+// worldParams are the arguments of update_world
+type worldParams struct {
+	models.World
+	actorParams
+}
+
+// createVibeParams are the arguments of create_vibe, whose id is optional
+type createVibeParams struct {
+	vibeParams
+	ID string `json:"id,omitempty" jsonschema:"maxLength=128,pattern=^[^\\s/?#]+$" jsonschema_description:"Generated from the name when omitted"`
+}
+
+// createWorldParams are the arguments of create_world, whose id is optional
+type createWorldParams struct {
+	worldParams
+	ID string `json:"id,omitempty" jsonschema:"maxLength=128,pattern=^[^\\s/?#]+$" jsonschema_description:"Generated from the name when omitted"`
+}
+
+// idParams are the arguments of tools acting on one entity by ID
+type idParams struct {
+	ID string `json:"id" jsonschema:"required"`
+	actorParams
+}
+
+// deleteVibeParams are the arguments of delete_vibe
+type deleteVibeParams struct {
+	ID            string `json:"id" jsonschema:"required"`
+	Mode          string `json:"mode,omitempty" jsonschema:"enum=reject,enum=reassign,enum=clear" jsonschema_description:"What happens to worlds using the vibe; reject by default"`
+	ReplacementID string `json:"replacementId,omitempty" jsonschema_description:"Vibe for the worlds with mode reassign"`
+	actorParams
+}
+
+// setWorldVibeParams are the arguments of set_world_vibe
+type setWorldVibeParams struct {
+	WorldID  string `json:"worldId" jsonschema:"required"`
+	VibeID   string `json:"vibeId" jsonschema:"required"`
+	Version  int64  `json:"version,omitempty" jsonschema:"minimum=0" jsonschema_description:"Expected world version; 0 skips the check"`
+	Duration int64  `json:"duration,omitempty" jsonschema:"minimum=0" jsonschema_description:"Transition length in milliseconds; 0 switches at once"`
+	actorParams
+}
+
+// connectWorldsParams are the arguments of connect_worlds
+type connectWorldsParams struct {
+	models.WorldEdge
+	actorParams
+}
+
+// disconnectWorldsParams are the arguments of disconnect_worlds
+type disconnectWorldsParams struct {
+	From string `json:"from" jsonschema:"required"`
+	To   string `json:"to" jsonschema:"required"`
+	actorParams
+}
+
+// applyBatchParams are the arguments of apply_batch
+type applyBatchParams struct {
+	Operations []repository.BatchOp `json:"operations" jsonschema:"required"`
+	actorParams
+}
+
+// importBundleParams are the arguments of import_bundle
+type importBundleParams struct {
+	Bundle   *repository.Bundle `json:"bundle" jsonschema:"required"`
+	Strategy string             `json:"strategy,omitempty" jsonschema:"enum=overwrite,enum=skip,enum=fail" jsonschema_description:"What happens to IDs already in use; skip by default"`
+	actorParams
+}
+
+// noParams is the input of tools without arguments
+type noParams struct{}
+
+// toolInputs holds a value of the argument type of every tool, from which
+// its input schema is derived
+var toolInputs = map[string]interface{}{
+	"create_vibe":       createVibeParams{},
+	"update_vibe":       vibeParams{},
+	"delete_vibe":       deleteVibeParams{},
+	"restore_vibe":      idParams{},
+	"create_world":      createWorldParams{},
+	"update_world":      worldParams{},
+	"delete_world":      idParams{},
+	"restore_world":     idParams{},
+	"set_world_vibe":    setWorldVibeParams{},
+	"connect_worlds":    connectWorldsParams{},
+	"disconnect_worlds": disconnectWorldsParams{},
+	"apply_batch":       applyBatchParams{},
+	"export_bundle":     noParams{},
+	"import_bundle":     importBundleParams{},
+
+	"ingest_sensor_reading": models.SensorReading{},
+
+	"streaming_startStreaming": streaming.StartStreamingRequest{},
+	"streaming_stopStreaming":  noParams{},
+	"streaming_status":         noParams{},
+	"streaming_streamWorld":    streaming.StreamWorldRequest{},
+	"streaming_updateConfig":   streaming.UpdateConfigRequest{},
+	"streaming_setInterval":    streaming.SetIntervalRequest{},
+	"streaming_listIntervals":  noParams{},
+	"streaming_clearInterval":  streaming.ClearIntervalRequest{},
+	"streaming_replay":         streaming.ReplayRequest{},
+
+	"categorical_extract":   CategoricalExtractRequest{},
+	"categorical_duplicate": CategoricalDuplicateRequest{},
+	"categorical_extend":    CategoricalExtendRequest{},
+	"ternary_logic_gate":    TernaryLogicGateRequest{},
+}
+
+// schemaResources maps the names of the schema:// resources to values of
+// the types they describe
+var schemaResources = map[string]interface{}{
+	"vibe":          models.Vibe{},
+	"world":         models.World{},
+	"edge":          models.WorldEdge{},
+	"sensorData":    models.SensorData{},
+	"sensorChannel": models.SensorChannel{},
+	"sensorReading": models.SensorReading{},
+	"sharing":       models.SharingSettings{},
+	"moment":        models.WorldMoment{},
+	"batchOp":       repository.BatchOp{},
+	"bundle":        repository.Bundle{},
+}
+
+// ToolInputSchema returns the JSON Schema of the arguments of the named
+// tool, or an unconstrained object schema for an unknown tool
+func ToolInputSchema(name string) json.RawMessage {
+	input, ok := toolInputs[name]
+	if !ok {
+		return json.RawMessage(`{"type":"object"}`)
+	}
+	return schema.For(input).JSON()
+}
+
+// WithInputSchema sets the input schema of a tool built with mcp.NewTool to
+// the one derived for the named tool
+func WithInputSchema(name string) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		t.InputSchema = mcp.ToolInputSchema{}
+		t.RawInputSchema = ToolInputSchema(name)
+	}
+}
+
+// RegisterSchemaResources publishes the schema of each model as
+// schema://{name}
+func RegisterSchemaResources(mcpServer *server.MCPServer) {
+	for name, value := range schemaResources {
+		uri := models.SchemaScheme + name
+		data, err := json.MarshalIndent(schema.Document(name, value), "", "  ")
+		if err != nil {
+			panic(fmt.Sprintf("encoding schema %s: %v", name, err))
+		}
+
+		mcpServer.AddResource(mcp.Resource{
+			URI:         uri,
+			Name:        name + " schema",
+			Description: fmt.Sprintf("JSON Schema of a %s", name),
+			MIMEType:    "application/schema+json",
+		}, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{
+				mcp.TextResourceContents{
+					URI:      uri,
+					MIMEType: "application/schema+json",
+					Text:     string(data),
+				},
+			}, nil
+		})
+	}
+}
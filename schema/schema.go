@@ -0,0 +1,249 @@
+// Package schema derives JSON Schemas from Go types, so MCP clients can see
+// the fields, enums and ranges that tools and resources accept.
+//
+// Properties come from the json struct tags. Constraints come from the
+// jsonschema tag, a comma-separated list of:
+//
+//	required          the property must be present
+//	enum=VALUE        an allowed value; repeat for each one
+//	minimum=N         inclusive lower bound
+//	maximum=N         inclusive upper bound
+//	maxLength=N       longest allowed string
+//	pattern=REGEXP    regular expression strings must match (no commas)
+//	format=NAME       string format, such as date-time
+//
+// On a slice field, every constraint but required applies to the elements.
+// A jsonschema_description tag sets the description.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect of generated documents
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// For returns the schema of v's type
+func For(v interface{}) *Schema {
+	return Of(reflect.TypeOf(v))
+}
+
+// Of returns the schema of t
+func Of(t reflect.Type) *Schema {
+	return (&generator{inProgress: make(map[reflect.Type]bool)}).schema(t)
+}
+
+// Document returns the schema of v's type as a standalone document
+func Document(title string, v interface{}) *Schema {
+	s := For(v)
+	s.Schema = Draft
+	s.Title = title
+	return s
+}
+
+// JSON encodes s, panicking on failure since a Schema always encodes
+func (s *Schema) JSON() json.RawMessage {
+	data, err := json.Marshal(s)
+	if err != nil {
+		panic(fmt.Sprintf("schema: %v", err))
+	}
+	return data
+}
+
+type generator struct {
+	inProgress map[reflect.Type]bool // Structs being generated, to cut cycles
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return &Schema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.object(t)
+	}
+	return &Schema{} // Interfaces and anything else accept any value
+}
+
+// object builds the schema of a struct, flattening embedded structs the way
+// encoding/json does
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	if g.inProgress[t] {
+		return s // A recursive reference; leave it open
+	}
+	g.inProgress[t] = true
+	defer delete(g.inProgress, t)
+
+	g.addFields(s, t, nil)
+	return s
+}
+
+// addFields adds the properties of t's fields to s. As in encoding/json, a
+// field hides any field of the same name in a struct embedded beside it, so
+// shadowed names the fields of outer structs, which are skipped.
+func (g *generator) addFields(s *Schema, t reflect.Type, shadowed map[string]bool) {
+	outer := make(map[string]bool, len(shadowed))
+	for name := range shadowed {
+		outer[name] = true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name, tagged := jsonName(field); (tagged || !field.Anonymous) && field.PkgPath == "" {
+			outer[name] = true
+		}
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, tagged := jsonName(field)
+		if name == "-" || shadowed[name] {
+			continue
+		}
+
+		if field.Anonymous && !tagged {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(s, embedded, outer)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue // Unexported
+		}
+
+		property := g.schema(field.Type)
+		required := constrain(property, field)
+		s.Properties[name] = property
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// jsonName returns the JSON name of a field and whether its json tag names it
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		return field.Name, false
+	}
+	return name, true
+}
+
+// constrain applies a field's jsonschema tags to its schema and reports
+// whether the field is required
+func constrain(s *Schema, field reflect.StructField) bool {
+	s.Description = field.Tag.Get("jsonschema_description")
+
+	target := s
+	if s.Type == "array" && s.Items != nil {
+		target = s.Items
+	}
+
+	required := false
+	for _, option := range strings.Split(field.Tag.Get("jsonschema"), ",") {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "required":
+			required = true
+		case "enum":
+			target.Enum = append(target.Enum, enumValue(target.Type, value))
+		case "minimum":
+			target.Minimum = parseFloat(field, value)
+		case "maximum":
+			target.Maximum = parseFloat(field, value)
+		case "maxLength":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				panic(fmt.Sprintf("schema: field %s: invalid maxLength %q", field.Name, value))
+			}
+			target.MaxLength = &n
+		case "pattern":
+			target.Pattern = value
+		case "format":
+			target.Format = value
+		case "":
+		default:
+			panic(fmt.Sprintf("schema: field %s: unknown jsonschema option %q", field.Name, key))
+		}
+	}
+	return required
+}
+
+// enumValue converts an enum tag value to the JSON type of the schema
+func enumValue(schemaType, value string) interface{} {
+	if schemaType == "integer" || schemaType == "number" {
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	}
+	return value
+}
+
+func parseFloat(field reflect.StructField, value string) *float64 {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		panic(fmt.Sprintf("schema: field %s: invalid bound %q", field.Name, value))
+	}
+	return &n
+}
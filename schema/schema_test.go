@@ -0,0 +1,112 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type sample struct {
+	ID       string            `json:"id" jsonschema:"required,maxLength=8,pattern=^[a-z]+$"`
+	Level    float64           `json:"level" jsonschema:"minimum=0,maximum=1" jsonschema_description:"How much"`
+	Kind     string            `json:"kind,omitempty" jsonschema:"enum=a,enum=b"`
+	Digits   []int8            `json:"digits,omitempty" jsonschema:"enum=-1,enum=0,enum=1"`
+	When     time.Time         `json:"when"`
+	Raw      json.RawMessage   `json:"raw,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Data     []byte            `json:"data,omitempty"`
+	Next     *sample           `json:"next,omitempty"`
+	Ignored  string            `json:"-"`
+	internal string
+	embedded
+}
+
+type embedded struct {
+	Actor string `json:"actor,omitempty"`
+}
+
+func TestFor(t *testing.T) {
+	s := For(sample{})
+
+	if s.Type != "object" || !reflect.DeepEqual(s.Required, []string{"id"}) {
+		t.Fatalf("Expected an object requiring id, got %+v", s)
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	if len(names) != 10 {
+		t.Errorf("Expected 10 properties including the embedded actor, got %v", names)
+	}
+
+	id := s.Properties["id"]
+	if id.Type != "string" || *id.MaxLength != 8 || id.Pattern != "^[a-z]+$" {
+		t.Errorf("Unexpected id schema %+v", id)
+	}
+	level := s.Properties["level"]
+	if level.Type != "number" || *level.Minimum != 0 || *level.Maximum != 1 || level.Description != "How much" {
+		t.Errorf("Unexpected level schema %+v", level)
+	}
+	if kind := s.Properties["kind"]; !reflect.DeepEqual(kind.Enum, []interface{}{"a", "b"}) {
+		t.Errorf("Expected kind to be an enum, got %+v", kind)
+	}
+	// Constraints on a slice apply to its items
+	if digits := s.Properties["digits"]; digits.Type != "array" || !reflect.DeepEqual(digits.Items.Enum, []interface{}{-1.0, 0.0, 1.0}) {
+		t.Errorf("Expected digits items to be an enum, got %+v", digits)
+	}
+	if when := s.Properties["when"]; when.Type != "string" || when.Format != "date-time" {
+		t.Errorf("Expected when to be a date-time, got %+v", when)
+	}
+	if raw := s.Properties["raw"]; raw.Type != "" {
+		t.Errorf("Expected raw to accept anything, got %+v", raw)
+	}
+	if labels := s.Properties["labels"]; labels.Type != "object" || labels.AdditionalProperties.Type != "string" {
+		t.Errorf("Expected labels to be a string map, got %+v", labels)
+	}
+	if data := s.Properties["data"]; data.Type != "string" || data.ContentEncoding != "base64" {
+		t.Errorf("Expected data to be base64, got %+v", data)
+	}
+	// Recursive references are left open instead of looping
+	if next := s.Properties["next"]; next.Type != "object" || len(next.Properties) != 0 {
+		t.Errorf("Expected next to be an open object, got %+v", next)
+	}
+}
+
+func TestOuterFieldHidesEmbedded(t *testing.T) {
+	s := For(struct {
+		sample
+		ID string `json:"id,omitempty" jsonschema_description:"Optional here"`
+	}{})
+
+	if len(s.Required) != 0 {
+		t.Errorf("Expected the outer id to drop the embedded requirement, got %v", s.Required)
+	}
+	if id := s.Properties["id"]; id.Description != "Optional here" || id.MaxLength != nil {
+		t.Errorf("Expected the outer id schema, got %+v", id)
+	}
+	if _, ok := s.Properties["level"]; !ok {
+		t.Errorf("Expected the other embedded fields to remain")
+	}
+}
+
+func TestDocument(t *testing.T) {
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(Document("sample", sample{}).JSON(), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON: %v", err)
+	}
+	if decoded["$schema"] != Draft || decoded["title"] != "sample" {
+		t.Errorf("Expected a titled draft document, got %v", decoded)
+	}
+}
+
+func TestUnknownOptionPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected an unknown option to panic")
+		}
+	}()
+	For(struct {
+		X string `json:"x" jsonschema:"minLength=2"`
+	}{})
+}
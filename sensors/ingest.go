@@ -0,0 +1,148 @@
+package sensors
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// ErrWorldMismatch is returned when a reading names another world than the
+// one it was sent to
+var ErrWorldMismatch = errors.New("reading is for another world")
+
+// maxRequestBytes limits the size of a request to the HTTP endpoint
+const maxRequestBytes = 1 << 20
+
+// WorldSource looks up worlds; readings are only accepted for its worlds
+type WorldSource interface {
+	GetWorld(id string) (models.World, error)
+}
+
+// Ingester accepts sensor readings for the worlds of a WorldSource and
+// records them in a Store
+type Ingester struct {
+	store  *Store
+	worlds WorldSource
+}
+
+// NewIngester creates an ingester recording readings in store
+func NewIngester(store *Store, worlds WorldSource) *Ingester {
+	return &Ingester{store: store, worlds: worlds}
+}
+
+// Store returns the store readings are recorded in
+func (i *Ingester) Store() *Store {
+	return i.store
+}
+
+// Ingest records a reading of an existing world and returns it as stored
+func (i *Ingester) Ingest(reading models.SensorReading) (models.SensorReading, error) {
+	if err := i.check(reading); err != nil {
+		return models.SensorReading{}, err
+	}
+	return i.store.Record(reading)
+}
+
+// check validates a reading and makes sure its world exists
+func (i *Ingester) check(reading models.SensorReading) error {
+	if err := reading.ValidateAt(i.store.now()); err != nil {
+		return err
+	}
+	_, err := i.worlds.GetWorld(reading.WorldID)
+	return err
+}
+
+// IngestJSON records a reading, or an array of readings, encoded as JSON.
+// Readings without a world ID are for worldID, and readings for another
+// world than a non-empty worldID are rejected. Either every reading is
+// recorded or, if any is invalid, none is.
+func (i *Ingester) IngestJSON(worldID string, data []byte) ([]models.SensorReading, error) {
+	var readings []models.SensorReading
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &readings); err != nil {
+			return nil, fmt.Errorf("invalid readings: %w", err)
+		}
+	} else {
+		var reading models.SensorReading
+		if err := json.Unmarshal(trimmed, &reading); err != nil {
+			return nil, fmt.Errorf("invalid reading: %w", err)
+		}
+		readings = []models.SensorReading{reading}
+	}
+
+	for n := range readings {
+		reading := &readings[n]
+		if reading.WorldID == "" {
+			reading.WorldID = worldID
+		} else if worldID != "" && reading.WorldID != worldID {
+			return nil, fmt.Errorf("reading %d: %w: %s", n, ErrWorldMismatch, reading.WorldID)
+		}
+		if err := i.check(*reading); err != nil {
+			return nil, fmt.Errorf("reading %d: %w", n, err)
+		}
+	}
+	for n, reading := range readings {
+		stored, err := i.store.Record(reading)
+		if err != nil {
+			return nil, fmt.Errorf("reading %d: %w", n, err)
+		}
+		readings[n] = stored
+	}
+	return readings, nil
+}
+
+// ServeHTTP accepts readings POSTed as JSON, either one reading or an array,
+// and answers with the readings as stored. A worldId query parameter sets
+// the world of readings that do not name one.
+func (i *Ingester) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBytes))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error reading request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	readings, err := i.IngestJSON(r.URL.Query().Get("worldId"), body)
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(statusOf(err))
+		json.NewEncoder(w).Encode(ErrorResponse(err))
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"readings": readings,
+	})
+}
+
+// ErrorResponse describes a failed ingestion, listing the invalid fields of
+// a reading that did not validate
+func ErrorResponse(err error) map[string]interface{} {
+	response := map[string]interface{}{
+		"success": false,
+		"error":   err.Error(),
+	}
+	var invalid *models.ValidationError
+	if errors.As(err, &invalid) {
+		response["fields"] = invalid.Fields
+	}
+	return response
+}
+
+// statusOf returns the HTTP status for an ingestion error
+func statusOf(err error) int {
+	if errors.Is(err, repository.ErrWorldNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
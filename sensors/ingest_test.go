@@ -0,0 +1,84 @@
+package sensors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestJSON(t *testing.T) {
+	store := NewStore(0, 0)
+	store.now = func() time.Time { return time.UnixMilli(2000) }
+	ingester := NewIngester(store, repository.NewRepository())
+
+	readings, err := ingester.IngestJSON("office-space", []byte(`[
+		{"timestamp": 1000, "sensorData": {"temperature": 21}},
+		{"timestamp": 2000, "sensorData": {"channels": {"co2": 700}}}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, readings, 2)
+	assert.Equal(t, "office-space", readings[1].WorldID)
+	assert.Equal(t, []string{models.SensorCO2, models.SensorTemperature}, ingester.Store().Current("office-space").Names())
+
+	// One bad reading rejects the whole array
+	_, err = ingester.IngestJSON("", []byte(`[
+		{"worldId": "virtual-garden", "sensorData": {"light": 200}},
+		{"worldId": "nowhere", "sensorData": {"light": 200}}
+	]`))
+	assert.True(t, errors.Is(err, repository.ErrWorldNotFound))
+	assert.Empty(t, ingester.Store().Recent("virtual-garden"))
+
+	_, err = ingester.IngestJSON("office-space", []byte(`{"worldId": "virtual-garden", "sensorData": {"light": 200}}`))
+	assert.True(t, errors.Is(err, ErrWorldMismatch))
+	_, err = ingester.IngestJSON("office-space", []byte(`{"sensorData": {"movement": 3}}`))
+	assert.True(t, errors.Is(err, models.ErrValidation))
+	_, err = ingester.IngestJSON("office-space", []byte(`{"timestamp": 99999999999999, "sensorData": {"light": 200}}`))
+	assert.True(t, errors.Is(err, models.ErrValidation), "a reading from the future is rejected")
+	_, err = ingester.IngestJSON("office-space", []byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestIngesterServeHTTP(t *testing.T) {
+	ingester := NewIngester(NewStore(0, 0), repository.NewRepository())
+
+	post := func(target, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		ingester.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+		return recorder
+	}
+
+	recorder := post("/sensors/readings?worldId=hybrid-studio", `{"sensorData": {"sound": 55, "channels": {"occupancyCount": 4}}}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var response struct {
+		Success  bool                   `json:"success"`
+		Readings []models.SensorReading `json:"readings"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.True(t, response.Success)
+	require.Len(t, response.Readings, 1)
+	assert.NotZero(t, response.Readings[0].Timestamp)
+
+	assert.Equal(t, http.StatusNotFound, post("/sensors/readings", `{"worldId": "nowhere", "sensorData": {"sound": 55}}`).Code)
+
+	recorder = post("/sensors/readings", `{"worldId": "hybrid-studio", "sensorData": {"humidity": 120}}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	var failure struct {
+		Fields []models.FieldError `json:"fields"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &failure))
+	require.Len(t, failure.Fields, 1)
+	assert.Equal(t, "sensorData.humidity", failure.Fields[0].Field)
+
+	recorder = httptest.NewRecorder()
+	ingester.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/sensors/readings", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
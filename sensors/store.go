@@ -0,0 +1,169 @@
+// Package sensors records the sensor readings of worlds, so moments can
+// carry what was actually measured.
+//
+// Readings reach a Store through an Ingester, which the MCP tool, the HTTP
+// endpoint and the NATS subscription share. The Store keeps a recent window
+// of readings per world, and Current merges them into the latest value of
+// each channel. A TimeSeries attached to the Store keeps their longer
+// history, downsampled to minutes and hours, for queries over time.
+package sensors
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// Defaults for the recent window kept per world
+const (
+	DefaultWindow      = 15 * time.Minute
+	DefaultMaxReadings = 1000
+)
+
+// Store keeps the recent sensor readings of each world in memory. It holds at
+// most MaxReadings readings per world, dropping those more than Window older
+// than its clock when it records another, and reports only those taken
+// within Window of its clock, so a world whose sensors went quiet reads as
+// unmeasured. It is safe for concurrent use.
+type Store struct {
+	mu          sync.RWMutex
+	window      time.Duration
+	maxReadings int
+	worlds      map[string][]models.SensorReading // Oldest first
+	history     *TimeSeries
+	now         func() time.Time
+}
+
+// NewStore creates a store keeping readings for window, at most maxReadings
+// per world. Zero values select DefaultWindow and DefaultMaxReadings.
+func NewStore(window time.Duration, maxReadings int) *Store {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	if maxReadings <= 0 {
+		maxReadings = DefaultMaxReadings
+	}
+	return &Store{
+		window:      window,
+		maxReadings: maxReadings,
+		worlds:      make(map[string][]models.SensorReading),
+		now:         time.Now,
+	}
+}
+
+// Record validates and stores a reading, stamped with the current time if it
+// has none, and returns it as stored. Readings arriving late are placed in
+// time order; readings from the future are rejected.
+func (s *Store) Record(reading models.SensorReading) (models.SensorReading, error) {
+	now := s.now()
+	if reading.Timestamp == 0 {
+		reading.Timestamp = now.UnixNano() / int64(time.Millisecond)
+	}
+	if err := reading.ValidateAt(now); err != nil {
+		return models.SensorReading{}, err
+	}
+	reading.SensorData.Channels = copyChannels(reading.SensorData.Channels)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	readings := s.worlds[reading.WorldID]
+	i := sort.Search(len(readings), func(i int) bool { return readings[i].Timestamp > reading.Timestamp })
+	readings = append(readings, models.SensorReading{})
+	copy(readings[i+1:], readings[i:])
+	readings[i] = reading
+	s.worlds[reading.WorldID] = s.prune(readings)
+	if s.history != nil {
+		s.history.Add(reading)
+	}
+	return reading, nil
+}
+
+// SetHistory makes the store add every reading it records to history too
+func (s *Store) SetHistory(history *TimeSeries) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = history
+}
+
+// History returns the time series readings are added to, or nil
+func (s *Store) History() *TimeSeries {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.history
+}
+
+// prune drops the readings that fell out of the window before now
+func (s *Store) prune(readings []models.SensorReading) []models.SensorReading {
+	oldest := s.now().UnixNano()/int64(time.Millisecond) - s.window.Milliseconds()
+	start := sort.Search(len(readings), func(i int) bool { return readings[i].Timestamp >= oldest })
+	if n := len(readings) - s.maxReadings; n > start {
+		start = n
+	}
+	if start == 0 {
+		return readings
+	}
+	return append([]models.SensorReading(nil), readings[start:]...)
+}
+
+// inWindow returns the readings taken within the window before now
+func (s *Store) inWindow(readings []models.SensorReading) []models.SensorReading {
+	oldest := s.now().UnixNano()/int64(time.Millisecond) - s.window.Milliseconds()
+	start := sort.Search(len(readings), func(i int) bool { return readings[i].Timestamp >= oldest })
+	return readings[start:]
+}
+
+// Latest returns the newest reading of a world
+func (s *Store) Latest(worldID string) (models.SensorReading, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	readings := s.worlds[worldID]
+	if len(readings) == 0 {
+		return models.SensorReading{}, false
+	}
+	return readings[len(readings)-1], true
+}
+
+// Recent returns the readings of a world in the window, oldest first
+func (s *Store) Recent(worldID string) []models.SensorReading {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]models.SensorReading{}, s.inWindow(s.worlds[worldID])...)
+}
+
+// Current returns the latest value of each channel measured in a world
+// within the window, or empty sensor data if nothing was measured
+func (s *Store) Current(worldID string) models.SensorData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var current models.SensorData
+	for _, reading := range s.inWindow(s.worlds[worldID]) {
+		for name, v := range reading.SensorData.Readings() {
+			current.Set(name, v)
+		}
+	}
+	return current
+}
+
+// Forget drops the readings of a world
+func (s *Store) Forget(worldID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.worlds, worldID)
+}
+
+// copyChannels keeps the store's readings apart from the caller's map
+func copyChannels(channels map[string]float64) map[string]float64 {
+	if channels == nil {
+		return nil
+	}
+	copied := make(map[string]float64, len(channels))
+	for name, v := range channels {
+		copied[name] = v
+	}
+	return copied
+}
@@ -0,0 +1,132 @@
+package sensors
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reading builds a reading of one channel at a Unix time in seconds
+func reading(worldID string, seconds int64, name string, v float64) models.SensorReading {
+	r := models.SensorReading{WorldID: worldID, Timestamp: seconds * 1000}
+	r.SensorData.Set(name, v)
+	return r
+}
+
+func TestStoreRecord(t *testing.T) {
+	store := NewStore(time.Minute, 3)
+	now := time.Unix(130, 0)
+	store.now = func() time.Time { return now }
+
+	_, err := store.Record(reading("office", 100, models.SensorTemperature, 21))
+	require.NoError(t, err)
+	_, err = store.Record(reading("office", 130, models.SensorCO2, 800))
+	require.NoError(t, err)
+	// A late reading goes in time order and does not hide newer values
+	_, err = store.Record(reading("office", 110, models.SensorTemperature, 19))
+	require.NoError(t, err)
+
+	latest, ok := store.Latest("office")
+	require.True(t, ok)
+	assert.Equal(t, int64(130000), latest.Timestamp)
+
+	current := store.Current("office")
+	temperature, _ := current.Get(models.SensorTemperature)
+	co2, _ := current.Get(models.SensorCO2)
+	assert.Equal(t, 19.0, temperature)
+	assert.Equal(t, 800.0, co2)
+
+	// The window follows the clock and holds at most three readings
+	now = time.Unix(165, 0)
+	_, err = store.Record(reading("office", 165, models.SensorTemperature, 22))
+	require.NoError(t, err)
+	timestamps := []int64{}
+	for _, r := range store.Recent("office") {
+		timestamps = append(timestamps, r.Timestamp/1000)
+	}
+	assert.Equal(t, []int64{110, 130, 165}, timestamps)
+	now = time.Unix(200, 0)
+	_, err = store.Record(reading("office", 200, models.SensorLight, 300))
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.SensorLight, models.SensorTemperature}, store.Current("office").Names())
+
+	store.Forget("office")
+	_, ok = store.Latest("office")
+	assert.False(t, ok)
+	assert.True(t, store.Current("office").IsEmpty())
+}
+
+func TestStoreStaleReadings(t *testing.T) {
+	store := NewStore(time.Minute, 0)
+	now := time.Unix(100, 0)
+	store.now = func() time.Time { return now }
+
+	_, err := store.Record(reading("office", 100, models.SensorTemperature, 21))
+	require.NoError(t, err)
+	_, err = store.Record(reading("office", 130, models.SensorCO2, 800))
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.SensorCO2, models.SensorTemperature}, store.Current("office").Names())
+
+	// Readings age out with the clock, even when no newer ones arrive
+	now = time.Unix(170, 0)
+	assert.Equal(t, []string{models.SensorCO2}, store.Current("office").Names())
+	assert.Len(t, store.Recent("office"), 1)
+	now = time.Unix(200, 0)
+	assert.True(t, store.Current("office").IsEmpty(), "Sensors quiet for the whole window measure nothing")
+	assert.Empty(t, store.Recent("office"))
+
+	latest, ok := store.Latest("office")
+	require.True(t, ok, "The newest reading is still known")
+	assert.Equal(t, int64(130000), latest.Timestamp)
+}
+
+func TestStoreRecordStampsAndValidates(t *testing.T) {
+	store := NewStore(0, 0)
+	store.now = func() time.Time { return time.UnixMilli(1700000000000) }
+
+	stored, err := store.Record(reading("office", 0, models.SensorHumidity, 45))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1700000000000), stored.Timestamp)
+
+	_, err = store.Record(reading("office", 1, models.SensorHumidity, 140))
+	assert.True(t, errors.Is(err, models.ErrValidation))
+	_, err = store.Record(models.SensorReading{WorldID: "office"})
+	assert.True(t, errors.Is(err, models.ErrValidation), "a reading needs a value")
+	assert.Len(t, store.Recent("office"), 1)
+
+	// The store keeps its own copy of the channels
+	r := reading("office", 1700000001, models.SensorCO2, 500)
+	_, err = store.Record(r)
+	require.NoError(t, err)
+	r.SensorData.Channels[models.SensorCO2] = 9000
+	co2, _ := store.Current("office").Get(models.SensorCO2)
+	assert.Equal(t, 500.0, co2)
+}
+
+func TestStoreFutureReadings(t *testing.T) {
+	store := NewStore(time.Minute, 0)
+	store.now = func() time.Time { return time.Unix(1000, 0) }
+
+	_, err := store.Record(reading("office", 990, models.SensorTemperature, 21))
+	require.NoError(t, err)
+	_, err = store.Record(reading("office", 995, models.SensorCO2, 800))
+	require.NoError(t, err)
+
+	// A sensor whose clock is a year ahead must not push the rest out of the window
+	_, err = store.Record(reading("office", 1000+365*24*3600, models.SensorTemperature, 99))
+	assert.True(t, errors.Is(err, models.ErrValidation))
+	assert.Len(t, store.Recent("office"), 2)
+
+	// Clocks running slightly ahead are tolerated, and the window stays on the store's clock
+	_, err = store.Record(reading("office", 1030, models.SensorTemperature, 22))
+	require.NoError(t, err)
+	timestamps := []int64{}
+	for _, r := range store.Recent("office") {
+		timestamps = append(timestamps, r.Timestamp/1000)
+	}
+	assert.Equal(t, []int64{990, 995, 1030}, timestamps)
+}
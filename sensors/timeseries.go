@@ -0,0 +1,353 @@
+package sensors
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// Channels the TimeSeries records from published moments
+const (
+	MomentOccupancy = "moment.occupancy"
+	MomentActivity  = "moment.activity"
+)
+
+// ErrInvalidQuery is returned for a time range or step that cannot be served
+var ErrInvalidQuery = errors.New("invalid sensor history query")
+
+// maxQueryBuckets limits the buckets a query returns per channel
+const maxQueryBuckets = 10000
+
+// Retention says how long a TimeSeries keeps each resolution. Points older
+// than the retention, counted back from now, are dropped as new ones arrive.
+type Retention struct {
+	Raw    time.Duration `json:"raw"`    // Every reading
+	Minute time.Duration `json:"minute"` // Per-minute buckets
+	Hour   time.Duration `json:"hour"`   // Per-hour buckets
+}
+
+// DefaultRetention keeps a day of readings, a week of minutes and 90 days
+// of hours
+var DefaultRetention = Retention{
+	Raw:    24 * time.Hour,
+	Minute: 7 * 24 * time.Hour,
+	Hour:   90 * 24 * time.Hour,
+}
+
+// Bucket summarizes the values of a channel from Start over a step. A raw
+// reading is a bucket with a Count of one.
+type Bucket struct {
+	Start int64   `json:"start"` // Unix milliseconds
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+	Sum   float64 `json:"sum"`
+	Last  float64 `json:"last"`
+	// Value combines the values with the aggregation rule of the channel
+	Value float64 `json:"value"`
+
+	lastAt int64 // Time of the Last value
+}
+
+// newBucket creates a bucket holding a single value taken at t
+func newBucket(start, t int64, v float64) Bucket {
+	return Bucket{Start: start, Count: 1, Min: v, Max: v, Avg: v, Sum: v, Last: v, lastAt: t}
+}
+
+// merge adds the values of other to b
+func (b *Bucket) merge(other Bucket) {
+	b.Count += other.Count
+	b.Sum += other.Sum
+	b.Avg = b.Sum / float64(b.Count)
+	b.Min = math.Min(b.Min, other.Min)
+	b.Max = math.Max(b.Max, other.Max)
+	if other.lastAt >= b.lastAt {
+		b.Last, b.lastAt = other.Last, other.lastAt
+	}
+}
+
+// valueFor sets Value by the aggregation rule of a channel
+func (b *Bucket) valueFor(channel models.SensorChannel) {
+	switch channel.Aggregation {
+	case models.AggregateSum:
+		b.Value = b.Sum
+	case models.AggregateMin:
+		b.Value = b.Min
+	case models.AggregateMax:
+		b.Value = b.Max
+	case models.AggregateLast:
+		b.Value = b.Last
+	default:
+		b.Value = b.Avg
+	}
+}
+
+// series holds one channel of one world at every resolution, oldest first
+type series struct {
+	raw     []Bucket
+	minutes []Bucket
+	hours   []Bucket
+}
+
+// add records the value v taken at t
+func (s *series) add(t int64, v float64) {
+	s.raw = insertBucket(s.raw, false, t, t, v)
+	s.minutes = insertBucket(s.minutes, true, floorTo(t, time.Minute), t, v)
+	s.hours = insertBucket(s.hours, true, floorTo(t, time.Hour), t, v)
+}
+
+// prune drops what is past the retention at now
+func (s *series) prune(retention Retention, now int64) {
+	s.raw = dropBefore(s.raw, now-retention.Raw.Milliseconds())
+	s.minutes = dropBefore(s.minutes, now-retention.Minute.Milliseconds())
+	s.hours = dropBefore(s.hours, now-retention.Hour.Milliseconds())
+}
+
+// insertBucket adds the value v taken at t as a bucket starting at start,
+// in order. With merge, it goes into an existing bucket with that start.
+func insertBucket(buckets []Bucket, merge bool, start, t int64, v float64) []Bucket {
+	i := sort.Search(len(buckets), func(i int) bool { return buckets[i].Start >= start })
+	if merge && i < len(buckets) && buckets[i].Start == start {
+		buckets[i].merge(newBucket(start, t, v))
+		return buckets
+	}
+	for i < len(buckets) && buckets[i].Start == start {
+		i++ // Raw readings taken at the same time keep their order
+	}
+	buckets = append(buckets, Bucket{})
+	copy(buckets[i+1:], buckets[i:])
+	buckets[i] = newBucket(start, t, v)
+	return buckets
+}
+
+// dropBefore removes the buckets starting before cutoff
+func dropBefore(buckets []Bucket, cutoff int64) []Bucket {
+	i := sort.Search(len(buckets), func(i int) bool { return buckets[i].Start >= cutoff })
+	if i == 0 {
+		return buckets
+	}
+	return append([]Bucket(nil), buckets[i:]...)
+}
+
+// floorTo rounds a time in Unix milliseconds down to a multiple of step
+func floorTo(t int64, step time.Duration) int64 {
+	ms := step.Milliseconds()
+	return t - ((t%ms)+ms)%ms
+}
+
+// TimeSeries keeps the history of sensor readings per world and channel,
+// downsampled to minutes and hours so it can be kept for longer than the
+// readings themselves. It lives in memory and is safe for concurrent use.
+type TimeSeries struct {
+	mu        sync.Mutex
+	retention Retention
+	worlds    map[string]map[string]*series // World ID, then channel name
+	now       func() time.Time
+}
+
+// NewTimeSeries creates a time series with the given retention. Zero
+// durations select those of DefaultRetention.
+func NewTimeSeries(retention Retention) *TimeSeries {
+	if retention.Raw <= 0 {
+		retention.Raw = DefaultRetention.Raw
+	}
+	if retention.Minute <= 0 {
+		retention.Minute = DefaultRetention.Minute
+	}
+	if retention.Hour <= 0 {
+		retention.Hour = DefaultRetention.Hour
+	}
+	return &TimeSeries{
+		retention: retention,
+		worlds:    make(map[string]map[string]*series),
+		now:       time.Now,
+	}
+}
+
+// Retention returns how long each resolution is kept
+func (ts *TimeSeries) Retention() Retention {
+	return ts.retention
+}
+
+// Add records every value of a reading. The reading must have a timestamp.
+func (ts *TimeSeries) Add(reading models.SensorReading) {
+	for name, v := range reading.SensorData.Readings() {
+		ts.AddValue(reading.WorldID, name, reading.Timestamp, v)
+	}
+}
+
+// AddMoment records the occupancy and activity of a moment, as the
+// MomentOccupancy and MomentActivity channels
+func (ts *TimeSeries) AddMoment(moment *models.WorldMoment) {
+	ts.AddValue(moment.WorldID, MomentOccupancy, moment.Timestamp, float64(moment.Occupancy))
+	ts.AddValue(moment.WorldID, MomentActivity, moment.Timestamp, moment.Activity)
+}
+
+// AddValue records a value of a channel taken at t, in Unix milliseconds
+func (ts *TimeSeries) AddValue(worldID, channel string, t int64, v float64) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	channels, ok := ts.worlds[worldID]
+	if !ok {
+		channels = make(map[string]*series)
+		ts.worlds[worldID] = channels
+	}
+	s, ok := channels[channel]
+	if !ok {
+		s = &series{}
+		channels[channel] = s
+	}
+	s.add(t, v)
+	s.prune(ts.retention, ts.nowMillis())
+}
+
+// Forget drops the history of a world
+func (ts *TimeSeries) Forget(worldID string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	delete(ts.worlds, worldID)
+}
+
+func (ts *TimeSeries) nowMillis() int64 {
+	return ts.now().UnixNano() / int64(time.Millisecond)
+}
+
+// Query selects the history of a world
+type Query struct {
+	From     time.Time     // Inclusive; zero means an hour before To
+	To       time.Time     // Exclusive; zero means now
+	Step     time.Duration // Width of the buckets; zero returns every reading
+	Channels []string      // Channels to return; empty means all
+}
+
+// QueryResult is the history of the channels of a world in buckets of Step,
+// oldest first. Channels without data in the range have no buckets.
+type QueryResult struct {
+	WorldID string `json:"worldId"`
+	From    int64  `json:"from"` // Unix milliseconds
+	To      int64  `json:"to"`
+	Step    string `json:"step,omitempty"`
+	// Resolution is the stored series the buckets were computed from: "raw",
+	// "1m" or "1h"
+	Resolution string              `json:"resolution"`
+	Channels   map[string][]Bucket `json:"channels"`
+}
+
+// resolution is one of the stored series
+type resolution struct {
+	name      string
+	size      time.Duration // Zero for raw readings
+	retention time.Duration
+	buckets   func(*series) []Bucket
+}
+
+// resolutions lists the stored series from the finest
+func (ts *TimeSeries) resolutions() []resolution {
+	return []resolution{
+		{"raw", 0, ts.retention.Raw, func(s *series) []Bucket { return s.raw }},
+		{"1m", time.Minute, ts.retention.Minute, func(s *series) []Bucket { return s.minutes }},
+		{"1h", time.Hour, ts.retention.Hour, func(s *series) []Bucket { return s.hours }},
+	}
+}
+
+// pick chooses the finest series the step can be computed from that still
+// covers from, or else the one kept longest
+func (ts *TimeSeries) pick(from int64, step time.Duration) resolution {
+	var usable []resolution
+	for _, r := range ts.resolutions() {
+		if r.size == 0 || (step > 0 && step%r.size == 0) {
+			usable = append(usable, r)
+		}
+	}
+	for _, r := range usable {
+		if from >= ts.nowMillis()-r.retention.Milliseconds() {
+			return r
+		}
+	}
+	return usable[len(usable)-1]
+}
+
+// Query returns the history of a world, downsampled to the step
+func (ts *TimeSeries) Query(worldID string, q Query) (QueryResult, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if q.To.IsZero() {
+		q.To = ts.now()
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-time.Hour)
+	}
+	from := q.From.UnixNano() / int64(time.Millisecond)
+	to := q.To.UnixNano() / int64(time.Millisecond)
+	switch {
+	case from >= to:
+		return QueryResult{}, fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	case q.Step < 0 || (q.Step > 0 && q.Step%time.Millisecond != 0):
+		return QueryResult{}, fmt.Errorf("%w: step must be a positive number of milliseconds", ErrInvalidQuery)
+	case q.Step > 0 && (to-from)/q.Step.Milliseconds() > maxQueryBuckets:
+		return QueryResult{}, fmt.Errorf("%w: more than %d steps between from and to", ErrInvalidQuery, maxQueryBuckets)
+	}
+
+	source := ts.pick(from, q.Step)
+	result := QueryResult{
+		WorldID:    worldID,
+		From:       from,
+		To:         to,
+		Resolution: source.name,
+		Channels:   make(map[string][]Bucket),
+	}
+	if q.Step > 0 {
+		result.Step = q.Step.String()
+	}
+
+	channels := q.Channels
+	if len(channels) == 0 {
+		for name := range ts.worlds[worldID] {
+			channels = append(channels, name)
+		}
+	}
+	for _, name := range channels {
+		buckets := []Bucket{}
+		if s, ok := ts.worlds[worldID][name]; ok {
+			s.prune(ts.retention, ts.nowMillis())
+			buckets = downsample(source.buckets(s), from, to, q.Step)
+		}
+		channel := models.LookupSensorChannel(name)
+		for i := range buckets {
+			buckets[i].valueFor(channel)
+		}
+		result.Channels[name] = buckets
+	}
+	return result, nil
+}
+
+// downsample merges the buckets starting in [from, to) into buckets of step,
+// or copies them if step is zero
+func downsample(buckets []Bucket, from, to int64, step time.Duration) []Bucket {
+	start := sort.Search(len(buckets), func(i int) bool { return buckets[i].Start >= from })
+	end := sort.Search(len(buckets), func(i int) bool { return buckets[i].Start >= to })
+
+	result := []Bucket{}
+	for _, bucket := range buckets[start:end] {
+		if step == 0 {
+			result = append(result, bucket)
+			continue
+		}
+		bucketStart := floorTo(bucket.Start, step)
+		if n := len(result); n > 0 && result[n-1].Start == bucketStart {
+			result[n-1].merge(bucket)
+			continue
+		}
+		bucket.Start = bucketStart
+		result = append(result, bucket)
+	}
+	return result
+}
@@ -0,0 +1,139 @@
+package sensors
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// base is a whole hour, so minute and hour buckets start on it
+var base = time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+// at returns the Unix milliseconds of an offset from base
+func at(offset time.Duration) int64 {
+	return base.Add(offset).UnixMilli()
+}
+
+func TestTimeSeriesDownsampling(t *testing.T) {
+	ts := NewTimeSeries(Retention{})
+	ts.now = func() time.Time { return base.Add(2 * time.Hour) }
+
+	// Two minutes of temperatures, the first minute arriving out of order
+	ts.AddValue("office", models.SensorTemperature, at(30*time.Second), 22)
+	ts.AddValue("office", models.SensorTemperature, at(0), 20)
+	ts.AddValue("office", models.SensorTemperature, at(time.Minute), 25)
+	ts.AddValue("office", models.SensorAirQuality, at(10*time.Second), 40)
+	ts.AddValue("office", models.SensorAirQuality, at(20*time.Second), 90)
+	ts.AddValue("office", models.SensorAirQuality, at(70*time.Minute), 10)
+
+	result, err := ts.Query("office", Query{From: base, To: base.Add(time.Hour), Step: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, "raw", result.Resolution)
+	assert.Equal(t, "1m0s", result.Step)
+
+	temperatures := result.Channels[models.SensorTemperature]
+	require.Len(t, temperatures, 2)
+	assert.Equal(t, Bucket{Start: at(0), Count: 2, Min: 20, Max: 22, Avg: 21, Sum: 42, Last: 22, Value: 21, lastAt: at(30 * time.Second)}, temperatures[0])
+	assert.Equal(t, at(time.Minute), temperatures[1].Start)
+
+	// Air quality aggregates by its worst value, and the reading past the
+	// end of the range is left out
+	quality := result.Channels[models.SensorAirQuality]
+	require.Len(t, quality, 1)
+	assert.Equal(t, 90.0, quality[0].Value)
+	assert.Equal(t, 65.0, quality[0].Avg)
+
+	// Hourly steps are computed from the readings while they are kept
+	result, err = ts.Query("office", Query{From: base, To: base.Add(2 * time.Hour), Step: time.Hour, Channels: []string{models.SensorAirQuality, "missing"}})
+	require.NoError(t, err)
+	assert.Equal(t, "raw", result.Resolution, "raw readings still cover the range")
+	require.Len(t, result.Channels[models.SensorAirQuality], 2)
+	assert.Equal(t, 3, result.Channels[models.SensorAirQuality][0].Count+result.Channels[models.SensorAirQuality][1].Count)
+	assert.Empty(t, result.Channels["missing"])
+	assert.Len(t, result.Channels, 2)
+
+	// Without a step every reading comes back
+	result, err = ts.Query("office", Query{From: base, To: base.Add(time.Hour), Channels: []string{models.SensorTemperature}})
+	require.NoError(t, err)
+	assert.Len(t, result.Channels[models.SensorTemperature], 3)
+}
+
+func TestTimeSeriesRetention(t *testing.T) {
+	now := base.Add(3 * time.Hour)
+	ts := NewTimeSeries(Retention{Raw: time.Hour, Minute: 2 * time.Hour, Hour: 24 * time.Hour})
+	ts.now = func() time.Time { return now }
+
+	for minute := 0; minute < 180; minute += 10 {
+		ts.AddValue("office", models.SensorSound, at(time.Duration(minute)*time.Minute), float64(minute))
+	}
+
+	// Readings from the first two hours are gone, so per-minute buckets
+	// serve the second hour and hour buckets the first
+	result, err := ts.Query("office", Query{From: base.Add(90 * time.Minute), To: now, Step: 10 * time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, "1m", result.Resolution)
+	assert.Len(t, result.Channels[models.SensorSound], 9)
+
+	result, err = ts.Query("office", Query{From: base, To: now, Step: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, "1h", result.Resolution)
+	hours := result.Channels[models.SensorSound]
+	require.Len(t, hours, 3)
+	assert.Equal(t, Bucket{Start: at(0), Count: 6, Min: 0, Max: 50, Avg: 25, Sum: 150, Last: 50, Value: 25, lastAt: at(50 * time.Minute)}, hours[0])
+
+	// A step finer than a minute can only use what raw readings are left
+	result, err = ts.Query("office", Query{From: base, To: now, Step: 30 * time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, "raw", result.Resolution)
+	assert.Len(t, result.Channels[models.SensorSound], 6)
+}
+
+func TestTimeSeriesInvalidQueries(t *testing.T) {
+	ts := NewTimeSeries(DefaultRetention)
+	for _, q := range []Query{
+		{From: base, To: base},
+		{From: base, To: base.Add(time.Hour), Step: -time.Minute},
+		{From: base, To: base.Add(time.Hour), Step: time.Microsecond},
+		{From: base, To: base.Add(30 * 24 * time.Hour), Step: time.Minute},
+	} {
+		_, err := ts.Query("office", q)
+		assert.True(t, errors.Is(err, ErrInvalidQuery), "query %+v", q)
+	}
+
+	// The default range is the last hour
+	ts.now = func() time.Time { return base }
+	result, err := ts.Query("office", Query{})
+	require.NoError(t, err)
+	assert.Equal(t, at(-time.Hour), result.From)
+	assert.Equal(t, at(0), result.To)
+	assert.Empty(t, result.Channels)
+}
+
+func TestStoreHistory(t *testing.T) {
+	store := NewStore(time.Minute, 0)
+	store.now = func() time.Time { return base.Add(40 * time.Minute) }
+	history := NewTimeSeries(DefaultRetention)
+	history.now = func() time.Time { return base.Add(time.Hour) }
+	store.SetHistory(history)
+	assert.Same(t, history, store.History())
+
+	for i := 0; i < 5; i++ {
+		r := models.SensorReading{WorldID: "office", Timestamp: at(time.Duration(i) * 10 * time.Minute)}
+		r.SensorData.Set(models.SensorHumidity, float64(40+i))
+		_, err := store.Record(r)
+		require.NoError(t, err)
+	}
+	history.AddMoment(&models.WorldMoment{WorldID: "office", Timestamp: at(0), Occupancy: 12, Activity: 0.12})
+
+	// The store keeps the last minute, the history all of it
+	assert.Len(t, store.Recent("office"), 1)
+	result, err := history.Query("office", Query{From: base, To: base.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Len(t, result.Channels[models.SensorHumidity], 5)
+	assert.Equal(t, 12.0, result.Channels[MomentOccupancy][0].Value)
+	assert.Equal(t, 0.12, result.Channels[MomentActivity][0].Value)
+}
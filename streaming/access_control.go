@@ -33,6 +33,30 @@ func CanAccessWorld(userID string, moment *models.WorldMoment) bool {
 	return false
 }
 
+// CanViewWorld determines if a user may see a world's moments. Worlds without
+// a creator are open to everyone; others are shared as their moments are.
+func CanViewWorld(userID string, world models.World) bool {
+	if world.CreatorID == "" {
+		return true
+	}
+	return CanAccessWorld(userID, &models.WorldMoment{WorldID: world.ID, CreatorID: world.CreatorID, Sharing: world.Sharing})
+}
+
+// CanModifyWorld determines if a user may change a world: its creator and the
+// users it is shared with may, and anyone may change a world without a
+// creator. Being public only lets others see a world.
+func CanModifyWorld(userID string, world models.World) bool {
+	if world.CreatorID == "" || userID == world.CreatorID {
+		return true
+	}
+	for _, allowedUser := range world.Sharing.AllowedUsers {
+		if userID == allowedUser {
+			return true
+		}
+	}
+	return false
+}
+
 // GetAccessibleContent filters the content of a WorldMoment based on the user's
 // permissions and the context level specified in the sharing settings
 func GetAccessibleContent(userID string, moment *models.WorldMoment) *models.WorldMoment {
@@ -68,6 +92,13 @@ func GetAccessibleContent(userID string, moment *models.WorldMoment) *models.Wor
 	case models.ContextLevelPartial:
 		// Provide moderate information but not custom/sensitive data
 		result.CustomData = ""
+		// Drop readings of private sensor channels, such as people counts
+		result.SensorData = moment.SensorData.WithoutPrivate()
+		if result.Vibe != nil {
+			vibe := *result.Vibe
+			vibe.SensorData = vibe.SensorData.WithoutPrivate()
+			result.Vibe = &vibe
+		}
 		// May keep binary data depending on format but strip any sensitive formats
 		if result.BinaryData != nil {
 			// Keep only non-sensitive formats
@@ -310,4 +310,32 @@ func TestGetAccessibleContent(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestGetAccessibleContentPrivateSensorChannels(t *testing.T) {
+	var sensors models.SensorData
+	sensors.Set(models.SensorTemperature, 22)
+	sensors.Set(models.SensorCO2, 700)
+	sensors.Set(models.SensorOccupancyCount, 12)
+
+	moment := &models.WorldMoment{
+		WorldID:    "world1",
+		CreatorID:  "creator",
+		SensorData: sensors,
+		Vibe:       &models.Vibe{ID: "vibe1", SensorData: sensors},
+		Sharing: models.SharingSettings{
+			IsPublic:     true,
+			ContextLevel: models.ContextLevelPartial,
+		},
+	}
+
+	// Private channels are only shared at the full context level
+	result := GetAccessibleContent("viewer", moment)
+	assert.Equal(t, []string{models.SensorCO2, models.SensorTemperature}, result.SensorData.Names())
+	assert.Equal(t, []string{models.SensorCO2, models.SensorTemperature}, result.Vibe.SensorData.Names())
+	assert.Contains(t, moment.SensorData.Names(), models.SensorOccupancyCount, "the moment itself is left alone")
+
+	moment.Sharing.ContextLevel = models.ContextLevelFull
+	result = GetAccessibleContent("viewer", moment)
+	assert.Equal(t, sensors.Names(), result.SensorData.Names())
+}
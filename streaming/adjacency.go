@@ -0,0 +1,57 @@
+package streaming
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// NeighborVibes returns the vibes shown at now by the worlds adjacent to a
+// world, most strongly connected first and then by world ID. Neighbors
+// without a vibe are left out. Without a WorldGraph there are no neighbors.
+func NeighborVibes(repo RepositoryInterface, worldID string, now time.Time) ([]*models.Vibe, error) {
+	graph, ok := repo.(WorldGraph)
+	if !ok {
+		return nil, nil
+	}
+	edges, err := graph.WorldEdges(worldID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(edges, func(i, j int) bool { return edges[i].Weight > edges[j].Weight })
+
+	neighbors := make([]*models.Vibe, 0, len(edges))
+	for _, edge := range edges {
+		vibe, err := worldVibeAt(repo, edge.To, now)
+		if err != nil {
+			continue // No vibe to contribute
+		}
+		neighbors = append(neighbors, &vibe)
+	}
+	return neighbors, nil
+}
+
+// WorldContext builds the comonadic context of a world: the vibe it shows at
+// now in the center, surrounded by the vibes of its neighbors
+func (vct *VibeContextualTransformer) WorldContext(repo RepositoryInterface, worldID string, now time.Time) (*ComonadicVibeContext, error) {
+	center, err := worldVibeAt(repo, worldID, now)
+	if err != nil {
+		return nil, err
+	}
+	neighbors, err := NeighborVibes(repo, worldID, now)
+	if err != nil {
+		return nil, err
+	}
+	return vct.newContext(&center, neighbors), nil
+}
+
+// TransformWorld transforms the vibe of a world in the context of the worlds
+// adjacent to it
+func (vct *VibeContextualTransformer) TransformWorld(repo RepositoryInterface, worldID string, now time.Time) (*models.Vibe, error) {
+	ctx, err := vct.WorldContext(repo, worldID, now)
+	if err != nil {
+		return nil, err
+	}
+	return vct.TransformWithContext(ctx.Extract(), ctx.Neighbors()), nil
+}
@@ -0,0 +1,90 @@
+package streaming
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// watchChanges subscribes to repository change events if the repository
+// publishes them and the service is not subscribed yet (not thread-safe)
+func (s *StreamingService) watchChanges() {
+	if s.stopChanges != nil {
+		return
+	}
+	notifier, ok := s.repo.(repository.ChangeNotifier)
+	if !ok {
+		return
+	}
+
+	events, stop := notifier.SubscribeChanges(0)
+	s.stopChanges = stop
+	go func() {
+		for event := range events {
+			s.handleChange(event)
+		}
+	}()
+}
+
+// handleChange publishes updates for the worlds affected by a repository
+// change. Vibe updates are sent whenever NATS is connected; moments only
+// while streaming is active, in addition to the periodic ones.
+func (s *StreamingService) handleChange(event repository.ChangeEvent) {
+	s.mu.RLock()
+	client := s.natsClient
+	momGen := s.momentGenerator
+	streamingActive := s.streamingActive
+	s.mu.RUnlock()
+
+	if !client.IsConnected() {
+		return
+	}
+
+	// Find the affected worlds and whether their vibe changed
+	var worldIDs []string
+	var vibe *models.Vibe
+	switch event.Type {
+	case repository.EventWorldCreated, repository.EventWorldVibeSet:
+		worldIDs = []string{event.ID}
+		if event.World != nil && event.World.CurrentVibe != "" {
+			if current, err := worldVibeAt(s.repo, event.ID, time.Now()); err == nil {
+				vibe = &current
+			}
+		}
+	case repository.EventWorldUpdated:
+		worldIDs = []string{event.ID}
+	case repository.EventVibeChanged:
+		if event.Vibe == nil {
+			return // A vibe in use cannot be deleted
+		}
+		vibe = event.Vibe
+		for _, world := range s.repo.GetAllWorlds() {
+			if world.CurrentVibe == event.ID {
+				worldIDs = append(worldIDs, world.ID)
+			}
+		}
+	default:
+		return
+	}
+
+	for _, worldID := range worldIDs {
+		if vibe != nil {
+			if err := client.PublishVibeUpdate(worldID, vibe); err != nil {
+				fmt.Printf("Error publishing vibe update for world %s: %v\n", worldID, err)
+			}
+		}
+		if !streamingActive || momGen == nil {
+			continue
+		}
+		moment, err := momGen.GenerateMoment(worldID)
+		if err != nil {
+			fmt.Printf("Error generating moment for world %s: %v\n", worldID, err)
+			continue
+		}
+		if err := publishSystemMoment(client, moment); err != nil {
+			fmt.Printf("Error publishing moment for world %s: %v\n", worldID, err)
+		}
+	}
+}
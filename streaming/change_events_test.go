@@ -0,0 +1,94 @@
+package streaming
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// momentWorlds returns the world IDs of the published moments
+func momentWorlds(client *MockNATSClient) []string {
+	var ids []string
+	for _, moment := range client.GetPublishedMoments() {
+		ids = append(ids, moment.WorldID)
+	}
+	return ids
+}
+
+// TestServiceReactsToRepositoryChanges tests that writes are published for
+// the affected world without waiting for the streaming interval
+func TestServiceReactsToRepositoryChanges(t *testing.T) {
+	repo := repository.NewRepository()
+	mockClient := NewMockNATSClient()
+	service := CreateStreamingService(repo, &StreamingConfig{StreamInterval: time.Hour}, mockClient)
+
+	assert.NoError(t, service.Start())
+	defer service.Stop()
+
+	// Setting a world's vibe publishes the new vibe for that world only
+	assert.NoError(t, repo.SetWorldVibe("office-space", "calm-clarity"))
+	assert.Eventually(t, func() bool {
+		vibe, ok := mockClient.GetPublishedVibes()["office-space"]
+		return ok && vibe.ID == "calm-clarity"
+	}, time.Second, 5*time.Millisecond)
+	assert.Len(t, mockClient.GetPublishedVibes(), 1)
+
+	// Moments are only published while streaming
+	assert.Empty(t, mockClient.GetPublishedMoments())
+	assert.NoError(t, service.StartStreaming())
+
+	// Editing a vibe reaches every world using it
+	vibe, _ := repo.GetVibe("calm-clarity")
+	vibe.Energy = 0.4
+	assert.NoError(t, repo.UpdateVibe(vibe))
+	assert.Eventually(t, func() bool {
+		return len(mockClient.GetPublishedMoments()) == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.ElementsMatch(t, []string{"office-space", "virtual-garden"}, momentWorlds(mockClient))
+	assert.Equal(t, 0.4, mockClient.GetPublishedVibes()["virtual-garden"].Energy)
+
+	// After Stop, writes are no longer published
+	service.Stop()
+	assert.NoError(t, repo.SetWorldVibe("hybrid-studio", "calm-clarity"))
+	time.Sleep(20 * time.Millisecond)
+	assert.NotContains(t, mockClient.GetPublishedVibes(), "hybrid-studio")
+}
+
+// TestServiceIgnoresChangesWithoutNotifier tests that repositories without
+// change events keep working with periodic streaming only
+func TestServiceIgnoresChangesWithoutNotifier(t *testing.T) {
+	repo := &staticRepository{worlds: map[string]models.World{}}
+	service := CreateStreamingService(repo, &StreamingConfig{StreamInterval: time.Hour}, NewMockNATSClient())
+
+	assert.NoError(t, service.Start())
+	defer service.Stop()
+	assert.Nil(t, service.stopChanges)
+}
+
+// staticRepository implements RepositoryInterface and nothing more
+type staticRepository struct {
+	worlds map[string]models.World
+}
+
+func (r *staticRepository) GetWorld(id string) (models.World, error) {
+	world, ok := r.worlds[id]
+	if !ok {
+		return models.World{}, repository.ErrWorldNotFound
+	}
+	return world, nil
+}
+
+func (r *staticRepository) GetAllWorlds() []models.World {
+	worlds := make([]models.World, 0, len(r.worlds))
+	for _, world := range r.worlds {
+		worlds = append(worlds, world)
+	}
+	return worlds
+}
+
+func (r *staticRepository) GetWorldVibe(worldID string) (models.Vibe, error) {
+	return models.Vibe{}, repository.ErrVibeNotFound
+}
@@ -0,0 +1,196 @@
+package streaming
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// UserHeader is the message header naming the user a command or query is
+// sent on behalf of
+const UserHeader = "Vibespace-User"
+
+// World commands received over NATS
+const (
+	CommandSetVibe = "setVibe"
+	CommandStream  = "stream"
+)
+
+// Errors replied to world commands
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrNoUser         = errors.New("commands need a user ID")
+	ErrAccessDenied   = errors.New("access denied")
+)
+
+// CommandSubject is the subject a world command is sent to, such as
+// "{streamID}.cmd.world.setVibe"
+func CommandSubject(streamID, command string) string {
+	return fmt.Sprintf("%s.cmd.world.%s", streamID, command)
+}
+
+// ActorRepository is implemented by repositories that attribute writes to
+// users; world commands that write need one
+type ActorRepository interface {
+	WithActor(actor string) repository.VibeWorldRepository
+}
+
+// Ensure Repository can run world commands
+var _ ActorRepository = (*repository.Repository)(nil)
+
+// WorldCommand is the body of a world command. The user is taken from the
+// UserHeader header, or userId if there is none.
+type WorldCommand struct {
+	WorldID  string          `json:"worldId"`
+	UserID   string          `json:"userId,omitempty"`
+	VibeID   string          `json:"vibeId,omitempty"`   // setVibe: the new vibe
+	Duration int64           `json:"duration,omitempty"` // setVibe: milliseconds to blend from the previous vibe over
+	Version  int64           `json:"version,omitempty"`  // setVibe: the world version the change is based on
+	Sharing  *SharingRequest `json:"sharing,omitempty"`  // stream: who may see the moment instead of the world's sharing
+}
+
+// subscribeCommands runs the world commands sent to the command subjects if
+// commands are enabled and the client can subscribe (not thread-safe)
+func (s *StreamingService) subscribeCommands() error {
+	if s.stopCommands != nil || !s.config.Commands {
+		return nil
+	}
+	subscriber, ok := s.natsClient.(NATSSubscriber)
+	if !ok {
+		return nil
+	}
+
+	prefix := CommandSubject(s.config.StreamID, "")
+	stop, err := subscriber.Subscribe(prefix+"*", func(msg Message) []byte {
+		return s.runCommandMessage(strings.TrimPrefix(msg.Subject, prefix), msg)
+	})
+	if err != nil {
+		return err
+	}
+	s.stopCommands = stop
+	return nil
+}
+
+// runCommandMessage runs the command in a message and returns the reply
+func (s *StreamingService) runCommandMessage(command string, msg Message) []byte {
+	var cmd WorldCommand
+	var response map[string]interface{}
+	if err := json.Unmarshal(msg.Data, &cmd); err != nil {
+		response = errorReply(fmt.Errorf("invalid command: %v", err))
+	} else {
+		if user := msg.Header.Get(UserHeader); user != "" {
+			cmd.UserID = user
+		}
+		response, err = s.RunCommand(command, &cmd)
+		if err != nil {
+			fmt.Printf("Error running command %s for user %s: %v\n", command, cmd.UserID, err)
+			response = errorReply(err)
+		}
+	}
+	reply, _ := json.Marshal(response)
+	return reply
+}
+
+// errorReply returns the reply to a command or query that failed
+func errorReply(err error) map[string]interface{} {
+	response := map[string]interface{}{
+		"success": false,
+		"error":   err.Error(),
+	}
+	var conflict *repository.ConflictError
+	if errors.As(err, &conflict) {
+		response["version"] = conflict.Actual
+	}
+	return response
+}
+
+// RunCommand runs a world command for cmd.UserID, as the set_world_vibe and
+// streaming_streamWorld tools would, and returns the reply. setVibe, and
+// stream with sharing of its own, need CanModifyWorld; stream needs
+// CanViewWorld.
+func (s *StreamingService) RunCommand(command string, cmd *WorldCommand) (map[string]interface{}, error) {
+	if command != CommandSetVibe && command != CommandStream {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCommand, command)
+	}
+	if cmd.UserID == "" {
+		return nil, ErrNoUser
+	}
+	if cmd.WorldID == "" {
+		return nil, fmt.Errorf("worldId is required")
+	}
+
+	s.mu.RLock()
+	repo := s.repo
+	s.mu.RUnlock()
+	world, err := repo.GetWorld(cmd.WorldID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case command == CommandSetVibe || cmd.Sharing != nil:
+		if !CanModifyWorld(cmd.UserID, world) {
+			return nil, fmt.Errorf("%w: user %s cannot change world %s", ErrAccessDenied, cmd.UserID, cmd.WorldID)
+		}
+		if command == CommandSetVibe {
+			return s.setVibeCommand(repo, cmd)
+		}
+		return s.streamCommand(cmd)
+	default:
+		if !CanViewWorld(cmd.UserID, world) {
+			return nil, fmt.Errorf("%w: user %s cannot stream world %s", ErrAccessDenied, cmd.UserID, cmd.WorldID)
+		}
+		return s.streamCommand(cmd)
+	}
+}
+
+// setVibeCommand sets a world's vibe on behalf of the user
+func (s *StreamingService) setVibeCommand(repo RepositoryInterface, cmd *WorldCommand) (map[string]interface{}, error) {
+	actors, ok := repo.(ActorRepository)
+	if !ok {
+		return nil, fmt.Errorf("the repository cannot change vibes")
+	}
+	if cmd.VibeID == "" {
+		return nil, fmt.Errorf("vibeId is required")
+	}
+
+	duration := time.Duration(cmd.Duration) * time.Millisecond
+	world, err := actors.WithActor(cmd.UserID).TransitionWorldVibe(cmd.WorldID, cmd.VibeID, duration, cmd.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("Vibe '%s' set for world '%s'", cmd.VibeID, cmd.WorldID)
+	if cmd.Duration > 0 {
+		message += fmt.Sprintf(" over %v", duration)
+	}
+	return map[string]interface{}{
+		"success": true,
+		"message": message,
+		"version": world.Version,
+	}, nil
+}
+
+// streamCommand streams a moment of the world to the user, like the
+// streaming_streamWorld tool
+func (s *StreamingService) streamCommand(cmd *WorldCommand) (map[string]interface{}, error) {
+	response, err := NewStreamingTools(s).StreamWorld(&StreamWorldRequest{
+		WorldID: cmd.WorldID,
+		UserID:  cmd.UserID,
+		Sharing: cmd.Sharing,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !response.Success {
+		return nil, errors.New(response.Message)
+	}
+	return map[string]interface{}{
+		"success": true,
+		"message": response.Message,
+	}, nil
+}
@@ -0,0 +1,123 @@
+package streaming
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+type commandReply struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Error   string `json:"error"`
+	Version int64  `json:"version"`
+}
+
+// sendCommand delivers a command as user and decodes the reply
+func sendCommand(t *testing.T, client *MockNATSClient, command, user, body string) commandReply {
+	msg := Message{Subject: CommandSubject("test", command), Data: []byte(body), Header: nats.Header{}}
+	if user != "" {
+		msg.Header.Set(UserHeader, user)
+	}
+	var reply commandReply
+	require.NoError(t, json.Unmarshal(client.Deliver(msg), &reply))
+	return reply
+}
+
+// TestWorldCommandsOverNATS tests that commands sent to the command subjects
+// change vibes and stream moments on behalf of their user
+func TestWorldCommandsOverNATS(t *testing.T) {
+	repo := repository.NewRepository()
+	client := NewMockNATSClient()
+	service := CreateStreamingService(repo, &StreamingConfig{StreamID: "test", Commands: true}, client)
+
+	require.NoError(t, service.Start())
+	assert.Equal(t, 1, client.Subscriptions())
+
+	// The reply carries the version stored, even for an unversioned command
+	reply := sendCommand(t, client, CommandSetVibe, "alice", `{"worldId": "office-space", "vibeId": "calm-clarity"}`)
+	assert.True(t, reply.Success, reply.Error)
+	assert.Equal(t, int64(2), reply.Version)
+	vibe, err := repo.GetWorldVibe("office-space")
+	require.NoError(t, err)
+	assert.Equal(t, "calm-clarity", vibe.ID)
+	reply = sendCommand(t, client, CommandSetVibe, "alice", `{"worldId": "office-space", "vibeId": "calm-clarity", "version": 2}`)
+	assert.True(t, reply.Success, reply.Error)
+	assert.Equal(t, int64(3), reply.Version)
+	history, err := repo.WorldHistory("office-space")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", history[len(history)-1].Actor, "Changes are attributed to the user")
+
+	// A stale version is refused with the current one
+	reply = sendCommand(t, client, CommandSetVibe, "alice", `{"worldId": "office-space", "vibeId": "focused-flow", "version": 1}`)
+	assert.False(t, reply.Success)
+	world, err := repo.GetWorld("office-space")
+	require.NoError(t, err)
+	assert.Equal(t, world.Version, reply.Version)
+
+	reply = sendCommand(t, client, CommandStream, "", `{"worldId": "virtual-garden", "userId": "bob"}`)
+	assert.True(t, reply.Success, reply.Error)
+	moments := client.GetPublishedMoments()
+	require.Len(t, moments, 1)
+	assert.Equal(t, "virtual-garden", moments[0].WorldID)
+	assert.Contains(t, moments[0].Viewers, "bob")
+
+	for command, body := range map[string]string{
+		"delete":       `{"worldId": "office-space"}`,
+		CommandSetVibe: `{"worldId": "nowhere", "userId": "alice", "vibeId": "calm-clarity"}`,
+		CommandStream:  `{"worldId": "office-space"}`,
+	} {
+		reply = sendCommand(t, client, command, "", body)
+		assert.False(t, reply.Success, "%s %s should fail", command, body)
+		assert.NotEmpty(t, reply.Error)
+	}
+
+	service.Stop()
+	assert.Equal(t, 0, client.Subscriptions())
+}
+
+// TestWorldCommandAccess tests that commands honor who a world belongs to
+// and who it is shared with
+func TestWorldCommandAccess(t *testing.T) {
+	repo := repository.NewRepository()
+	_, err := repo.CreateWorld(models.World{
+		ID:        "alice-room",
+		Name:      "Alice's Room",
+		Type:      models.WorldTypeVirtual,
+		CreatorID: "alice",
+		Sharing:   models.SharingSettings{IsPublic: true, AllowedUsers: []string{"bob"}},
+	})
+	require.NoError(t, err)
+	service := CreateStreamingService(repo, &StreamingConfig{StreamID: "test"}, NewMockNATSClient())
+
+	setVibe := func(user string) error {
+		_, err := service.RunCommand(CommandSetVibe, &WorldCommand{WorldID: "alice-room", UserID: user, VibeID: "calm-clarity"})
+		return err
+	}
+	assert.NoError(t, setVibe("alice"))
+	assert.NoError(t, setVibe("bob"), "Users the world is shared with may change it")
+	assert.ErrorIs(t, setVibe("carol"), ErrAccessDenied, "Public worlds are only public to see")
+
+	_, err = service.RunCommand(CommandStream, &WorldCommand{WorldID: "alice-room", UserID: "carol"})
+	assert.NoError(t, err)
+	_, err = service.RunCommand(CommandStream, &WorldCommand{
+		WorldID: "alice-room",
+		UserID:  "carol",
+		Sharing: &SharingRequest{IsPublic: true},
+	})
+	assert.ErrorIs(t, err, ErrAccessDenied, "Only those who may change a world choose its sharing")
+
+	world, err := repo.GetWorld("alice-room")
+	require.NoError(t, err)
+	world.Sharing = models.SharingSettings{}
+	assert.True(t, CanViewWorld("alice", world))
+	assert.False(t, CanViewWorld("bob", world))
+	assert.False(t, CanModifyWorld("bob", world))
+	assert.True(t, CanModifyWorld("bob", models.World{ID: "unowned"}))
+}
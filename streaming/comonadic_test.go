@@ -3,9 +3,12 @@ package streaming
 import (
 	"math"
 	"testing"
+	"time"
 
 	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestComonadicVibeContext(t *testing.T) {
@@ -390,3 +393,51 @@ func BenchmarkComonadicExtension(b *testing.B) {
 		_ = ctx.Extend(transform)
 	}
 }
+
+func TestWorldContext(t *testing.T) {
+	repo := repository.NewRepository()
+	require.NoError(t, repo.ConnectWorlds(models.WorldEdge{From: "office-space", To: "virtual-garden", Weight: 0.2, Kind: models.EdgeKindPortal}))
+	require.NoError(t, repo.ConnectWorlds(models.WorldEdge{From: "hybrid-studio", To: "office-space", Weight: 0.9, Kind: models.EdgeKindWall}))
+	transformer := NewVibeContextualTransformer(5)
+
+	t.Run("Neighbors Strongest First", func(t *testing.T) {
+		neighbors, err := NeighborVibes(repo, "office-space", time.Now())
+		require.NoError(t, err)
+		require.Len(t, neighbors, 2)
+		assert.Equal(t, "energetic-spark", neighbors[0].ID)
+		assert.Equal(t, "calm-clarity", neighbors[1].ID)
+
+		neighbors, err = NeighborVibes(repo, "virtual-garden", time.Now())
+		require.NoError(t, err)
+		require.Len(t, neighbors, 1)
+		assert.Equal(t, "focused-flow", neighbors[0].ID)
+	})
+
+	t.Run("Context From The Graph", func(t *testing.T) {
+		ctx, err := transformer.WorldContext(repo, "office-space", time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, "focused-flow", ctx.Extract().ID)
+		assert.Len(t, ctx.Neighbors(), 2)
+
+		_, err = transformer.WorldContext(repo, "missing", time.Now())
+		assert.Equal(t, repository.ErrWorldNotFound, err)
+	})
+
+	t.Run("Transform World", func(t *testing.T) {
+		expected := NewVibeContextualTransformer(5).TransformWithContext(
+			&models.Vibe{ID: "focused-flow", Energy: 0.7},
+			[]*models.Vibe{{ID: "energetic-spark", Energy: 0.9}, {ID: "calm-clarity", Energy: 0.3}})
+
+		result, err := NewVibeContextualTransformer(5).TransformWorld(repo, "office-space", time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, "focused-flow", result.ID)
+		assert.InDelta(t, expected.Energy, result.Energy, 1e-9)
+	})
+
+	t.Run("Isolated World", func(t *testing.T) {
+		require.NoError(t, repo.DisconnectWorlds("office-space", "virtual-garden"))
+		neighbors, err := NeighborVibes(repo, "virtual-garden", time.Now())
+		require.NoError(t, err)
+		assert.Empty(t, neighbors)
+	})
+}
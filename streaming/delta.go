@@ -0,0 +1,284 @@
+package streaming
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// DefaultKeyframeInterval is how often a world's full moment is published
+// while only deltas would otherwise be
+const DefaultKeyframeInterval = time.Minute
+
+// DeltaConfig says which changes of a world's moments are worth publishing.
+// A numeric field counts as changed once it differs from what subscribers
+// last saw by at least its threshold; a zero threshold counts any difference.
+type DeltaConfig struct {
+	Occupancy int                // People
+	Activity  float64            // On the 0 to 1 scale
+	Sensors   float64            // Any sensor channel not in Channels
+	Channels  map[string]float64 // By sensor channel name
+	// Keyframe is how often the full moment of a world is published even if
+	// nothing changed, so new subscribers catch up and quiet worlds show
+	// they are alive. Zero selects DefaultKeyframeInterval.
+	Keyframe time.Duration
+}
+
+// DefaultDeltaConfig ignores jitter in activity and in the common sensors
+func DefaultDeltaConfig() DeltaConfig {
+	return DeltaConfig{
+		Occupancy: 1,
+		Activity:  0.05,
+		Channels: map[string]float64{
+			models.SensorTemperature: 0.5,
+			models.SensorHumidity:    2,
+			models.SensorLight:       10,
+			models.SensorSound:       3,
+			models.SensorCO2:         25,
+		},
+		Keyframe: DefaultKeyframeInterval,
+	}
+}
+
+// keyframeInterval returns Keyframe or its default
+func (c DeltaConfig) keyframeInterval() time.Duration {
+	if c.Keyframe <= 0 {
+		return DefaultKeyframeInterval
+	}
+	return c.Keyframe
+}
+
+// sensorThreshold returns the threshold of a sensor channel
+func (c DeltaConfig) sensorThreshold(name string) float64 {
+	if threshold, ok := c.Channels[name]; ok {
+		return threshold
+	}
+	return c.Sensors
+}
+
+// MomentDelta is what changed in a world's moment since the moment at Base.
+// Only the fields that changed are set.
+type MomentDelta struct {
+	WorldID        string              `json:"worldId"`
+	Timestamp      int64               `json:"timestamp"` // Unix milliseconds
+	Base           int64               `json:"base"`      // Timestamp of the moment it applies to
+	VibeID         *string             `json:"vibeId,omitempty"`
+	Vibe           *models.Vibe        `json:"vibe,omitempty"`
+	Occupancy      *int                `json:"occupancy,omitempty"`
+	Activity       *float64            `json:"activity,omitempty"`
+	SensorData     map[string]float64  `json:"sensorData,omitempty"`     // New readings by channel
+	RemovedSensors []string            `json:"removedSensors,omitempty"` // Channels without a reading now
+	CustomData     *string             `json:"customData,omitempty"`
+	Viewers        *[]string           `json:"viewers,omitempty"`
+	Rollup         *models.WorldRollup `json:"rollup,omitempty"`
+}
+
+// DeltaSubject is the subject the deltas of a world's public moments are
+// published to. Deltas for a user go to the same subject ending in
+// ".user.{userId}", as moments do.
+func DeltaSubject(streamID, worldID string) string {
+	return fmt.Sprintf("%s.world.delta.%s", streamID, worldID)
+}
+
+// changed reports whether a number moved by at least threshold
+func changed(from, to, threshold float64) bool {
+	return from != to && math.Abs(to-from) >= threshold
+}
+
+// Diff returns the meaningful changes from base to next, or nil if there
+// are none. Changes to sharing, the creator and binary data are not deltas;
+// see needsKeyframe.
+func (c DeltaConfig) Diff(base, next *models.WorldMoment) *MomentDelta {
+	delta := &MomentDelta{WorldID: next.WorldID, Timestamp: next.Timestamp, Base: base.Timestamp}
+	changes := false
+
+	if next.VibeID != base.VibeID {
+		vibeID := next.VibeID
+		delta.VibeID = &vibeID
+		changes = true
+	}
+	if next.Vibe != nil && !reflect.DeepEqual(base.Vibe, next.Vibe) {
+		delta.Vibe = next.Vibe
+		changes = true
+	}
+	if changed(float64(base.Occupancy), float64(next.Occupancy), float64(c.Occupancy)) {
+		occupancy := next.Occupancy
+		delta.Occupancy = &occupancy
+		changes = true
+	}
+	if changed(base.Activity, next.Activity, c.Activity) {
+		activity := next.Activity
+		delta.Activity = &activity
+		changes = true
+	}
+
+	baseReadings := base.SensorData.Readings()
+	for _, name := range next.SensorData.Names() {
+		v, _ := next.SensorData.Get(name)
+		if old, ok := baseReadings[name]; ok && !changed(old, v, c.sensorThreshold(name)) {
+			continue
+		}
+		if delta.SensorData == nil {
+			delta.SensorData = make(map[string]float64)
+		}
+		delta.SensorData[name] = v
+		changes = true
+	}
+	for _, name := range base.SensorData.Names() {
+		if _, ok := next.SensorData.Get(name); !ok {
+			delta.RemovedSensors = append(delta.RemovedSensors, name)
+			changes = true
+		}
+	}
+
+	if next.CustomData != base.CustomData {
+		customData := next.CustomData
+		delta.CustomData = &customData
+		changes = true
+	}
+	if !sameViewers(base.Viewers, next.Viewers) {
+		viewers := append([]string{}, next.Viewers...)
+		delta.Viewers = &viewers
+		changes = true
+	}
+	if next.Rollup != nil && !reflect.DeepEqual(base.Rollup, next.Rollup) {
+		delta.Rollup = next.Rollup
+		changes = true
+	}
+
+	if !changes {
+		return nil
+	}
+	return delta
+}
+
+// sameViewers compares viewer lists regardless of order
+func sameViewers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return reflect.DeepEqual(a, b)
+}
+
+// Apply returns base with the changes of the delta, leaving base as it is
+func (d *MomentDelta) Apply(base *models.WorldMoment) *models.WorldMoment {
+	moment := *base
+	moment.Timestamp = d.Timestamp
+	if d.VibeID != nil {
+		moment.VibeID = *d.VibeID
+	}
+	if d.Vibe != nil {
+		moment.Vibe = d.Vibe
+	}
+	if d.Occupancy != nil {
+		moment.Occupancy = *d.Occupancy
+	}
+	if d.Activity != nil {
+		moment.Activity = *d.Activity
+	}
+	if len(d.SensorData) > 0 || len(d.RemovedSensors) > 0 {
+		removed := make(map[string]bool, len(d.RemovedSensors))
+		for _, name := range d.RemovedSensors {
+			removed[name] = true
+		}
+		var sensorData models.SensorData
+		for name, v := range base.SensorData.Readings() {
+			if !removed[name] {
+				sensorData.Set(name, v)
+			}
+		}
+		for name, v := range d.SensorData {
+			sensorData.Set(name, v)
+		}
+		moment.SensorData = sensorData
+	}
+	if d.CustomData != nil {
+		moment.CustomData = *d.CustomData
+	}
+	if d.Viewers != nil {
+		moment.Viewers = append([]string{}, (*d.Viewers)...)
+	}
+	if d.Rollup != nil {
+		moment.Rollup = d.Rollup
+	}
+	return &moment
+}
+
+// needsKeyframe reports whether next changed in a way a delta cannot carry:
+// who may see it, who created it, its binary payloads, or a vibe or rollup
+// that went away
+func needsKeyframe(base, next *models.WorldMoment) bool {
+	return next.CreatorID != base.CreatorID ||
+		!reflect.DeepEqual(next.Sharing, base.Sharing) ||
+		!reflect.DeepEqual(next.BinaryData, base.BinaryData) ||
+		!reflect.DeepEqual(next.BalancedTernaryData, base.BalancedTernaryData) ||
+		(next.Vibe == nil && base.Vibe != nil) ||
+		(next.Rollup == nil && base.Rollup != nil)
+}
+
+// DeltaPublisher is implemented by NATS clients that can publish deltas
+type DeltaPublisher interface {
+	// PublishMomentDelta publishes what changed from base to next to
+	// everyone who can see the world, each seeing only what they may
+	PublishMomentDelta(base, next *models.WorldMoment) error
+}
+
+// deltaState is what the subscribers of a world last saw
+type deltaState struct {
+	base       *models.WorldMoment
+	keyframeAt time.Time
+}
+
+// deltaStream decides what the streaming loop publishes for each moment: a
+// full keyframe, a delta or nothing. It is only used by the streaming
+// goroutine.
+type deltaStream struct {
+	config DeltaConfig
+	worlds map[string]*deltaState
+}
+
+func newDeltaStream(config DeltaConfig) *deltaStream {
+	return &deltaStream{config: config, worlds: make(map[string]*deltaState)}
+}
+
+// publish publishes a generated moment as a keyframe when its world is due
+// one, as a delta when something meaningful changed, or not at all. Clients
+// that cannot publish deltas get the full moment instead.
+func (d *deltaStream) publish(client NATSClientInterface, moment *models.WorldMoment, now time.Time) error {
+	prepareSystemMoment(moment)
+	state, ok := d.worlds[moment.WorldID]
+	if !ok || now.Sub(state.keyframeAt) >= d.config.keyframeInterval() || needsKeyframe(state.base, moment) {
+		if err := client.PublishWorldMoment(moment, moment.CreatorID); err != nil {
+			return err
+		}
+		d.worlds[moment.WorldID] = &deltaState{base: moment, keyframeAt: now}
+		return nil
+	}
+
+	delta := d.config.Diff(state.base, moment)
+	if delta == nil {
+		return nil
+	}
+	publisher, ok := client.(DeltaPublisher)
+	if !ok {
+		if err := client.PublishWorldMoment(moment, moment.CreatorID); err != nil {
+			return err
+		}
+		state.base = moment
+		return nil
+	}
+	next := delta.Apply(state.base)
+	if err := publisher.PublishMomentDelta(state.base, next); err != nil {
+		return err
+	}
+	state.base = next
+	return nil
+}
@@ -0,0 +1,182 @@
+package streaming
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deltaTestMoment(timestamp int64, occupancy int, activity float64) *models.WorldMoment {
+	moment := &models.WorldMoment{
+		WorldID:   "office-space",
+		Timestamp: timestamp,
+		VibeID:    "focused",
+		Occupancy: occupancy,
+		Activity:  activity,
+		CreatorID: "alice",
+		Sharing:   models.SharingSettings{IsPublic: true, ContextLevel: models.ContextLevelPartial},
+	}
+	moment.SensorData.Set(models.SensorTemperature, 21)
+	moment.SensorData.Set(models.SensorCO2, 600)
+	return moment
+}
+
+// TestDeltaDiff tests which changes make a delta and that applying it
+// gives the new moment
+func TestDeltaDiff(t *testing.T) {
+	config := DefaultDeltaConfig()
+	base := deltaTestMoment(1000, 10, 0.1)
+
+	// Jitter below the thresholds is not a change
+	next := deltaTestMoment(2000, 10, 0.12)
+	next.SensorData.Set(models.SensorTemperature, 21.2)
+	next.SensorData.Set(models.SensorCO2, 610)
+	assert.Nil(t, config.Diff(base, next))
+
+	next = deltaTestMoment(3000, 12, 0.3)
+	next.SensorData = models.SensorData{}
+	next.SensorData.Set(models.SensorTemperature, 22)
+	next.SensorData.Set(models.SensorNoiseHigh, 40)
+	next.Viewers = []string{"bob"}
+	delta := config.Diff(base, next)
+	require.NotNil(t, delta)
+	assert.Equal(t, int64(1000), delta.Base)
+	assert.Equal(t, int64(3000), delta.Timestamp)
+	assert.Nil(t, delta.VibeID)
+	assert.Equal(t, 12, *delta.Occupancy)
+	assert.Equal(t, 0.3, *delta.Activity)
+	assert.Equal(t, map[string]float64{models.SensorTemperature: 22, models.SensorNoiseHigh: 40}, delta.SensorData)
+	assert.Equal(t, []string{models.SensorCO2}, delta.RemovedSensors)
+	assert.Equal(t, []string{"bob"}, *delta.Viewers)
+
+	applied := delta.Apply(base)
+	assert.Equal(t, next, applied)
+	assert.Equal(t, 10, base.Occupancy, "Apply leaves the base alone")
+	co2, ok := base.SensorData.Get(models.SensorCO2)
+	assert.True(t, ok)
+	assert.Equal(t, 600.0, co2)
+
+	// Deltas are compact on the wire
+	data, err := json.Marshal(&MomentDelta{WorldID: "office-space", Timestamp: 2, Base: 1, Occupancy: new(int)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"worldId":"office-space","timestamp":2,"base":1,"occupancy":0}`, string(data))
+
+	// Sharing is not a delta
+	shared := deltaTestMoment(4000, 10, 0.1)
+	shared.Sharing.AllowedUsers = []string{"bob"}
+	assert.True(t, needsKeyframe(base, shared))
+	assert.False(t, needsKeyframe(base, next))
+}
+
+// TestDeltaStream tests when keyframes and deltas are published
+func TestDeltaStream(t *testing.T) {
+	client := NewMockNATSClient()
+	require.NoError(t, client.Connect())
+	config := DefaultDeltaConfig()
+	config.Keyframe = time.Minute
+	stream := newDeltaStream(config)
+	now := time.Unix(1700000000, 0)
+
+	// The first moment of a world is a keyframe; unchanged ones are skipped
+	require.NoError(t, stream.publish(client, deltaTestMoment(1, 10, 0.1), now))
+	require.NoError(t, stream.publish(client, deltaTestMoment(2, 10, 0.1), now.Add(time.Second)))
+	assert.Len(t, client.GetPublishedMoments(), 1)
+	assert.Empty(t, client.GetPublishedDeltas())
+
+	// Small changes add up against what subscribers last saw
+	require.NoError(t, stream.publish(client, deltaTestMoment(3, 10, 0.13), now.Add(2*time.Second)))
+	assert.Empty(t, client.GetPublishedDeltas())
+	require.NoError(t, stream.publish(client, deltaTestMoment(4, 10, 0.16), now.Add(3*time.Second)))
+	require.Len(t, client.GetPublishedDeltas(), 1)
+	delta := client.GetPublishedDeltas()[0]
+	assert.Equal(t, 0.16, *delta.Activity)
+	assert.Equal(t, int64(1), delta.Base)
+	assert.Nil(t, delta.Occupancy)
+
+	// A change of sharing and the keyframe interval both send the full moment
+	shared := deltaTestMoment(5, 10, 0.16)
+	shared.Sharing.AllowedUsers = []string{"bob"}
+	require.NoError(t, stream.publish(client, shared, now.Add(4*time.Second)))
+	assert.Len(t, client.GetPublishedMoments(), 2)
+	shared = deltaTestMoment(6, 10, 0.16)
+	shared.Sharing.AllowedUsers = []string{"bob"}
+	require.NoError(t, stream.publish(client, shared, now.Add(4*time.Second+time.Minute)))
+	assert.Len(t, client.GetPublishedMoments(), 3)
+	assert.Len(t, client.GetPublishedDeltas(), 1)
+
+	// A failed keyframe is tried again
+	client.SetPublishMomentError(assert.AnError)
+	assert.Error(t, stream.publish(client, deltaTestMoment(7, 10, 0.1), now.Add(time.Hour)))
+	client.SetPublishMomentError(nil)
+	require.NoError(t, stream.publish(client, deltaTestMoment(8, 10, 0.1), now.Add(time.Hour)))
+	assert.Len(t, client.GetPublishedMoments(), 4)
+}
+
+// TestDeltaSubjects tests that each user gets the delta of what they may see
+func TestDeltaSubjects(t *testing.T) {
+	client := NewNATSClientWithStreamID("nats://localhost:4222", "ies")
+	base := deltaTestMoment(1, 10, 0.1)
+	base.Sharing = models.SharingSettings{AllowedUsers: []string{"bob"}, ContextLevel: models.ContextLevelPartial}
+
+	// People counts are private: only the creator hears of them
+	next := *base
+	next.Timestamp = 2
+	next.SensorData.Set(models.SensorOccupancyCount, 9)
+	subjects, err := client.createDeltaSubjects(base, &next)
+	require.NoError(t, err)
+	assert.Len(t, subjects, 1)
+	assert.Contains(t, subjects, "ies.world.delta.office-space.user.alice")
+
+	next.Occupancy = 11
+	subjects, err = client.createDeltaSubjects(base, &next)
+	require.NoError(t, err)
+	assert.Len(t, subjects, 2)
+	var delta MomentDelta
+	require.NoError(t, json.Unmarshal(subjects["ies.world.delta.office-space.user.bob"], &delta))
+	assert.Equal(t, 11, *delta.Occupancy)
+	assert.Empty(t, delta.SensorData)
+
+	// Public worlds also publish on the world's delta subject
+	base.Sharing.IsPublic = true
+	next.Sharing.IsPublic = true
+	subjects, err = client.createDeltaSubjects(base, &next)
+	require.NoError(t, err)
+	assert.Contains(t, subjects, DeltaSubject("ies", "office-space"))
+}
+
+// TestDeltaStreaming tests that the streaming loop only publishes changes
+// once it has sent a keyframe of each world
+func TestDeltaStreaming(t *testing.T) {
+	repo := repository.NewRepository()
+	client := NewMockNATSClient()
+	config := DefaultDeltaConfig()
+	service := CreateStreamingService(repo, &StreamingConfig{
+		StreamInterval: 10 * time.Millisecond,
+		Delta:          &config,
+	}, client)
+	require.NoError(t, service.StartStreaming())
+	defer service.Stop()
+
+	require.Eventually(t, func() bool { return len(client.GetPublishedMoments()) >= 3 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, client.GetPublishedMoments(), 3, "Unchanged worlds are not published again")
+	assert.Empty(t, client.GetPublishedDeltas())
+
+	world, err := repo.GetWorld("virtual-garden")
+	require.NoError(t, err)
+	world.Occupancy += 5
+	require.NoError(t, repo.UpdateWorld(world))
+	require.Eventually(t, func() bool {
+		for _, delta := range client.GetPublishedDeltas() {
+			if delta.WorldID == "virtual-garden" && delta.Occupancy != nil && *delta.Occupancy == world.Occupancy {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,265 @@
+package streaming
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nuid"
+)
+
+// Defaults for JetStream
+const (
+	DefaultDuplicateWindow = 2 * time.Minute
+	DefaultReplayLimit     = 1000
+	jetStreamTimeout       = 5 * time.Second
+	replayBatch            = 100
+)
+
+// ErrJetStreamDisabled is returned when replaying from a client that does
+// not publish through JetStream
+var ErrJetStreamDisabled = errors.New("JetStream is not enabled")
+
+// JetStreamConfig makes the client keep everything it publishes under
+// {streamID}.world.> in a JetStream stream, which it creates or updates on
+// connecting. Subscribers that were offline can then replay what they missed.
+type JetStreamConfig struct {
+	Stream            string        // Stream name; defaults to the stream ID in upper case followed by "_WORLD"
+	MaxAge            time.Duration // How long messages are kept; zero keeps them until another limit is reached
+	MaxMsgs           int64         // Zero means no limit
+	MaxBytes          int64         // Zero means no limit
+	MaxMsgsPerSubject int64         // Zero means no limit
+	Memory            bool          // Keep messages in memory rather than in files
+	Replicas          int           // Defaults to 1
+	Duplicates        time.Duration // Window in which a retried publish is stored once; defaults to DefaultDuplicateWindow
+}
+
+// streamName returns the name of the stream for a stream ID
+func (c JetStreamConfig) streamName(streamID string) string {
+	if c.Stream != "" {
+		return c.Stream
+	}
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', '/', '\\', ' ', '\t':
+			return '_'
+		}
+		return r
+	}, strings.ToUpper(streamID))
+	return name + "_WORLD"
+}
+
+// streamConfig returns the configuration of the stream for a stream ID
+func (c JetStreamConfig) streamConfig(streamID string) jetstream.StreamConfig {
+	config := jetstream.StreamConfig{
+		Name:              c.streamName(streamID),
+		Subjects:          []string{streamID + ".world.>"},
+		Retention:         jetstream.LimitsPolicy,
+		MaxAge:            c.MaxAge,
+		MaxMsgs:           -1,
+		MaxBytes:          -1,
+		MaxMsgsPerSubject: -1,
+		Storage:           jetstream.FileStorage,
+		Replicas:          c.Replicas,
+		Duplicates:        c.Duplicates,
+	}
+	if c.MaxMsgs > 0 {
+		config.MaxMsgs = c.MaxMsgs
+	}
+	if c.MaxBytes > 0 {
+		config.MaxBytes = c.MaxBytes
+	}
+	if c.MaxMsgsPerSubject > 0 {
+		config.MaxMsgsPerSubject = c.MaxMsgsPerSubject
+	}
+	if c.Memory {
+		config.Storage = jetstream.MemoryStorage
+	}
+	if config.Replicas <= 0 {
+		config.Replicas = 1
+	}
+	if config.Duplicates <= 0 {
+		config.Duplicates = DefaultDuplicateWindow
+	}
+	return config
+}
+
+// jetStreamAPI is the part of JetStream the client uses
+type jetStreamAPI interface {
+	CreateOrUpdateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error)
+	PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+	OrderedConsumer(ctx context.Context, stream string, cfg jetstream.OrderedConsumerConfig) (jetstream.Consumer, error)
+}
+
+// Ensure JetStream provides what the client uses
+var _ jetStreamAPI = (jetstream.JetStream)(nil)
+
+// SetJetStream makes the client publish through JetStream with config from
+// its next connection, or through core NATS again if config is nil
+func (c *NATSClient) SetJetStream(config *JetStreamConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.jsConfig = config
+	c.js = nil
+}
+
+// connectJetStream provisions the stream on a new connection if JetStream
+// is configured (not thread-safe)
+func (c *NATSClient) connectJetStream() error {
+	c.js = nil
+	if c.jsConfig == nil {
+		return nil
+	}
+	conn, ok := c.conn.(*nats.Conn)
+	if !ok {
+		return fmt.Errorf("JetStream needs a NATS connection")
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return err
+	}
+	return c.provisionStream(js)
+}
+
+// provisionStream creates or updates the stream and publishes through js
+// from then on (not thread-safe)
+func (c *NATSClient) provisionStream(js jetStreamAPI) error {
+	ctx, cancel := context.WithTimeout(context.Background(), jetStreamTimeout)
+	defer cancel()
+	if _, err := js.CreateOrUpdateStream(ctx, c.jsConfig.streamConfig(c.streamID)); err != nil {
+		return fmt.Errorf("failed to provision JetStream stream: %w", err)
+	}
+	c.js = js
+	return nil
+}
+
+// messageID identifies one publish on subject. A publish JetStream receives
+// twice within the duplicate window, as when it is retried after a lost
+// acknowledgement, is stored once; the same content published again, such as
+// a world going back to its previous vibe, is a new message.
+func messageID(subject string) string {
+	return subject + "#" + nuid.Next()
+}
+
+// publish sends data on subject, through JetStream and waiting for its
+// acknowledgement if enabled (not thread-safe)
+func (c *NATSClient) publish(subject string, data []byte) error {
+	if c.js == nil {
+		return c.conn.Publish(subject, data)
+	}
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	msg.Header.Set(nats.MsgIdHdr, messageID(subject))
+	msg.Header.Set(nats.ExpectedStreamHdr, c.jsConfig.streamName(c.streamID))
+
+	ctx, cancel := context.WithTimeout(context.Background(), jetStreamTimeout)
+	defer cancel()
+	_, err := c.js.PublishMsg(ctx, msg)
+	return err
+}
+
+// ReplayOptions selects the messages to replay from the stream
+type ReplayOptions struct {
+	Subject  string    // Subject within {streamID}.world.>, wildcards allowed; defaults to all of them
+	Since    time.Time // Replay from this time...
+	Sequence uint64    // ...or from this stream sequence; with neither, everything kept is replayed
+	Limit    int       // At most this many messages; defaults to DefaultReplayLimit
+}
+
+// ReplayedMessage is a message stored in the stream
+type ReplayedMessage struct {
+	Subject  string          `json:"subject"`
+	Sequence uint64          `json:"sequence"`
+	Time     time.Time       `json:"time"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// Replayer is implemented by NATS clients that can replay what they published
+type Replayer interface {
+	Replay(ctx context.Context, opts ReplayOptions) ([]ReplayedMessage, error)
+}
+
+// Ensure NATSClient can replay
+var _ Replayer = (*NATSClient)(nil)
+
+// Replay returns the stored messages selected by opts, oldest first
+func (c *NATSClient) Replay(ctx context.Context, opts ReplayOptions) ([]ReplayedMessage, error) {
+	c.mu.Lock()
+	js := c.js
+	streamID := c.streamID
+	var name string
+	if c.jsConfig != nil {
+		name = c.jsConfig.streamName(streamID)
+	}
+	c.mu.Unlock()
+	if js == nil {
+		return nil, ErrJetStreamDisabled
+	}
+
+	prefix := streamID + ".world."
+	if opts.Subject == "" {
+		opts.Subject = prefix + ">"
+	} else if !strings.HasPrefix(opts.Subject, prefix) {
+		return nil, fmt.Errorf("replay subject must start with %s", prefix)
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultReplayLimit
+	}
+
+	config := jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{opts.Subject},
+		DeliverPolicy:  jetstream.DeliverAllPolicy,
+	}
+	switch {
+	case opts.Sequence > 0:
+		config.DeliverPolicy = jetstream.DeliverByStartSequencePolicy
+		config.OptStartSeq = opts.Sequence
+	case !opts.Since.IsZero():
+		since := opts.Since
+		config.DeliverPolicy = jetstream.DeliverByStartTimePolicy
+		config.OptStartTime = &since
+	}
+	consumer, err := js.OrderedConsumer(ctx, name, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create replay consumer: %w", err)
+	}
+
+	replayed := []ReplayedMessage{}
+	for len(replayed) < opts.Limit {
+		batch := opts.Limit - len(replayed)
+		if batch > replayBatch {
+			batch = replayBatch
+		}
+		msgs, err := consumer.Fetch(batch, jetstream.FetchMaxWait(time.Second))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch replayed messages: %w", err)
+		}
+		fetched, pending := 0, uint64(0)
+		for msg := range msgs.Messages() {
+			meta, err := msg.Metadata()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read replayed message: %w", err)
+			}
+			replayed = append(replayed, ReplayedMessage{
+				Subject:  msg.Subject(),
+				Sequence: meta.Sequence.Stream,
+				Time:     meta.Timestamp,
+				Data:     json.RawMessage(msg.Data()),
+			})
+			fetched++
+			pending = meta.NumPending
+		}
+		if err := msgs.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+			return nil, fmt.Errorf("failed to fetch replayed messages: %w", err)
+		}
+		if fetched == 0 || pending == 0 {
+			break // Caught up with the stream
+		}
+	}
+	return replayed, nil
+}
@@ -0,0 +1,210 @@
+package streaming
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// runNATSServer starts a NATS server in the test process, with JetStream
+// storing in a temporary directory if enabled
+func runNATSServer(t *testing.T, enableJetStream bool) *server.Server {
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: enableJetStream,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+	ns.Start()
+	t.Cleanup(ns.Shutdown)
+	require.True(t, ns.ReadyForConnections(5*time.Second), "NATS server not ready")
+	return ns
+}
+
+// newJetStreamTestClient returns a client connected to a new server and
+// publishing through its JetStream, and JetStream seen by another connection
+func newJetStreamTestClient(t *testing.T, config *JetStreamConfig) (*NATSClient, jetstream.JetStream) {
+	ns := runNATSServer(t, true)
+	client := NewNATSClientWithStreamID(ns.ClientURL(), "ies")
+	client.SetJetStream(config)
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Close)
+
+	conn, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(conn.Close)
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+	return client, js
+}
+
+// TestJetStreamStreamConfig tests how the stream is provisioned
+func TestJetStreamStreamConfig(t *testing.T) {
+	_, js := newJetStreamTestClient(t, &JetStreamConfig{MaxAge: time.Hour, MaxMsgs: 500})
+	stream, err := js.Stream(context.Background(), "IES_WORLD")
+	require.NoError(t, err)
+	config := stream.CachedInfo().Config
+	assert.Equal(t, []string{"ies.world.>"}, config.Subjects)
+	assert.Equal(t, time.Hour, config.MaxAge)
+	assert.Equal(t, int64(500), config.MaxMsgs)
+	assert.Equal(t, int64(-1), config.MaxBytes)
+	assert.Equal(t, jetstream.FileStorage, config.Storage)
+	assert.Equal(t, 1, config.Replicas)
+	assert.Equal(t, DefaultDuplicateWindow, config.Duplicates)
+
+	memory := JetStreamConfig{Memory: true, Stream: "MOMENTS"}.streamConfig("team.topos")
+	assert.Equal(t, "MOMENTS", memory.Name)
+	assert.Equal(t, jetstream.MemoryStorage, memory.Storage)
+	assert.Equal(t, "TEAM_TOPOS_WORLD", JetStreamConfig{}.streamName("team.topos"))
+}
+
+// TestJetStreamPublishAndReplay tests that every publish is stored, even of
+// content published before, and can be replayed from a sequence or filtered
+// by subject
+func TestJetStreamPublishAndReplay(t *testing.T) {
+	client, _ := newJetStreamTestClient(t, &JetStreamConfig{})
+	calm := &models.Vibe{ID: "calm", Name: "Calm", Energy: 0.2, Mood: "calm"}
+	busy := &models.Vibe{ID: "busy", Name: "Busy", Energy: 0.9, Mood: "energetic"}
+
+	// The world goes back to its first vibe: both updates to it are kept
+	require.NoError(t, client.PublishVibeUpdate("office-space", calm))
+	require.NoError(t, client.PublishVibeUpdate("office-space", busy))
+	require.NoError(t, client.PublishVibeUpdate("office-space", calm))
+	require.NoError(t, client.PublishWorldMoment(&models.WorldMoment{
+		WorldID:   "office-space",
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Sharing:   models.SharingSettings{IsPublic: true},
+	}, "alice"))
+
+	replayed, err := client.Replay(context.Background(), ReplayOptions{})
+	require.NoError(t, err)
+	require.Len(t, replayed, 5, "Three vibe updates, and the moment on the world and creator subjects")
+	var vibeIDs []string
+	for _, msg := range replayed[:3] {
+		assert.Equal(t, "ies.world.vibe.office-space", msg.Subject)
+		var got models.Vibe
+		require.NoError(t, json.Unmarshal(msg.Data, &got))
+		vibeIDs = append(vibeIDs, got.ID)
+	}
+	assert.Equal(t, []string{"calm", "busy", "calm"}, vibeIDs, "Replay ends on the current vibe")
+	assert.Equal(t, uint64(1), replayed[0].Sequence)
+
+	replayed, err = client.Replay(context.Background(), ReplayOptions{Sequence: 2, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, replayed, 1)
+	assert.Equal(t, uint64(2), replayed[0].Sequence)
+
+	replayed, err = client.Replay(context.Background(), ReplayOptions{Subject: "ies.world.moment.*"})
+	require.NoError(t, err)
+	require.Len(t, replayed, 1)
+	assert.Equal(t, "ies.world.moment.office-space", replayed[0].Subject)
+
+	replayed, err = client.Replay(context.Background(), ReplayOptions{Since: time.Now().Add(time.Minute)})
+	require.NoError(t, err)
+	assert.Empty(t, replayed)
+
+	_, err = client.Replay(context.Background(), ReplayOptions{Subject: "other.world.>"})
+	assert.Error(t, err)
+}
+
+// TestJetStreamDeduplicatesRetries tests that a publish sent again with its
+// message ID, as a retry is, is stored once
+func TestJetStreamDeduplicatesRetries(t *testing.T) {
+	client, js := newJetStreamTestClient(t, &JetStreamConfig{})
+	require.NoError(t, client.PublishVibeUpdate("office-space", &models.Vibe{ID: "calm", Name: "Calm", Mood: "calm"}))
+
+	replayed, err := client.Replay(context.Background(), ReplayOptions{})
+	require.NoError(t, err)
+	require.Len(t, replayed, 1)
+	stored, err := js.Stream(context.Background(), "IES_WORLD")
+	require.NoError(t, err)
+	msg, err := stored.GetMsg(context.Background(), replayed[0].Sequence)
+	require.NoError(t, err)
+	id := msg.Header.Get(nats.MsgIdHdr)
+	assert.Contains(t, id, "ies.world.vibe.office-space#")
+
+	retry := nats.NewMsg(msg.Subject)
+	retry.Data = msg.Data
+	retry.Header.Set(nats.MsgIdHdr, id)
+	ack, err := js.PublishMsg(context.Background(), retry)
+	require.NoError(t, err)
+	assert.True(t, ack.Duplicate)
+	replayed, err = client.Replay(context.Background(), ReplayOptions{})
+	require.NoError(t, err)
+	assert.Len(t, replayed, 1)
+}
+
+// TestJetStreamUnavailable tests that a client configured for JetStream does
+// not connect to a server without it, and tries again on the next Connect
+func TestJetStreamUnavailable(t *testing.T) {
+	ns := runNATSServer(t, false)
+	client := NewNATSClientWithStreamID(ns.ClientURL(), "ies")
+	client.SetJetStream(&JetStreamConfig{})
+	defer client.Close()
+
+	assert.Error(t, client.Connect())
+	assert.False(t, client.IsConnected())
+	assert.Error(t, client.PublishVibeUpdate("office-space", &models.Vibe{ID: "calm"}), "Nothing is published unkept")
+	assert.Error(t, client.Connect())
+
+	// Without JetStream configured, core NATS is enough
+	client.SetJetStream(nil)
+	require.NoError(t, client.Connect())
+	assert.True(t, client.IsConnected())
+}
+
+// TestReplayWithoutJetStream tests that replaying needs JetStream
+func TestReplayWithoutJetStream(t *testing.T) {
+	client := NewNATSClientWithStreamID("nats://localhost:4222", "ies")
+	_, err := client.Replay(context.Background(), ReplayOptions{})
+	assert.ErrorIs(t, err, ErrJetStreamDisabled)
+
+	service := CreateStreamingService(&MockRepository{}, &StreamingConfig{StreamID: "ies"}, NewMockNATSClient())
+	_, err = service.Replay(context.Background(), ReplayOptions{})
+	assert.ErrorIs(t, err, ErrJetStreamDisabled)
+
+	response, err := NewStreamingTools(service).Replay(&ReplayRequest{WorldID: "office-space"})
+	require.NoError(t, err)
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Message, ErrJetStreamDisabled.Error())
+}
+
+// TestReplayToolKeepsUserSubjectsPrivate tests that the replay tool only
+// returns public subjects, whatever world ID it is given
+func TestReplayToolKeepsUserSubjectsPrivate(t *testing.T) {
+	client, _ := newJetStreamTestClient(t, &JetStreamConfig{})
+	require.NoError(t, client.PublishWorldMoment(&models.WorldMoment{
+		WorldID:   "office-space",
+		CreatorID: "alice",
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Sharing:   models.SharingSettings{IsPublic: true, AllowedUsers: []string{"bob"}, ContextLevel: models.ContextLevelFull},
+	}, "alice"))
+	tools := NewStreamingTools(CreateStreamingService(&MockRepository{}, &StreamingConfig{StreamID: "ies"}, client))
+
+	for _, worldID := range []string{"", "office-space"} {
+		response, err := tools.Replay(&ReplayRequest{WorldID: worldID})
+		require.NoError(t, err)
+		require.True(t, response.Success, response.Message)
+		require.Len(t, response.Messages, 1)
+		assert.Equal(t, "ies.world.moment.office-space", response.Messages[0].Subject)
+	}
+
+	for _, worldID := range []string{">", "*", "office-space.user.>", "office-space.user.bob", "office space"} {
+		response, err := tools.Replay(&ReplayRequest{WorldID: worldID})
+		require.NoError(t, err)
+		assert.False(t, response.Success, "worldId %q", worldID)
+		assert.Empty(t, response.Messages, "worldId %q", worldID)
+	}
+}
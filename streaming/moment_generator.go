@@ -9,12 +9,20 @@ import (
 // MomentGenerator creates WorldMoment objects from repository data
 type MomentGenerator struct {
 	repo RepositoryInterface
+	// SummarizeChildren adds a rollup of their children to the moments of
+	// parent worlds, if the repository nests worlds
+	SummarizeChildren bool
+	// Sensors provides the sensor data of moments; without it they have none
+	Sensors SensorSource
+
+	now func() time.Time
 }
 
 // NewMomentGenerator creates a new moment generator with the given repository
 func NewMomentGenerator(repo RepositoryInterface) *MomentGenerator {
 	return &MomentGenerator{
 		repo: repo,
+		now:  time.Now,
 	}
 }
 
@@ -26,9 +34,10 @@ func (g *MomentGenerator) GenerateMoment(worldID string) (*models.WorldMoment, e
 		return nil, err
 	}
 
-	// Get the world's vibe
+	// Get the world's vibe, blended if it is changing
+	now := g.now().UTC()
 	var vibePtr *models.Vibe
-	vibe, err := g.repo.GetWorldVibe(worldID)
+	vibe, err := worldVibeAt(g.repo, worldID, now)
 	if err == nil {
 		// Make a copy of the vibe
 		vibeCopy := vibe
@@ -36,7 +45,6 @@ func (g *MomentGenerator) GenerateMoment(worldID string) (*models.WorldMoment, e
 	}
 
 	// Create the moment - convert timestamp to milliseconds for consistent handling
-	now := time.Now().UTC()
 	timestamp := now.UnixNano() / int64(time.Millisecond)
 	
 	// Calculate activity from world properties
@@ -65,13 +73,16 @@ func (g *MomentGenerator) GenerateMoment(worldID string) (*models.WorldMoment, e
 		Timestamp:   timestamp,
 		VibeID:      world.CurrentVibe,
 		Vibe:        vibePtr,
-		Occupancy:   world.Occupancy,     // Use current world occupancy
-		Activity:    activity,            // Use calculated activity level
-		SensorData:  models.SensorData{}, // Initialize empty sensor data
-		CreatorID:   world.CreatorID,     // Inherit creator from world
-		Viewers:     []string{},          // Initialize empty viewers list
-		Sharing:     sharing,             // Use the sharing settings
-		CustomData:  "",                  // Initialize empty custom data
+		Occupancy:   world.Occupancy,       // Use current world occupancy
+		Activity:    activity,              // Use calculated activity level
+		SensorData:  g.sensorData(worldID), // Latest sensor readings, if any
+		CreatorID:   world.CreatorID,       // Inherit creator from world
+		Viewers:     []string{},            // Initialize empty viewers list
+		Sharing:     sharing,               // Use the sharing settings
+		CustomData:  "",                    // Initialize empty custom data
+	}
+	if g.SummarizeChildren {
+		moment.Rollup = g.childrenRollup(worldID)
 	}
 
 	return moment, nil
@@ -111,16 +122,33 @@ func (e *GeneratorError) Error() string {
 	return e.message
 }
 
+// sensorData returns what the sensors of a world measured last
+func (g *MomentGenerator) sensorData(worldID string) models.SensorData {
+	if g.Sensors == nil {
+		return models.SensorData{}
+	}
+	return g.Sensors.Current(worldID)
+}
+
+// childrenRollup summarizes the children of a world, or returns nil if it
+// has none or the repository does not nest worlds
+func (g *MomentGenerator) childrenRollup(worldID string) *models.WorldRollup {
+	provider, ok := g.repo.(WorldRollupProvider)
+	if !ok {
+		return nil
+	}
+	rollup, err := provider.WorldRollup(worldID)
+	if err != nil || rollup.Descendants == 0 {
+		return nil
+	}
+	return &rollup
+}
+
 // calculateActivity determines the activity level of a world based on its properties
 func calculateActivity(world models.World) float64 {
 	// This is a placeholder implementation
 	// In a real system, this would consider recent changes, occupancy trends, sensor data, etc.
 	
 	// For now, just use occupancy as a simple activity metric
-	activity := float64(world.Occupancy) / 100.0
-	if activity > 1.0 {
-		activity = 1.0
-	}
-	
-	return activity
+	return models.OccupancyActivity(world.Occupancy)
 }
\ No newline at end of file
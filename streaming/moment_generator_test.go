@@ -2,6 +2,7 @@ package streaming
 
 import (
 	"testing"
+	"time"
 
 	"github.com/bmorphism/vibespace-mcp-go/models"
 	"github.com/bmorphism/vibespace-mcp-go/repository"
@@ -262,4 +263,54 @@ func TestCalculateActivity(t *testing.T) {
 			assert.Equal(t, tc.expected, activity)
 		})
 	}
-}
\ No newline at end of file
+}
+
+// TestGenerateMomentDuringTransition tests that moments carry the blended vibe
+// while a world changes vibe
+func TestGenerateMomentDuringTransition(t *testing.T) {
+	repo := repository.NewRepository()
+	assert.NoError(t, repo.AddVibe(models.Vibe{ID: "calm", Name: "Calm", Energy: 0.2, Mood: models.MoodCalm}))
+	assert.NoError(t, repo.AddVibe(models.Vibe{ID: "energetic", Name: "Energetic", Energy: 0.9, Mood: models.MoodEnergetic}))
+	assert.NoError(t, repo.AddWorld(models.World{ID: "studio", Name: "Studio", Type: models.WorldTypeVirtual, CurrentVibe: "calm"}))
+	world, err := repo.TransitionWorldVibe("studio", "energetic", time.Second, 0)
+	assert.NoError(t, err)
+
+	// Halfway through, however long the test takes
+	generator := NewMomentGenerator(repo)
+	generator.now = func() time.Time { return time.UnixMilli(world.Transition.StartedAt + world.Transition.Duration/2) }
+
+	moment, err := generator.GenerateMoment("studio")
+	assert.NoError(t, err)
+	assert.Equal(t, "energetic", moment.VibeID)
+	if assert.NotNil(t, moment.Vibe) {
+		assert.Equal(t, "calm+energetic", moment.Vibe.ID)
+		assert.Greater(t, moment.Vibe.Energy, 0.2)
+		assert.Less(t, moment.Vibe.Energy, 0.9)
+	}
+}
+
+// TestGenerateMomentSummarizesChildren tests that parent worlds summarize
+// their children when enabled
+func TestGenerateMomentSummarizesChildren(t *testing.T) {
+	repo := repository.NewRepositoryWithSampleData(false)
+	assert.NoError(t, repo.AddWorld(models.World{ID: "floor", Name: "Floor", Type: models.WorldTypePhysical}))
+	assert.NoError(t, repo.AddWorld(models.World{ID: "room", Name: "Room", Type: models.WorldTypePhysical, ParentID: "floor", Occupancy: 40}))
+
+	generator := NewMomentGenerator(repo)
+	moment, err := generator.GenerateMoment("floor")
+	assert.NoError(t, err)
+	assert.Nil(t, moment.Rollup, "Expected no summary unless enabled")
+
+	generator.SummarizeChildren = true
+	moment, err = generator.GenerateMoment("floor")
+	assert.NoError(t, err)
+	if assert.NotNil(t, moment.Rollup) {
+		assert.Equal(t, []string{"room"}, moment.Rollup.Children)
+		assert.Equal(t, 40, moment.Rollup.Occupancy)
+		assert.Equal(t, 0.4, moment.Rollup.Activity)
+	}
+
+	moment, err = generator.GenerateMoment("room")
+	assert.NoError(t, err)
+	assert.Nil(t, moment.Rollup, "Expected no summary for a world without children")
+}
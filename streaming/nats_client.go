@@ -82,6 +82,8 @@ type NATSClient struct {
 	lastConnectTime time.Time
 	lastError       error
 	rateLimiter     *RateLimiter
+	jsConfig        *JetStreamConfig // Publish through JetStream, if set
+	js              jetStreamAPI     // JetStream of the current connection, if enabled
 	mu              sync.Mutex
 }
 
@@ -147,7 +149,9 @@ func (c *NATSClient) Connect() error {
 		// Disconnect handler
 		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
 			c.mu.Lock()
-			c.connected = false
+			if c.current(nc) {
+				c.connected = false
+			}
 			c.disconnectCount++
 			c.lastError = err
 			c.mu.Unlock()
@@ -157,7 +161,9 @@ func (c *NATSClient) Connect() error {
 		// Reconnect handler
 		nats.ReconnectHandler(func(nc *nats.Conn) {
 			c.mu.Lock()
-			c.connected = true
+			if c.current(nc) {
+				c.connected = true
+			}
 			c.reconnectCount++
 			c.mu.Unlock()
 			fmt.Printf("NATS reconnected to %s (reconnect count: %d)\n", 
@@ -167,7 +173,9 @@ func (c *NATSClient) Connect() error {
 		// Closed handler
 		nats.ClosedHandler(func(nc *nats.Conn) {
 			c.mu.Lock()
-			c.connected = false
+			if c.current(nc) {
+				c.connected = false
+			}
 			c.mu.Unlock()
 			fmt.Printf("NATS connection closed\n")
 		}),
@@ -178,6 +186,14 @@ func (c *NATSClient) Connect() error {
 		return fmt.Errorf("failed to connect to NATS: %w", err)
 	}
 
+	// Without the stream, publishes would not be kept; fail rather than
+	// fall back to core NATS, so the next Connect tries again
+	if err := c.connectJetStream(); err != nil {
+		c.conn.Close()
+		c.conn = nil
+		c.lastError = err
+		return err
+	}
 	c.connected = true
 	
 	// Log successful connection
@@ -186,6 +202,14 @@ func (c *NATSClient) Connect() error {
 	return nil
 }
 
+// current reports whether nc is the client's connection, so the handlers
+// ignore connections closed by Connect after they were replaced (not
+// thread-safe)
+func (c *NATSClient) current(nc *nats.Conn) bool {
+	conn, ok := c.conn.(*nats.Conn)
+	return ok && conn == nc
+}
+
 // Close disconnects from the NATS server
 func (c *NATSClient) Close() {
 	c.mu.Lock()
@@ -195,6 +219,7 @@ func (c *NATSClient) Close() {
 		c.conn.Close()
 	}
 	c.connected = false
+	c.js = nil
 }
 
 // prepareWorldMoment prepares a world moment for publishing
@@ -206,8 +231,8 @@ func (c *NATSClient) prepareWorldMoment(moment *models.WorldMoment, userID strin
 	}
 	
 	// Validate moment data
-	if moment.WorldID == "" {
-		return nil, fmt.Errorf("world ID is required")
+	if err := moment.Validate(); err != nil {
+		return nil, err
 	}
 	
 	return moment, nil
@@ -330,7 +355,7 @@ func (c *NATSClient) PublishWorldMoment(moment *models.WorldMoment, userID strin
 	// Publish to all subjects
 	publishCount := 0
 	for subject, data := range subjectData {
-		if err := c.conn.Publish(subject, data); err != nil {
+		if err := c.publish(subject, data); err != nil {
 			return fmt.Errorf("failed to publish to subject %s: %w", subject, err)
 		}
 		publishCount++
@@ -342,6 +367,80 @@ func (c *NATSClient) PublishWorldMoment(moment *models.WorldMoment, userID strin
 	return nil
 }
 
+// createDeltaSubjects creates the delta subjects of a change from base to
+// next, like createMomentSubjects does for moments. Users allowed to see the
+// world get the delta between what they may see of each moment, if any.
+func (c *NATSClient) createDeltaSubjects(base, next *models.WorldMoment) (map[string][]byte, error) {
+	subjects := make(map[string][]byte)
+	if next.WorldID == "" {
+		return nil, fmt.Errorf("world ID is required")
+	}
+
+	exact := DeltaConfig{}
+	subject := DeltaSubject(c.streamID, next.WorldID)
+	if delta := exact.Diff(base, next); delta != nil {
+		data, err := json.Marshal(delta)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal moment delta: %w", err)
+		}
+		if next.Sharing.IsPublic {
+			subjects[subject] = data
+		}
+		subjects[fmt.Sprintf("%s.user.%s", subject, next.CreatorID)] = data
+	}
+
+	for _, allowedUserID := range next.Sharing.AllowedUsers {
+		if allowedUserID == next.CreatorID {
+			continue
+		}
+		userBase := GetAccessibleContent(allowedUserID, base)
+		userNext := GetAccessibleContent(allowedUserID, next)
+		if userBase == nil || userNext == nil {
+			continue
+		}
+		delta := exact.Diff(userBase, userNext)
+		if delta == nil {
+			continue // Nothing changed that this user can see
+		}
+		data, err := json.Marshal(delta)
+		if err != nil {
+			fmt.Printf("Warning: Failed to marshal filtered delta for user %s: %v\n", allowedUserID, err)
+			continue
+		}
+		subjects[fmt.Sprintf("%s.user.%s", subject, allowedUserID)] = data
+	}
+
+	return subjects, nil
+}
+
+// PublishMomentDelta publishes what changed from base to next, which must be
+// moments of the same world, on the delta subjects
+func (c *NATSClient) PublishMomentDelta(base, next *models.WorldMoment) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to NATS server")
+	}
+	if !c.rateLimiter.TryAcquire() {
+		return fmt.Errorf("rate limit exceeded, too many messages being published")
+	}
+
+	subjectData, err := c.createDeltaSubjects(base, next)
+	if err != nil {
+		return fmt.Errorf("failed to create delta subjects: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.connected || c.conn == nil {
+		return fmt.Errorf("not connected to NATS server")
+	}
+	for subject, data := range subjectData {
+		if err := c.publish(subject, data); err != nil {
+			return fmt.Errorf("failed to publish to subject %s: %w", subject, err)
+		}
+	}
+	return nil
+}
+
 // prepareVibeUpdate prepares a vibe update for publishing
 // This function is extracted to make it testable without an actual NATS connection
 func (c *NATSClient) prepareVibeUpdate(worldID string, vibe *models.Vibe) (string, []byte, error) {
@@ -393,7 +492,7 @@ func (c *NATSClient) PublishVibeUpdate(worldID string, vibe *models.Vibe) error
 	}
 	
 	// Publish the data
-	err = c.conn.Publish(subject, data)
+	err = c.publish(subject, data)
 	if err != nil {
 		return fmt.Errorf("failed to publish vibe update: %w", err)
 	}
@@ -453,4 +552,37 @@ func (c *NATSClient) GetConnectionStatus() ConnectionStatus {
 	}
 	
 	return status
-}
\ No newline at end of file
+}
+// natsSubscriber is the part of a NATS connection that receives messages
+type natsSubscriber interface {
+	Subscribe(subject string, cb nats.MsgHandler) (*nats.Subscription, error)
+}
+
+// Subscribe calls handler for each message on subject and sends back its
+// result to senders expecting a reply
+func (c *NATSClient) Subscribe(subject string, handler MessageHandler) (func(), error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.connected || c.conn == nil {
+		return nil, fmt.Errorf("not connected to NATS server")
+	}
+	subscriber, ok := c.conn.(natsSubscriber)
+	if !ok {
+		return nil, fmt.Errorf("NATS connection cannot subscribe")
+	}
+
+	sub, err := subscriber.Subscribe(subject, func(msg *nats.Msg) {
+		reply := handler(Message{Subject: msg.Subject, Data: msg.Data, Header: msg.Header})
+		if reply == nil || msg.Reply == "" {
+			return
+		}
+		if err := msg.Respond(reply); err != nil {
+			fmt.Printf("Error replying on %s: %v\n", msg.Subject, err)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+	}
+	return func() { sub.Unsubscribe() }, nil
+}
@@ -1,6 +1,8 @@
 package streaming
 
 import (
+	"github.com/nats-io/nats.go"
+
 	"github.com/bmorphism/vibespace-mcp-go/models"
 )
 
@@ -26,5 +28,25 @@ type NATSClientInterface interface {
 	GetConnectionStatus() ConnectionStatus
 }
 
-// Ensure NATSClient implements the interface
-var _ NATSClientInterface = (*NATSClient)(nil)
\ No newline at end of file
+// Message is a message received from NATS
+type Message struct {
+	Subject string
+	Data    []byte
+	Header  nats.Header
+}
+
+// MessageHandler handles a received message. A non-nil result is sent back
+// when the sender expects a reply.
+type MessageHandler func(msg Message) []byte
+
+// NATSSubscriber is implemented by NATS clients that can receive messages
+type NATSSubscriber interface {
+	// Subscribe calls handler for each message on subject, which may contain
+	// wildcards, until the returned function is called
+	Subscribe(subject string, handler MessageHandler) (func(), error)
+}
+
+// Ensure NATSClient implements the interfaces
+var _ NATSClientInterface = (*NATSClient)(nil)
+var _ NATSSubscriber = (*NATSClient)(nil)
+var _ DeltaPublisher = (*NATSClient)(nil)
\ No newline at end of file
@@ -2,6 +2,7 @@ package streaming
 
 import (
 	"errors"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,12 +20,21 @@ type MockNATSClient struct {
 	lastError         error
 	publishedMoments  []*models.WorldMoment
 	publishedVibes    map[string]*models.Vibe
+	publishedDeltas   []*MomentDelta
 	connectError      error
 	publishMomentError error
 	publishVibeError   error
+	subscriptions      map[int]mockSubscription
+	nextSubscription   int
 	mu                sync.Mutex
 }
 
+// mockSubscription is a handler subscribed to a subject of the mock
+type mockSubscription struct {
+	subject string
+	handler MessageHandler
+}
+
 // NewMockNATSClient creates a new mock NATS client for testing
 func NewMockNATSClient() *MockNATSClient {
 	return &MockNATSClient{
@@ -33,6 +43,7 @@ func NewMockNATSClient() *MockNATSClient {
 		connected:        false,
 		publishedMoments: []*models.WorldMoment{},
 		publishedVibes:   make(map[string]*models.Vibe),
+		subscriptions:    make(map[int]mockSubscription),
 	}
 }
 
@@ -95,6 +106,24 @@ func (m *MockNATSClient) PublishWorldMoment(moment *models.WorldMoment, userID s
 	return nil
 }
 
+// PublishMomentDelta implements the DeltaPublisher.PublishMomentDelta method,
+// recording the full delta from base to next
+func (m *MockNATSClient) PublishMomentDelta(base, next *models.WorldMoment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.publishMomentError != nil {
+		return m.publishMomentError
+	}
+	if !m.connected {
+		return errors.New("not connected to NATS server")
+	}
+	if delta := (DeltaConfig{}).Diff(base, next); delta != nil {
+		m.publishedDeltas = append(m.publishedDeltas, delta)
+	}
+	return nil
+}
+
 // PublishVibeUpdate implements the NATSClientInterface.PublishVibeUpdate method
 func (m *MockNATSClient) PublishVibeUpdate(worldID string, vibe *models.Vibe) error {
 	m.mu.Lock()
@@ -146,6 +175,13 @@ func (m *MockNATSClient) GetPublishedMoments() []*models.WorldMoment {
 	return m.publishedMoments
 }
 
+// GetPublishedDeltas returns all published deltas for testing verification
+func (m *MockNATSClient) GetPublishedDeltas() []*MomentDelta {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.publishedDeltas
+}
+
 // GetPublishedVibes returns all published vibes for testing verification
 func (m *MockNATSClient) GetPublishedVibes() map[string]*models.Vibe {
 	m.mu.Lock()
@@ -167,4 +203,66 @@ func (m *MockNATSClient) SimulateReconnect() {
 	defer m.mu.Unlock()
 	m.connected = true
 	m.reconnectCount++
-}
\ No newline at end of file
+}
+
+// Subscribe implements the NATSSubscriber.Subscribe method
+func (m *MockNATSClient) Subscribe(subject string, handler MessageHandler) (func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.connected {
+		return nil, errors.New("not connected to NATS server")
+	}
+	id := m.nextSubscription
+	m.nextSubscription++
+	m.subscriptions[id] = mockSubscription{subject: subject, handler: handler}
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.subscriptions, id)
+	}, nil
+}
+
+// Deliver simulates a message arriving on subject. It returns the reply of
+// the first subscribed handler that gives one, or nil.
+func (m *MockNATSClient) Deliver(msg Message) []byte {
+	m.mu.Lock()
+	var handlers []MessageHandler
+	for id := 0; id < m.nextSubscription; id++ {
+		if sub, ok := m.subscriptions[id]; ok && subjectMatches(sub.subject, msg.Subject) {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	m.mu.Unlock()
+
+	var reply []byte
+	for _, handler := range handlers {
+		if result := handler(msg); result != nil && reply == nil {
+			reply = result
+		}
+	}
+	return reply
+}
+
+// Subscriptions returns the number of active subscriptions
+func (m *MockNATSClient) Subscriptions() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.subscriptions)
+}
+
+// subjectMatches reports whether subject matches pattern, where "*" matches
+// one token and a final ">" matches one or more
+func subjectMatches(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, token := range patternTokens {
+		if token == ">" {
+			return i == len(patternTokens)-1 && len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) || (token != "*" && token != subjectTokens[i]) {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
@@ -0,0 +1,150 @@
+package streaming
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// Queries answered over NATS
+const (
+	QueryWorldList = "world.list" // list is models.ReservedWorldID
+	QueryWorld     = "world."     // Followed by the world ID
+	QueryMoment    = "moment."    // Followed by the world ID
+)
+
+// ErrUnknownQuery is replied to queries the server does not answer
+var ErrUnknownQuery = errors.New("unknown query")
+
+// QuerySubject is the subject a query is sent to, such as
+// "{streamID}.query.world.office-space"
+func QuerySubject(streamID, query string) string {
+	return fmt.Sprintf("%s.query.%s", streamID, query)
+}
+
+// subscribeQueries answers the queries sent to the query subjects if queries
+// are enabled and the client can subscribe (not thread-safe)
+func (s *StreamingService) subscribeQueries() error {
+	if s.stopQueries != nil || !s.config.Queries {
+		return nil
+	}
+	subscriber, ok := s.natsClient.(NATSSubscriber)
+	if !ok {
+		return nil
+	}
+
+	prefix := QuerySubject(s.config.StreamID, "")
+	stop, err := subscriber.Subscribe(prefix+">", func(msg Message) []byte {
+		return s.runQueryMessage(strings.TrimPrefix(msg.Subject, prefix), msg)
+	})
+	if err != nil {
+		return err
+	}
+	s.stopQueries = stop
+	return nil
+}
+
+// runQueryMessage answers the query in a message and returns the reply
+func (s *StreamingService) runQueryMessage(query string, msg Message) []byte {
+	response, err := s.RunQuery(query, msg.Header.Get(UserHeader))
+	if err != nil {
+		response = errorReply(err)
+	}
+	reply, _ := json.Marshal(response)
+	return reply
+}
+
+// RunQuery answers a query for userID, who may be empty, and returns the
+// reply. Only worlds the user can see, by CanViewWorld, are answered for, as
+// viewWorld shows them, and moments show what GetAccessibleContent lets the
+// user see.
+func (s *StreamingService) RunQuery(query, userID string) (map[string]interface{}, error) {
+	s.mu.RLock()
+	repo := s.repo
+	generator := s.momentGenerator
+	s.mu.RUnlock()
+
+	if query == QueryWorldList {
+		worlds := []models.World{}
+		for _, world := range repo.GetAllWorlds() {
+			if CanViewWorld(userID, world) {
+				worlds = append(worlds, viewWorld(userID, world))
+			}
+		}
+		return map[string]interface{}{
+			"success": true,
+			"worlds":  worlds,
+		}, nil
+	}
+
+	var worldID string
+	switch {
+	case strings.HasPrefix(query, QueryWorld):
+		worldID = strings.TrimPrefix(query, QueryWorld)
+	case strings.HasPrefix(query, QueryMoment):
+		worldID = strings.TrimPrefix(query, QueryMoment)
+	}
+	if worldID == "" {
+		return nil, fmt.Errorf("%w %q", ErrUnknownQuery, query)
+	}
+	world, err := repo.GetWorld(worldID)
+	if err != nil {
+		return nil, err
+	}
+	if !CanViewWorld(userID, world) {
+		return nil, fmt.Errorf("%w: user %s cannot see world %s", ErrAccessDenied, userID, worldID)
+	}
+
+	if strings.HasPrefix(query, QueryWorld) {
+		return map[string]interface{}{
+			"success": true,
+			"world":   viewWorld(userID, world),
+		}, nil
+	}
+	moment, err := generator.GenerateMoment(worldID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate moment: %w", err)
+	}
+	return map[string]interface{}{
+		"success": true,
+		"moment":  viewMoment(userID, world, moment),
+	}, nil
+}
+
+// viewWorld returns what a user who can see a world may see of it. Only its
+// creator sees who created it and who it is shared with.
+func viewWorld(userID string, world models.World) models.World {
+	if world.CreatorID != "" && userID == world.CreatorID {
+		return world
+	}
+	world.CreatorID = ""
+	world.Sharing.AllowedUsers = nil
+	return world
+}
+
+// viewMoment returns what a user who can see a world may see of its moment,
+// hiding its creator and sharing list like viewWorld. Moments of worlds
+// without a creator are shown to everyone as the system's public moments, at
+// their context level.
+func viewMoment(userID string, world models.World, moment *models.WorldMoment) *models.WorldMoment {
+	if world.CreatorID != "" && userID == world.CreatorID {
+		return GetAccessibleContent(userID, moment)
+	}
+
+	var view *models.WorldMoment
+	if world.CreatorID != "" {
+		view = GetAccessibleContent(userID, moment)
+	} else {
+		open := *moment
+		open.CreatorID = "system"
+		open.Sharing.IsPublic = true
+		view = GetAccessibleContent(userID, &open)
+		view.Sharing = moment.Sharing
+	}
+	view.CreatorID = ""
+	view.Sharing.AllowedUsers = nil
+	return view
+}
@@ -0,0 +1,153 @@
+package streaming
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+	"github.com/bmorphism/vibespace-mcp-go/sensors"
+)
+
+type queryReply struct {
+	Success bool                `json:"success"`
+	Error   string              `json:"error"`
+	World   *models.World       `json:"world"`
+	Worlds  []models.World      `json:"worlds"`
+	Moment  *models.WorldMoment `json:"moment"`
+}
+
+// sendQuery delivers a query as user and decodes the reply
+func sendQuery(t *testing.T, client *MockNATSClient, query, user string) queryReply {
+	msg := Message{Subject: QuerySubject("test", query), Header: nats.Header{}}
+	if user != "" {
+		msg.Header.Set(UserHeader, user)
+	}
+	var reply queryReply
+	require.NoError(t, json.Unmarshal(client.Deliver(msg), &reply))
+	return reply
+}
+
+func worldIDs(worlds []models.World) []string {
+	ids := make([]string, len(worlds))
+	for i, world := range worlds {
+		ids[i] = world.ID
+	}
+	return ids
+}
+
+// TestQueriesOverNATS tests that queries sent to the query subjects are
+// answered with what their user may see
+func TestQueriesOverNATS(t *testing.T) {
+	repo := repository.NewRepository()
+	_, err := repo.CreateWorld(models.World{
+		ID:        "alice.room",
+		Name:      "Alice's Room",
+		Type:      models.WorldTypeVirtual,
+		CreatorID: "alice",
+		Sharing:   models.SharingSettings{AllowedUsers: []string{"bob"}, ContextLevel: models.ContextLevelPartial},
+	})
+	require.NoError(t, err)
+	store := sensors.NewStore(0, 0)
+	_, err = sensors.NewIngester(store, repo).IngestJSON("alice.room", []byte(`{"sensorData": {"temperature": 21, "channels": {"occupancyCount": 3}}}`))
+	require.NoError(t, err)
+	client := NewMockNATSClient()
+	service := CreateStreamingService(repo, &StreamingConfig{StreamID: "test", Queries: true, Sensors: store}, client)
+
+	require.NoError(t, service.Start())
+	assert.Equal(t, 2, client.Subscriptions(), "Sensor readings and queries")
+
+	reply := sendQuery(t, client, QueryWorldList, "")
+	assert.True(t, reply.Success, reply.Error)
+	assert.NotContains(t, worldIDs(reply.Worlds), "alice.room")
+	assert.Contains(t, worldIDs(reply.Worlds), "office-space", "Worlds without a creator are open to everyone")
+	reply = sendQuery(t, client, QueryWorldList, "bob")
+	assert.Contains(t, worldIDs(reply.Worlds), "alice.room")
+
+	reply = sendQuery(t, client, QueryWorld+"alice.room", "bob")
+	assert.True(t, reply.Success, reply.Error)
+	require.NotNil(t, reply.World)
+	assert.Equal(t, "Alice's Room", reply.World.Name)
+	reply = sendQuery(t, client, QueryWorld+"alice.room", "carol")
+	assert.False(t, reply.Success)
+	assert.Contains(t, reply.Error, ErrAccessDenied.Error())
+
+	// Moments are filtered by the context level of their sharing
+	reply = sendQuery(t, client, QueryMoment+"alice.room", "alice")
+	assert.True(t, reply.Success, reply.Error)
+	require.NotNil(t, reply.Moment)
+	assert.Equal(t, "alice.room", reply.Moment.WorldID)
+	assert.Equal(t, []string{models.SensorOccupancyCount, models.SensorTemperature}, reply.Moment.SensorData.Names())
+	reply = sendQuery(t, client, QueryMoment+"alice.room", "bob")
+	require.NotNil(t, reply.Moment)
+	assert.Equal(t, []string{models.SensorTemperature}, reply.Moment.SensorData.Names())
+	reply = sendQuery(t, client, QueryMoment+"office-space", "")
+	assert.True(t, reply.Success, reply.Error)
+	require.NotNil(t, reply.Moment)
+	assert.Equal(t, "office-space", reply.Moment.WorldID)
+	assert.Empty(t, reply.Moment.CreatorID)
+
+	// No world can be called list, so world.list always lists
+	_, err = repo.CreateWorld(models.World{ID: "list", Name: "List", Type: models.WorldTypeVirtual})
+	assert.ErrorIs(t, err, models.ErrValidation)
+
+	for _, query := range []string{"world.nowhere", "moment.nowhere", "vibe.calm-clarity", "world."} {
+		reply = sendQuery(t, client, query, "alice")
+		assert.False(t, reply.Success, "%s should fail", query)
+		assert.NotEmpty(t, reply.Error)
+	}
+
+	service.Stop()
+	assert.Equal(t, 0, client.Subscriptions())
+}
+
+// TestQueriesHideSharing tests that only the creator of a world is told who
+// created it and who it is shared with
+func TestQueriesHideSharing(t *testing.T) {
+	repo := repository.NewRepository()
+	_, err := repo.CreateWorld(models.World{
+		ID:        "alice-room",
+		Name:      "Alice's Room",
+		Type:      models.WorldTypeVirtual,
+		CreatorID: "alice",
+		Sharing:   models.SharingSettings{IsPublic: true, AllowedUsers: []string{"bob"}, ContextLevel: models.ContextLevelFull},
+	})
+	require.NoError(t, err)
+	service := CreateStreamingService(repo, &StreamingConfig{StreamID: "test"}, NewMockNATSClient())
+
+	reply, err := service.RunQuery(QueryWorld+"alice-room", "alice")
+	require.NoError(t, err)
+	world := reply["world"].(models.World)
+	assert.Equal(t, "alice", world.CreatorID)
+	assert.Equal(t, []string{"bob"}, world.Sharing.AllowedUsers)
+
+	for _, user := range []string{"bob", "carol", ""} {
+		reply, err = service.RunQuery(QueryWorld+"alice-room", user)
+		require.NoError(t, err)
+		world = reply["world"].(models.World)
+		assert.Empty(t, world.CreatorID, "Creator shown to %q", user)
+		assert.Empty(t, world.Sharing.AllowedUsers, "Sharing list shown to %q", user)
+		assert.True(t, world.Sharing.IsPublic)
+
+		reply, err = service.RunQuery(QueryWorldList, user)
+		require.NoError(t, err)
+		for _, world := range reply["worlds"].([]models.World) {
+			assert.Empty(t, world.CreatorID, "Creator of %s listed to %q", world.ID, user)
+			assert.Empty(t, world.Sharing.AllowedUsers, "Sharing list of %s listed to %q", world.ID, user)
+		}
+
+		reply, err = service.RunQuery(QueryMoment+"alice-room", user)
+		require.NoError(t, err)
+		moment := reply["moment"].(*models.WorldMoment)
+		assert.Empty(t, moment.CreatorID, "Creator of the moment shown to %q", user)
+		assert.Empty(t, moment.Sharing.AllowedUsers, "Sharing list of the moment shown to %q", user)
+	}
+
+	stored, err := repo.GetWorld("alice-room")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, stored.Sharing.AllowedUsers, "Views leave the world as stored")
+}
@@ -1,6 +1,8 @@
 package streaming
 
 import (
+	"time"
+
 	"github.com/bmorphism/vibespace-mcp-go/models"
 	"github.com/bmorphism/vibespace-mcp-go/repository"
 )
@@ -18,5 +20,35 @@ type RepositoryInterface interface {
 	GetWorldVibe(worldID string) (models.Vibe, error)
 }
 
+// VibeBlender is implemented by repositories whose worlds can change vibe
+// gradually; moments then show the blended vibe of the moment
+type VibeBlender interface {
+	BlendedWorldVibe(worldID string, now time.Time) (models.Vibe, error)
+}
+
+// WorldRollupProvider is implemented by repositories that nest worlds; parent
+// worlds can then summarize their children in moments
+type WorldRollupProvider interface {
+	WorldRollup(worldID string) (models.WorldRollup, error)
+}
+
+// WorldGraph is implemented by repositories that store which worlds are
+// adjacent; their vibes then form the neighbors of a world's vibe context
+type WorldGraph interface {
+	WorldEdges(worldID string) ([]models.WorldEdge, error)
+}
+
+// worldVibeAt returns the vibe a world shows at now, blended while the world
+// is in a vibe transition
+func worldVibeAt(repo RepositoryInterface, worldID string, now time.Time) (models.Vibe, error) {
+	if blender, ok := repo.(VibeBlender); ok {
+		return blender.BlendedWorldVibe(worldID, now)
+	}
+	return repo.GetWorldVibe(worldID)
+}
+
 // Ensure Repository implements the interface
-var _ RepositoryInterface = (*repository.Repository)(nil)
\ No newline at end of file
+var _ RepositoryInterface = (*repository.Repository)(nil)
+var _ VibeBlender = (*repository.Repository)(nil)
+var _ WorldRollupProvider = (*repository.Repository)(nil)
+var _ WorldGraph = (*repository.Repository)(nil)
\ No newline at end of file
@@ -0,0 +1,310 @@
+package streaming
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+)
+
+// MinStreamInterval is the shortest interval a world can be streamed at
+const MinStreamInterval = 100 * time.Millisecond
+
+// ErrInvalidSchedule is returned for a streaming schedule that cannot be set
+var ErrInvalidSchedule = errors.New("invalid streaming schedule")
+
+// Sources of the interval a world is streamed at
+const (
+	IntervalFromWorld   = "world"
+	IntervalFromType    = "type"
+	IntervalFromDefault = "default"
+)
+
+// Schedule is the streaming interval set for a world or for a world type
+type Schedule struct {
+	WorldID    string           `json:"worldId,omitempty"`
+	WorldType  models.WorldType `json:"worldType,omitempty"`
+	Interval   string           `json:"interval"`
+	IntervalMs int64            `json:"intervalMs"`
+}
+
+func newSchedule(worldID string, worldType models.WorldType, interval time.Duration) Schedule {
+	return Schedule{
+		WorldID:    worldID,
+		WorldType:  worldType,
+		Interval:   interval.String(),
+		IntervalMs: interval.Milliseconds(),
+	}
+}
+
+// Schedules says how often the moments of each world are streamed. The
+// interval set for a world wins over the one set for its type; worlds with
+// neither follow the StreamInterval of the service. It is safe for
+// concurrent use.
+type Schedules struct {
+	mu      sync.RWMutex
+	worlds  map[string]time.Duration
+	types   map[models.WorldType]time.Duration
+	changed chan struct{} // Wakes the scheduler to pick up changes
+}
+
+// NewSchedules creates an empty set of schedules
+func NewSchedules() *Schedules {
+	return &Schedules{
+		worlds:  make(map[string]time.Duration),
+		types:   make(map[models.WorldType]time.Duration),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// checkInterval rejects intervals shorter than MinStreamInterval
+func checkInterval(interval time.Duration) error {
+	if interval < MinStreamInterval {
+		return fmt.Errorf("%w: interval must be at least %v", ErrInvalidSchedule, MinStreamInterval)
+	}
+	return nil
+}
+
+// SetWorld streams a world at interval
+func (sc *Schedules) SetWorld(worldID string, interval time.Duration) error {
+	if worldID == "" {
+		return fmt.Errorf("%w: world ID is required", ErrInvalidSchedule)
+	}
+	if err := checkInterval(interval); err != nil {
+		return err
+	}
+	sc.mu.Lock()
+	sc.worlds[worldID] = interval
+	sc.mu.Unlock()
+	sc.notify()
+	return nil
+}
+
+// SetType streams the worlds of a type at interval, unless they have an
+// interval of their own
+func (sc *Schedules) SetType(worldType models.WorldType, interval time.Duration) error {
+	switch worldType {
+	case models.WorldTypePhysical, models.WorldTypeVirtual, models.WorldTypeHybrid:
+	default:
+		return fmt.Errorf("%w: unknown world type %q", ErrInvalidSchedule, worldType)
+	}
+	if err := checkInterval(interval); err != nil {
+		return err
+	}
+	sc.mu.Lock()
+	sc.types[worldType] = interval
+	sc.mu.Unlock()
+	sc.notify()
+	return nil
+}
+
+// ClearWorld removes the interval of a world and reports whether it had one
+func (sc *Schedules) ClearWorld(worldID string) bool {
+	sc.mu.Lock()
+	_, ok := sc.worlds[worldID]
+	delete(sc.worlds, worldID)
+	sc.mu.Unlock()
+	if ok {
+		sc.notify()
+	}
+	return ok
+}
+
+// ClearType removes the interval of a world type and reports whether it had one
+func (sc *Schedules) ClearType(worldType models.WorldType) bool {
+	sc.mu.Lock()
+	_, ok := sc.types[worldType]
+	delete(sc.types, worldType)
+	sc.mu.Unlock()
+	if ok {
+		sc.notify()
+	}
+	return ok
+}
+
+// IntervalOf returns the interval a world is streamed at and where it comes
+// from, given the default interval
+func (sc *Schedules) IntervalOf(world models.World, defaultInterval time.Duration) (time.Duration, string) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	if interval, ok := sc.worlds[world.ID]; ok {
+		return interval, IntervalFromWorld
+	}
+	if interval, ok := sc.types[world.Type]; ok {
+		return interval, IntervalFromType
+	}
+	return defaultInterval, IntervalFromDefault
+}
+
+// List returns the schedules of world types, then those of worlds, each
+// ordered by name
+func (sc *Schedules) List() []Schedule {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	schedules := make([]Schedule, 0, len(sc.types)+len(sc.worlds))
+	for worldType, interval := range sc.types {
+		schedules = append(schedules, newSchedule("", worldType, interval))
+	}
+	for worldID, interval := range sc.worlds {
+		schedules = append(schedules, newSchedule(worldID, "", interval))
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		if (schedules[i].WorldID == "") != (schedules[j].WorldID == "") {
+			return schedules[i].WorldID == ""
+		}
+		if schedules[i].WorldType != schedules[j].WorldType {
+			return schedules[i].WorldType < schedules[j].WorldType
+		}
+		return schedules[i].WorldID < schedules[j].WorldID
+	})
+	return schedules
+}
+
+// own returns the worlds streamed on an interval of their own: those with an
+// interval set, and those of worlds whose type has one
+func (sc *Schedules) own(worlds []models.World) map[string]time.Duration {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	own := make(map[string]time.Duration, len(sc.worlds))
+	for worldID, interval := range sc.worlds {
+		own[worldID] = interval
+	}
+	if len(sc.types) == 0 {
+		return own
+	}
+	for _, world := range worlds {
+		if _, ok := own[world.ID]; ok {
+			continue
+		}
+		if interval, ok := sc.types[world.Type]; ok {
+			own[world.ID] = interval
+		}
+	}
+	return own
+}
+
+// notify wakes the scheduler without blocking
+func (sc *Schedules) notify() {
+	select {
+	case sc.changed <- struct{}{}:
+	default:
+	}
+}
+
+// scheduleEntry is one schedule the scheduler runs. The entry with an empty
+// world ID streams every world without an interval of its own.
+type scheduleEntry struct {
+	worldID  string
+	interval time.Duration
+	last     time.Time // When it last ran, or was first planned
+	next     time.Time
+	index    int // In the queue
+}
+
+// scheduleQueue orders entries by when they are next due; it implements
+// heap.Interface
+type scheduleQueue []*scheduleEntry
+
+func (q scheduleQueue) Len() int           { return len(q) }
+func (q scheduleQueue) Less(i, j int) bool { return q[i].next.Before(q[j].next) }
+func (q scheduleQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *scheduleQueue) Push(x interface{}) {
+	entry := x.(*scheduleEntry)
+	entry.index = len(*q)
+	*q = append(*q, entry)
+}
+
+func (q *scheduleQueue) Pop() interface{} {
+	old := *q
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return entry
+}
+
+// scheduler multiplexes the schedules of every world on a single timer: it
+// keeps them in a heap by when they are next due, so each wakeup costs
+// O(log n) however many worlds have a schedule of their own. It is only
+// used by the streaming goroutine.
+type scheduler struct {
+	queue   scheduleQueue
+	entries map[string]*scheduleEntry
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{entries: make(map[string]*scheduleEntry)}
+}
+
+// plan makes the scheduler run the given intervals, by world ID, from now.
+// Entries that keep their interval keep their timing; an entry whose
+// interval changed is next due one new interval after it last ran. Intervals
+// that are not positive never run.
+func (sch *scheduler) plan(intervals map[string]time.Duration, now time.Time) {
+	for worldID, entry := range sch.entries {
+		if intervals[worldID] <= 0 {
+			heap.Remove(&sch.queue, entry.index)
+			delete(sch.entries, worldID)
+		}
+	}
+	for worldID, interval := range intervals {
+		if interval <= 0 {
+			continue
+		}
+		entry, ok := sch.entries[worldID]
+		if !ok {
+			entry = &scheduleEntry{worldID: worldID, interval: interval, last: now, next: now.Add(interval)}
+			sch.entries[worldID] = entry
+			heap.Push(&sch.queue, entry)
+			continue
+		}
+		if entry.interval == interval {
+			continue
+		}
+		entry.interval = interval
+		entry.next = entry.last.Add(interval)
+		if entry.next.Before(now) {
+			entry.next = now
+		}
+		heap.Fix(&sch.queue, entry.index)
+	}
+}
+
+// wait returns how long until the next entry is due
+func (sch *scheduler) wait(now time.Time) time.Duration {
+	if len(sch.queue) == 0 {
+		return time.Hour
+	}
+	if wait := sch.queue[0].next.Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// due returns the world IDs of the entries due at now, in the order they
+// fell due, and schedules their next run. An entry that fell more than an
+// interval behind skips the runs it missed.
+func (sch *scheduler) due(now time.Time) []string {
+	var due []string
+	for len(sch.queue) > 0 && !sch.queue[0].next.After(now) {
+		entry := sch.queue[0]
+		due = append(due, entry.worldID)
+		entry.last = now
+		entry.next = entry.next.Add(entry.interval)
+		if !entry.next.After(now) {
+			entry.next = now.Add(entry.interval)
+		}
+		heap.Fix(&sch.queue, 0)
+	}
+	return due
+}
@@ -0,0 +1,162 @@
+package streaming
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSchedules tests setting, resolving, listing and clearing intervals
+func TestSchedules(t *testing.T) {
+	schedules := NewSchedules()
+	room := models.World{ID: "conference-room", Type: models.WorldTypePhysical}
+	lounge := models.World{ID: "study-lounge", Type: models.WorldTypePhysical}
+	garden := models.World{ID: "garden", Type: models.WorldTypeVirtual}
+
+	require.NoError(t, schedules.SetWorld(room.ID, time.Second))
+	require.NoError(t, schedules.SetType(models.WorldTypePhysical, time.Minute))
+
+	interval, source := schedules.IntervalOf(room, 5*time.Second)
+	assert.Equal(t, time.Second, interval)
+	assert.Equal(t, IntervalFromWorld, source)
+	interval, source = schedules.IntervalOf(lounge, 5*time.Second)
+	assert.Equal(t, time.Minute, interval)
+	assert.Equal(t, IntervalFromType, source)
+	interval, source = schedules.IntervalOf(garden, 5*time.Second)
+	assert.Equal(t, 5*time.Second, interval)
+	assert.Equal(t, IntervalFromDefault, source)
+
+	assert.Equal(t, map[string]time.Duration{room.ID: time.Second, lounge.ID: time.Minute},
+		schedules.own([]models.World{room, lounge, garden}))
+	assert.Equal(t, []Schedule{
+		{WorldType: models.WorldTypePhysical, Interval: "1m0s", IntervalMs: 60000},
+		{WorldID: room.ID, Interval: "1s", IntervalMs: 1000},
+	}, schedules.List())
+
+	for _, err := range []error{
+		schedules.SetWorld("", time.Second),
+		schedules.SetWorld(room.ID, MinStreamInterval/2),
+		schedules.SetType("UNDERWATER", time.Second),
+	} {
+		assert.True(t, errors.Is(err, ErrInvalidSchedule), "got %v", err)
+	}
+
+	assert.True(t, schedules.ClearWorld(room.ID))
+	assert.False(t, schedules.ClearWorld(room.ID))
+	interval, _ = schedules.IntervalOf(room, 5*time.Second)
+	assert.Equal(t, time.Minute, interval, "Cleared worlds fall back to their type")
+	assert.True(t, schedules.ClearType(models.WorldTypePhysical))
+	assert.Empty(t, schedules.List())
+}
+
+// TestSchedulerMultiplexes tests that the scheduler runs every interval on
+// time and picks up changes to the plan
+func TestSchedulerMultiplexes(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	sched := newScheduler()
+	sched.plan(map[string]time.Duration{"": 5 * time.Second, "room": time.Second, "paused": 0}, start)
+	assert.Equal(t, time.Second, sched.wait(start))
+
+	var runs []string
+	for d := time.Second; d <= 5*time.Second; d += time.Second {
+		runs = append(runs, sched.due(at(d))...)
+	}
+	assert.ElementsMatch(t, []string{"room", "room", "room", "room", "room", ""}, runs,
+		"Worlds with no positive interval never run")
+
+	// A longer interval counts from the last run; a removed world stops
+	sched.plan(map[string]time.Duration{"": 2 * time.Second}, at(5*time.Second))
+	assert.Equal(t, 2*time.Second, sched.wait(at(5*time.Second)))
+	assert.Empty(t, sched.due(at(6*time.Second)))
+	assert.Equal(t, []string{""}, sched.due(at(7*time.Second)))
+
+	// Falling behind skips the missed runs
+	assert.Equal(t, []string{""}, sched.due(at(20*time.Second)))
+	assert.Equal(t, 2*time.Second, sched.wait(at(20*time.Second)))
+}
+
+// TestStreamingPerWorldIntervals tests that worlds with an interval of their
+// own, or of their type, are streamed at it while the others wait for the
+// default interval
+func TestStreamingPerWorldIntervals(t *testing.T) {
+	client := NewMockNATSClient()
+	service := CreateStreamingService(repository.NewRepository(), &StreamingConfig{
+		StreamInterval: time.Hour,
+	}, client)
+	require.NoError(t, service.Schedules().SetWorld("office-space", MinStreamInterval))
+	require.NoError(t, service.StartStreaming())
+	defer service.Stop()
+
+	published := func(worldID string) int {
+		n := 0
+		for _, moment := range client.GetPublishedMoments() {
+			if moment.WorldID == worldID {
+				n++
+			}
+		}
+		return n
+	}
+	require.Eventually(t, func() bool { return published("office-space") >= 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.Zero(t, published("virtual-garden"))
+
+	// Setting a type interval while streaming takes effect right away
+	require.NoError(t, service.Schedules().SetType(models.WorldTypeVirtual, MinStreamInterval))
+	require.Eventually(t, func() bool { return published("virtual-garden") >= 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Zero(t, published("hybrid-studio"))
+}
+
+// TestStreamingIntervalTools tests the tools that set, list and clear
+// streaming intervals
+func TestStreamingIntervalTools(t *testing.T) {
+	service := CreateStreamingService(repository.NewRepository(), &StreamingConfig{
+		StreamInterval: 5 * time.Second,
+	}, NewMockNATSClient())
+	tools := NewStreamingTools(service)
+
+	set, err := tools.SetInterval(&SetIntervalRequest{WorldID: "office-space", Interval: 1000})
+	require.NoError(t, err)
+	assert.True(t, set.Success, set.Message)
+	assert.Equal(t, &Schedule{WorldID: "office-space", Interval: "1s", IntervalMs: 1000}, set.Schedule)
+	set, err = tools.SetInterval(&SetIntervalRequest{WorldType: "VIRTUAL", Interval: 60000})
+	require.NoError(t, err)
+	assert.True(t, set.Success, set.Message)
+
+	for _, req := range []*SetIntervalRequest{
+		{Interval: 1000},
+		{WorldID: "office-space", WorldType: "VIRTUAL", Interval: 1000},
+		{WorldID: "no-such-world", Interval: 1000},
+		{WorldID: "office-space", Interval: 10},
+	} {
+		set, err = tools.SetInterval(req)
+		require.NoError(t, err)
+		assert.False(t, set.Success, "%+v should be rejected", req)
+	}
+
+	list, err := tools.ListIntervals()
+	require.NoError(t, err)
+	assert.Equal(t, "5s", list.DefaultInterval)
+	assert.Len(t, list.Schedules, 2)
+	assert.Equal(t, []WorldInterval{
+		{WorldID: "hybrid-studio", Interval: "5s", IntervalMs: 5000, Source: IntervalFromDefault},
+		{WorldID: "office-space", Interval: "1s", IntervalMs: 1000, Source: IntervalFromWorld},
+		{WorldID: "virtual-garden", Interval: "1m0s", IntervalMs: 60000, Source: IntervalFromType},
+	}, list.Worlds)
+
+	cleared, err := tools.ClearInterval(&ClearIntervalRequest{WorldID: "office-space"})
+	require.NoError(t, err)
+	assert.True(t, cleared.Success, cleared.Message)
+	cleared, err = tools.ClearInterval(&ClearIntervalRequest{WorldID: "office-space"})
+	require.NoError(t, err)
+	assert.False(t, cleared.Success, "Nothing left to clear")
+	cleared, err = tools.ClearInterval(&ClearIntervalRequest{WorldType: "VIRTUAL"})
+	require.NoError(t, err)
+	assert.True(t, cleared.Success, cleared.Message)
+	assert.Empty(t, service.Schedules().List())
+}
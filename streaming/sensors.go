@@ -0,0 +1,74 @@
+package streaming
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/sensors"
+)
+
+// SensorSource provides what the sensors of a world measured last; moments
+// then carry it as their sensor data
+type SensorSource interface {
+	Current(worldID string) models.SensorData
+}
+
+// Ensure the sensor store can feed moments
+var _ SensorSource = (*sensors.Store)(nil)
+
+// SensorSubject is the subject sensor readings of a world are sent to. The
+// body is one reading or an array of them; worldId may be left out.
+func SensorSubject(streamID, worldID string) string {
+	return fmt.Sprintf("%s.sensor.reading.%s", streamID, worldID)
+}
+
+// subscribeSensors ingests the readings sent to the sensor subjects if a
+// sensor store is configured and the client can subscribe (not thread-safe)
+func (s *StreamingService) subscribeSensors() error {
+	if s.stopSensors != nil || s.config.Sensors == nil {
+		return nil
+	}
+	subscriber, ok := s.natsClient.(NATSSubscriber)
+	if !ok {
+		return nil
+	}
+
+	ingester := sensors.NewIngester(s.config.Sensors, s.repo)
+	prefix := SensorSubject(s.config.StreamID, "")
+	stop, err := subscriber.Subscribe(prefix+"*", func(msg Message) []byte {
+		return ingestSensorMessage(ingester, strings.TrimPrefix(msg.Subject, prefix), msg.Data)
+	})
+	if err != nil {
+		return err
+	}
+	s.stopSensors = stop
+	return nil
+}
+
+// momentHistory returns the time series periodic moments are recorded in,
+// or nil
+func (s *StreamingService) momentHistory() *sensors.TimeSeries {
+	if s.config == nil || s.config.Sensors == nil {
+		return nil
+	}
+	return s.config.Sensors.History()
+}
+
+// ingestSensorMessage records the readings in a message and returns the reply
+func ingestSensorMessage(ingester *sensors.Ingester, worldID string, data []byte) []byte {
+	var response interface{}
+	readings, err := ingester.IngestJSON(worldID, data)
+	if err != nil {
+		fmt.Printf("Error ingesting sensor readings for world %s: %v\n", worldID, err)
+		response = sensors.ErrorResponse(err)
+	} else {
+		response = map[string]interface{}{
+			"success":  true,
+			"readings": readings,
+		}
+	}
+	reply, _ := json.Marshal(response)
+	return reply
+}
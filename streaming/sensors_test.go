@@ -0,0 +1,90 @@
+package streaming
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+	"github.com/bmorphism/vibespace-mcp-go/sensors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSensorIngestionOverNATS tests that readings sent to the sensor subjects
+// are recorded and show up in the moments of their world
+func TestSensorIngestionOverNATS(t *testing.T) {
+	repo := repository.NewRepository()
+	store := sensors.NewStore(0, 0)
+	client := NewMockNATSClient()
+	service := CreateStreamingService(repo, &StreamingConfig{StreamID: "test", Sensors: store}, client)
+
+	require.NoError(t, service.Start())
+	assert.Equal(t, 1, client.Subscriptions())
+
+	reply := client.Deliver(Message{
+		Subject: SensorSubject("test", "office-space"),
+		Data:    []byte(`{"sensorData": {"temperature": 22.5, "channels": {"co2": 650}}}`),
+	})
+	var response struct {
+		Success  bool                   `json:"success"`
+		Readings []models.SensorReading `json:"readings"`
+	}
+	require.NoError(t, json.Unmarshal(reply, &response))
+	assert.True(t, response.Success)
+	require.Len(t, response.Readings, 1)
+	assert.Equal(t, "office-space", response.Readings[0].WorldID)
+
+	moment, err := service.momentGenerator.GenerateMoment("office-space")
+	require.NoError(t, err)
+	temperature, _ := moment.SensorData.Get(models.SensorTemperature)
+	assert.Equal(t, 22.5, temperature)
+	assert.Equal(t, []string{models.SensorCO2, models.SensorTemperature}, moment.SensorData.Names())
+	assert.NoError(t, moment.Validate())
+
+	// Readings for unknown worlds are refused with the reason
+	reply = client.Deliver(Message{
+		Subject: SensorSubject("test", "nowhere"),
+		Data:    []byte(`{"sensorData": {"light": 10}}`),
+	})
+	require.NoError(t, json.Unmarshal(reply, &response))
+	assert.False(t, response.Success)
+
+	service.Stop()
+	assert.Equal(t, 0, client.Subscriptions())
+}
+
+func TestMomentsWithoutSensors(t *testing.T) {
+	generator := NewMomentGenerator(repository.NewRepository())
+	moment, err := generator.GenerateMoment("office-space")
+	require.NoError(t, err)
+	assert.True(t, moment.SensorData.IsEmpty())
+}
+
+func TestSubjectMatches(t *testing.T) {
+	assert.True(t, subjectMatches("a.*.c", "a.b.c"))
+	assert.False(t, subjectMatches("a.*", "a.b.c"))
+	assert.True(t, subjectMatches("a.>", "a.b.c"))
+	assert.False(t, subjectMatches("a.>", "a"))
+	assert.False(t, subjectMatches("a.b", "a.c"))
+}
+
+// TestPeriodicMomentsRecordedInHistory tests that the occupancy and activity
+// of streamed moments go to the sensor history
+func TestPeriodicMomentsRecordedInHistory(t *testing.T) {
+	store := sensors.NewStore(0, 0)
+	store.SetHistory(sensors.NewTimeSeries(sensors.DefaultRetention))
+	client := NewMockNATSClient()
+	service := CreateStreamingService(repository.NewRepository(), &StreamingConfig{
+		StreamInterval: 10 * time.Millisecond,
+		Sensors:        store,
+	}, client)
+	require.NoError(t, service.StartStreaming())
+	defer service.Stop()
+
+	require.Eventually(t, func() bool {
+		result, err := store.History().Query("office-space", sensors.Query{Channels: []string{sensors.MomentOccupancy}})
+		return err == nil && len(result.Channels[sensors.MomentOccupancy]) > 0
+	}, time.Second, 10*time.Millisecond)
+}
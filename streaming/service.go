@@ -1,11 +1,13 @@
 package streaming
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/sensors"
 )
 
 // StreamingConfig holds configuration for the streaming service
@@ -16,6 +18,12 @@ type StreamingConfig struct {
 	StreamID       string        // Stream identifier (default: "ies")
 	StreamInterval time.Duration // Interval between streaming moments
 	AutoStart      bool          // Whether to start streaming automatically
+	SummarizeChildren bool       // Whether moments of parent worlds summarize their children
+	Sensors        *sensors.Store // Where sensor readings received over NATS go and moments take their sensor data from, if set; periodic moments go to its history
+	Delta          *DeltaConfig   // Publish periodic moments only when they change, as deltas between keyframes, if set
+	JetStream      *JetStreamConfig // Keep what is published in a JetStream stream for replay, if set
+	Commands       bool             // Run the world commands sent to the command subjects
+	Queries        bool             // Answer the queries sent to the query subjects
 }
 
 // StreamingService manages NATS streaming for world moments
@@ -26,6 +34,11 @@ type StreamingService struct {
 	repo            RepositoryInterface
 	streamingActive bool
 	stopChan        chan struct{}
+	stopChanges     func() // Ends the repository change subscription, if any
+	stopSensors     func() // Ends the sensor reading subscription, if any
+	stopCommands    func() // Ends the world command subscription, if any
+	stopQueries     func() // Ends the query subscription, if any
+	schedules       *Schedules // Per-world and per-type streaming intervals
 	mu              sync.RWMutex // Use RWMutex for better read concurrency
 	once            sync.Once    // Ensure single initialization
 }
@@ -51,6 +64,7 @@ func NewStreamingService(repo RepositoryInterface, config *StreamingConfig) *Str
 	
 	// Create NATS client with the configured stream ID
 	natsClient := NewNATSClientWithStreamID(config.NATSUrl, config.StreamID)
+	natsClient.SetJetStream(config.JetStream)
 	
 	return CreateStreamingService(repo, config, natsClient)
 }
@@ -58,13 +72,19 @@ func NewStreamingService(repo RepositoryInterface, config *StreamingConfig) *Str
 // CreateStreamingService creates a new streaming service with a custom NATS client
 // This allows dependency injection for testing
 func CreateStreamingService(repo RepositoryInterface, config *StreamingConfig, natsClient NATSClientInterface) *StreamingService {
+	momentGenerator := NewMomentGenerator(repo)
+	momentGenerator.SummarizeChildren = config.SummarizeChildren
+	if config.Sensors != nil {
+		momentGenerator.Sensors = config.Sensors
+	}
 	return &StreamingService{
 		natsClient:      natsClient,
-		momentGenerator: NewMomentGenerator(repo),
+		momentGenerator: momentGenerator,
 		config:          config,
 		repo:            repo,
 		streamingActive: false,
 		stopChan:        make(chan struct{}),
+		schedules:       NewSchedules(),
 	}
 }
 
@@ -77,6 +97,16 @@ func (s *StreamingService) Start() error {
 	if err := s.natsClient.Connect(); err != nil {
 		return fmt.Errorf("failed to connect to NATS: %w", err)
 	}
+	s.watchChanges()
+	if err := s.subscribeSensors(); err != nil {
+		return fmt.Errorf("failed to subscribe to sensor readings: %w", err)
+	}
+	if err := s.subscribeCommands(); err != nil {
+		return fmt.Errorf("failed to subscribe to world commands: %w", err)
+	}
+	if err := s.subscribeQueries(); err != nil {
+		return fmt.Errorf("failed to subscribe to queries: %w", err)
+	}
 
 	// Start streaming if autoStart is enabled
 	if s.config.AutoStart {
@@ -96,6 +126,24 @@ func (s *StreamingService) Stop() {
 		s.stopStreaming()
 	}
 
+	// Stop reacting to repository changes
+	if s.stopChanges != nil {
+		s.stopChanges()
+		s.stopChanges = nil
+	}
+	if s.stopSensors != nil {
+		s.stopSensors()
+		s.stopSensors = nil
+	}
+	if s.stopCommands != nil {
+		s.stopCommands()
+		s.stopCommands = nil
+	}
+	if s.stopQueries != nil {
+		s.stopQueries()
+		s.stopQueries = nil
+	}
+
 	// Close NATS connection
 	s.natsClient.Close()
 }
@@ -122,7 +170,20 @@ func (s *StreamingService) startStreaming() error {
 
 	// Reset the stop channel
 	s.stopChan = make(chan struct{})
+	if s.schedules == nil {
+		s.schedules = NewSchedules()
+	}
 	s.streamingActive = true
+	s.watchChanges()
+	if err := s.subscribeSensors(); err != nil {
+		fmt.Printf("Error subscribing to sensor readings: %v\n", err)
+	}
+	if err := s.subscribeCommands(); err != nil {
+		fmt.Printf("Error subscribing to world commands: %v\n", err)
+	}
+	if err := s.subscribeQueries(); err != nil {
+		fmt.Printf("Error subscribing to queries: %v\n", err)
+	}
 
 	// Start the streaming goroutine
 	go s.streamMoments()
@@ -154,14 +215,54 @@ func (s *StreamingService) stopStreaming() {
 	s.streamingActive = false
 }
 
-// streamMoments is the main streaming loop that publishes world moments at regular intervals
+// Schedules returns the streaming intervals of worlds and world types
+func (s *StreamingService) Schedules() *Schedules {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.schedules == nil {
+		s.schedules = NewSchedules()
+	}
+	return s.schedules
+}
+
+// defaultInterval returns the interval of worlds without a schedule of their own
+func (s *StreamingService) defaultInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.StreamInterval
+}
+
+// streamIntervals returns the intervals the streaming loop runs, by world ID.
+// The empty ID stands for every world without an interval of its own.
+func (s *StreamingService) streamIntervals(schedules *Schedules, repo RepositoryInterface) map[string]time.Duration {
+	var worlds []models.World
+	if repo != nil {
+		worlds = repo.GetAllWorlds()
+	}
+	intervals := schedules.own(worlds)
+	intervals[""] = s.defaultInterval()
+	return intervals
+}
+
+// streamMoments is the main streaming loop that publishes world moments on
+// their schedules. One scheduler runs every schedule; worlds without one of
+// their own are streamed together every StreamInterval. The plan is updated
+// when the schedules change and each time those worlds are streamed, which
+// picks up new worlds and a new StreamInterval. With a Delta config, moments
+// that did not change are skipped and changes go out as deltas.
 func (s *StreamingService) streamMoments() {
 	// Get snapshot of configuration and stop channel to avoid races
 	s.mu.RLock()
-	interval := s.config.StreamInterval
 	stopChan := s.stopChan
 	momGen := s.momentGenerator
 	client := s.natsClient
+	repo := s.repo
+	schedules := s.schedules
+	history := s.momentHistory()
+	var deltas *deltaStream
+	if s.config.Delta != nil {
+		deltas = newDeltaStream(*s.config.Delta)
+	}
 	s.mu.RUnlock()
 
 	if momGen == nil {
@@ -169,47 +270,92 @@ func (s *StreamingService) streamMoments() {
 		return
 	}
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	publish := func(moment *models.WorldMoment) {
+		var err error
+		if deltas != nil {
+			err = deltas.publish(client, moment, time.Now())
+		} else {
+			err = publishSystemMoment(client, moment)
+		}
+		if err != nil {
+			fmt.Printf("Error publishing moment for world %s: %v\n", moment.WorldID, err)
+			return
+		}
+		if history != nil {
+			history.AddMoment(moment)
+		}
+	}
+
+	intervals := s.streamIntervals(schedules, repo)
+	sched := newScheduler()
+	sched.plan(intervals, time.Now())
+	timer := time.NewTimer(sched.wait(time.Now()))
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			// Generate and publish moments for all worlds
-			moments, err := momGen.GenerateAllMoments()
-			if err != nil {
-				fmt.Printf("Error generating moments: %v\n", err)
-				continue
-			}
+		case <-timer.C:
+			for _, worldID := range sched.due(time.Now()) {
+				if worldID != "" {
+					moment, err := momGen.GenerateMoment(worldID)
+					if err != nil {
+						fmt.Printf("Error generating moment for world %s: %v\n", worldID, err)
+						continue
+					}
+					publish(moment)
+					continue
+				}
 
-			// Publish each moment
-			for _, moment := range moments {
-				// For automatic streaming, we use the "system" as the creator ID
-				// if it's not already set
-				creatorID := moment.CreatorID
-				if creatorID == "" {
-					creatorID = "system"
+				// Generate and publish moments for the other worlds
+				moments, err := momGen.GenerateAllMoments()
+				if err != nil {
+					fmt.Printf("Error generating moments: %v\n", err)
 				}
-				
-				// Set default sharing settings for automated moments if needed
-				if !moment.Sharing.IsPublic && len(moment.Sharing.AllowedUsers) == 0 && moment.Sharing.ContextLevel == "" {
-					// By default, system-generated moments are public with partial context
-					moment.Sharing = models.SharingSettings{
-						IsPublic:     true,
-						AllowedUsers: []string{},
-						ContextLevel: models.ContextLevelPartial,
+				for _, moment := range moments {
+					if _, own := intervals[moment.WorldID]; !own {
+						publish(moment)
 					}
 				}
-				
-				if err := client.PublishWorldMoment(moment, creatorID); err != nil {
-					fmt.Printf("Error publishing moment for world %s: %v\n", moment.WorldID, err)
-				}
+				intervals = s.streamIntervals(schedules, repo)
+				sched.plan(intervals, time.Now())
 			}
 
+		case <-schedules.changed:
+			intervals = s.streamIntervals(schedules, repo)
+			sched.plan(intervals, time.Now())
+
 		case <-stopChan:
 			// Streaming has been stopped
 			return
 		}
+		timer.Reset(sched.wait(time.Now()))
+	}
+}
+
+// publishSystemMoment publishes a moment generated by the service itself
+// rather than requested by a user
+func publishSystemMoment(client NATSClientInterface, moment *models.WorldMoment) error {
+	prepareSystemMoment(moment)
+	return client.PublishWorldMoment(moment, moment.CreatorID)
+}
+
+// prepareSystemMoment fills in the creator and sharing of a moment generated
+// by the service itself
+func prepareSystemMoment(moment *models.WorldMoment) {
+	// For automatic streaming, we use the "system" as the creator ID
+	// if it's not already set
+	if moment.CreatorID == "" {
+		moment.CreatorID = "system"
+	}
+
+	// Set default sharing settings for automated moments if needed
+	if !moment.Sharing.IsPublic && len(moment.Sharing.AllowedUsers) == 0 && moment.Sharing.ContextLevel == "" {
+		// By default, system-generated moments are public with partial context
+		moment.Sharing = models.SharingSettings{
+			IsPublic:     true,
+			AllowedUsers: []string{},
+			ContextLevel: models.ContextLevelPartial,
+		}
 	}
 }
 
@@ -273,6 +419,19 @@ func (s *StreamingService) IsStreaming() bool {
 	return s.streamingActive
 }
 
+// Replay returns the messages kept in the JetStream stream that opts selects
+func (s *StreamingService) Replay(ctx context.Context, opts ReplayOptions) ([]ReplayedMessage, error) {
+	s.mu.RLock()
+	client := s.natsClient
+	s.mu.RUnlock()
+
+	replayer, ok := client.(Replayer)
+	if !ok {
+		return nil, ErrJetStreamDisabled
+	}
+	return replayer.Replay(ctx, opts)
+}
+
 // PublishVibeUpdate publishes a vibe update for a specific world
 func (s *StreamingService) PublishVibeUpdate(worldID string, vibe *models.Vibe) error {
 	s.mu.Lock()
@@ -13,7 +13,7 @@ func TestGetStreamingToolMethodsFull(t *testing.T) {
 	methods := streaming.GetStreamingToolMethods()
 	
 	// Check all methods are present
-	assert.Len(t, methods, 5, "Should have 5 methods")
+	assert.Len(t, methods, 9, "Should have 9 methods")
 	
 	// Check method names match expected
 	expectedNames := []string{
@@ -22,6 +22,10 @@ func TestGetStreamingToolMethodsFull(t *testing.T) {
 		"streaming_status",
 		"streaming_streamWorld", 
 		"streaming_updateConfig",
+		"streaming_setInterval",
+		"streaming_listIntervals",
+		"streaming_clearInterval",
+		"streaming_replay",
 	}
 	
 	for _, name := range expectedNames {
@@ -1,9 +1,12 @@
 package streaming
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/bmorphism/vibespace-mcp-go/models"
 )
@@ -22,7 +25,7 @@ func NewStreamingTools(service *StreamingService) *StreamingTools {
 
 // StartStreamingRequest is the request for starting streaming
 type StartStreamingRequest struct {
-	Interval int `json:"interval"` // Stream interval in milliseconds
+	Interval int `json:"interval" jsonschema:"minimum=0"` // Stream interval in milliseconds
 }
 
 // StartStreamingResponse is the response for the start streaming request
@@ -156,7 +159,7 @@ func (t *StreamingTools) Status() (*StatusResponse, error) {
 
 // StreamWorldRequest is the request for streaming a specific world
 type StreamWorldRequest struct {
-	WorldID string             `json:"worldId"`
+	WorldID string             `json:"worldId" jsonschema:"required"`
 	UserID  string             `json:"userId"`
 	Sharing *SharingRequest    `json:"sharing,omitempty"`
 }
@@ -165,7 +168,7 @@ type StreamWorldRequest struct {
 type SharingRequest struct {
 	IsPublic     bool     `json:"isPublic"`
 	AllowedUsers []string `json:"allowedUsers,omitempty"`
-	ContextLevel string   `json:"contextLevel,omitempty"`
+	ContextLevel string   `json:"contextLevel,omitempty" jsonschema:"enum=none,enum=partial,enum=full"`
 }
 
 // StreamWorldResponse is the response for the stream world request
@@ -264,10 +267,10 @@ func (t *StreamingTools) StreamWorld(req *StreamWorldRequest) (*StreamWorldRespo
 // UpdateConfigRequest is the request for updating streaming configuration
 type UpdateConfigRequest struct {
 	NATSHost       string `json:"natsHost"`       // NATS host (e.g., "nonlocal.info")
-	NATSPort       int    `json:"natsPort"`       // NATS port (default: 4222)
+	NATSPort       int    `json:"natsPort" jsonschema:"minimum=0,maximum=65535"`       // NATS port (default: 4222)
 	NATSUrl        string `json:"natsUrl"`        // Complete NATS URL (overrides NATSHost/NATSPort if set)
 	StreamID       string `json:"streamId"`       // Stream identifier (default: "ies")
-	StreamInterval int    `json:"streamInterval"` // in milliseconds
+	StreamInterval int    `json:"streamInterval" jsonschema:"minimum=0"` // in milliseconds
 }
 
 // UpdateConfigResponse is the response for the update config request
@@ -327,9 +330,11 @@ func (t *StreamingTools) UpdateConfig(req *UpdateConfigRequest) (*UpdateConfigRe
 		t.service.natsClient.Close()
 		
 		// Create a new client with the updated configuration
-		t.service.natsClient = NewNATSClientWithStreamID(
+		natsClient := NewNATSClientWithStreamID(
 			t.service.config.NATSUrl, 
 			t.service.config.StreamID)
+		natsClient.SetJetStream(t.service.config.JetStream)
+		t.service.natsClient = natsClient
 		
 		// Reconnect and resume streaming if needed
 		err := t.service.natsClient.Connect()
@@ -367,6 +372,232 @@ func (t *StreamingTools) UpdateConfig(req *UpdateConfigRequest) (*UpdateConfigRe
 	}, nil
 }
 
+// SetIntervalRequest is the request for setting the streaming interval of a
+// world or of a world type; exactly one of them is required
+type SetIntervalRequest struct {
+	WorldID   string `json:"worldId,omitempty"`
+	WorldType string `json:"worldType,omitempty" jsonschema:"enum=PHYSICAL,enum=VIRTUAL,enum=HYBRID"`
+	Interval  int    `json:"interval" jsonschema:"required,minimum=100"` // in milliseconds
+}
+
+// SetIntervalResponse is the response for the set interval request
+type SetIntervalResponse struct {
+	Success  bool      `json:"success"`
+	Message  string    `json:"message"`
+	Schedule *Schedule `json:"schedule,omitempty"`
+}
+
+// scheduleTarget names the world or world type of a schedule request, or
+// explains why the request names neither or both
+func scheduleTarget(worldID, worldType string) (string, bool) {
+	switch {
+	case worldID == "" && worldType == "":
+		return "World ID or world type is required", false
+	case worldID != "" && worldType != "":
+		return "Only one of world ID and world type can be given", false
+	case worldID != "":
+		return "world " + worldID, true
+	}
+	return "world type " + worldType, true
+}
+
+// SetInterval sets how often a world, or every world of a type, is streamed
+func (t *StreamingTools) SetInterval(req *SetIntervalRequest) (*SetIntervalResponse, error) {
+	target, ok := scheduleTarget(req.WorldID, req.WorldType)
+	if !ok {
+		return &SetIntervalResponse{
+			Success: false,
+			Message: target,
+		}, nil
+	}
+	if req.WorldID != "" && t.service.repo != nil {
+		if _, err := t.service.repo.GetWorld(req.WorldID); err != nil {
+			return &SetIntervalResponse{
+				Success: false,
+				Message: fmt.Sprintf("Failed to set streaming interval: %v", err),
+			}, nil
+		}
+	}
+
+	interval := time.Duration(req.Interval) * time.Millisecond
+	schedules := t.service.Schedules()
+	var err error
+	if req.WorldID != "" {
+		err = schedules.SetWorld(req.WorldID, interval)
+	} else {
+		err = schedules.SetType(models.WorldType(req.WorldType), interval)
+	}
+	if err != nil {
+		return &SetIntervalResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to set streaming interval: %v", err),
+		}, nil
+	}
+
+	schedule := newSchedule(req.WorldID, models.WorldType(req.WorldType), interval)
+	return &SetIntervalResponse{
+		Success:  true,
+		Message:  fmt.Sprintf("Streaming %s every %v", target, interval),
+		Schedule: &schedule,
+	}, nil
+}
+
+// WorldInterval is the interval a world is streamed at and where it comes
+// from: "world", "type" or "default"
+type WorldInterval struct {
+	WorldID    string `json:"worldId"`
+	Interval   string `json:"interval"`
+	IntervalMs int64  `json:"intervalMs"`
+	Source     string `json:"source"`
+}
+
+// ListIntervalsResponse is the response for the list intervals request
+type ListIntervalsResponse struct {
+	DefaultInterval string          `json:"defaultInterval"`
+	Schedules       []Schedule      `json:"schedules"`
+	Worlds          []WorldInterval `json:"worlds,omitempty"`
+}
+
+// ListIntervals lists the streaming intervals set for worlds and world types,
+// and the interval every world is streamed at
+func (t *StreamingTools) ListIntervals() (*ListIntervalsResponse, error) {
+	defaultInterval := t.service.defaultInterval()
+	schedules := t.service.Schedules()
+	response := &ListIntervalsResponse{
+		DefaultInterval: defaultInterval.String(),
+		Schedules:       schedules.List(),
+	}
+	if t.service.repo == nil {
+		return response, nil
+	}
+
+	worlds := t.service.repo.GetAllWorlds()
+	sort.Slice(worlds, func(i, j int) bool { return worlds[i].ID < worlds[j].ID })
+	for _, world := range worlds {
+		interval, source := schedules.IntervalOf(world, defaultInterval)
+		response.Worlds = append(response.Worlds, WorldInterval{
+			WorldID:    world.ID,
+			Interval:   interval.String(),
+			IntervalMs: interval.Milliseconds(),
+			Source:     source,
+		})
+	}
+	return response, nil
+}
+
+// ClearIntervalRequest is the request for clearing the streaming interval of
+// a world or of a world type; exactly one of them is required
+type ClearIntervalRequest struct {
+	WorldID   string `json:"worldId,omitempty"`
+	WorldType string `json:"worldType,omitempty" jsonschema:"enum=PHYSICAL,enum=VIRTUAL,enum=HYBRID"`
+}
+
+// ClearIntervalResponse is the response for the clear interval request
+type ClearIntervalResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// ClearInterval removes the streaming interval of a world or world type, so
+// its worlds fall back to the interval of their type or the default one
+func (t *StreamingTools) ClearInterval(req *ClearIntervalRequest) (*ClearIntervalResponse, error) {
+	target, ok := scheduleTarget(req.WorldID, req.WorldType)
+	if !ok {
+		return &ClearIntervalResponse{
+			Success: false,
+			Message: target,
+		}, nil
+	}
+
+	schedules := t.service.Schedules()
+	var cleared bool
+	if req.WorldID != "" {
+		cleared = schedules.ClearWorld(req.WorldID)
+	} else {
+		cleared = schedules.ClearType(models.WorldType(req.WorldType))
+	}
+	if !cleared {
+		return &ClearIntervalResponse{
+			Success: false,
+			Message: fmt.Sprintf("No streaming interval set for %s", target),
+		}, nil
+	}
+	return &ClearIntervalResponse{
+		Success: true,
+		Message: fmt.Sprintf("Cleared streaming interval of %s", target),
+	}, nil
+}
+
+// ReplayRequest is the request for replaying what was published, from a
+// time or a stream sequence
+type ReplayRequest struct {
+	WorldID  string `json:"worldId,omitempty" jsonschema:"pattern=^[^\\s.*>]+$"` // Only this world's messages
+	Since    int64  `json:"since,omitempty" jsonschema:"minimum=0"`              // Unix milliseconds
+	Sequence uint64 `json:"sequence,omitempty" jsonschema:"minimum=0"`           // Stream sequence; wins over since
+	Limit    int    `json:"limit,omitempty" jsonschema:"minimum=0,maximum=1000"` // Defaults to 1000
+}
+
+// ReplayResponse is the response for the replay request
+type ReplayResponse struct {
+	Success  bool              `json:"success"`
+	Message  string            `json:"message"`
+	Messages []ReplayedMessage `json:"messages,omitempty"`
+}
+
+// Replay returns the public moments, deltas and vibe updates kept in the
+// JetStream stream. Messages for single users are not replayed.
+func (t *StreamingTools) Replay(req *ReplayRequest) (*ReplayResponse, error) {
+	if req.Limit > DefaultReplayLimit {
+		return &ReplayResponse{
+			Success: false,
+			Message: fmt.Sprintf("Limit must be at most %d", DefaultReplayLimit),
+		}, nil
+	}
+
+	// Public subjects have a kind and a world ID after "world"; a world ID
+	// spanning several tokens or holding a wildcard would reach the subjects
+	// of single users
+	worldID := "*"
+	if req.WorldID != "" {
+		if !isSubjectToken(req.WorldID) {
+			return &ReplayResponse{
+				Success: false,
+				Message: fmt.Sprintf("Invalid worldId %q: must not contain '.', '*', '>' or spaces", req.WorldID),
+			}, nil
+		}
+		worldID = req.WorldID
+	}
+	opts := ReplayOptions{
+		Subject:  fmt.Sprintf("%s.world.*.%s", t.service.config.StreamID, worldID),
+		Sequence: req.Sequence,
+		Limit:    req.Limit,
+	}
+	if req.Since > 0 {
+		opts.Since = time.Unix(0, req.Since*int64(time.Millisecond))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	messages, err := t.service.Replay(ctx, opts)
+	if err != nil {
+		return &ReplayResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to replay: %v", err),
+		}, nil
+	}
+	return &ReplayResponse{
+		Success:  true,
+		Message:  fmt.Sprintf("Replayed %d messages", len(messages)),
+		Messages: messages,
+	}, nil
+}
+
+// isSubjectToken reports whether s can be used as a single token of a NATS
+// subject, matching only itself
+func isSubjectToken(s string) bool {
+	return s != "" && !strings.ContainsAny(s, ".*>") && strings.IndexFunc(s, unicode.IsSpace) < 0
+}
+
 // GetStreamingToolMethods returns the available streaming tool methods
 func GetStreamingToolMethods() map[string]interface{} {
 	return map[string]interface{}{
@@ -375,5 +606,9 @@ func GetStreamingToolMethods() map[string]interface{} {
 		"streaming_status":         (*StreamingTools).Status,
 		"streaming_streamWorld":    (*StreamingTools).StreamWorld,
 		"streaming_updateConfig":   (*StreamingTools).UpdateConfig,
+		"streaming_setInterval":    (*StreamingTools).SetInterval,
+		"streaming_listIntervals":  (*StreamingTools).ListIntervals,
+		"streaming_clearInterval":  (*StreamingTools).ClearInterval,
+		"streaming_replay":         (*StreamingTools).Replay,
 	}
 }
\ No newline at end of file
@@ -12,7 +12,7 @@ func TestGetStreamingToolMethods(t *testing.T) {
 	methods := GetStreamingToolMethods()
 	
 	// Verify the correct methods are included
-	assert.Len(t, methods, 5, "Should return 5 methods")
+	assert.Len(t, methods, 9, "Should return 9 methods")
 	
 	// Check each expected method is present by key name
 	expectedPrefixes := []string{
@@ -21,6 +21,10 @@ func TestGetStreamingToolMethods(t *testing.T) {
 		"streaming_status",
 		"streaming_streamWorld",
 		"streaming_updateConfig",
+		"streaming_setInterval",
+		"streaming_listIntervals",
+		"streaming_clearInterval",
+		"streaming_replay",
 	}
 	
 	for _, prefix := range expectedPrefixes {
@@ -702,13 +702,7 @@ func NewVibeContextualTransformer(maxHistory int) *VibeContextualTransformer {
 // TransformWithContext applies comonadic transformations to vibes
 func (vct *VibeContextualTransformer) TransformWithContext(center *models.Vibe, neighbors []*models.Vibe) *models.Vibe {
 	// Create comonadic context
-	ctx := &ComonadicVibeContext{
-		center:    center,
-		neighbors: neighbors,
-		temporal:  append([]*models.Vibe{}, vct.history...), // Copy history
-		gradient:  vct.calculateGradient(center),
-		coherence: vct.calculateCoherence(center, neighbors),
-	}
+	ctx := vct.newContext(center, neighbors)
 	
 	// Apply comonadic extension with context-aware transformation
 	transformed := ctx.Extend(func(c *ComonadicVibeContext) *models.Vibe {
@@ -721,6 +715,17 @@ func (vct *VibeContextualTransformer) TransformWithContext(center *models.Vibe,
 	return transformed.Extract()
 }
 
+// newContext places center among its neighbors and the transformer's history
+func (vct *VibeContextualTransformer) newContext(center *models.Vibe, neighbors []*models.Vibe) *ComonadicVibeContext {
+	return &ComonadicVibeContext{
+		center:    center,
+		neighbors: neighbors,
+		temporal:  append([]*models.Vibe{}, vct.history...), // Copy history
+		gradient:  vct.calculateGradient(center),
+		coherence: vct.calculateCoherence(center, neighbors),
+	}
+}
+
 // calculateGradient determines the directional tendency of a vibe
 func (vct *VibeContextualTransformer) calculateGradient(vibe *models.Vibe) TernaryState {
 	if len(vct.history) < 2 {
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// testWorldAdjacency tests connecting worlds, listing their edges and
+// dropping the edges of a world moved to the trash
+func testWorldAdjacency(t *testing.T, repo repository.VibeWorldRepository) {
+	for _, id := range []string{"lobby", "cafe", "library", "metaverse"} {
+		if err := repo.AddWorld(models.World{ID: id, Name: id, Type: models.WorldTypeHybrid}); err != nil {
+			t.Fatalf("Error adding world %s: %v", id, err)
+		}
+	}
+
+	edges := []models.WorldEdge{
+		{From: "lobby", To: "cafe", Weight: 0.8, Kind: models.EdgeKindDoor},
+		{From: "library", To: "lobby", Weight: 0.3, Kind: models.EdgeKindWall},
+		{From: "metaverse", To: "lobby", Kind: models.EdgeKindPortal},
+	}
+	for _, edge := range edges {
+		if err := repo.ConnectWorlds(edge); err != nil {
+			t.Fatalf("Error connecting %s and %s: %v", edge.From, edge.To, err)
+		}
+	}
+
+	if err := repo.ConnectWorlds(models.WorldEdge{From: "lobby", To: "missing"}); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected ErrWorldNotFound for a missing world, got %v", err)
+	}
+	if err := repo.ConnectWorlds(models.WorldEdge{From: "lobby", To: "lobby"}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected a self loop to be rejected, got %v", err)
+	}
+	if err := repo.ConnectWorlds(models.WorldEdge{From: "lobby", To: "cafe", Weight: 2}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected a weight above 1 to be rejected, got %v", err)
+	}
+
+	// Edges are listed from the world's side, the default weight filled in
+	got, err := repo.WorldEdges("lobby")
+	if err != nil {
+		t.Fatalf("Error listing edges: %v", err)
+	}
+	want := []models.WorldEdge{
+		{From: "lobby", To: "cafe", Weight: 0.8, Kind: models.EdgeKindDoor},
+		{From: "lobby", To: "library", Weight: 0.3, Kind: models.EdgeKindWall},
+		{From: "lobby", To: "metaverse", Weight: 1, Kind: models.EdgeKindPortal},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	if _, err := repo.WorldEdges("missing"); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected ErrWorldNotFound for a missing world, got %v", err)
+	}
+
+	// Connecting again replaces the edge, whichever way round
+	if err := repo.ConnectWorlds(models.WorldEdge{From: "cafe", To: "lobby", Weight: 0.5}); err != nil {
+		t.Fatalf("Error reconnecting: %v", err)
+	}
+	if got, _ := repo.WorldEdges("cafe"); len(got) != 1 || got[0].To != "lobby" || got[0].Weight != 0.5 || got[0].Kind != "" {
+		t.Errorf("Expected the replaced edge from the cafe, got %+v", got)
+	}
+
+	if err := repo.DisconnectWorlds("library", "cafe"); err != repository.ErrEdgeNotFound {
+		t.Errorf("Expected ErrEdgeNotFound, got %v", err)
+	}
+	if err := repo.DisconnectWorlds("lobby", "library"); err != nil {
+		t.Fatalf("Error disconnecting: %v", err)
+	}
+
+	// A world in the trash loses its edges, and gets none back when restored
+	if err := repo.DeleteWorld("metaverse"); err != nil {
+		t.Fatalf("Error deleting world: %v", err)
+	}
+	if err := repo.RestoreWorld("metaverse"); err != nil {
+		t.Fatalf("Error restoring world: %v", err)
+	}
+	all, err := repo.AllWorldEdges()
+	if err != nil || len(all) != 1 || all[0].From != "cafe" || all[0].To != "lobby" {
+		t.Errorf("Expected only the cafe and lobby connected, got %+v, %v", all, err)
+	}
+
+	// Batches connect worlds created earlier in the batch
+	_, err = repo.ApplyBatch([]repository.BatchOp{
+		{Op: repository.BatchCreateWorld, World: &models.World{ID: "terrace", Type: models.WorldTypePhysical}},
+		{Op: repository.BatchConnect, Edge: &models.WorldEdge{From: "terrace", To: "cafe", Weight: 0.6}},
+		{Op: repository.BatchDisconnect, Edge: &models.WorldEdge{From: "lobby", To: "cafe"}},
+	})
+	if err != nil {
+		t.Fatalf("Error applying batch: %v", err)
+	}
+	if got, _ := repo.WorldEdges("cafe"); len(got) != 1 || got[0].To != "terrace" {
+		t.Errorf("Expected the cafe connected to the terrace only, got %+v", got)
+	}
+
+	// Edges travel in bundles
+	bundle, err := repo.ExportBundle()
+	if err != nil {
+		t.Fatalf("Error exporting: %v", err)
+	}
+	if len(bundle.Edges) != 1 {
+		t.Fatalf("Expected one edge in the bundle, got %+v", bundle.Edges)
+	}
+	target := repository.NewRepositoryWithSampleData(false)
+	result, err := target.ImportBundle(bundle, repository.ImportFail)
+	if err != nil {
+		t.Fatalf("Error importing: %v", err)
+	}
+	if result.Edges.Created != 1 {
+		t.Errorf("Expected one edge created, got %+v", result.Edges)
+	}
+	if all, _ := target.AllWorldEdges(); !reflect.DeepEqual(all, bundle.Edges) {
+		t.Errorf("Expected the imported edges %+v, got %+v", bundle.Edges, all)
+	}
+	if result, err := target.ImportBundle(bundle, repository.ImportSkip); err != nil || result.Edges.Skipped != 1 {
+		t.Errorf("Expected the edge to be skipped, got %+v, %v", result.Edges, err)
+	}
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// repositoryConstructor creates a new repository holding only the sample
+// vibes and worlds, released at the end of the test
+type repositoryConstructor func(t *testing.T) repository.VibeWorldRepository
+
+// repositoryBackends lists every repository backend the shared suite runs on
+var repositoryBackends = []struct {
+	name string
+	open repositoryConstructor
+}{
+	{"Memory", func(t *testing.T) repository.VibeWorldRepository {
+		return repository.NewRepository()
+	}},
+	{"File", func(t *testing.T) repository.VibeWorldRepository {
+		repo, err := repository.NewFileRepositoryWithSampleData(t.TempDir(), true)
+		if err != nil {
+			t.Fatalf("Error creating file repository: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	}},
+	{"SQLite", func(t *testing.T) repository.VibeWorldRepository {
+		repo, err := repository.NewSQLRepositoryWithSampleData(openTestSQLite(t), true)
+		if err != nil {
+			t.Fatalf("Error creating SQL repository: %v", err)
+		}
+		return repo
+	}},
+}
+
+// TestRepositoryBackends runs the shared repository suite on every backend
+func TestRepositoryBackends(t *testing.T) {
+	for _, backend := range repositoryBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			runRepositorySuite(t, backend.open)
+		})
+	}
+}
+
+// runRepositorySuite runs the tests every backend must pass, each on a new
+// repository from open
+func runRepositorySuite(t *testing.T, open repositoryConstructor) {
+	suite := []struct {
+		name string
+		test func(t *testing.T, repo repository.VibeWorldRepository)
+	}{
+		{"Repository", testRepository},
+		{"SensorData", testSensorData},
+		{"WorldFeatures", testWorldFeatures},
+		{"HybridWorlds", testHybridWorlds},
+		{"Concurrency", testConcurrency},
+		{"Integration", testIntegration},
+		{"QueryVibes", testQueryVibes},
+		{"QueryWorlds", testQueryWorlds},
+		{"QueryPagination", testQueryPagination},
+		{"OptimisticConcurrency", testOptimisticConcurrency},
+		{"History", testHistory},
+		{"ChangeEvents", testChangeEvents},
+		{"Trash", testTrash},
+		{"VibeDependents", testVibeDependents},
+		{"ApplyBatch", testApplyBatch},
+		{"Bundle", testBundle},
+		{"Validation", testValidation},
+		{"CreateIDs", testCreateIDs},
+		{"VibeInheritance", testVibeInheritance},
+		{"VibeTransitions", testVibeTransitions},
+		{"WorldHierarchy", testWorldHierarchy},
+		{"WorldAdjacency", testWorldAdjacency},
+		{"SensorChannels", testSensorChannels},
+	}
+	for _, tc := range suite {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, open(t))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// scenarioBatch creates a vibe and a world using it, then moves an existing world onto it
+func scenarioBatch() []repository.BatchOp {
+	return []repository.BatchOp{
+		{Op: repository.BatchCreateVibe, Vibe: &models.Vibe{ID: "batch-vibe", Name: "Batch Vibe", Energy: 0.5}},
+		{Op: repository.BatchCreateWorld, World: &models.World{ID: "batch-world", Name: "Batch World", Type: models.WorldTypeVirtual, CurrentVibe: "batch-vibe"}},
+		{Op: repository.BatchSetWorldVibe, WorldID: "office-space", VibeID: "batch-vibe", Version: 1},
+		{Op: repository.BatchDeleteVibe, ID: "focused-flow"},
+	}
+}
+
+// testApplyBatch tests that batches are applied all or nothing
+func testApplyBatch(t *testing.T, repo repository.VibeWorldRepository) {
+	events, stop := repo.SubscribeChanges(64)
+	defer stop()
+
+	// A failure at the end leaves nothing behind
+	failing := append(scenarioBatch(), repository.BatchOp{Op: repository.BatchSetWorldVibe, WorldID: "no-such-world", VibeID: "batch-vibe"})
+	_, err := repo.ApplyBatch(failing)
+	var batchErr *repository.BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 4 || !errors.Is(err, repository.ErrWorldNotFound) {
+		t.Fatalf("Expected operation 4 to fail with ErrWorldNotFound, got %v", err)
+	}
+	if _, err := repo.GetVibe("batch-vibe"); err != repository.ErrVibeNotFound {
+		t.Errorf("Expected batch-vibe not to exist after a failed batch, got %v", err)
+	}
+	if world, _ := repo.GetWorld("office-space"); world.CurrentVibe != "focused-flow" || world.Version != 1 {
+		t.Errorf("Expected office-space untouched, got %q at version %d", world.CurrentVibe, world.Version)
+	}
+	if _, err := repo.VibeHistory("batch-vibe"); err != repository.ErrVibeNotFound {
+		t.Errorf("Expected no history for a failed batch, got %v", err)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Expected no events for a failed batch, got %s", event.Type)
+	default:
+	}
+
+	// Each operation sees the ones before it: focused-flow is only free once office-space moved
+	if _, err := repo.WithActor("alice").ApplyBatch(scenarioBatch()); err != nil {
+		t.Fatalf("Error applying batch: %v", err)
+	}
+	world, err := repo.GetWorld("batch-world")
+	if err != nil || world.CurrentVibe != "batch-vibe" {
+		t.Errorf("Expected batch-world on batch-vibe, got %+v (%v)", world, err)
+	}
+	if world, _ := repo.GetWorld("office-space"); world.CurrentVibe != "batch-vibe" || world.Version != 2 {
+		t.Errorf("Expected office-space on batch-vibe at version 2, got %q at %d", world.CurrentVibe, world.Version)
+	}
+	if _, err := repo.GetVibe("focused-flow"); err != repository.ErrVibeNotFound {
+		t.Errorf("Expected focused-flow deleted, got %v", err)
+	}
+	entries, _ := repo.VibeHistory("batch-vibe")
+	if len(entries) != 1 || entries[0].Actor != "alice" {
+		t.Errorf("Expected one history entry by alice, got %+v", entries)
+	}
+	for i := 0; i < 4; i++ {
+		nextEvent(t, events)
+	}
+
+	// Conflicts inside a batch are reported like single writes
+	_, err = repo.ApplyBatch([]repository.BatchOp{{Op: repository.BatchSetWorldVibe, WorldID: "office-space", VibeID: "calm-clarity", Version: 1}})
+	if !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("Expected a version conflict, got %v", err)
+	}
+
+	// Malformed batches are rejected before anything runs
+	malformed := [][]repository.BatchOp{
+		nil,
+		{{Op: "rename_world", ID: "office-space"}},
+		{{Op: repository.BatchDeleteWorld, ID: "virtual-garden"}, {Op: repository.BatchCreateVibe}},
+		{{Op: repository.BatchDeleteVibe, ID: "calm-clarity", Mode: "cascade"}},
+	}
+	for i, ops := range malformed {
+		if _, err := repo.ApplyBatch(ops); !errors.Is(err, repository.ErrInvalidBatch) {
+			t.Errorf("Batch %d: expected ErrInvalidBatch, got %v", i, err)
+		}
+	}
+	if _, err := repo.GetWorld("virtual-garden"); err != nil {
+		t.Errorf("Expected virtual-garden to survive a malformed batch, got %v", err)
+	}
+}
+
+// TestFileRepositoryBatch tests that batches are replayed from the journal
+func TestFileRepositoryBatch(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error opening file repository: %v", err)
+	}
+	repo.SetCompactEvery(0)
+	repo.AddVibe(models.Vibe{ID: "focused-flow", Name: "Focused"})
+	repo.AddWorld(models.World{ID: "office-space", Name: "Office", Type: models.WorldTypePhysical, CurrentVibe: "focused-flow"})
+	if _, err := repo.ApplyBatch(scenarioBatch()[:3]); err != nil {
+		t.Fatalf("Error applying batch: %v", err)
+	}
+	repo.Close()
+
+	reopened, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error reopening file repository: %v", err)
+	}
+	defer reopened.Close()
+	if world, err := reopened.GetWorld("batch-world"); err != nil || world.CurrentVibe != "batch-vibe" {
+		t.Errorf("Expected batch-world after replay, got %+v (%v)", world, err)
+	}
+}
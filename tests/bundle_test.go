@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// testBundle tests exporting a repository and importing it with each strategy
+func testBundle(t *testing.T, repo repository.VibeWorldRepository) {
+	world, _ := repo.GetWorld("office-space")
+	world.Sharing = models.SharingSettings{AllowedUsers: []string{"alice"}, ContextLevel: models.ContextLevelPartial}
+	if err := repo.UpdateWorld(world); err != nil {
+		t.Fatalf("Error updating world: %v", err)
+	}
+
+	bundle, err := repo.ExportBundle()
+	if err != nil {
+		t.Fatalf("Error exporting bundle: %v", err)
+	}
+	if bundle.Format != repository.BundleFormat || len(bundle.Vibes) != len(repo.GetAllVibes()) || len(bundle.Worlds) != len(repo.GetAllWorlds()) {
+		t.Fatalf("Expected every vibe and world in the bundle, got %+v", bundle)
+	}
+
+	// The bundle survives encoding and loads into an empty repository of either backend
+	var buf bytes.Buffer
+	if err := repository.WriteBundle(&buf, bundle); err != nil {
+		t.Fatalf("Error writing bundle: %v", err)
+	}
+	decoded, err := repository.ReadBundle(&buf)
+	if err != nil {
+		t.Fatalf("Error reading bundle: %v", err)
+	}
+	sqlTarget, err := repository.NewSQLRepositoryWithSampleData(openTestSQLite(t), false)
+	if err != nil {
+		t.Fatalf("Error creating SQL repository: %v", err)
+	}
+	targets := map[string]repository.VibeWorldRepository{
+		"memory": repository.NewRepositoryWithSampleData(false),
+		"sqlite": sqlTarget,
+	}
+	for name, target := range targets {
+		result, err := target.ImportBundle(decoded, repository.ImportFail)
+		if err != nil {
+			t.Fatalf("%s: error importing bundle: %v", name, err)
+		}
+		if result.Vibes.Created != len(bundle.Vibes) || result.Worlds.Created != len(bundle.Worlds) {
+			t.Errorf("%s: expected everything created, got %+v", name, result)
+		}
+		imported, err := target.GetWorld("office-space")
+		if err != nil || imported.CurrentVibe != world.CurrentVibe || imported.Version != 1 {
+			t.Errorf("%s: expected office-space on %q at version 1, got %+v (%v)", name, world.CurrentVibe, imported, err)
+		}
+		if len(imported.Sharing.AllowedUsers) != 1 || imported.Sharing.ContextLevel != models.ContextLevelPartial {
+			t.Errorf("%s: expected sharing settings to be imported, got %+v", name, imported.Sharing)
+		}
+	}
+
+	// Existing entities are kept, rejected or replaced according to the strategy
+	vibe, _ := repo.GetVibe("calm-clarity")
+	vibe.Name = "Changed After Export"
+	if err := repo.UpdateVibe(vibe); err != nil {
+		t.Fatalf("Error updating vibe: %v", err)
+	}
+
+	result, err := repo.ImportBundle(bundle, repository.ImportSkip)
+	if err != nil || result.Vibes.Skipped != len(bundle.Vibes) || result.Vibes.Created != 0 {
+		t.Errorf("Expected every vibe skipped, got %+v (%v)", result, err)
+	}
+	if vibe, _ := repo.GetVibe("calm-clarity"); vibe.Name != "Changed After Export" {
+		t.Errorf("Expected skip to keep the existing vibe, got %q", vibe.Name)
+	}
+
+	if _, err := repo.ImportBundle(bundle, repository.ImportFail); !errors.Is(err, repository.ErrImportConflict) {
+		t.Errorf("Expected ErrImportConflict, got %v", err)
+	}
+
+	result, err = repo.WithActor("restorer").ImportBundle(bundle, repository.ImportOverwrite)
+	if err != nil || result.Vibes.Overwritten != len(bundle.Vibes) {
+		t.Fatalf("Expected every vibe overwritten, got %+v (%v)", result, err)
+	}
+	restored, _ := repo.GetVibe("calm-clarity")
+	if restored.Name == "Changed After Export" || restored.Version != vibe.Version+2 {
+		t.Errorf("Expected the exported vibe at version %d, got %q at %d", vibe.Version+2, restored.Name, restored.Version)
+	}
+	entries, _ := repo.VibeHistory("calm-clarity")
+	if last := entries[len(entries)-1]; last.Actor != "restorer" {
+		t.Errorf("Expected the import attributed to restorer, got %q", last.Actor)
+	}
+
+	// A bundle that cannot be applied in full changes nothing
+	broken := repository.NewBundle(
+		[]models.Vibe{{ID: "bundle-vibe", Name: "Bundle Vibe"}},
+		[]models.World{{ID: "bundle-world", Name: "Bundle World", Type: models.WorldTypeVirtual, CurrentVibe: "no-such-vibe"}})
+	if _, err := repo.ImportBundle(broken, repository.ImportOverwrite); !errors.Is(err, repository.ErrVibeNotFound) {
+		t.Errorf("Expected ErrVibeNotFound, got %v", err)
+	}
+	if _, err := repo.GetVibe("bundle-vibe"); err != repository.ErrVibeNotFound {
+		t.Errorf("Expected bundle-vibe not to exist after a failed import, got %v", err)
+	}
+
+	invalid := []repository.Bundle{
+		{Format: "something-else", Version: 1},
+		{Format: repository.BundleFormat, Version: repository.BundleVersion + 1},
+		repository.NewBundle([]models.Vibe{{ID: "twice"}, {ID: "twice"}}, nil),
+	}
+	for _, b := range invalid {
+		if _, err := repo.ImportBundle(b, repository.ImportSkip); !errors.Is(err, repository.ErrInvalidBundle) {
+			t.Errorf("Expected ErrInvalidBundle for %+v, got %v", b, err)
+		}
+	}
+}
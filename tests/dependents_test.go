@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// dependentIDs lists the IDs of the worlds using a vibe
+func dependentIDs(t *testing.T, repo repository.VibeWorldRepository, vibeID string) []string {
+	t.Helper()
+	worlds, err := repo.GetVibeWorlds(vibeID)
+	if err != nil {
+		t.Fatalf("Error listing worlds using %s: %v", vibeID, err)
+	}
+	return worldIDs(worlds)
+}
+
+// testVibeDependents tests the vibe-to-worlds index and the delete modes
+func testVibeDependents(t *testing.T, repo repository.VibeWorldRepository) {
+	repo.AddWorld(models.World{ID: "annex", Name: "Annex", Type: models.WorldTypePhysical, CurrentVibe: "focused-flow"})
+	if ids := dependentIDs(t, repo, "focused-flow"); !reflect.DeepEqual(ids, []string{"annex", "office-space"}) {
+		t.Errorf("Expected annex and office-space, got %v", ids)
+	}
+
+	// The index follows vibe changes and deletions
+	repo.SetWorldVibe("office-space", "calm-clarity")
+	repo.DeleteWorld("virtual-garden")
+	if ids := dependentIDs(t, repo, "calm-clarity"); !reflect.DeepEqual(ids, []string{"office-space"}) {
+		t.Errorf("Expected only office-space, got %v", ids)
+	}
+	if _, err := repo.GetVibeWorlds("no-such-vibe"); err != repository.ErrVibeNotFound {
+		t.Errorf("Expected ErrVibeNotFound, got %v", err)
+	}
+
+	// Rejecting leaves everything untouched
+	if _, err := repo.DeleteVibeWithOptions("focused-flow", repository.VibeDeleteOptions{}); err != repository.ErrVibeInUse {
+		t.Errorf("Expected ErrVibeInUse, got %v", err)
+	}
+	for _, replacement := range []string{"", "focused-flow", "no-such-vibe"} {
+		_, err := repo.DeleteVibeWithOptions("focused-flow", repository.VibeDeleteOptions{Mode: repository.DeleteReassign, ReplacementID: replacement})
+		if err != repository.ErrInvalidReplacement {
+			t.Errorf("Expected ErrInvalidReplacement for replacement %q, got %v", replacement, err)
+		}
+	}
+	if _, err := repo.GetVibe("focused-flow"); err != nil {
+		t.Fatalf("Expected focused-flow to survive failed deletes, got %v", err)
+	}
+
+	// Reassigning moves every dependent world in the same write
+	affected, err := repo.WithActor("alice").DeleteVibeWithOptions("focused-flow", repository.VibeDeleteOptions{
+		Mode:          repository.DeleteReassign,
+		ReplacementID: "energetic-spark",
+	})
+	if err != nil {
+		t.Fatalf("Error deleting with reassign: %v", err)
+	}
+	if !reflect.DeepEqual(affected, []string{"annex"}) {
+		t.Errorf("Expected annex to be affected, got %v", affected)
+	}
+	annex, _ := repo.GetWorld("annex")
+	if annex.CurrentVibe != "energetic-spark" || annex.Version != 2 {
+		t.Errorf("Expected annex on energetic-spark at version 2, got %q at %d", annex.CurrentVibe, annex.Version)
+	}
+	entries, _ := repo.WorldHistory("annex")
+	if last := entries[len(entries)-1]; last.Actor != "alice" {
+		t.Errorf("Expected the reassignment to be attributed to alice, got %q", last.Actor)
+	}
+	if ids := dependentIDs(t, repo, "energetic-spark"); !reflect.DeepEqual(ids, []string{"annex", "hybrid-studio"}) {
+		t.Errorf("Expected annex and hybrid-studio, got %v", ids)
+	}
+
+	// Clearing leaves the worlds without a vibe
+	affected, err = repo.DeleteVibeWithOptions("energetic-spark", repository.VibeDeleteOptions{Mode: repository.DeleteClear})
+	if err != nil || len(affected) != 2 {
+		t.Fatalf("Expected two worlds cleared, got %v (%v)", affected, err)
+	}
+	for _, id := range affected {
+		world, _ := repo.GetWorld(id)
+		if world.CurrentVibe != "" {
+			t.Errorf("Expected %s to have no vibe, got %q", id, world.CurrentVibe)
+		}
+	}
+
+	// Unused vibes are deleted in any mode
+	repo.AddVibe(models.Vibe{ID: "unused", Name: "Unused"})
+	affected, err = repo.DeleteVibeWithOptions("unused", repository.VibeDeleteOptions{Mode: repository.DeleteReject})
+	if err != nil || len(affected) != 0 {
+		t.Errorf("Expected unused vibe deleted without affected worlds, got %v (%v)", affected, err)
+	}
+	if _, err := repo.DeleteVibeWithOptions("unused", repository.VibeDeleteOptions{}); err != repository.ErrVibeNotFound {
+		t.Errorf("Expected ErrVibeNotFound, got %v", err)
+	}
+}
+
+// TestFileRepositoryDependents tests that the index is rebuilt on reopen
+func TestFileRepositoryDependents(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error opening file repository: %v", err)
+	}
+	repo.SetCompactEvery(0)
+	repo.AddVibe(models.Vibe{ID: "shared", Name: "Shared"})
+	repo.AddWorld(models.World{ID: "snapshotted", Name: "Snapshotted", Type: models.WorldTypeVirtual, CurrentVibe: "shared"})
+	repo.Compact()
+	repo.AddWorld(models.World{ID: "journaled", Name: "Journaled", Type: models.WorldTypeVirtual, CurrentVibe: "shared"})
+	repo.Close()
+
+	reopened, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error reopening file repository: %v", err)
+	}
+	defer reopened.Close()
+
+	if ids := dependentIDs(t, reopened, "shared"); !reflect.DeepEqual(ids, []string{"journaled", "snapshotted"}) {
+		t.Errorf("Expected both worlds after reopening, got %v", ids)
+	}
+	if err := reopened.DeleteVibe("shared"); err != repository.ErrVibeInUse {
+		t.Errorf("Expected ErrVibeInUse after reopening, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// nextEvent waits briefly for the next change event
+func nextEvent(t *testing.T, events <-chan repository.ChangeEvent) repository.ChangeEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for a change event")
+		return repository.ChangeEvent{}
+	}
+}
+
+// testChangeEvents tests that committed writes are published as typed
+// events and failed writes are not
+func testChangeEvents(t *testing.T, repo repository.VibeWorldRepository) {
+	events, stop := repo.SubscribeChanges(16)
+
+	repo.WithActor("alice").AddWorld(models.World{ID: "event-world", Name: "Events", Type: models.WorldTypeVirtual, CurrentVibe: "focused-flow"})
+	repo.SetWorldVibe("event-world", "calm-clarity")
+	world, _ := repo.GetWorld("event-world")
+	world.Description = "Now with a description"
+	repo.UpdateWorld(world)
+
+	vibe, _ := repo.GetVibe("energetic-spark")
+	vibe.Energy = 1.0
+	repo.UpdateVibe(vibe)
+
+	// Failed writes publish nothing
+	if err := repo.DeleteVibe("calm-clarity"); err != repository.ErrVibeInUse {
+		t.Fatalf("Expected ErrVibeInUse, got %v", err)
+	}
+	if err := repo.SetWorldVibe("event-world", "no-such-vibe"); err != repository.ErrVibeNotFound {
+		t.Fatalf("Expected ErrVibeNotFound, got %v", err)
+	}
+
+	repo.DeleteWorld("event-world")
+
+	expected := []struct {
+		typ repository.ChangeEventType
+		id  string
+	}{
+		{repository.EventWorldCreated, "event-world"},
+		{repository.EventWorldVibeSet, "event-world"},
+		{repository.EventWorldUpdated, "event-world"},
+		{repository.EventVibeChanged, "energetic-spark"},
+		{repository.EventWorldDeleted, "event-world"},
+	}
+	var received []repository.ChangeEvent
+	for i, e := range expected {
+		event := nextEvent(t, events)
+		received = append(received, event)
+		if event.Type != e.typ || event.ID != e.id {
+			t.Errorf("Event %d: expected %s for %s, got %s for %s", i, e.typ, e.id, event.Type, event.ID)
+		}
+	}
+
+	if received[0].Actor != "alice" || received[0].World == nil || received[0].World.Version != 1 {
+		t.Errorf("Expected the created world attributed to alice, got %+v", received[0])
+	}
+	if received[1].PreviousVibe != "focused-flow" || received[1].World.CurrentVibe != "calm-clarity" {
+		t.Errorf("Expected focused-flow -> calm-clarity, got %+v", received[1])
+	}
+	if received[3].Vibe == nil || received[3].Vibe.Energy != 1.0 {
+		t.Errorf("Expected the updated vibe in the event, got %+v", received[3].Vibe)
+	}
+	if received[4].World != nil {
+		t.Errorf("Expected no world in a delete event")
+	}
+
+	// Cancelling closes the channel and stops delivery
+	stop()
+	repo.SetWorldVibe("office-space", "calm-clarity")
+	if _, ok := <-events; ok {
+		t.Errorf("Expected the channel to be closed after cancelling")
+	}
+	stop()
+}
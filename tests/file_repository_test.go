@@ -0,0 +1,309 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// TestFileRepository tests that the file-backed repository persists writes across restarts
+func TestFileRepository(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error opening file repository: %v", err)
+	}
+
+	if len(repo.GetAllVibes()) != 0 || len(repo.GetAllWorlds()) != 0 {
+		t.Fatalf("Expected a new file repository to be empty")
+	}
+
+	vibe := models.Vibe{
+		ID:     "durable-vibe",
+		Name:   "Durable Vibe",
+		Energy: 0.4,
+		Mood:   models.MoodCalm,
+		Colors: []string{"#112233"},
+	}
+	if err := repo.AddVibe(vibe); err != nil {
+		t.Fatalf("Error adding vibe: %v", err)
+	}
+
+	world := models.World{
+		ID:          "durable-world",
+		Name:        "Durable World",
+		Type:        models.WorldTypePhysical,
+		CurrentVibe: "durable-vibe",
+		Features:    []string{"thick walls"},
+	}
+	if err := repo.AddWorld(world); err != nil {
+		t.Fatalf("Error adding world: %v", err)
+	}
+
+	world.Occupancy = 12
+	if err := repo.UpdateWorld(world); err != nil {
+		t.Fatalf("Error updating world: %v", err)
+	}
+
+	if err := repo.AddVibe(models.Vibe{ID: "temporary-vibe", Name: "Temporary"}); err != nil {
+		t.Fatalf("Error adding vibe: %v", err)
+	}
+	if err := repo.DeleteVibe("temporary-vibe"); err != nil {
+		t.Fatalf("Error deleting vibe: %v", err)
+	}
+
+	if err := repo.Close(); err != nil {
+		t.Fatalf("Error closing repository: %v", err)
+	}
+
+	// Reopen and verify the state was replayed from the journal
+	reopened, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error reopening file repository: %v", err)
+	}
+	defer reopened.Close()
+
+	gotWorld, err := reopened.GetWorld("durable-world")
+	if err != nil {
+		t.Fatalf("Error getting world after reopen: %v", err)
+	}
+	if gotWorld.Occupancy != 12 {
+		t.Errorf("Expected occupancy 12 after reopen, got %d", gotWorld.Occupancy)
+	}
+	if len(gotWorld.Features) != 1 || gotWorld.Features[0] != "thick walls" {
+		t.Errorf("Expected features to survive reopen, got %v", gotWorld.Features)
+	}
+
+	gotVibe, err := reopened.GetWorldVibe("durable-world")
+	if err != nil {
+		t.Fatalf("Error getting world vibe after reopen: %v", err)
+	}
+	if gotVibe.Name != "Durable Vibe" {
+		t.Errorf("Expected vibe name 'Durable Vibe', got '%s'", gotVibe.Name)
+	}
+
+	if _, err := reopened.GetVibe("temporary-vibe"); err != repository.ErrVibeNotFound {
+		t.Errorf("Expected deleted vibe to stay deleted, got %v", err)
+	}
+
+	// Relation checks still apply to the file-backed repository
+	if err := reopened.DeleteVibe("durable-vibe"); err != repository.ErrVibeInUse {
+		t.Errorf("Expected ErrVibeInUse, got %v", err)
+	}
+}
+
+// TestFileRepositoryCompaction tests snapshotting and replay on top of a snapshot
+func TestFileRepositoryCompaction(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error opening file repository: %v", err)
+	}
+	repo.SetCompactEvery(0)
+
+	if err := repo.AddVibe(models.Vibe{ID: "before-snapshot", Name: "Before"}); err != nil {
+		t.Fatalf("Error adding vibe: %v", err)
+	}
+	for _, id := range []string{"east-wing", "west-wing", "roof"} {
+		if err := repo.AddWorld(models.World{ID: id, Type: models.WorldTypePhysical}); err != nil {
+			t.Fatalf("Error adding world: %v", err)
+		}
+	}
+	if err := repo.ConnectWorlds(models.WorldEdge{From: "east-wing", To: "west-wing", Weight: 0.4}); err != nil {
+		t.Fatalf("Error connecting worlds: %v", err)
+	}
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("Error compacting: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, repository.JournalFileName))
+	if err != nil {
+		t.Fatalf("Error reading journal: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("Expected empty journal after compaction, got %d bytes", info.Size())
+	}
+
+	if err := repo.AddVibe(models.Vibe{ID: "after-snapshot", Name: "After"}); err != nil {
+		t.Fatalf("Error adding vibe: %v", err)
+	}
+	if err := repo.ConnectWorlds(models.WorldEdge{From: "roof", To: "east-wing", Kind: models.EdgeKindDoor}); err != nil {
+		t.Fatalf("Error connecting worlds: %v", err)
+	}
+	repo.Close()
+
+	reopened, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error reopening file repository: %v", err)
+	}
+	defer reopened.Close()
+
+	for _, id := range []string{"before-snapshot", "after-snapshot"} {
+		if _, err := reopened.GetVibe(id); err != nil {
+			t.Errorf("Expected vibe %s after reopen, got %v", id, err)
+		}
+	}
+	if edges, err := reopened.WorldEdges("east-wing"); err != nil || len(edges) != 2 {
+		t.Errorf("Expected the edges from the snapshot and the journal after reopen, got %+v, %v", edges, err)
+	}
+}
+
+// TestFileRepositoryCrashRecovery tests that a torn journal tail is discarded
+// and that a complete but corrupt record is reported
+func TestFileRepositoryCrashRecovery(t *testing.T) {
+	t.Run("TornTail", func(t *testing.T) {
+		dir := t.TempDir()
+
+		repo, err := repository.NewFileRepository(dir)
+		if err != nil {
+			t.Fatalf("Error opening file repository: %v", err)
+		}
+		if err := repo.AddVibe(models.Vibe{ID: "acknowledged", Name: "Acknowledged"}); err != nil {
+			t.Fatalf("Error adding vibe: %v", err)
+		}
+		repo.Close()
+
+		// Simulate a crash in the middle of writing the next record
+		journal, err := os.OpenFile(filepath.Join(dir, repository.JournalFileName), os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatalf("Error opening journal: %v", err)
+		}
+		journal.WriteString(`{"seq":2,"mutations":[{"op":"putVibe","id":"torn"`)
+		journal.Close()
+
+		reopened, err := repository.NewFileRepository(dir)
+		if err != nil {
+			t.Fatalf("Expected torn tail to be tolerated, got %v", err)
+		}
+		if _, err := reopened.GetVibe("acknowledged"); err != nil {
+			t.Errorf("Expected acknowledged vibe to survive, got %v", err)
+		}
+		if _, err := reopened.GetVibe("torn"); err != repository.ErrVibeNotFound {
+			t.Errorf("Expected torn vibe to be discarded, got %v", err)
+		}
+
+		// New writes must start on a clean line
+		if err := reopened.AddVibe(models.Vibe{ID: "after-crash", Name: "After Crash"}); err != nil {
+			t.Fatalf("Error adding vibe after crash: %v", err)
+		}
+		reopened.Close()
+
+		again, err := repository.NewFileRepository(dir)
+		if err != nil {
+			t.Fatalf("Error reopening after crash recovery: %v", err)
+		}
+		defer again.Close()
+		if _, err := again.GetVibe("after-crash"); err != nil {
+			t.Errorf("Expected vibe written after recovery, got %v", err)
+		}
+	})
+
+	t.Run("CorruptRecord", func(t *testing.T) {
+		dir := t.TempDir()
+		content := "not json\n"
+		if err := os.WriteFile(filepath.Join(dir, repository.JournalFileName), []byte(content), 0o644); err != nil {
+			t.Fatalf("Error writing journal: %v", err)
+		}
+
+		_, err := repository.NewFileRepository(dir)
+		if !errors.Is(err, repository.ErrCorruptJournal) {
+			t.Errorf("Expected ErrCorruptJournal, got %v", err)
+		}
+	})
+
+	t.Run("WriteAfterClose", func(t *testing.T) {
+		repo, err := repository.NewFileRepository(t.TempDir())
+		if err != nil {
+			t.Fatalf("Error opening file repository: %v", err)
+		}
+		repo.Close()
+
+		err = repo.AddVibe(models.Vibe{ID: "unpersisted", Name: "Unpersisted"})
+		if !errors.Is(err, repository.ErrRepositoryClosed) {
+			t.Errorf("Expected ErrRepositoryClosed, got %v", err)
+		}
+		if _, err := repo.GetVibe("unpersisted"); err != repository.ErrVibeNotFound {
+			t.Errorf("Expected failed write to leave repository unchanged, got %v", err)
+		}
+	})
+}
+
+// TestFileRepositoryAutoCompaction tests that the journal is folded into a snapshot automatically
+func TestFileRepositoryAutoCompaction(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error opening file repository: %v", err)
+	}
+	repo.SetCompactEvery(3)
+
+	for _, id := range []string{"one", "two", "three"} {
+		if err := repo.AddVibe(models.Vibe{ID: id, Name: id}); err != nil {
+			t.Fatalf("Error adding vibe %s: %v", id, err)
+		}
+	}
+
+	// A manual compaction waits for the background one and leaves the same state
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("Error compacting: %v", err)
+	}
+	repo.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, repository.SnapshotFileName)); err != nil {
+		t.Fatalf("Expected snapshot file, got %v", err)
+	}
+
+	reopened, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error reopening file repository: %v", err)
+	}
+	defer reopened.Close()
+
+	if len(reopened.GetAllVibes()) != 3 {
+		t.Errorf("Expected 3 vibes after reopen, got %d", len(reopened.GetAllVibes()))
+	}
+}
+
+// TestFileRepositorySampleData tests that sample data seeds a new directory
+// only, and survives a restart like any other write
+func TestFileRepositorySampleData(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := repository.NewFileRepositoryWithSampleData(dir, true)
+	if err != nil {
+		t.Fatalf("Error opening file repository: %v", err)
+	}
+	sample := repository.NewRepository()
+	if len(repo.GetAllVibes()) != len(sample.GetAllVibes()) || len(repo.GetAllWorlds()) != len(sample.GetAllWorlds()) {
+		t.Fatalf("Expected the sample vibes and worlds, got %d vibes and %d worlds", len(repo.GetAllVibes()), len(repo.GetAllWorlds()))
+	}
+	if world, err := repo.GetWorld("office-space"); err != nil || world.Version != 1 {
+		t.Errorf("Expected office-space at version 1, got %+v, %v", world, err)
+	}
+	if err := repo.DeleteWorld("office-space"); err != nil {
+		t.Fatalf("Error deleting world: %v", err)
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatalf("Error closing repository: %v", err)
+	}
+
+	// Reopening keeps the store as it was left instead of seeding it again
+	reopened, err := repository.NewFileRepositoryWithSampleData(dir, true)
+	if err != nil {
+		t.Fatalf("Error reopening file repository: %v", err)
+	}
+	defer reopened.Close()
+	if _, err := reopened.GetWorld("office-space"); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected the deleted world to stay deleted, got %v", err)
+	}
+	if _, err := reopened.GetWorld("virtual-garden"); err != nil {
+		t.Errorf("Expected the other sample worlds to persist, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// testWorldHierarchy tests nesting worlds, listing them and rolling them up
+func testWorldHierarchy(t *testing.T, repo repository.VibeWorldRepository) {
+	worlds := []models.World{
+		{ID: "hq", Name: "HQ", Type: models.WorldTypePhysical, Occupancy: 2},
+		{ID: "floor-1", Name: "Floor 1", Type: models.WorldTypePhysical, ParentID: "hq"},
+		{ID: "room-101", Name: "Room 101", Type: models.WorldTypePhysical, ParentID: "floor-1", Occupancy: 10},
+		{ID: "room-102", Name: "Room 102", Type: models.WorldTypePhysical, ParentID: "floor-1", Occupancy: 50},
+		{ID: "floor-2", Name: "Floor 2", Type: models.WorldTypePhysical, ParentID: "hq", Occupancy: 30},
+	}
+	for _, world := range worlds {
+		if err := repo.AddWorld(world); err != nil {
+			t.Fatalf("Error adding world %s: %v", world.ID, err)
+		}
+	}
+
+	if err := repo.AddWorld(models.World{ID: "attic", Type: models.WorldTypePhysical, ParentID: "missing"}); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected a missing parent to be rejected, got %v", err)
+	}
+
+	children, err := repo.ChildWorlds("floor-1")
+	if err != nil || len(children) != 2 || children[0].ID != "room-101" || children[1].ID != "room-102" {
+		t.Errorf("Expected rooms 101 and 102 on floor 1, got %+v, %v", children, err)
+	}
+	if children, err := repo.ChildWorlds("room-101"); err != nil || len(children) != 0 {
+		t.Errorf("Expected no children in a room, got %+v, %v", children, err)
+	}
+	if _, err := repo.ChildWorlds("missing"); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected ErrWorldNotFound for a missing world, got %v", err)
+	}
+
+	ancestors, err := repo.WorldAncestors("room-102")
+	if err != nil || len(ancestors) != 2 || ancestors[0].ID != "floor-1" || ancestors[1].ID != "hq" {
+		t.Errorf("Expected floor 1 then hq, got %+v, %v", ancestors, err)
+	}
+
+	// Occupancy adds up; activity averages the rooms and the floor without rooms
+	rollup, err := repo.WorldRollup("hq")
+	if err != nil {
+		t.Fatalf("Error rolling up hq: %v", err)
+	}
+	if !reflect.DeepEqual(rollup.Children, []string{"floor-1", "floor-2"}) || rollup.Descendants != 4 ||
+		rollup.Occupancy != 92 || rollup.Leaves != 3 {
+		t.Errorf("Unexpected rollup %+v", rollup)
+	}
+	if rollup.Activity < 0.299 || rollup.Activity > 0.301 {
+		t.Errorf("Expected an activity of 0.3, got %v", rollup.Activity)
+	}
+
+	// A world cannot move into its own subtree
+	hq, _ := repo.GetWorld("hq")
+	hq.ParentID = "room-101"
+	if err := repo.UpdateWorld(hq); !errors.Is(err, repository.ErrWorldCycle) {
+		t.Errorf("Expected ErrWorldCycle, got %v", err)
+	}
+
+	// A world with children cannot be deleted, and a child needs its parent back
+	if err := repo.DeleteWorld("floor-1"); err != repository.ErrWorldHasChildren {
+		t.Errorf("Expected ErrWorldHasChildren, got %v", err)
+	}
+	if err := repo.DeleteWorld("floor-2"); err != nil {
+		t.Fatalf("Error deleting floor 2: %v", err)
+	}
+	room, _ := repo.GetWorld("room-102")
+	room.ParentID = "floor-2"
+	if err := repo.UpdateWorld(room); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected a deleted parent to be rejected, got %v", err)
+	}
+	if err := repo.RestoreWorld("floor-2"); err != nil {
+		t.Fatalf("Error restoring floor 2: %v", err)
+	}
+	if err := repo.UpdateWorld(room); err != nil {
+		t.Fatalf("Error moving room 102: %v", err)
+	}
+	if children, _ := repo.ChildWorlds("floor-2"); len(children) != 1 || children[0].ParentID != "floor-2" {
+		t.Errorf("Expected room 102 on floor 2, got %+v", children)
+	}
+
+	// Imports create parents first, whatever the order of the bundle
+	bundle, err := repo.ExportBundle()
+	if err != nil {
+		t.Fatalf("Error exporting: %v", err)
+	}
+	for i, j := 0, len(bundle.Worlds)-1; i < j; i, j = i+1, j-1 {
+		bundle.Worlds[i], bundle.Worlds[j] = bundle.Worlds[j], bundle.Worlds[i]
+	}
+	target := repository.NewRepositoryWithSampleData(false)
+	if _, err := target.ImportBundle(bundle, repository.ImportFail); err != nil {
+		t.Fatalf("Error importing: %v", err)
+	}
+	if ancestors, _ := target.WorldAncestors("room-101"); len(ancestors) != 2 {
+		t.Errorf("Expected room 101 to be imported nested, got %+v", ancestors)
+	}
+}
@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// changedFields returns the field names of a history entry's diff
+func changedFields(entry repository.HistoryEntry) map[string]bool {
+	fields := make(map[string]bool)
+	for _, change := range entry.Changes {
+		fields[change.Field] = true
+	}
+	return fields
+}
+
+// testHistory tests that writes are recorded with actor and diff, and that
+// worlds can be read as they were at a point in time
+func testHistory(t *testing.T, repo repository.VibeWorldRepository) {
+	// Sample data predates history: no entries, and the current state applies at any time
+	entries, err := repo.WorldHistory("office-space")
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Expected empty history for untouched world, got %d entries, %v", len(entries), err)
+	}
+	if _, err := repo.WorldHistory("no-such-world"); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected ErrWorldNotFound, got %v", err)
+	}
+
+	alice := repo.WithActor("alice")
+	bob := repo.WithActor("bob")
+
+	beforeCreate := time.Now()
+	if err := alice.AddWorld(models.World{ID: "history-world", Name: "Original", Type: models.WorldTypeVirtual, CurrentVibe: "calm-clarity"}); err != nil {
+		t.Fatalf("Error adding world: %v", err)
+	}
+	afterCreate := time.Now()
+
+	if err := bob.SetWorldVibe("history-world", "energetic-spark"); err != nil {
+		t.Fatalf("Error setting world vibe: %v", err)
+	}
+	afterSetVibe := time.Now()
+
+	world, _ := repo.GetWorld("history-world")
+	world.Sharing = models.SharingSettings{IsPublic: true, ContextLevel: models.ContextLevelFull}
+	if err := alice.UpdateWorld(world); err != nil {
+		t.Fatalf("Error updating world: %v", err)
+	}
+	afterUpdate := time.Now()
+
+	if err := repo.DeleteWorld("history-world"); err != nil {
+		t.Fatalf("Error deleting world: %v", err)
+	}
+
+	// History outlives the world
+	entries, err = repo.WorldHistory("history-world")
+	if err != nil {
+		t.Fatalf("Error reading history: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 history entries, got %d", len(entries))
+	}
+
+	expected := []struct {
+		op    repository.HistoryOp
+		actor string
+		field string
+	}{
+		{repository.HistoryCreate, "alice", "name"},
+		{repository.HistoryUpdate, "bob", "currentVibe"},
+		{repository.HistoryUpdate, "alice", "sharing"},
+		{repository.HistoryDelete, "", "name"},
+	}
+	for i, e := range expected {
+		entry := entries[i]
+		if entry.Op != e.op || entry.Actor != e.actor {
+			t.Errorf("Entry %d: expected %s by %q, got %s by %q", i, e.op, e.actor, entry.Op, entry.Actor)
+		}
+		if !changedFields(entry)[e.field] {
+			t.Errorf("Entry %d: expected %s in changes, got %v", i, e.field, entry.Changes)
+		}
+		if i > 0 && entry.Seq <= entries[i-1].Seq {
+			t.Errorf("Entry %d: expected increasing sequence numbers", i)
+		}
+	}
+
+	// The vibe change only touches the vibe and the version
+	if fields := changedFields(entries[1]); len(fields) != 2 || !fields["version"] {
+		t.Errorf("Expected currentVibe and version to change, got %v", entries[1].Changes)
+	}
+	var change struct{ Before, After string }
+	setVibe := entries[1].Changes[0]
+	json.Unmarshal(setVibe.Before, &change.Before)
+	json.Unmarshal(setVibe.After, &change.After)
+	if change.Before != "calm-clarity" || change.After != "energetic-spark" {
+		t.Errorf("Expected calm-clarity -> energetic-spark, got %s -> %s", change.Before, change.After)
+	}
+
+	// Point-in-time reads
+	if _, err := repo.WorldAt("history-world", beforeCreate); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected world not to exist before creation, got %v", err)
+	}
+	past, err := repo.WorldAt("history-world", afterCreate)
+	if err != nil || past.CurrentVibe != "calm-clarity" {
+		t.Errorf("Expected calm-clarity after creation, got %q (%v)", past.CurrentVibe, err)
+	}
+	past, err = repo.WorldAt("history-world", afterSetVibe)
+	if err != nil || past.CurrentVibe != "energetic-spark" || past.Sharing.IsPublic {
+		t.Errorf("Expected private world with energetic-spark, got %+v (%v)", past, err)
+	}
+	past, err = repo.WorldAt("history-world", afterUpdate)
+	if err != nil || !past.Sharing.IsPublic {
+		t.Errorf("Expected public world after sharing update, got %+v (%v)", past, err)
+	}
+	if _, err := repo.WorldAt("history-world", time.Now()); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected world not to exist after deletion, got %v", err)
+	}
+
+	// A world that predates history was as before its first recorded change
+	if err := repo.SetWorldVibe("office-space", "calm-clarity"); err != nil {
+		t.Fatalf("Error setting world vibe: %v", err)
+	}
+	past, err = repo.WorldAt("office-space", beforeCreate)
+	if err != nil || past.CurrentVibe != "focused-flow" {
+		t.Errorf("Expected office-space to have had focused-flow, got %q (%v)", past.CurrentVibe, err)
+	}
+
+	// Vibes have history too
+	if err := bob.UpdateVibe(models.Vibe{ID: "calm-clarity", Name: "Calmer Clarity", Energy: 0.2}); err != nil {
+		t.Fatalf("Error updating vibe: %v", err)
+	}
+	vibeEntries, err := repo.VibeHistory("calm-clarity")
+	if err != nil || len(vibeEntries) != 1 || vibeEntries[0].Actor != "bob" {
+		t.Errorf("Expected one vibe history entry by bob, got %v (%v)", vibeEntries, err)
+	}
+	pastVibe, err := repo.VibeAt("calm-clarity", beforeCreate)
+	if err != nil || pastVibe.Name != "Calm Clarity" {
+		t.Errorf("Expected original vibe name, got %q (%v)", pastVibe.Name, err)
+	}
+}
+
+// TestFileRepositoryHistory tests that history survives restarts and compaction
+func TestFileRepositoryHistory(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error opening file repository: %v", err)
+	}
+	repo.SetCompactEvery(0)
+
+	repo.WithActor("alice").AddVibe(models.Vibe{ID: "durable", Name: "Durable"})
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("Error compacting: %v", err)
+	}
+	repo.WithActor("bob").UpdateVibe(models.Vibe{ID: "durable", Name: "Renamed"})
+	repo.Close()
+
+	reopened, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error reopening file repository: %v", err)
+	}
+	defer reopened.Close()
+
+	entries, err := reopened.VibeHistory("durable")
+	if err != nil {
+		t.Fatalf("Error reading history: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Actor != "alice" || entries[1].Actor != "bob" {
+		t.Fatalf("Expected entries by alice (snapshot) and bob (journal), got %+v", entries)
+	}
+
+	// New entries continue the sequence
+	reopened.UpdateVibe(models.Vibe{ID: "durable", Name: "Again"})
+	entries, _ = reopened.VibeHistory("durable")
+	if len(entries) != 3 || entries[2].Seq <= entries[1].Seq {
+		t.Errorf("Expected a third entry with a higher sequence number, got %+v", entries)
+	}
+}
+
+// TestHistoryLimit tests that times before the dropped history entries are
+// reported as unknown, also after a restart
+func TestHistoryLimit(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error opening file repository: %v", err)
+	}
+	repo.SetCompactEvery(0)
+	repo.SetHistoryLimit(2)
+
+	beforeCreate := time.Now()
+	repo.AddVibe(models.Vibe{ID: "short-lived", Name: "First"})
+	afterCreate := time.Now()
+	repo.UpdateVibe(models.Vibe{ID: "short-lived", Name: "Second"})
+	repo.UpdateVibe(models.Vibe{ID: "short-lived", Name: "Third"})
+
+	entries, _ := repo.VibeHistory("short-lived")
+	if len(entries) != 2 || entries[0].Op != repository.HistoryUpdate {
+		t.Fatalf("Expected the two updates to be kept, got %+v", entries)
+	}
+
+	// The vibe existed before the first kept entry, but not before its create
+	if _, err := repo.VibeAt("short-lived", beforeCreate); err != repository.ErrHistoryTruncated {
+		t.Errorf("Expected ErrHistoryTruncated before the dropped create, got %v", err)
+	}
+	vibe, err := repo.VibeAt("short-lived", afterCreate)
+	if err != nil || vibe.Name != "First" {
+		t.Errorf("Expected the created vibe after the dropped create, got %q (%v)", vibe.Name, err)
+	}
+
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("Error compacting: %v", err)
+	}
+	repo.Close()
+
+	reopened, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error reopening file repository: %v", err)
+	}
+	defer reopened.Close()
+
+	if _, err := reopened.VibeAt("short-lived", beforeCreate); err != repository.ErrHistoryTruncated {
+		t.Errorf("Expected ErrHistoryTruncated after reopening, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// testCreateIDs tests that creates reject IDs in use and generate missing ones
+func testCreateIDs(t *testing.T, repo repository.VibeWorldRepository) {
+	err := repo.AddVibe(models.Vibe{ID: "calm-clarity", Name: "Impostor", Energy: 0.9})
+	var duplicate *repository.DuplicateIDError
+	if !errors.As(err, &duplicate) || duplicate.Kind != "vibe" || duplicate.ID != "calm-clarity" || !errors.Is(err, repository.ErrDuplicateID) {
+		t.Errorf("Expected a duplicate vibe error, got %v", err)
+	}
+	if vibe, _ := repo.GetVibe("calm-clarity"); vibe.Name == "Impostor" || vibe.Version != 1 {
+		t.Errorf("Expected calm-clarity to be kept, got %+v", vibe)
+	}
+	if err := repo.AddWorld(models.World{ID: "office-space", Name: "Impostor", Type: models.WorldTypeVirtual}); !errors.Is(err, repository.ErrDuplicateID) {
+		t.Errorf("Expected a duplicate world error, got %v", err)
+	}
+
+	// Missing IDs come from the name, with a suffix once the slug is taken
+	created, err := repo.CreateVibe(models.Vibe{Name: "Calm  Clarity!", Energy: 0.3})
+	if err != nil || created.ID != "calm-clarity-2" || created.Version != 1 {
+		t.Fatalf("Expected calm-clarity-2 at version 1, got %+v, %v", created, err)
+	}
+	if stored, err := repo.GetVibe("calm-clarity-2"); err != nil || stored.Name != "Calm  Clarity!" {
+		t.Errorf("Expected the created vibe to be stored, got %+v, %v", stored, err)
+	}
+	world, err := repo.CreateWorld(models.World{Name: "Office Space", Type: models.WorldTypePhysical, CurrentVibe: created.ID})
+	if err != nil || world.ID != "office-space-2" {
+		t.Errorf("Expected office-space-2, got %+v, %v", world, err)
+	}
+
+	// Generated IDs skip the trash, so tombstones stay restorable
+	if err := repo.DeleteWorld("office-space-2"); err != nil {
+		t.Fatalf("Error deleting world: %v", err)
+	}
+	world, err = repo.CreateWorld(models.World{Name: "Office Space", Type: models.WorldTypePhysical})
+	if err != nil || world.ID != "office-space-3" {
+		t.Errorf("Expected office-space-3, got %+v, %v", world, err)
+	}
+	if err := repo.RestoreWorld("office-space-2"); err != nil {
+		t.Errorf("Expected office-space-2 to be restorable, got %v", err)
+	}
+
+	// A name without letters or digits gets a UUID
+	unnamed, err := repo.CreateVibe(models.Vibe{Name: "✨", Energy: 0.5})
+	if err != nil || len(unnamed.ID) != 36 {
+		t.Errorf("Expected a UUID, got %+v, %v", unnamed, err)
+	}
+
+	// Names of resource URIs such as world://list are never generated
+	reserved, err := repo.CreateWorld(models.World{Name: "List", Type: models.WorldTypeVirtual})
+	if err != nil || reserved.ID != "list-2" {
+		t.Errorf("Expected list-2, got %+v, %v", reserved, err)
+	}
+
+	// Batch creates may leave the ID out and learn it from the result
+	ids, err := repo.ApplyBatch([]repository.BatchOp{
+		{Op: repository.BatchCreateVibe, Vibe: &models.Vibe{Name: "Batch Made", Energy: 0.5}},
+		{Op: repository.BatchCreateWorld, World: &models.World{Name: "Batch Made", Type: models.WorldTypeVirtual, CurrentVibe: "calm-clarity"}},
+		{Op: repository.BatchSetWorldVibe, WorldID: "office-space", VibeID: "calm-clarity"},
+	})
+	if err != nil || len(ids) != 3 || ids[0] != "batch-made" || ids[1] != "batch-made" || ids[2] != "" {
+		t.Errorf("Expected generated IDs for the creates only, got %q, %v", ids, err)
+	}
+
+	// So may the entities of an imported bundle
+	bundle := repository.NewBundle(
+		[]models.Vibe{{Name: "Imported", Energy: 0.5}},
+		[]models.World{{Name: "Imported", Type: models.WorldTypeVirtual}, {Name: "Imported", Type: models.WorldTypeVirtual}})
+	result, err := repo.ImportBundle(bundle, repository.ImportFail)
+	if err != nil {
+		t.Fatalf("Error importing bundle without IDs: %v", err)
+	}
+	if got := result.Vibes.GeneratedIDs; len(got) != 1 || got[0] != "imported" {
+		t.Errorf("Expected the imported vibe to get imported, got %q", got)
+	}
+	if got := result.Worlds.GeneratedIDs; result.Worlds.Created != 2 || len(got) != 2 || got[0] != "imported" || got[1] != "imported-2" {
+		t.Errorf("Expected two imported worlds, got %+v", result.Worlds)
+	}
+
+	// Batches reject duplicates too
+	_, err = repo.ApplyBatch([]repository.BatchOp{
+		{Op: repository.BatchCreateVibe, Vibe: &models.Vibe{ID: "fresh", Name: "Fresh", Energy: 0.5}},
+		{Op: repository.BatchCreateVibe, Vibe: &models.Vibe{ID: "fresh", Name: "Fresh again", Energy: 0.5}},
+	})
+	var batchErr *repository.BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 1 || !errors.Is(err, repository.ErrDuplicateID) {
+		t.Errorf("Expected operation 1 to be a duplicate, got %v", err)
+	}
+}
+
+// TestSlug tests how names become ID fragments
+func TestSlug(t *testing.T) {
+	cases := map[string]string{
+		"Calm Clarity":       "calm-clarity",
+		"  Deep -- Focus!  ": "deep-focus",
+		"Café 24/7":          "caf-24-7",
+		"✨":                  "",
+		"ALREADY-a-slug":     "already-a-slug",
+	}
+	for name, want := range cases {
+		if got := repository.Slug(name); got != want {
+			t.Errorf("Slug(%q) = %q, expected %q", name, got, want)
+		}
+	}
+}
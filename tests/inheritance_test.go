@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// testVibeInheritance tests that derived vibes resolve, and follow their parent
+func testVibeInheritance(t *testing.T, repo repository.VibeWorldRepository) {
+	base := models.Vibe{ID: "focus-base", Name: "Focused", Energy: 0.6, Mood: models.MoodFocused, Colors: []string{"#0000FF"}}
+	if err := repo.AddVibe(base); err != nil {
+		t.Fatalf("Error adding base vibe: %v", err)
+	}
+
+	// Without overrides, a new derived vibe overrides the fields it sets
+	warm, err := repo.CreateVibe(models.Vibe{ID: "ember-focus", ParentID: "focus-base", Colors: []string{"#FFAA00"}})
+	if err != nil {
+		t.Fatalf("Error creating derived vibe: %v", err)
+	}
+	if !reflect.DeepEqual(warm.Overrides, []string{"colors"}) || warm.Name != "Focused" || warm.Energy != 0.6 || warm.Colors[0] != "#FFAA00" {
+		t.Errorf("Expected the base values with warm colors, got %+v", warm)
+	}
+	low := models.Vibe{ID: "ember-low", ParentID: "ember-focus", Name: "Low Ember", Energy: 0, Overrides: []string{"name", "energy"}}
+	if err := repo.AddVibe(low); err != nil {
+		t.Fatalf("Error adding grandchild vibe: %v", err)
+	}
+
+	world := models.World{ID: "forge", Name: "Forge", Type: models.WorldTypeVirtual, CurrentVibe: "ember-focus"}
+	if err := repo.AddWorld(world); err != nil {
+		t.Fatalf("Error adding world: %v", err)
+	}
+	if effective, err := repo.GetWorldVibe("forge"); err != nil || effective.Mood != models.MoodFocused || effective.Colors[0] != "#FFAA00" {
+		t.Errorf("Expected the world to see the effective vibe, got %+v, %v", effective, err)
+	}
+
+	// Editing the base updates every derived vibe, as separate changes
+	events, stop := repo.SubscribeChanges(16)
+	defer stop()
+	base, _ = repo.GetVibe("focus-base")
+	base.Mood = models.MoodCalm
+	base.Energy = 0.8
+	if err := repo.UpdateVibe(base); err != nil {
+		t.Fatalf("Error updating base vibe: %v", err)
+	}
+	for _, id := range []string{"focus-base", "ember-focus", "ember-low"} {
+		if event := nextEvent(t, events); event.Type != repository.EventVibeChanged || event.ID != id || event.Vibe.Mood != models.MoodCalm {
+			t.Errorf("Expected a calm change to %s, got %s %s", id, event.Type, event.ID)
+		}
+	}
+	warm, _ = repo.GetVibe("ember-focus")
+	if warm.Energy != 0.8 || warm.Colors[0] != "#FFAA00" || warm.Version != 2 {
+		t.Errorf("Expected ember-focus at version 2 with the new energy, got %+v", warm)
+	}
+	lowStored, _ := repo.GetVibe("ember-low")
+	if lowStored.Energy != 0 || lowStored.Name != "Low Ember" || !reflect.DeepEqual(lowStored.Colors, []string{"#FFAA00"}) {
+		t.Errorf("Expected ember-low to keep its overrides, got %+v", lowStored)
+	}
+
+	// Updates keep the stored overrides unless given new ones
+	warm.Colors = []string{"#FF5500"}
+	warm.Overrides = nil
+	if err := repo.UpdateVibe(warm); err != nil {
+		t.Fatalf("Error updating derived vibe: %v", err)
+	}
+	if warm, _ = repo.GetVibe("ember-focus"); !reflect.DeepEqual(warm.Overrides, []string{"colors"}) || warm.Colors[0] != "#FF5500" {
+		t.Errorf("Expected the colors override to be kept, got %+v", warm)
+	}
+
+	if err := repo.DeleteVibe("focus-base"); err != repository.ErrVibeHasDerived {
+		t.Errorf("Expected ErrVibeHasDerived, got %v", err)
+	}
+	base, _ = repo.GetVibe("focus-base")
+	base.ParentID = "ember-low"
+	if err := repo.UpdateVibe(base); err != repository.ErrInheritanceCycle {
+		t.Errorf("Expected ErrInheritanceCycle, got %v", err)
+	}
+	if err := repo.AddVibe(models.Vibe{ID: "orphan", ParentID: "missing", Energy: 0.5}); err != repository.ErrVibeNotFound {
+		t.Errorf("Expected ErrVibeNotFound for a missing parent, got %v", err)
+	}
+	err = repo.AddVibe(models.Vibe{ID: "odd", ParentID: "focus-base", Overrides: []string{"creatorId"}})
+	if !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected an unknown override to fail validation, got %v", err)
+	}
+
+	// A restored derived vibe takes its parent's current values
+	if err := repo.DeleteVibe("ember-low"); err != nil {
+		t.Fatalf("Error deleting derived vibe: %v", err)
+	}
+	warm.Mood = models.MoodEnergetic
+	warm.Overrides = []string{"colors", "mood"}
+	if err := repo.UpdateVibe(warm); err != nil {
+		t.Fatalf("Error updating derived vibe: %v", err)
+	}
+	if err := repo.RestoreVibe("ember-low"); err != nil {
+		t.Fatalf("Error restoring derived vibe: %v", err)
+	}
+	if restored, _ := repo.GetVibe("ember-low"); restored.Mood != models.MoodEnergetic || restored.Colors[0] != "#FF5500" {
+		t.Errorf("Expected the restored vibe to inherit current values, got %+v", restored)
+	}
+
+	// Bundles list derived vibes before their parent by ID; import reorders them
+	bundle, err := repo.ExportBundle()
+	if err != nil {
+		t.Fatalf("Error exporting: %v", err)
+	}
+	target := repository.NewRepositoryWithSampleData(false)
+	if _, err := target.ImportBundle(bundle, repository.ImportFail); err != nil {
+		t.Fatalf("Error importing: %v", err)
+	}
+	imported, _ := target.GetVibe("ember-low")
+	if imported.ParentID != "ember-focus" || !reflect.DeepEqual(imported.Overrides, []string{"name", "energy"}) || imported.Mood != models.MoodEnergetic {
+		t.Errorf("Expected ember-low to be imported as derived, got %+v", imported)
+	}
+}
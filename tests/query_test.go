@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func vibeIDs(vibes []models.Vibe) []string {
+	ids := make([]string, len(vibes))
+	for i, vibe := range vibes {
+		ids[i] = vibe.ID
+	}
+	return ids
+}
+
+func worldIDs(worlds []models.World) []string {
+	ids := make([]string, len(worlds))
+	for i, world := range worlds {
+		ids[i] = world.ID
+	}
+	return ids
+}
+
+// testQueryVibes tests filtering and sorting vibes
+func testQueryVibes(t *testing.T, repo repository.VibeWorldRepository) {
+	err := repo.AddVibe(models.Vibe{
+		ID:        "public-glow",
+		Name:      "Public Glow",
+		Energy:    0.5,
+		Mood:      "calm",
+		CreatorID: "alice",
+		Sharing:   models.SharingSettings{IsPublic: true},
+	})
+	if err != nil {
+		t.Fatalf("Error adding vibe: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		query    repository.VibeQuery
+		expected []string
+	}{
+		{"All", repository.VibeQuery{}, []string{"calm-clarity", "energetic-spark", "focused-flow", "public-glow"}},
+		{"ByMood", repository.VibeQuery{Filter: repository.VibeFilter{Mood: "calm"}}, []string{"calm-clarity", "public-glow"}},
+		{"ByEnergyRange", repository.VibeQuery{Filter: repository.VibeFilter{MinEnergy: floatPtr(0.5), MaxEnergy: floatPtr(0.7)}}, []string{"focused-flow", "public-glow"}},
+		{"ByCreator", repository.VibeQuery{Filter: repository.VibeFilter{CreatorID: "alice"}}, []string{"public-glow"}},
+		{"Public", repository.VibeQuery{Filter: repository.VibeFilter{IsPublic: boolPtr(true)}}, []string{"public-glow"}},
+		{"Private", repository.VibeQuery{Filter: repository.VibeFilter{IsPublic: boolPtr(false)}}, []string{"calm-clarity", "energetic-spark", "focused-flow"}},
+		{"SortByName", repository.VibeQuery{ListOptions: repository.ListOptions{SortBy: repository.SortByName}}, []string{"calm-clarity", "energetic-spark", "focused-flow", "public-glow"}},
+		{"SortByEnergyDesc", repository.VibeQuery{ListOptions: repository.ListOptions{SortBy: repository.SortByEnergy, Descending: true}}, []string{"energetic-spark", "focused-flow", "public-glow", "calm-clarity"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := repo.QueryVibes(tc.query)
+			if err != nil {
+				t.Fatalf("Error querying vibes: %v", err)
+			}
+			if got := fmt.Sprint(vibeIDs(page.Items)); got != fmt.Sprint(tc.expected) {
+				t.Errorf("Expected %v, got %s", tc.expected, got)
+			}
+			if page.NextCursor != "" {
+				t.Errorf("Expected a single page, got cursor %q", page.NextCursor)
+			}
+		})
+	}
+
+	_, err = repo.QueryVibes(repository.VibeQuery{ListOptions: repository.ListOptions{SortBy: repository.SortByOccupancy}})
+	if !errors.Is(err, repository.ErrInvalidSort) {
+		t.Errorf("Expected ErrInvalidSort when sorting vibes by occupancy, got %v", err)
+	}
+}
+
+// testQueryWorlds tests filtering and sorting worlds
+func testQueryWorlds(t *testing.T, repo repository.VibeWorldRepository) {
+	err := repo.AddWorld(models.World{
+		ID:          "alice-lab",
+		Name:        "Alice's Lab",
+		Type:        models.WorldTypeVirtual,
+		CurrentVibe: "focused-flow",
+		CreatorID:   "alice",
+		Occupancy:   7,
+		Features:    []string{"spatial audio"},
+		Sharing:     models.SharingSettings{IsPublic: true},
+	})
+	if err != nil {
+		t.Fatalf("Error adding world: %v", err)
+	}
+	if err := repo.AddWorld(models.World{ID: "empty-room", Name: "Empty Room", Type: models.WorldTypePhysical, Occupancy: 3}); err != nil {
+		t.Fatalf("Error adding world: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		query    repository.WorldQuery
+		expected []string
+	}{
+		{"ByType", repository.WorldQuery{Filter: repository.WorldFilter{Type: models.WorldTypeVirtual}}, []string{"alice-lab", "virtual-garden"}},
+		{"ByMood", repository.WorldQuery{Filter: repository.WorldFilter{Mood: "focused"}}, []string{"alice-lab", "office-space"}},
+		{"ByEnergy", repository.WorldQuery{Filter: repository.WorldFilter{MinEnergy: floatPtr(0.8)}}, []string{"hybrid-studio"}},
+		{"ByCreator", repository.WorldQuery{Filter: repository.WorldFilter{CreatorID: "alice"}}, []string{"alice-lab"}},
+		{"ByFeature", repository.WorldQuery{Filter: repository.WorldFilter{Feature: "spatial audio"}}, []string{"alice-lab", "hybrid-studio"}},
+		{"Public", repository.WorldQuery{Filter: repository.WorldFilter{IsPublic: boolPtr(true)}}, []string{"alice-lab"}},
+		{"SortByName", repository.WorldQuery{ListOptions: repository.ListOptions{SortBy: repository.SortByName}}, []string{"alice-lab", "hybrid-studio", "empty-room", "office-space", "virtual-garden"}},
+		{"SortByEnergy", repository.WorldQuery{ListOptions: repository.ListOptions{SortBy: repository.SortByEnergy}}, []string{"empty-room", "virtual-garden", "alice-lab", "office-space", "hybrid-studio"}},
+		{"SortByOccupancyDesc", repository.WorldQuery{ListOptions: repository.ListOptions{SortBy: repository.SortByOccupancy, Descending: true}}, []string{"alice-lab", "empty-room", "virtual-garden", "office-space", "hybrid-studio"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := repo.QueryWorlds(tc.query)
+			if err != nil {
+				t.Fatalf("Error querying worlds: %v", err)
+			}
+			if got := fmt.Sprint(worldIDs(page.Items)); got != fmt.Sprint(tc.expected) {
+				t.Errorf("Expected %v, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+// testQueryPagination tests walking a listing page by page with cursors
+func testQueryPagination(t *testing.T, repo repository.VibeWorldRepository) {
+	for i := 0; i < 7; i++ {
+		err := repo.AddWorld(models.World{
+			ID:        fmt.Sprintf("room-%d", i),
+			Name:      fmt.Sprintf("Room %d", i),
+			Type:      models.WorldTypeVirtual,
+			Occupancy: i % 3, // Duplicate sort keys are broken by ID
+		})
+		if err != nil {
+			t.Fatalf("Error adding world: %v", err)
+		}
+	}
+
+	query := repository.WorldQuery{
+		Filter:      repository.WorldFilter{Type: models.WorldTypeVirtual},
+		ListOptions: repository.ListOptions{SortBy: repository.SortByOccupancy, Limit: 3},
+	}
+
+	var got []string
+	pages := 0
+	for {
+		page, err := repo.QueryWorlds(query)
+		if err != nil {
+			t.Fatalf("Error querying page %d: %v", pages, err)
+		}
+		pages++
+		got = append(got, worldIDs(page.Items)...)
+
+		// A world added mid-walk before the cursor must not shift later pages
+		if pages == 1 {
+			if err := repo.AddWorld(models.World{ID: "room-00", Type: models.WorldTypeVirtual}); err != nil {
+				t.Fatalf("Error adding world: %v", err)
+			}
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	expected := []string{"room-0", "room-3", "room-6", "virtual-garden", "room-1", "room-4", "room-2", "room-5"}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if pages != 3 {
+		t.Errorf("Expected 3 pages, got %d", pages)
+	}
+
+	// Cursors are bound to the ordering they were issued for
+	query.SortBy = repository.SortByName
+	if _, err := repo.QueryWorlds(query); !errors.Is(err, repository.ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor for a cursor from another ordering, got %v", err)
+	}
+
+	query.Cursor = "not-a-cursor"
+	if _, err := repo.QueryWorlds(query); !errors.Is(err, repository.ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
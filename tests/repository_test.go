@@ -11,10 +11,7 @@ import (
 	"github.com/bmorphism/vibespace-mcp-go/repository"
 )
 
-func TestRepository(t *testing.T) {
-	// Create a new repository
-	repo := repository.NewRepository()
-
+func testRepository(t *testing.T, repo repository.VibeWorldRepository) {
 	// Test GetAllVibes
 	vibes := repo.GetAllVibes()
 	if len(vibes) != 3 {
@@ -206,11 +203,8 @@ func TestRepository(t *testing.T) {
 	}
 }
 
-// TestSensorData tests the functionality for managing vibe sensor data
-func TestSensorData(t *testing.T) {
-	// Create a new repository
-	repo := repository.NewRepository()
-	
+// testSensorData tests the functionality for managing vibe sensor data
+func testSensorData(t *testing.T, repo repository.VibeWorldRepository) {
 	// Test 1: Create a vibe with sensor data
 	temperature := 22.5
 	humidity := 40.0
@@ -362,11 +356,8 @@ func TestSensorData(t *testing.T) {
 	}
 }
 
-// TestWorldFeatures tests the functionality for managing world features
-func TestWorldFeatures(t *testing.T) {
-	// Create a new repository
-	repo := repository.NewRepository()
-
+// testWorldFeatures tests the functionality for managing world features
+func testWorldFeatures(t *testing.T, repo repository.VibeWorldRepository) {
 	// Test 1: Create a world with multiple features
 	multiFeatureWorld := models.World{
 		ID:          "multi-feature-world",
@@ -519,11 +510,8 @@ func TestWorldFeatures(t *testing.T) {
 	}
 }
 
-// TestHybridWorlds tests the functionality specific to hybrid worlds
-func TestHybridWorlds(t *testing.T) {
-	// Create a new repository
-	repo := repository.NewRepository()
-
+// testHybridWorlds tests the functionality specific to hybrid worlds
+func testHybridWorlds(t *testing.T, repo repository.VibeWorldRepository) {
 	// Test 1: Create a new hybrid world with specific hybrid characteristics
 	hybridWorld := models.World{
 		ID:          "test-hybrid-world",
@@ -681,11 +669,8 @@ func TestHybridWorlds(t *testing.T) {
 	}
 }
 
-// TestConcurrency tests thread safety of the repository implementation
-func TestConcurrency(t *testing.T) {
-	// Create a new repository
-	repo := repository.NewRepository()
-	
+// testConcurrency tests thread safety of the repository implementation
+func testConcurrency(t *testing.T, repo repository.VibeWorldRepository) {
 	// Test 1: Concurrent reads of vibes
 	t.Run("ConcurrentVibeReads", func(t *testing.T) {
 		var wg sync.WaitGroup
@@ -998,7 +983,9 @@ func TestConcurrency(t *testing.T) {
 						if err != nil {
 							errorCh <- fmt.Errorf("high concurrency %d: error getting world for update: %v", id, err)
 						} else {
-							// Just update the world with the same data (non-destructive operation)
+							// Just update the world with the same data (non-destructive operation).
+							// Other goroutines write it too, so skip the version check.
+							world.Version = 0
 							err = repo.UpdateWorld(world)
 							if err != nil {
 								errorCh <- fmt.Errorf("high concurrency %d: error updating world: %v", id, err)
@@ -1022,11 +1009,8 @@ func TestConcurrency(t *testing.T) {
 	})
 }
 
-// TestIntegration performs end-to-end tests that combine multiple operations
-func TestIntegration(t *testing.T) {
-	// Create a new repository
-	repo := repository.NewRepository()
-	
+// testIntegration performs end-to-end tests that combine multiple operations
+func testIntegration(t *testing.T, repo repository.VibeWorldRepository) {
 	// Test 1: Complex vibe lifecycle
 	t.Run("ComplexVibeLifecycle", func(t *testing.T) {
 		// Step 1: Create a new vibe with sensor data
@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// testSensorChannels tests that vibes keep readings of channels beyond the
+// fixed sensor fields, and that those readings are validated
+func testSensorChannels(t *testing.T, repo repository.VibeWorldRepository) {
+	vibe := models.Vibe{ID: "stuffy", Name: "Stuffy", Energy: 0.2}
+	vibe.SensorData.Set(models.SensorTemperature, 24)
+	vibe.SensorData.Set(models.SensorCO2, 1400)
+	vibe.SensorData.Set(models.SensorNoiseLow, 38.5)
+	if err := repo.AddVibe(vibe); err != nil {
+		t.Fatalf("Error adding vibe: %v", err)
+	}
+
+	stored, err := repo.GetVibe("stuffy")
+	if err != nil {
+		t.Fatalf("Error getting vibe: %v", err)
+	}
+	if want := vibe.SensorData.Readings(); !reflect.DeepEqual(stored.SensorData.Readings(), want) {
+		t.Errorf("Expected readings %v, got %v", want, stored.SensorData.Readings())
+	}
+
+	// Updates replace the channels rather than merging them
+	stored.SensorData.Channels = map[string]float64{models.SensorAirQuality: 80}
+	if err := repo.UpdateVibe(stored); err != nil {
+		t.Fatalf("Error updating vibe: %v", err)
+	}
+	if updated, _ := repo.GetVibe("stuffy"); !reflect.DeepEqual(updated.SensorData.Channels, stored.SensorData.Channels) {
+		t.Errorf("Expected channels %v, got %v", stored.SensorData.Channels, updated.SensorData.Channels)
+	}
+
+	stored.SensorData.Channels = map[string]float64{models.SensorAirQuality: 800}
+	if err := repo.UpdateVibe(stored); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected an air quality above 500 to be rejected, got %v", err)
+	}
+}
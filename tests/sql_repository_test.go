@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteCounter gives every in-memory SQLite database a unique name
+var sqliteCounter int64
+
+// openTestSQLite opens a private in-memory SQLite database closed at the end of the test
+func openTestSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	name := fmt.Sprintf("vibespace-test-%d", atomic.AddInt64(&sqliteCounter, 1))
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?mode=memory&cache=shared&_pragma=foreign_keys(1)", name))
+	if err != nil {
+		t.Fatalf("Error opening sqlite: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestSQLRepositoryMigrations tests that migrations are recorded and applied only once
+func TestSQLRepositoryMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vibespace.db")
+	open := func() *sql.DB {
+		db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)")
+		if err != nil {
+			t.Fatalf("Error opening sqlite: %v", err)
+		}
+		db.SetMaxOpenConns(1)
+		return db
+	}
+
+	db := open()
+	repo, err := repository.NewSQLRepository(db)
+	if err != nil {
+		t.Fatalf("Error creating SQL repository: %v", err)
+	}
+
+	version, err := repository.SchemaVersion(db)
+	if err != nil {
+		t.Fatalf("Error reading schema version: %v", err)
+	}
+	if version < 1 {
+		t.Errorf("Expected schema version of at least 1, got %d", version)
+	}
+
+	temperature := 20.5
+	err = repo.AddVibe(models.Vibe{
+		ID:         "persisted",
+		Name:       "Persisted",
+		Colors:     []string{"#000000", "#FFFFFF"},
+		SensorData: models.SensorData{Temperature: &temperature},
+		Sharing:    models.SharingSettings{AllowedUsers: []string{"alice", "bob"}, ContextLevel: models.ContextLevelNone},
+	})
+	if err != nil {
+		t.Fatalf("Error adding vibe: %v", err)
+	}
+	db.Close()
+
+	// Reopening must not re-run migrations or lose data
+	db = open()
+	defer db.Close()
+	repo, err = repository.NewSQLRepository(db)
+	if err != nil {
+		t.Fatalf("Error reopening SQL repository: %v", err)
+	}
+
+	reopenedVersion, err := repository.SchemaVersion(db)
+	if err != nil {
+		t.Fatalf("Error reading schema version: %v", err)
+	}
+	if reopenedVersion != version {
+		t.Errorf("Expected schema version %d after reopen, got %d", version, reopenedVersion)
+	}
+
+	vibe, err := repo.GetVibe("persisted")
+	if err != nil {
+		t.Fatalf("Error getting vibe after reopen: %v", err)
+	}
+	if len(vibe.Colors) != 2 || vibe.Colors[1] != "#FFFFFF" {
+		t.Errorf("Expected colors to keep their order, got %v", vibe.Colors)
+	}
+	if vibe.SensorData.Temperature == nil || *vibe.SensorData.Temperature != temperature {
+		t.Errorf("Expected temperature %f, got %v", temperature, vibe.SensorData.Temperature)
+	}
+	if vibe.SensorData.Humidity != nil {
+		t.Errorf("Expected humidity to stay unset, got %v", *vibe.SensorData.Humidity)
+	}
+	if len(vibe.Sharing.AllowedUsers) != 2 || vibe.Sharing.ContextLevel != models.ContextLevelNone {
+		t.Errorf("Expected sharing settings to round-trip, got %+v", vibe.Sharing)
+	}
+
+	// A failing migration leaves the schema at the previous version
+	err = repository.Migrate(db, []repository.Migration{
+		{Version: version + 1, Name: "broken", Statements: []string{"CREATE TABLE broken (", "SELECT 1"}},
+	})
+	if err == nil {
+		t.Errorf("Expected broken migration to fail")
+	}
+	if current, _ := repository.SchemaVersion(db); current != version {
+		t.Errorf("Expected schema version %d after failed migration, got %d", version, current)
+	}
+}
+
+// TestSQLRepositoryFindWorlds tests filtering worlds in the database
+func TestSQLRepositoryFindWorlds(t *testing.T) {
+	repo, err := repository.NewSQLRepositoryWithSampleData(openTestSQLite(t), true)
+	if err != nil {
+		t.Fatalf("Error creating SQL repository: %v", err)
+	}
+
+	err = repo.AddWorld(models.World{
+		ID:          "alice-lab",
+		Name:        "Alice's Lab",
+		Type:        models.WorldTypeVirtual,
+		CurrentVibe: "calm-clarity",
+		CreatorID:   "alice",
+	})
+	if err != nil {
+		t.Fatalf("Error adding world: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		filter   repository.WorldFilter
+		expected []string
+	}{
+		{"ByType", repository.WorldFilter{Type: models.WorldTypeVirtual}, []string{"alice-lab", "virtual-garden"}},
+		{"ByMood", repository.WorldFilter{Mood: "energetic"}, []string{"hybrid-studio"}},
+		{"ByCreator", repository.WorldFilter{CreatorID: "alice"}, []string{"alice-lab"}},
+		{"Combined", repository.WorldFilter{Type: models.WorldTypeVirtual, Mood: "calm"}, []string{"alice-lab", "virtual-garden"}},
+		{"NoMatch", repository.WorldFilter{Type: models.WorldTypePhysical, CreatorID: "alice"}, []string{}},
+		{"All", repository.WorldFilter{}, []string{"alice-lab", "hybrid-studio", "office-space", "virtual-garden"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			worlds, err := repo.FindWorlds(tc.filter)
+			if err != nil {
+				t.Fatalf("Error finding worlds: %v", err)
+			}
+			if len(worlds) != len(tc.expected) {
+				t.Fatalf("Expected %d worlds, got %d", len(tc.expected), len(worlds))
+			}
+			for i, world := range worlds {
+				if world.ID != tc.expected[i] {
+					t.Errorf("Expected world %d to be %s, got %s", i, tc.expected[i], world.ID)
+				}
+			}
+		})
+	}
+}
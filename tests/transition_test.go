@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// testVibeTransitions tests that a world blends from its old vibe to its new one
+func testVibeTransitions(t *testing.T, repo repository.VibeWorldRepository) {
+	calm := models.Vibe{ID: "dawn-calm", Name: "Calm", Energy: 0.2, Mood: models.MoodCalm, Colors: []string{"#0000FF"}}
+	energetic := models.Vibe{ID: "dawn-energetic", Name: "Energetic", Energy: 0.9, Mood: models.MoodEnergetic, Colors: []string{"#FFFF00"}}
+	for _, vibe := range []models.Vibe{calm, energetic} {
+		if err := repo.AddVibe(vibe); err != nil {
+			t.Fatalf("Error adding vibe %s: %v", vibe.ID, err)
+		}
+	}
+	world := models.World{ID: "dawn-room", Name: "Dawn Room", Type: models.WorldTypeVirtual, CurrentVibe: "dawn-calm"}
+	if err := repo.AddWorld(world); err != nil {
+		t.Fatalf("Error adding world: %v", err)
+	}
+
+	// A stale version is rejected before the transition starts
+	_, err := repo.TransitionWorldVibe("dawn-room", "dawn-energetic", time.Minute, 7)
+	if !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("Expected a version conflict, got %v", err)
+	}
+
+	stored, err := repo.TransitionWorldVibe("dawn-room", "dawn-energetic", time.Minute, 1)
+	if err != nil {
+		t.Fatalf("Error starting transition: %v", err)
+	}
+	if stored.Version != 2 {
+		t.Errorf("Expected the stored world at version 2, got %d", stored.Version)
+	}
+	world, _ = repo.GetWorld("dawn-room")
+	if world.CurrentVibe != "dawn-energetic" || world.Transition == nil || world.Transition.FromVibe != "dawn-calm" || world.Transition.Duration != 60000 {
+		t.Fatalf("Expected a one minute transition from dawn-calm, got %+v", world)
+	}
+	start := time.UnixMilli(world.Transition.StartedAt)
+
+	// The blend starts at the old vibe, passes through a mix, and ends at the new one
+	if vibe, err := repo.BlendedWorldVibe("dawn-room", start); err != nil || vibe.ID != "dawn-calm" {
+		t.Errorf("Expected the old vibe at the start, got %+v, %v", vibe, err)
+	}
+	mid, err := repo.BlendedWorldVibe("dawn-room", start.Add(30*time.Second))
+	if err != nil {
+		t.Fatalf("Error blending world vibe: %v", err)
+	}
+	if mid.ID != "dawn-calm+dawn-energetic" || mid.Energy < 0.54 || mid.Energy > 0.56 || mid.Colors[0] == calm.Colors[0] {
+		t.Errorf("Expected an even blend halfway, got %+v", mid)
+	}
+	if vibe, err := repo.BlendedWorldVibe("dawn-room", start.Add(time.Minute)); err != nil || vibe.ID != "dawn-energetic" {
+		t.Errorf("Expected the new vibe at the end, got %+v, %v", vibe, err)
+	}
+
+	// Updates keep the transition; an immediate change ends it
+	world.Name = "Sunrise Room"
+	if err := repo.UpdateWorld(world); err != nil {
+		t.Fatalf("Error updating world: %v", err)
+	}
+	if world, _ = repo.GetWorld("dawn-room"); world.Transition == nil {
+		t.Errorf("Expected an update to keep the transition")
+	}
+	if err := repo.SetWorldVibe("dawn-room", "dawn-calm"); err != nil {
+		t.Fatalf("Error setting world vibe: %v", err)
+	}
+	if world, _ = repo.GetWorld("dawn-room"); world.Transition != nil {
+		t.Errorf("Expected an immediate change to end the transition, got %+v", world.Transition)
+	}
+
+	// A transition whose old vibe is deleted ends at once
+	if _, err := repo.TransitionWorldVibe("dawn-room", "dawn-energetic", time.Hour, 0); err != nil {
+		t.Fatalf("Error starting transition: %v", err)
+	}
+	if err := repo.DeleteVibe("dawn-calm"); err != nil {
+		t.Fatalf("Error deleting vibe: %v", err)
+	}
+	if vibe, err := repo.BlendedWorldVibe("dawn-room", time.Now()); err != nil || vibe.ID != "dawn-energetic" {
+		t.Errorf("Expected the new vibe without the old one, got %+v, %v", vibe, err)
+	}
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// testTrash tests soft deletion, restoring and purging
+func testTrash(t *testing.T, repo repository.VibeWorldRepository) {
+	// Deleting a vibe in use is still rejected
+	if err := repo.DeleteVibe("focused-flow"); err != repository.ErrVibeInUse {
+		t.Fatalf("Expected ErrVibeInUse, got %v", err)
+	}
+
+	if err := repo.WithActor("alice").DeleteWorld("office-space"); err != nil {
+		t.Fatalf("Error deleting world: %v", err)
+	}
+	if err := repo.DeleteVibe("focused-flow"); err != nil {
+		t.Fatalf("Error deleting vibe no longer in use: %v", err)
+	}
+	if _, err := repo.GetWorld("office-space"); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected deleted world to be gone, got %v", err)
+	}
+
+	deletedWorlds := repo.DeletedWorlds()
+	if len(deletedWorlds) != 1 || deletedWorlds[0].ID != "office-space" || deletedWorlds[0].DeletedBy != "alice" {
+		t.Fatalf("Expected office-space in the trash, deleted by alice, got %+v", deletedWorlds)
+	}
+	if deletedWorlds[0].Name != "Modern Office" || deletedWorlds[0].DeletedAt.IsZero() {
+		t.Errorf("Expected the full world with its deletion time, got %+v", deletedWorlds[0])
+	}
+	if deletedVibes := repo.DeletedVibes(); len(deletedVibes) != 1 || deletedVibes[0].ID != "focused-flow" {
+		t.Fatalf("Expected focused-flow in the trash, got %+v", deletedVibes)
+	}
+
+	// The world cannot come back pointing at a deleted vibe
+	if err := repo.RestoreWorld("office-space"); err != repository.ErrVibeNotFound {
+		t.Fatalf("Expected ErrVibeNotFound while the vibe is deleted, got %v", err)
+	}
+	if err := repo.RestoreVibe("focused-flow"); err != nil {
+		t.Fatalf("Error restoring vibe: %v", err)
+	}
+	if err := repo.WithActor("bob").RestoreWorld("office-space"); err != nil {
+		t.Fatalf("Error restoring world: %v", err)
+	}
+
+	world, err := repo.GetWorld("office-space")
+	if err != nil || world.CurrentVibe != "focused-flow" || world.Version != 2 {
+		t.Errorf("Expected the restored world at version 2 with its vibe, got %+v (%v)", world, err)
+	}
+	if len(repo.DeletedWorlds()) != 0 || len(repo.DeletedVibes()) != 0 {
+		t.Errorf("Expected an empty trash after restoring")
+	}
+	if err := repo.RestoreWorld("office-space"); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected ErrWorldNotFound restoring a world that is not in the trash, got %v", err)
+	}
+
+	entries, _ := repo.WorldHistory("office-space")
+	if last := entries[len(entries)-1]; last.Op != repository.HistoryRestore || last.Actor != "bob" {
+		t.Errorf("Expected a restore by bob in the history, got %s by %q", last.Op, last.Actor)
+	}
+
+	// Creating an entity with a deleted ID discards the tombstone
+	repo.DeleteWorld("virtual-garden")
+	repo.AddWorld(models.World{ID: "virtual-garden", Name: "New Garden", Type: models.WorldTypeVirtual})
+	if len(repo.DeletedWorlds()) != 0 {
+		t.Errorf("Expected re-creating a world to discard its tombstone")
+	}
+
+	// Expired tombstones are hidden, cannot be restored and are purged
+	repo.DeleteWorld("hybrid-studio")
+	repo.SetTrashRetention(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if len(repo.DeletedWorlds()) != 0 {
+		t.Errorf("Expected expired tombstones to be hidden")
+	}
+	if err := repo.RestoreWorld("hybrid-studio"); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected ErrWorldNotFound restoring an expired world, got %v", err)
+	}
+	purged, err := repo.PurgeTrash()
+	if err != nil || purged != 1 {
+		t.Errorf("Expected one tombstone purged, got %d (%v)", purged, err)
+	}
+
+	// Zero retention keeps tombstones forever
+	repo.SetTrashRetention(0)
+	repo.DeleteWorld("virtual-garden")
+	time.Sleep(time.Millisecond)
+	if purged, _ := repo.PurgeTrash(); purged != 0 || len(repo.DeletedWorlds()) != 1 {
+		t.Errorf("Expected tombstones to be kept without retention")
+	}
+}
+
+// TestFileRepositoryTrash tests that the trash survives restarts and compaction
+func TestFileRepositoryTrash(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error opening file repository: %v", err)
+	}
+	repo.SetCompactEvery(0)
+
+	repo.AddVibe(models.Vibe{ID: "compacted", Name: "Compacted"})
+	repo.AddVibe(models.Vibe{ID: "journaled", Name: "Journaled"})
+	repo.DeleteVibe("compacted")
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("Error compacting: %v", err)
+	}
+	repo.DeleteVibe("journaled")
+	repo.Close()
+
+	reopened, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error reopening file repository: %v", err)
+	}
+	defer reopened.Close()
+
+	if deleted := reopened.DeletedVibes(); len(deleted) != 2 {
+		t.Fatalf("Expected two vibes in the trash, got %+v", deleted)
+	}
+	if err := reopened.RestoreVibe("compacted"); err != nil {
+		t.Errorf("Error restoring vibe from the snapshot: %v", err)
+	}
+	if err := reopened.RestoreVibe("journaled"); err != nil {
+		t.Errorf("Error restoring vibe from the journal: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// testValidation tests that every write rejects invalid vibes and worlds
+func testValidation(t *testing.T, repo repository.VibeWorldRepository) {
+	events, stop := repo.SubscribeChanges(16)
+	defer stop()
+
+	badVibe := models.Vibe{ID: "bad-vibe", Name: "Bad", Energy: 7.3, Colors: []string{"not-a-color"}}
+	badWorld := models.World{ID: "bad-world", Name: "Bad", Type: "banana"}
+
+	if err := repo.AddVibe(badVibe); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected AddVibe to fail validation, got %v", err)
+	}
+	if _, err := repo.GetVibe("bad-vibe"); err != repository.ErrVibeNotFound {
+		t.Errorf("Expected the invalid vibe not to be stored, got %v", err)
+	}
+	if err := repo.AddWorld(badWorld); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected AddWorld to fail validation, got %v", err)
+	}
+
+	vibe, _ := repo.GetVibe("calm-clarity")
+	vibe.Mood = "grumpy"
+	err := repo.UpdateVibe(vibe)
+	var invalid *models.ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Fields) != 1 || invalid.Fields[0].Field != "mood" {
+		t.Errorf("Expected UpdateVibe to report the mood, got %v", err)
+	}
+	world, _ := repo.GetWorld("office-space")
+	world.Occupancy = -4
+	if err := repo.UpdateWorld(world); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected UpdateWorld to fail validation, got %v", err)
+	}
+	if stored, _ := repo.GetWorld("office-space"); stored.Occupancy == -4 || stored.Version != 1 {
+		t.Errorf("Expected office-space unchanged, got %+v", stored)
+	}
+
+	// Batches and imports go through the same checks
+	_, err = repo.ApplyBatch([]repository.BatchOp{
+		{Op: repository.BatchCreateVibe, Vibe: &models.Vibe{ID: "fine", Name: "Fine", Energy: 0.5}},
+		{Op: repository.BatchCreateWorld, World: &badWorld},
+	})
+	var batchErr *repository.BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 1 || !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected operation 1 to fail validation, got %v", err)
+	}
+	bundle := repository.NewBundle([]models.Vibe{badVibe}, nil)
+	if _, err := repo.ImportBundle(bundle, repository.ImportOverwrite); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected ImportBundle to fail validation, got %v", err)
+	}
+	if _, err := repo.GetVibe("fine"); err != repository.ErrVibeNotFound {
+		t.Errorf("Expected nothing stored by failed writes, got %v", err)
+	}
+
+	select {
+	case event := <-events:
+		t.Errorf("Expected no events for rejected writes, got %s", event.Type)
+	default:
+	}
+}
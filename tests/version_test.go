@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmorphism/vibespace-mcp-go/models"
+	"github.com/bmorphism/vibespace-mcp-go/repository"
+)
+
+// testOptimisticConcurrency tests versioning and compare-and-swap updates
+func testOptimisticConcurrency(t *testing.T, repo repository.VibeWorldRepository) {
+	if err := repo.AddVibe(models.Vibe{ID: "cas-vibe", Name: "CAS Vibe", Version: 42}); err != nil {
+		t.Fatalf("Error adding vibe: %v", err)
+	}
+	vibe, _ := repo.GetVibe("cas-vibe")
+	if vibe.Version != 1 {
+		t.Errorf("Expected a new vibe to start at version 1, got %d", vibe.Version)
+	}
+
+	// Two clients read the same version; the second write must be rejected
+	first, second := vibe, vibe
+	first.Name = "First Writer"
+	second.Name = "Second Writer"
+	if err := repo.UpdateVibe(first); err != nil {
+		t.Fatalf("Error updating vibe: %v", err)
+	}
+	err := repo.UpdateVibe(second)
+	var conflict *repository.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a ConflictError, got %v", err)
+	}
+	if conflict.Kind != "vibe" || conflict.Expected != 1 || conflict.Actual != 2 {
+		t.Errorf("Unexpected conflict details: %+v", conflict)
+	}
+	if !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("Expected errors.Is(err, ErrVersionConflict)")
+	}
+	vibe, _ = repo.GetVibe("cas-vibe")
+	if vibe.Name != "First Writer" || vibe.Version != 2 {
+		t.Errorf("Expected first write at version 2 to win, got %q at version %d", vibe.Name, vibe.Version)
+	}
+
+	// Re-reading and retrying succeeds
+	vibe.Name = "Second Writer"
+	if err := repo.UpdateVibe(vibe); err != nil {
+		t.Errorf("Expected retry with current version to succeed, got %v", err)
+	}
+
+	// Version zero keeps last-write-wins semantics
+	if err := repo.UpdateVibe(models.Vibe{ID: "cas-vibe", Name: "Blind Write"}); err != nil {
+		t.Errorf("Expected unversioned update to succeed, got %v", err)
+	}
+	vibe, _ = repo.GetVibe("cas-vibe")
+	if vibe.Version != 4 {
+		t.Errorf("Expected version 4, got %d", vibe.Version)
+	}
+
+	// Saving returns the vibe as stored, versioned or not
+	saved, err := repo.SaveVibe(models.Vibe{ID: "cas-vibe", Name: "Saved Write"})
+	if err != nil || saved.Version != 5 || saved.Name != "Saved Write" {
+		t.Errorf("Expected the saved vibe at version 5, got %+v, %v", saved, err)
+	}
+	if _, err := repo.SaveVibe(models.Vibe{ID: "cas-vibe", Name: "Stale Save", Version: 4}); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("Expected conflict for stale save, got %v", err)
+	}
+
+	// Worlds
+	if err := repo.AddWorld(models.World{ID: "cas-world", Name: "CAS World", Type: models.WorldTypeVirtual}); err != nil {
+		t.Fatalf("Error adding world: %v", err)
+	}
+	world, _ := repo.GetWorld("cas-world")
+	stale := world
+
+	world.Occupancy = 5
+	if err := repo.UpdateWorld(world); err != nil {
+		t.Fatalf("Error updating world: %v", err)
+	}
+	stale.Occupancy = 9
+	if err := repo.UpdateWorld(stale); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("Expected conflict for stale world update, got %v", err)
+	}
+
+	// SetWorldVibe bumps the version and honours the expected version
+	if err := repo.SetWorldVibeIfVersion("cas-world", "cas-vibe", stale.Version); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("Expected conflict for stale SetWorldVibe, got %v", err)
+	}
+	if err := repo.SetWorldVibeIfVersion("cas-world", "cas-vibe", 2); err != nil {
+		t.Fatalf("Error setting world vibe: %v", err)
+	}
+	if err := repo.SetWorldVibe("cas-world", "cas-vibe"); err != nil {
+		t.Fatalf("Error setting world vibe: %v", err)
+	}
+	world, _ = repo.GetWorld("cas-world")
+	if world.Version != 4 || world.Occupancy != 5 || world.CurrentVibe != "cas-vibe" {
+		t.Errorf("Unexpected world after updates: version %d, occupancy %d, vibe %q", world.Version, world.Occupancy, world.CurrentVibe)
+	}
+	world.Version = 0
+	if saved, err := repo.SaveWorld(world); err != nil || saved.Version != 5 {
+		t.Errorf("Expected the saved world at version 5, got %+v, %v", saved, err)
+	}
+
+	// A conflict takes precedence over missing references and changes nothing
+	stale.CurrentVibe = "missing-vibe"
+	if err := repo.UpdateWorld(stale); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("Expected conflict, got %v", err)
+	}
+	if err := repo.SetWorldVibeIfVersion("missing-world", "cas-vibe", 1); err != repository.ErrWorldNotFound {
+		t.Errorf("Expected ErrWorldNotFound, got %v", err)
+	}
+}
+
+// TestFileRepositoryVersions tests that versions survive a restart
+func TestFileRepositoryVersions(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error opening file repository: %v", err)
+	}
+	repo.AddVibe(models.Vibe{ID: "versioned", Name: "Versioned"})
+	repo.UpdateVibe(models.Vibe{ID: "versioned", Name: "Versioned Again"})
+	repo.Close()
+
+	reopened, err := repository.NewFileRepository(dir)
+	if err != nil {
+		t.Fatalf("Error reopening file repository: %v", err)
+	}
+	defer reopened.Close()
+
+	vibe, err := reopened.GetVibe("versioned")
+	if err != nil {
+		t.Fatalf("Error getting vibe: %v", err)
+	}
+	if vibe.Version != 2 {
+		t.Errorf("Expected version 2 after reopen, got %d", vibe.Version)
+	}
+	if err := reopened.UpdateVibe(models.Vibe{ID: "versioned", Version: 1}); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("Expected conflict against replayed version, got %v", err)
+	}
+}